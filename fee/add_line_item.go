@@ -4,65 +4,165 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"time"
 
 	"encore.app/fee/model"
 	"encore.app/fee/utils"
 	temporal "encore.app/fee/workflow"
 	"encore.dev/beta/errs"
 	"encore.dev/rlog"
-	"go.temporal.io/api/serviceerror"
 )
 
+const maxExternalRefLength = 128
+
 type AddLineItemParams struct {
 	Amount         int64  `json:"amount"`
+	PriceID        string `json:"price_id"` // when set, the amount is taken from the catalog
+	Quantity       int64  `json:"quantity"` // used with price_id, defaults to 1
 	Description    string `json:"description"`
+	ExternalRef    string `json:"external_ref"` // caller's own transaction or event ID, unique per bill
 	IdempotencyKey string `header:"X-Idempotency-Key"`
 }
 
 type AddLineItemResponse struct {
 	LineItemID  string `json:"line_item_id"`
 	Amount      int64  `json:"amount"`
+	PriceID     string `json:"price_id,omitempty"`
+	Quantity    int64  `json:"quantity,omitempty"`
 	BillID      string `json:"bill_id"`
 	Description string `json:"description"`
+	ExternalRef string `json:"external_ref,omitempty"`
+	Duplicate   bool   `json:"duplicate,omitempty"` // the external_ref was already billed, nothing was added
+	Queued      bool   `json:"queued,omitempty"`    // the bill is scheduled, the item is added when it starts
+	Total       int64  `json:"total,omitempty"`     // running total of the bill after this line item
 	WorkflowID  string `json:"workflow_id"`
 }
 
-//encore:api public method=POST path=/api/bills/:billID/line-items tag:idempotency
+// AddLineItem adds a line item through a workflow update and waits until it is persisted,
+// so the response carries the bill's new running total. Rejections by the bill, such as a
+// subscription policy or a bill that is closing, are returned as errors.
+//
+//encore:api auth method=POST path=/api/bills/:billID/line-items tag:idempotency tag:scope_line_items_write
 func (s *Service) AddLineItem(ctx context.Context, billID string, params *AddLineItemParams) (*AddLineItemResponse, error) {
-	if params.Amount <= 0 {
-		return nil, &errs.Error{
-			Code:    errs.InvalidArgument,
-			Message: "amount must be positive",
-		}
+	signal, err := s.newLineItemSignal(ctx, billID, params)
+	if err != nil {
+		return nil, err
 	}
+	workflowID := temporal.BillCycleWorkflowID(requestTenant(), billID)
+
+	original, err := s.findLineItemByExternalRef(ctx, billID, params.ExternalRef)
+	if err != nil {
+		return nil, err
+	}
+	if original != nil {
+		return &AddLineItemResponse{
+			LineItemID:  original.LineItemID,
+			Amount:      original.Amount,
+			BillID:      billID,
+			ExternalRef: params.ExternalRef,
+			Duplicate:   true,
+			WorkflowID:  workflowID,
+		}, nil
+	}
+
+	var result temporal.AddLineItemUpdateResult
+	if err := s.updateBill(ctx, billID, temporal.AddLineItemUpdate, signal, &result); err != nil {
+		return nil, err
+	}
+
+	return &AddLineItemResponse{
+		LineItemID:  result.LineItemID,
+		Amount:      result.Amount,
+		PriceID:     params.PriceID,
+		Quantity:    params.Quantity,
+		BillID:      billID,
+		Description: params.Description,
+		ExternalRef: params.ExternalRef,
+		Duplicate:   result.Duplicate,
+		Queued:      result.Queued,
+		Total:       result.Total,
+		WorkflowID:  workflowID,
+	}, nil
+}
+
+// newLineItemSignal validates a line item request and resolves its amount, either
+// from the raw amount or from the catalog price.
+func (s *Service) newLineItemSignal(ctx context.Context, billID string, params *AddLineItemParams) (temporal.AddLineItemSignalRequest, error) {
 	signal := temporal.AddLineItemSignalRequest{
 		LineItemID: utils.UUID(),
 		Amount:     params.Amount,
 		BillID:     billID,
+		Actor:      requestActor(""),
+		RequestID:  requestID(),
 	}
-	if params.Description != "" {
+	if params.PriceID != "" {
+		if params.Amount != 0 {
+			return signal, &errs.Error{
+				Code:    errs.InvalidArgument,
+				Message: "amount must not be provided together with price_id",
+			}
+		}
+		if params.Quantity < 0 {
+			return signal, &errs.Error{
+				Code:    errs.InvalidArgument,
+				Message: "quantity must be positive",
+			}
+		}
+		if params.Quantity == 0 {
+			params.Quantity = 1
+		}
+		price, err := s.resolvePrice(ctx, params.PriceID, model.PriceTypeOneTime, time.Now())
+		if err != nil {
+			return signal, err
+		}
+		signal.Amount = price.UnitAmount * params.Quantity
+		signal.Currency = price.Currency
+		signal.Metadata = &model.LineItemMetadata{
+			Description: params.Description,
+			PriceID:     price.PriceID,
+			Quantity:    params.Quantity,
+			UnitAmount:  price.UnitAmount,
+		}
+	} else if params.Description != "" {
 		signal.Metadata = &model.LineItemMetadata{Description: params.Description}
 	}
-	workflowID := temporal.BillCycleWorkflowID(billID)
+	if signal.Amount <= 0 {
+		return signal, &errs.Error{
+			Code:    errs.InvalidArgument,
+			Message: "amount must be positive",
+		}
+	}
+	if params.ExternalRef != "" {
+		if len(params.ExternalRef) > maxExternalRefLength {
+			return signal, &errs.Error{
+				Code:    errs.InvalidArgument,
+				Message: fmt.Sprintf("external_ref must not be longer than %d characters", maxExternalRefLength),
+			}
+		}
+		// Retries with the same external_ref get the same line item ID, even if they race. The key is
+		// scoped like the usage event one, the external_ref segment keeps the two apart.
+		signal.LineItemID = utils.NameUUID(requestTenant() + "/" + billID + "/external_ref/" + params.ExternalRef)
+		if signal.Metadata == nil {
+			signal.Metadata = &model.LineItemMetadata{}
+		}
+		signal.Metadata.ExternalRef = params.ExternalRef
+	}
+	return signal, nil
+}
 
-	err := s.client.SignalWorkflow(ctx, workflowID, "", temporal.AddLineItemSignal, signal)
+// findLineItemByExternalRef returns the line item already billed for externalRef, or nil if there is none.
+func (s *Service) findLineItemByExternalRef(ctx context.Context, billID, externalRef string) (*model.LineItem, error) {
+	if externalRef == "" {
+		return nil, nil
+	}
+	lineItem, err := s.db.GetLineItemByExternalRef(ctx, requestTenant(), billID, externalRef)
 	if err != nil {
-		var notFound *serviceerror.NotFound
-		if errors.As(err, &notFound) || errors.Is(err, sql.ErrNoRows) {
-			return nil, &errs.Error{
-				Code:    errs.NotFound,
-				Message: "bill not found or already closed",
-			}
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
 		}
-		rlog.Error("failed to signal add line item workflow", "error", err)
+		rlog.Error("failed to get line item by external ref", "error", err, "bill_id", billID)
 		return nil, err
 	}
-
-	return &AddLineItemResponse{
-		LineItemID:  signal.LineItemID,
-		Amount:      params.Amount,
-		BillID:      billID,
-		Description: params.Description,
-		WorkflowID:  workflowID,
-	}, nil
+	return lineItem, nil
 }
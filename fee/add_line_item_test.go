@@ -2,13 +2,31 @@ package fee
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"testing"
 
+	"encore.app/fee/model"
+	"encore.app/fee/utils"
 	temporal "encore.app/fee/workflow"
+	"encore.dev/beta/errs"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"go.temporal.io/sdk/client"
+	temporalsdk "go.temporal.io/sdk/temporal"
 )
 
+// matchAddLineItemUpdate matches an add line item update whose request satisfies fn.
+func matchAddLineItemUpdate(billID string, fn func(temporal.AddLineItemSignalRequest) bool) interface{} {
+	return mock.MatchedBy(func(options client.UpdateWorkflowOptions) bool {
+		if options.WorkflowID != temporal.BillCycleWorkflowID(model.DefaultTenant, billID) || options.UpdateName != temporal.AddLineItemUpdate {
+			return false
+		}
+		signal, ok := options.Args[0].(temporal.AddLineItemSignalRequest)
+		return ok && fn(signal)
+	})
+}
+
 func TestAddLineItem(t *testing.T) {
 	mockTemporalClient := &mockTemporalClient{}
 
@@ -24,21 +42,159 @@ func TestAddLineItem(t *testing.T) {
 
 	// Mock expectations
 	mockTemporalClient.On(
-		"SignalWorkflow",
+		"UpdateWorkflow",
 		mock.Anything, // context
-		temporal.BillCycleWorkflowID(billID),
-		"",
-		temporal.AddLineItemSignal,
-		mock.Anything,
-	).Return(nil)
+		matchAddLineItemUpdate(billID, func(signal temporal.AddLineItemSignalRequest) bool { return signal.Amount == 100 }),
+	).Return(&mockUpdateHandle{result: temporal.AddLineItemUpdateResult{LineItemID: "line-item-1", Amount: 100, Total: 350}}, nil)
 
 	resp, err := service.AddLineItem(context.Background(), billID, params)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, resp)
+	assert.Equal(t, "line-item-1", resp.LineItemID)
 	assert.Equal(t, params.Amount, resp.Amount)
+	assert.Equal(t, int64(350), resp.Total)
 	assert.Equal(t, billID, resp.BillID)
 	assert.Equal(t, params.Description, resp.Description)
 
 	mockTemporalClient.AssertExpectations(t)
 }
+
+func TestAddLineItem_Rejected(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantCode errs.ErrCode
+	}{
+		{
+			name:     "policy forbids line items",
+			err:      temporalsdk.NewApplicationError("line items are not allowed on subscription bills", temporal.ErrTypePolicyForbidden),
+			wantCode: errs.FailedPrecondition,
+		},
+		{
+			name:     "bill is closing",
+			err:      temporalsdk.NewApplicationError("bill is closing", temporal.ErrTypeBillClosing),
+			wantCode: errs.FailedPrecondition,
+		},
+		{
+			name:     "currency mismatch",
+			err:      temporalsdk.NewApplicationError("line item currency does not match bill currency", temporal.ErrTypeInvalidLineItem),
+			wantCode: errs.InvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _, mockTemporalClient := setup(t)
+			mockTemporalClient.On("UpdateWorkflow", mock.Anything, mock.Anything).Return(&mockUpdateHandle{err: tt.err}, nil).Once()
+
+			_, err := service.AddLineItem(context.Background(), "test-bill-id", &AddLineItemParams{Amount: 100})
+
+			var errsErr *errs.Error
+			assert.True(t, errors.As(err, &errsErr))
+			assert.Equal(t, tt.wantCode, errsErr.Code)
+		})
+	}
+}
+
+func TestAddLineItem_WithPrice(t *testing.T) {
+	service, mockDB, mockTemporalClient := setup(t)
+
+	billID := "test-bill-id"
+	params := &AddLineItemParams{
+		PriceID:  "api-calls-usd",
+		Quantity: 3,
+	}
+	price := &model.Price{
+		PriceID:    params.PriceID,
+		Currency:   "USD",
+		Type:       string(model.PriceTypeOneTime),
+		Active:     true,
+		UnitAmount: 25,
+	}
+
+	mockDB.On("GetPrice", mock.Anything, model.DefaultTenant, params.PriceID, mock.Anything).Return(price, nil).Once()
+	mockTemporalClient.On(
+		"UpdateWorkflow",
+		mock.Anything,
+		matchAddLineItemUpdate(billID, func(signal temporal.AddLineItemSignalRequest) bool {
+			return signal.Amount == 75 && signal.Currency == "USD" && signal.Metadata.PriceID == params.PriceID
+		}),
+	).Return(&mockUpdateHandle{result: temporal.AddLineItemUpdateResult{LineItemID: "line-item-1", Amount: 75, Total: 75}}, nil)
+
+	resp, err := service.AddLineItem(context.Background(), billID, params)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(75), resp.Amount)
+	assert.Equal(t, int64(3), resp.Quantity)
+
+	mockDB.AssertExpectations(t)
+	mockTemporalClient.AssertExpectations(t)
+}
+
+func TestAddLineItem_WithPriceAndAmount(t *testing.T) {
+	service, _, _ := setup(t)
+
+	_, err := service.AddLineItem(context.Background(), "test-bill-id", &AddLineItemParams{
+		PriceID: "api-calls-usd",
+		Amount:  100,
+	})
+
+	var errsErr *errs.Error
+	assert.True(t, errors.As(err, &errsErr))
+	assert.Equal(t, errs.InvalidArgument, errsErr.Code)
+}
+
+func TestAddLineItem_ExternalRef(t *testing.T) {
+	service, mockDB, mockTemporalClient := setup(t)
+
+	billID := "test-bill-id"
+	params := &AddLineItemParams{
+		Amount:      100,
+		ExternalRef: "txn-123",
+	}
+
+	var lineItemIDs []string
+	mockDB.On("GetLineItemByExternalRef", mock.Anything, model.DefaultTenant, billID, "txn-123").Return(nil, sql.ErrNoRows).Twice()
+	mockTemporalClient.On(
+		"UpdateWorkflow",
+		mock.Anything,
+		matchAddLineItemUpdate(billID, func(signal temporal.AddLineItemSignalRequest) bool {
+			return signal.Metadata.ExternalRef == "txn-123"
+		}),
+	).Run(func(args mock.Arguments) {
+		signal := args.Get(1).(client.UpdateWorkflowOptions).Args[0].(temporal.AddLineItemSignalRequest)
+		lineItemIDs = append(lineItemIDs, signal.LineItemID)
+	}).Return(&mockUpdateHandle{}, nil).Twice()
+
+	_, err := service.AddLineItem(context.Background(), billID, params)
+	assert.NoError(t, err)
+
+	// A retry that races the first call is sent with the same line item ID.
+	_, err = service.AddLineItem(context.Background(), billID, params)
+	assert.NoError(t, err)
+	assert.Len(t, lineItemIDs, 2)
+	assert.Equal(t, lineItemIDs[0], lineItemIDs[1])
+	assert.Equal(t, utils.NameUUID(model.DefaultTenant+"/"+billID+"/external_ref/txn-123"), lineItemIDs[0])
+
+	mockDB.AssertExpectations(t)
+	mockTemporalClient.AssertExpectations(t)
+}
+
+func TestAddLineItem_DuplicateExternalRef(t *testing.T) {
+	service, mockDB, mockTemporalClient := setup(t)
+
+	billID := "test-bill-id"
+	original := &model.LineItem{LineItemID: "original-line-item", Amount: 100}
+	mockDB.On("GetLineItemByExternalRef", mock.Anything, model.DefaultTenant, billID, "txn-123").Return(original, nil).Once()
+
+	resp, err := service.AddLineItem(context.Background(), billID, &AddLineItemParams{
+		Amount:      100,
+		ExternalRef: "txn-123",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "original-line-item", resp.LineItemID)
+	assert.True(t, resp.Duplicate)
+	mockTemporalClient.AssertNotCalled(t, "UpdateWorkflow", mock.Anything, mock.Anything)
+}
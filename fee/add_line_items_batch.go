@@ -0,0 +1,122 @@
+package fee
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	temporal "encore.app/fee/workflow"
+	"encore.dev/beta/errs"
+	"encore.dev/rlog"
+	"go.temporal.io/api/serviceerror"
+)
+
+// maxBatchLineItems caps a batch so the signal payload stays well below Temporal's limits.
+const maxBatchLineItems = 100
+
+const (
+	BatchItemAccepted = "ACCEPTED"
+	BatchItemRejected = "REJECTED"
+)
+
+type AddLineItemsBatchParams struct {
+	Items          []AddLineItemParams `json:"items"`
+	IdempotencyKey string              `header:"X-Idempotency-Key"`
+}
+
+type BatchLineItemResult struct {
+	Index      int    `json:"index"`
+	Status     string `json:"status"`
+	LineItemID string `json:"line_item_id,omitempty"`
+	Amount     int64  `json:"amount,omitempty"`
+	Duplicate  bool   `json:"duplicate,omitempty"` // the external_ref was already billed, nothing was added
+	Error      string `json:"error,omitempty"`
+}
+
+type AddLineItemsBatchResponse struct {
+	BillID     string                `json:"bill_id"`
+	WorkflowID string                `json:"workflow_id"`
+	Accepted   int                   `json:"accepted"`
+	Rejected   int                   `json:"rejected"`
+	Results    []BatchLineItemResult `json:"results"`
+}
+
+// AddLineItemsBatch validates up to maxBatchLineItems line items and delivers the valid ones
+// to the bill workflow as a single signal. Invalid items are reported per index and skipped.
+// Encore reserves ':' for path parameters, so the batch method is a sub-path of line-items.
+//
+//encore:api auth method=POST path=/api/bills/:billID/line-items/batch tag:idempotency tag:scope_line_items_write
+func (s *Service) AddLineItemsBatch(ctx context.Context, billID string, params *AddLineItemsBatchParams) (*AddLineItemsBatchResponse, error) {
+	if len(params.Items) == 0 {
+		return nil, &errs.Error{
+			Code:    errs.InvalidArgument,
+			Message: "items must not be empty",
+		}
+	}
+	if len(params.Items) > maxBatchLineItems {
+		return nil, &errs.Error{
+			Code:    errs.InvalidArgument,
+			Message: fmt.Sprintf("items must not contain more than %d line items", maxBatchLineItems),
+		}
+	}
+
+	workflowID := temporal.BillCycleWorkflowID(requestTenant(), billID)
+	resp := &AddLineItemsBatchResponse{
+		BillID:     billID,
+		WorkflowID: workflowID,
+		Results:    make([]BatchLineItemResult, len(params.Items)),
+	}
+	signal := temporal.AddLineItemsSignalRequest{BillID: billID, Actor: requestActor(""), RequestID: requestID()}
+	refs := make(map[string]int)
+	for i := range params.Items {
+		item, err := s.newLineItemSignal(ctx, billID, &params.Items[i])
+		if err != nil {
+			var errsErr *errs.Error
+			if !errors.As(err, &errsErr) {
+				return nil, err
+			}
+			resp.Results[i] = BatchLineItemResult{Index: i, Status: BatchItemRejected, Error: errsErr.Message}
+			resp.Rejected++
+			continue
+		}
+		if ref := params.Items[i].ExternalRef; ref != "" {
+			if first, ok := refs[ref]; ok {
+				resp.Results[i] = BatchLineItemResult{Index: i, Status: BatchItemRejected, Error: fmt.Sprintf("external_ref duplicates item %d", first)}
+				resp.Rejected++
+				continue
+			}
+			refs[ref] = i
+			original, err := s.findLineItemByExternalRef(ctx, billID, ref)
+			if err != nil {
+				return nil, err
+			}
+			if original != nil {
+				resp.Results[i] = BatchLineItemResult{Index: i, Status: BatchItemAccepted, LineItemID: original.LineItemID, Amount: original.Amount, Duplicate: true}
+				resp.Accepted++
+				continue
+			}
+		}
+		resp.Results[i] = BatchLineItemResult{Index: i, Status: BatchItemAccepted, LineItemID: item.LineItemID, Amount: item.Amount}
+		resp.Accepted++
+		signal.Items = append(signal.Items, item)
+	}
+	if len(signal.Items) == 0 {
+		return resp, nil
+	}
+
+	err := s.client.SignalWorkflow(ctx, workflowID, "", temporal.AddLineItemsSignal, signal)
+	if err != nil {
+		var notFound *serviceerror.NotFound
+		if errors.As(err, &notFound) || errors.Is(err, sql.ErrNoRows) {
+			return nil, &errs.Error{
+				Code:    errs.NotFound,
+				Message: "bill not found or already closed",
+			}
+		}
+		rlog.Error("failed to signal add line items workflow", "error", err)
+		return nil, err
+	}
+
+	return resp, nil
+}
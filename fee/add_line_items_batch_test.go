@@ -0,0 +1,63 @@
+package fee
+
+import (
+	"context"
+	"encore.app/fee/model"
+	"errors"
+	"testing"
+
+	temporal "encore.app/fee/workflow"
+	"encore.dev/beta/errs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAddLineItemsBatch(t *testing.T) {
+	service, _, mockTemporalClient := setup(t)
+
+	billID := "test-bill-id"
+	params := &AddLineItemsBatchParams{
+		Items: []AddLineItemParams{
+			{Amount: 100, Description: "first"},
+			{Amount: -5, Description: "invalid"},
+			{Amount: 300, Description: "third"},
+		},
+	}
+
+	mockTemporalClient.On(
+		"SignalWorkflow",
+		mock.Anything,
+		temporal.BillCycleWorkflowID(model.DefaultTenant, billID),
+		"",
+		temporal.AddLineItemsSignal,
+		mock.MatchedBy(func(signal temporal.AddLineItemsSignalRequest) bool {
+			return len(signal.Items) == 2 && signal.Items[0].Amount == 100 && signal.Items[1].Amount == 300
+		}),
+	).Return(nil).Once()
+
+	resp, err := service.AddLineItemsBatch(context.Background(), billID, params)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, resp.Accepted)
+	assert.Equal(t, 1, resp.Rejected)
+	assert.Len(t, resp.Results, 3)
+	assert.Equal(t, BatchItemAccepted, resp.Results[0].Status)
+	assert.NotEmpty(t, resp.Results[0].LineItemID)
+	assert.Equal(t, BatchItemRejected, resp.Results[1].Status)
+	assert.Equal(t, "amount must be positive", resp.Results[1].Error)
+	assert.Equal(t, 2, resp.Results[2].Index)
+
+	mockTemporalClient.AssertExpectations(t)
+}
+
+func TestAddLineItemsBatch_TooManyItems(t *testing.T) {
+	service, _, _ := setup(t)
+
+	params := &AddLineItemsBatchParams{Items: make([]AddLineItemParams, maxBatchLineItems+1)}
+
+	_, err := service.AddLineItemsBatch(context.Background(), "test-bill-id", params)
+
+	var errsErr *errs.Error
+	assert.True(t, errors.As(err, &errsErr))
+	assert.Equal(t, errs.InvalidArgument, errsErr.Code)
+}
@@ -0,0 +1,218 @@
+package fee
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"encore.app/fee/model"
+	"encore.app/fee/utils"
+	"encore.dev/beta/auth"
+	"encore.dev/beta/errs"
+	"encore.dev/rlog"
+)
+
+const (
+	defaultRotationGrace = 24 * time.Hour
+	maxRotationGrace     = 30 * 24 * time.Hour
+)
+
+type IssueAPIKeyParams struct {
+	IdempotencyKey string   `header:"X-Idempotency-Key"`
+	Name           string   `json:"name"`
+	Scopes         []string `json:"scopes"` // bills:read, bills:write, line_items:write, bills:close or admin
+	// TenantID issues the key for another tenant, only the bootstrap key may set it.
+	// Keys are issued for the caller's tenant by default.
+	TenantID string `json:"tenant_id"`
+}
+
+func (p *IssueAPIKeyParams) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("name is a required field")
+	}
+	if len(p.Name) > 128 {
+		return fmt.Errorf("name must be at most 128 characters")
+	}
+	if len(p.Scopes) == 0 {
+		return fmt.Errorf("scopes is a required field")
+	}
+	for _, s := range p.Scopes {
+		if _, err := model.ToScope(s); err != nil {
+			return err
+		}
+	}
+	if p.TenantID != "" {
+		return model.ValidateTenantID(p.TenantID)
+	}
+	return nil
+}
+
+type RotateAPIKeyParams struct {
+	IdempotencyKey string `header:"X-Idempotency-Key"`
+	// GracePeriod keeps the old key working while clients switch over, 24h by default.
+	GracePeriod utils.Duration `json:"grace_period"`
+}
+
+func (p *RotateAPIKeyParams) Validate() error {
+	if p.GracePeriod.Duration == 0 {
+		p.GracePeriod.Duration = defaultRotationGrace
+	}
+	if p.GracePeriod.Duration < 0 || p.GracePeriod.Duration > maxRotationGrace {
+		return fmt.Errorf("grace_period must be between 0s and 720h")
+	}
+	return nil
+}
+
+// IssuedAPIKey is a new API key. The key is only ever returned here.
+type IssuedAPIKey struct {
+	APIKey *model.APIKey `json:"api_key"`
+	Key    string        `json:"key"`
+}
+
+type ListAPIKeysParams struct {
+	IncludeRevoked bool `query:"include_revoked"`
+}
+
+type ListAPIKeysResponse struct {
+	APIKeys []*model.APIKey `json:"api_keys"`
+}
+
+// IssueAPIKey creates an API key with the given scopes for the caller's tenant. The bootstrap
+// key onboards new tenants by issuing their first admin key.
+//
+//encore:api auth method=POST path=/api/admin/api-keys tag:idempotency tag:scope_admin
+func (s *Service) IssueAPIKey(ctx context.Context, params *IssueAPIKeyParams) (*IssuedAPIKey, error) {
+	if err := params.Validate(); err != nil {
+		return nil, &errs.Error{
+			Code:    errs.InvalidArgument,
+			Message: err.Error(),
+		}
+	}
+
+	tenantID := requestTenant()
+	if params.TenantID != "" && params.TenantID != tenantID {
+		if principal, _ := auth.Data().(*AuthData); principal == nil || principal.KeyID != bootstrapKeyID {
+			return nil, &errs.Error{
+				Code:    errs.PermissionDenied,
+				Message: "only the bootstrap key may issue keys for another tenant",
+			}
+		}
+		tenantID = params.TenantID
+	}
+
+	keyID, key, err := newAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	apiKey := &model.APIKey{
+		KeyID:     keyID,
+		Name:      params.Name,
+		KeyHash:   hashAPIKey(key),
+		CreatedBy: requestActor(""),
+	}
+	for _, s := range params.Scopes {
+		apiKey.Scopes = append(apiKey.Scopes, model.Scope(s))
+	}
+	if err := s.db.CreateAPIKey(ctx, tenantID, apiKey); err != nil {
+		rlog.Error("failed to create api key", "error", err)
+		return nil, err
+	}
+	return &IssuedAPIKey{APIKey: apiKey, Key: key}, nil
+}
+
+// ListAPIKeys lists the API keys of the caller's tenant, without their secrets.
+//
+//encore:api auth method=GET path=/api/admin/api-keys tag:scope_admin
+func (s *Service) ListAPIKeys(ctx context.Context, params *ListAPIKeysParams) (*ListAPIKeysResponse, error) {
+	keys, err := s.db.ListAPIKeys(ctx, requestTenant(), params.IncludeRevoked)
+	if err != nil {
+		rlog.Error("failed to list api keys", "error", err)
+		return nil, err
+	}
+	if keys == nil {
+		keys = []*model.APIKey{}
+	}
+	return &ListAPIKeysResponse{APIKeys: keys}, nil
+}
+
+// RotateAPIKey issues a replacement with the same name and scopes. The old key keeps working
+// for the grace period.
+//
+//encore:api auth method=POST path=/api/admin/api-keys/:keyID/rotate tag:idempotency tag:scope_admin
+func (s *Service) RotateAPIKey(ctx context.Context, keyID string, params *RotateAPIKeyParams) (*IssuedAPIKey, error) {
+	if err := params.Validate(); err != nil {
+		return nil, &errs.Error{
+			Code:    errs.InvalidArgument,
+			Message: err.Error(),
+		}
+	}
+
+	tenantID := requestTenant()
+	old, err := s.db.GetAPIKey(ctx, tenantID, keyID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apiKeyNotFound()
+		}
+		rlog.Error("failed to get api key", "error", err, "key_id", keyID)
+		return nil, err
+	}
+	if !old.Active(time.Now()) {
+		return nil, &errs.Error{
+			Code:    errs.FailedPrecondition,
+			Message: "api key is revoked or expired",
+		}
+	}
+
+	nextID, key, err := newAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	next := &model.APIKey{
+		KeyID:       nextID,
+		Name:        old.Name,
+		KeyHash:     hashAPIKey(key),
+		Scopes:      old.Scopes,
+		CreatedBy:   requestActor(""),
+		RotatedFrom: old.KeyID,
+	}
+	if err := s.db.RotateAPIKey(ctx, tenantID, next, time.Now().Add(params.GracePeriod.Duration)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &errs.Error{
+				Code:    errs.FailedPrecondition,
+				Message: "api key is revoked or expired",
+			}
+		}
+		rlog.Error("failed to rotate api key", "error", err, "key_id", keyID)
+		return nil, err
+	}
+	return &IssuedAPIKey{APIKey: next, Key: key}, nil
+}
+
+// RevokeAPIKey stops an API key from authenticating at once.
+//
+//encore:api auth method=DELETE path=/api/admin/api-keys/:keyID tag:scope_admin
+func (s *Service) RevokeAPIKey(ctx context.Context, keyID string) (*model.APIKey, error) {
+	tenantID := requestTenant()
+	if err := s.db.RevokeAPIKey(ctx, tenantID, keyID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apiKeyNotFound()
+		}
+		rlog.Error("failed to revoke api key", "error", err, "key_id", keyID)
+		return nil, err
+	}
+	key, err := s.db.GetAPIKey(ctx, tenantID, keyID)
+	if err != nil {
+		rlog.Error("failed to get api key", "error", err, "key_id", keyID)
+		return nil, err
+	}
+	return key, nil
+}
+
+func apiKeyNotFound() error {
+	return &errs.Error{
+		Code:    errs.NotFound,
+		Message: "api key not found or already revoked",
+	}
+}
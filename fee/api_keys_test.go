@@ -0,0 +1,115 @@
+package fee
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"encore.app/fee/model"
+	"encore.app/fee/utils"
+	"encore.dev/beta/errs"
+	"encore.dev/et"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestIssueAPIKey(t *testing.T) {
+	service, mockDB, _ := setup(t)
+
+	mockDB.On("CreateAPIKey", mock.Anything, model.DefaultTenant, mock.MatchedBy(func(key *model.APIKey) bool {
+		return key.Name == "billing-worker" && len(key.Scopes) == 2 && key.KeyHash != ""
+	})).Return(nil).Once()
+
+	resp, err := service.IssueAPIKey(context.Background(), &IssueAPIKeyParams{
+		Name:   "billing-worker",
+		Scopes: []string{"bills:read", "line_items:write"},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, hashAPIKey(resp.Key), resp.APIKey.KeyHash)
+	keyID, ok := parseAPIKey(resp.Key)
+	assert.True(t, ok)
+	assert.Equal(t, resp.APIKey.KeyID, keyID)
+	mockDB.AssertExpectations(t)
+}
+
+func TestIssueAPIKey_InvalidScope(t *testing.T) {
+	service, mockDB, _ := setup(t)
+
+	_, err := service.IssueAPIKey(context.Background(), &IssueAPIKeyParams{Name: "worker", Scopes: []string{"bills:delete"}})
+
+	var errsErr *errs.Error
+	assert.True(t, errors.As(err, &errsErr))
+	assert.Equal(t, errs.InvalidArgument, errsErr.Code)
+	mockDB.AssertNotCalled(t, "CreateAPIKey", mock.Anything, model.DefaultTenant, mock.Anything)
+}
+
+func TestIssueAPIKey_OtherTenant(t *testing.T) {
+	service, mockDB, _ := setup(t)
+	et.OverrideAuthInfo("0123456789abcdef", &AuthData{KeyID: "0123456789abcdef", TenantID: "acme", Scopes: []model.Scope{model.ScopeAdmin}})
+
+	_, err := service.IssueAPIKey(context.Background(), &IssueAPIKeyParams{Name: "worker", Scopes: []string{"admin"}, TenantID: "globex"})
+
+	var errsErr *errs.Error
+	assert.True(t, errors.As(err, &errsErr))
+	assert.Equal(t, errs.PermissionDenied, errsErr.Code)
+	mockDB.AssertNotCalled(t, "CreateAPIKey", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestIssueAPIKey_BootstrapOnboardsTenant(t *testing.T) {
+	service, mockDB, _ := setup(t)
+	et.OverrideAuthInfo(bootstrapKeyID, &AuthData{KeyID: bootstrapKeyID, TenantID: model.DefaultTenant, Scopes: []model.Scope{model.ScopeAdmin}})
+
+	mockDB.On("CreateAPIKey", mock.Anything, "globex", mock.Anything).Return(nil).Once()
+
+	_, err := service.IssueAPIKey(context.Background(), &IssueAPIKeyParams{Name: "globex-admin", Scopes: []string{"admin"}, TenantID: "globex"})
+
+	assert.NoError(t, err)
+	mockDB.AssertExpectations(t)
+}
+
+func TestRotateAPIKey(t *testing.T) {
+	service, mockDB, _ := setup(t)
+
+	old := &model.APIKey{KeyID: "0123456789abcdef", Name: "billing-worker", Scopes: []model.Scope{model.ScopeBillsRead}}
+	mockDB.On("GetAPIKey", mock.Anything, model.DefaultTenant, old.KeyID).Return(old, nil).Once()
+	mockDB.On("RotateAPIKey", mock.Anything, model.DefaultTenant, mock.MatchedBy(func(next *model.APIKey) bool {
+		return next.RotatedFrom == old.KeyID && next.Name == old.Name && next.KeyID != old.KeyID
+	}), mock.MatchedBy(func(expiresAt time.Time) bool {
+		return time.Until(expiresAt) > 59*time.Minute && time.Until(expiresAt) <= time.Hour
+	})).Return(nil).Once()
+
+	resp, err := service.RotateAPIKey(context.Background(), old.KeyID, &RotateAPIKeyParams{GracePeriod: utils.Duration{Duration: time.Hour}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Scope{model.ScopeBillsRead}, resp.APIKey.Scopes)
+	mockDB.AssertExpectations(t)
+}
+
+func TestRotateAPIKey_Revoked(t *testing.T) {
+	service, mockDB, _ := setup(t)
+
+	revokedAt := time.Now()
+	mockDB.On("GetAPIKey", mock.Anything, model.DefaultTenant, "0123456789abcdef").Return(&model.APIKey{KeyID: "0123456789abcdef", RevokedAt: &revokedAt}, nil).Once()
+
+	_, err := service.RotateAPIKey(context.Background(), "0123456789abcdef", &RotateAPIKeyParams{})
+
+	var errsErr *errs.Error
+	assert.True(t, errors.As(err, &errsErr))
+	assert.Equal(t, errs.FailedPrecondition, errsErr.Code)
+	mockDB.AssertNotCalled(t, "RotateAPIKey", mock.Anything, model.DefaultTenant, mock.Anything, mock.Anything)
+}
+
+func TestRevokeAPIKey_NotFound(t *testing.T) {
+	service, mockDB, _ := setup(t)
+
+	mockDB.On("RevokeAPIKey", mock.Anything, model.DefaultTenant, "missing").Return(sql.ErrNoRows).Once()
+
+	_, err := service.RevokeAPIKey(context.Background(), "missing")
+
+	var errsErr *errs.Error
+	assert.True(t, errors.As(err, &errsErr))
+	assert.Equal(t, errs.NotFound, errsErr.Code)
+}
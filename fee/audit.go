@@ -0,0 +1,151 @@
+package fee
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"encore.app/fee/model"
+	"encore.dev"
+	"encore.dev/beta/errs"
+	"encore.dev/rlog"
+)
+
+type ListBillAuditParams struct {
+	Limit  int    `query:"limit"`
+	Cursor string `query:"cursor"`
+}
+
+// SearchAuditParams filters the audit log of the tenant. All filters are optional and combine
+// with AND, the time range is [from, to) as RFC 3339 timestamps.
+type SearchAuditParams struct {
+	BillID    string   `query:"bill_id"`
+	Event     []string `query:"event"` // one or more, also comma separated; all events when empty
+	Actor     string   `query:"actor"`
+	RequestID string   `query:"request_id"`
+	From      string   `query:"from"`
+	To        string   `query:"to"`
+	Limit     int      `query:"limit"`
+	Cursor    string   `query:"cursor"`
+}
+
+type AuditResponse struct {
+	Entries    []*model.AuditEntry `json:"entries"`
+	HasMore    bool                `json:"has_more"`
+	NextCursor string              `json:"next_cursor,omitempty"` // pass as cursor, with the same filters, for the next page
+}
+
+const auditCursorKind = "audit"
+
+// auditCursor is the signed cursor of an audit list, entries are read in the order they were written.
+type auditCursor struct {
+	Seq int64 `json:"seq"`
+}
+
+// ListBillAudit returns the financial events of a bill in the order they happened.
+//
+//encore:api auth method=GET path=/api/bills/:billID/audit tag:scope_bills_read
+func (s *Service) ListBillAudit(ctx context.Context, billID string, params *ListBillAuditParams) (*AuditResponse, error) {
+	exists, err := s.db.IsBillExists(ctx, requestTenant(), billID)
+	if err != nil {
+		rlog.Error("failed in checking bill exists in db", "error", err)
+		return nil, err
+	}
+	if !exists {
+		return nil, &errs.Error{
+			Code:    errs.NotFound,
+			Message: "bill not found",
+		}
+	}
+	return s.listAudit(ctx, model.AuditFilter{BillID: billID}, params.Limit, params.Cursor)
+}
+
+// SearchAudit searches the audit log of every bill of the tenant, for compliance reviews.
+//
+//encore:api auth method=GET path=/api/admin/audit tag:scope_admin
+func (s *Service) SearchAudit(ctx context.Context, params *SearchAuditParams) (*AuditResponse, error) {
+	filter, err := params.filter()
+	if err != nil {
+		return nil, &errs.Error{
+			Code:    errs.InvalidArgument,
+			Message: err.Error(),
+		}
+	}
+	return s.listAudit(ctx, filter, params.Limit, params.Cursor)
+}
+
+// filter validates the filter parameters.
+func (p *SearchAuditParams) filter() (model.AuditFilter, error) {
+	filter := model.AuditFilter{BillID: p.BillID, Actor: p.Actor, RequestID: p.RequestID}
+	for _, value := range p.Event {
+		for _, e := range strings.Split(value, ",") {
+			if e = strings.TrimSpace(e); e == "" {
+				continue
+			}
+			event, err := model.ToAuditEvent(strings.ToUpper(e))
+			if err != nil {
+				return filter, fmt.Errorf("invalid event")
+			}
+			filter.Events = append(filter.Events, event)
+		}
+	}
+	var err error
+	if filter.From, err = parseTimeParam("from", p.From); err != nil {
+		return filter, err
+	}
+	if filter.To, err = parseTimeParam("to", p.To); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+func (s *Service) listAudit(ctx context.Context, filter model.AuditFilter, limit int, cursorParam string) (*AuditResponse, error) {
+	if limit == 0 {
+		limit = 50
+	}
+	if limit < 0 || limit > 500 {
+		return nil, &errs.Error{
+			Code:    errs.InvalidArgument,
+			Message: "limit must be between 1 and 500",
+		}
+	}
+	var cursor auditCursor
+	if cursorParam != "" {
+		if err := decodeCursor(auditCursorKind, cursorParam, &cursor); err != nil {
+			return nil, &errs.Error{
+				Code:    errs.InvalidArgument,
+				Message: err.Error(),
+			}
+		}
+	}
+
+	entries, hasMore, err := s.db.ListAuditEntries(ctx, requestTenant(), filter, limit, cursor.Seq)
+	if err != nil {
+		rlog.Error("failed to list audit entries", "error", err)
+		return nil, err
+	}
+	if entries == nil {
+		entries = []*model.AuditEntry{}
+	}
+	resp := &AuditResponse{Entries: entries, HasMore: hasMore}
+	if hasMore {
+		resp.NextCursor = encodeCursor(auditCursorKind, auditCursor{Seq: entries[len(entries)-1].Seq})
+	}
+	return resp, nil
+}
+
+// requestID identifies the request behind a change in the audit log: the caller's X-Request-ID
+// when it sent one, the trace ID otherwise.
+func requestID() string {
+	req := encore.CurrentRequest()
+	if id := req.Headers.Get("X-Request-ID"); id != "" {
+		if len(id) > 128 {
+			id = id[:128]
+		}
+		return id
+	}
+	if req.Trace != nil {
+		return req.Trace.TraceID
+	}
+	return ""
+}
@@ -0,0 +1,101 @@
+package fee
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"encore.app/fee/model"
+	"encore.dev/beta/errs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestListBillAudit(t *testing.T) {
+	service, mockDB, _ := setup(t)
+	billID := "test-bill-id"
+	entries := []*model.AuditEntry{
+		{Seq: 1, BillID: billID, Event: model.AuditBillCreated, Actor: "key:abc", After: json.RawMessage(`{"status":"OPEN"}`)},
+		{Seq: 4, BillID: billID, LineItemID: "li-1", Event: model.AuditLineItemAdded, Actor: "key:abc", After: json.RawMessage(`{"amount":100}`)},
+	}
+
+	mockDB.On("IsBillExists", mock.Anything, model.DefaultTenant, billID).Return(true, nil).Once()
+	mockDB.On("ListAuditEntries", mock.Anything, model.DefaultTenant, model.AuditFilter{BillID: billID}, 2, int64(0)).Return(entries, true, nil).Once()
+
+	resp, err := service.ListBillAudit(context.Background(), billID, &ListBillAuditParams{Limit: 2})
+
+	assert.NoError(t, err)
+	assert.Len(t, resp.Entries, 2)
+	assert.True(t, resp.HasMore)
+	assert.NotEmpty(t, resp.NextCursor)
+
+	// The next page starts after the last entry of this one.
+	mockDB.On("IsBillExists", mock.Anything, model.DefaultTenant, billID).Return(true, nil).Once()
+	mockDB.On("ListAuditEntries", mock.Anything, model.DefaultTenant, model.AuditFilter{BillID: billID}, 2, int64(4)).Return([]*model.AuditEntry{}, false, nil).Once()
+
+	next, err := service.ListBillAudit(context.Background(), billID, &ListBillAuditParams{Limit: 2, Cursor: resp.NextCursor})
+
+	assert.NoError(t, err)
+	assert.Empty(t, next.Entries)
+	assert.False(t, next.HasMore)
+	assert.Empty(t, next.NextCursor)
+	mockDB.AssertExpectations(t)
+}
+
+func TestListBillAudit_BillNotFound(t *testing.T) {
+	service, mockDB, _ := setup(t)
+
+	mockDB.On("IsBillExists", mock.Anything, model.DefaultTenant, "missing").Return(false, nil).Once()
+
+	_, err := service.ListBillAudit(context.Background(), "missing", &ListBillAuditParams{})
+
+	var errsErr *errs.Error
+	assert.True(t, errors.As(err, &errsErr))
+	assert.Equal(t, errs.NotFound, errsErr.Code)
+	mockDB.AssertExpectations(t)
+}
+
+func TestListBillAudit_InvalidCursor(t *testing.T) {
+	service, mockDB, _ := setup(t)
+
+	mockDB.On("IsBillExists", mock.Anything, model.DefaultTenant, "test-bill-id").Return(true, nil).Once()
+
+	_, err := service.ListBillAudit(context.Background(), "test-bill-id", &ListBillAuditParams{Cursor: "tampered.cursor"})
+
+	var errsErr *errs.Error
+	assert.True(t, errors.As(err, &errsErr))
+	assert.Equal(t, errs.InvalidArgument, errsErr.Code)
+	mockDB.AssertNotCalled(t, "ListAuditEntries", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSearchAudit(t *testing.T) {
+	service, mockDB, _ := setup(t)
+
+	mockDB.On("ListAuditEntries", mock.Anything, model.DefaultTenant, mock.MatchedBy(func(filter model.AuditFilter) bool {
+		return filter.Actor == "key:abc" && filter.From != nil && filter.To == nil &&
+			len(filter.Events) == 2 && filter.Events[0] == model.AuditBillClosed && filter.Events[1] == model.AuditLineItemVoided
+	}), 50, int64(0)).Return([]*model.AuditEntry{{Seq: 7, Event: model.AuditBillClosed}}, false, nil).Once()
+
+	resp, err := service.SearchAudit(context.Background(), &SearchAuditParams{
+		Event: []string{"bill_closed,LINE_ITEM_VOIDED"},
+		Actor: "key:abc",
+		From:  "2025-01-01T00:00:00Z",
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, resp.Entries, 1)
+	assert.False(t, resp.HasMore)
+	mockDB.AssertExpectations(t)
+}
+
+func TestSearchAudit_InvalidEvent(t *testing.T) {
+	service, mockDB, _ := setup(t)
+
+	_, err := service.SearchAudit(context.Background(), &SearchAuditParams{Event: []string{"BILL_DELETED"}})
+
+	var errsErr *errs.Error
+	assert.True(t, errors.As(err, &errsErr))
+	assert.Equal(t, errs.InvalidArgument, errsErr.Code)
+	mockDB.AssertNotCalled(t, "ListAuditEntries", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
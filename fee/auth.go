@@ -0,0 +1,164 @@
+package fee
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"encore.app/fee/model"
+	"encore.dev/beta/auth"
+	"encore.dev/beta/errs"
+	"encore.dev/middleware"
+	"encore.dev/rlog"
+)
+
+// AuthData is the principal of an authenticated request. Everything the request reads or
+// changes belongs to its tenant.
+type AuthData struct {
+	KeyID    string        `json:"key_id"`
+	TenantID string        `json:"tenant_id"`
+	Name     string        `json:"name"`
+	Scopes   []model.Scope `json:"scopes"`
+}
+
+// Allows tells whether the principal carries the scope.
+func (d *AuthData) Allows(scope model.Scope) bool {
+	return (&model.APIKey{Scopes: d.Scopes}).Allows(scope)
+}
+
+// bootstrapKeyID is the principal of the BootstrapAdminKey secret.
+const bootstrapKeyID = "bootstrap"
+
+// apiKeyPrefix starts every API key, the key is fee_<key_id>_<secret>.
+const apiKeyPrefix = "fee_"
+
+// AuthHandler authenticates requests by the API key sent as "Authorization: Bearer <key>".
+//
+//encore:authhandler
+func (s *Service) AuthHandler(ctx context.Context, token string) (auth.UID, *AuthData, error) {
+	unauthenticated := &errs.Error{
+		Code:    errs.Unauthenticated,
+		Message: "invalid api key",
+	}
+
+	if bootstrap := secrets.BootstrapAdminKey; bootstrap != "" && subtle.ConstantTimeCompare([]byte(token), []byte(bootstrap)) == 1 {
+		return bootstrapKeyID, &AuthData{KeyID: bootstrapKeyID, TenantID: model.DefaultTenant, Name: bootstrapKeyID, Scopes: []model.Scope{model.ScopeAdmin}}, nil
+	}
+
+	keyID, ok := parseAPIKey(token)
+	if !ok {
+		return "", nil, unauthenticated
+	}
+	key, err := s.db.LookupAPIKey(ctx, keyID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil, unauthenticated
+		}
+		rlog.Error("failed to get api key", "error", err, "key_id", keyID)
+		return "", nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashAPIKey(token)), []byte(key.KeyHash)) != 1 || !key.Active(time.Now()) {
+		return "", nil, unauthenticated
+	}
+	return auth.UID(key.KeyID), &AuthData{KeyID: key.KeyID, TenantID: key.TenantID, Name: key.Name, Scopes: key.Scopes}, nil
+}
+
+// scopeTags maps the endpoint tags to the scope an API key needs to call the endpoint.
+var scopeTags = map[string]model.Scope{
+	"scope_bills_read":       model.ScopeBillsRead,
+	"scope_bills_write":      model.ScopeBillsWrite,
+	"scope_line_items_write": model.ScopeLineItemsWrite,
+	"scope_bills_close":      model.ScopeBillsClose,
+	"scope_admin":            model.ScopeAdmin,
+}
+
+// ScopeMiddleware rejects requests whose API key lacks the scope the endpoint is tagged with.
+//
+//encore:middleware target=all
+func (s *Service) ScopeMiddleware(req middleware.Request, next middleware.Next) middleware.Response {
+	data := req.Data()
+	if data.API == nil {
+		return next(req)
+	}
+	for _, tag := range data.API.Tags {
+		scope, ok := scopeTags[tag]
+		if !ok {
+			continue
+		}
+		principal, _ := auth.Data().(*AuthData)
+		if principal == nil {
+			return middleware.Response{Err: &errs.Error{
+				Code:    errs.Unauthenticated,
+				Message: "api key required",
+			}}
+		}
+		if !principal.Allows(scope) {
+			return middleware.Response{Err: &errs.Error{
+				Code:    errs.PermissionDenied,
+				Message: "api key lacks scope " + string(scope),
+			}}
+		}
+	}
+	return next(req)
+}
+
+// requestActor is who the current request acts as, recorded with the changes it makes. An
+// optional onBehalfOf, e.g. the operator behind a service key, is kept next to the key.
+func requestActor(onBehalfOf string) string {
+	actor := "api"
+	if principal, ok := auth.Data().(*AuthData); ok && principal != nil {
+		actor = "key:" + principal.KeyID
+	}
+	if onBehalfOf != "" {
+		actor += " (" + onBehalfOf + ")"
+	}
+	return actor
+}
+
+// requestTenant is the tenant of the current request, the tenant of its API key. Requests
+// without a principal, such as Pub/Sub deliveries and cron jobs, act for the default tenant.
+func requestTenant() string {
+	if principal, ok := auth.Data().(*AuthData); ok && principal != nil && principal.TenantID != "" {
+		return principal.TenantID
+	}
+	return model.DefaultTenant
+}
+
+// newAPIKey generates a key and its ID. Keys carry 256 random bits, a plain SHA-256 is enough to store them.
+func newAPIKey() (keyID, key string, err error) {
+	id := make([]byte, 8)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+	keyID = hex.EncodeToString(id)
+	return keyID, apiKeyPrefix + keyID + "_" + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// parseAPIKey returns the key ID of a well-formed API key.
+func parseAPIKey(key string) (string, bool) {
+	rest, ok := strings.CutPrefix(key, apiKeyPrefix)
+	if !ok {
+		return "", false
+	}
+	keyID, secret, ok := strings.Cut(rest, "_")
+	if !ok || len(keyID) != 16 || secret == "" {
+		return "", false
+	}
+	return keyID, true
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,83 @@
+package fee
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"encore.app/fee/model"
+	"encore.dev/beta/errs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestNewAPIKey_Parses(t *testing.T) {
+	keyID, key, err := newAPIKey()
+
+	assert.NoError(t, err)
+	parsed, ok := parseAPIKey(key)
+	assert.True(t, ok)
+	assert.Equal(t, keyID, parsed)
+}
+
+func TestParseAPIKey_Malformed(t *testing.T) {
+	for _, key := range []string{"", "secret", "fee_", "fee_0123456789abcdef", "fee_short_secret", "sk_0123456789abcdef_secret"} {
+		_, ok := parseAPIKey(key)
+		assert.False(t, ok, key)
+	}
+}
+
+func TestAuthHandler(t *testing.T) {
+	keyID, key, err := newAPIKey()
+	assert.NoError(t, err)
+	expired := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name    string
+		token   string
+		stored  *model.APIKey
+		wantErr bool
+	}{
+		{"Valid", key, &model.APIKey{KeyID: keyID, TenantID: "acme", KeyHash: hashAPIKey(key), Scopes: []model.Scope{model.ScopeBillsRead}}, false},
+		{"WrongSecret", key + "x", &model.APIKey{KeyID: keyID, KeyHash: hashAPIKey(key)}, true},
+		{"Expired", key, &model.APIKey{KeyID: keyID, KeyHash: hashAPIKey(key), ExpiresAt: &expired}, true},
+		{"Revoked", key, &model.APIKey{KeyID: keyID, KeyHash: hashAPIKey(key), RevokedAt: &expired}, true},
+		{"Unknown", key, nil, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			service, mockDB, _ := setup(t)
+			if tc.stored != nil {
+				mockDB.On("LookupAPIKey", mock.Anything, keyID).Return(tc.stored, nil).Once()
+			} else {
+				mockDB.On("LookupAPIKey", mock.Anything, keyID).Return(nil, sql.ErrNoRows).Once()
+			}
+
+			uid, data, err := service.AuthHandler(context.Background(), tc.token)
+
+			if tc.wantErr {
+				var errsErr *errs.Error
+				assert.True(t, errors.As(err, &errsErr))
+				assert.Equal(t, errs.Unauthenticated, errsErr.Code)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, keyID, string(uid))
+			assert.Equal(t, "acme", data.TenantID)
+			assert.True(t, data.Allows(model.ScopeBillsRead))
+			assert.False(t, data.Allows(model.ScopeBillsClose))
+		})
+	}
+}
+
+func TestAuthHandler_MalformedTokenSkipsLookup(t *testing.T) {
+	service, mockDB, _ := setup(t)
+
+	_, _, err := service.AuthHandler(context.Background(), "not-a-key")
+
+	assert.Error(t, err)
+	mockDB.AssertNotCalled(t, "LookupAPIKey", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,91 @@
+package fee
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"encore.app/fee/model"
+	"encore.dev/rlog"
+)
+
+type VerifyBillChainResponse struct {
+	Valid  bool              `json:"valid"`
+	Links  int64             `json:"links"`            // links checked, up to and including a broken one
+	Head   string            `json:"head,omitempty"`   // hash of the last link, keep it outside the database to detect a rewritten tail
+	Broken *model.ChainBreak `json:"broken,omitempty"` // the first link that doesn't verify
+}
+
+// billChainPage is how many links VerifyBillChain reads at a time.
+const billChainPage = 500
+
+// VerifyBillChain walks the bill chain of the tenant from its first link and reports the first
+// broken link. A link is broken when it was removed or changed, or when the bill it seals, still
+// closed, no longer matches it.
+//
+//encore:api auth method=GET path=/api/admin/bill-chain/verify tag:scope_admin
+func (s *Service) VerifyBillChain(ctx context.Context) (*VerifyBillChainResponse, error) {
+	tenantID := requestTenant()
+	resp := &VerifyBillChainResponse{Valid: true}
+	var prevHash string
+	var seq int64
+	for {
+		links, err := s.db.ListBillChain(ctx, tenantID, seq, billChainPage)
+		if err != nil {
+			rlog.Error("failed to list bill chain", "error", err)
+			return nil, err
+		}
+		for _, link := range links {
+			resp.Links++
+			reason, err := s.verifyChainLink(ctx, tenantID, link, seq, prevHash)
+			if err != nil {
+				return nil, err
+			}
+			if reason != "" {
+				resp.Valid = false
+				resp.Broken = &model.ChainBreak{Seq: link.Seq, BillID: link.BillID, Reason: reason}
+				return resp, nil
+			}
+			seq, prevHash = link.Seq, link.Hash
+		}
+		if len(links) < billChainPage {
+			break
+		}
+	}
+	resp.Head = prevHash
+	return resp, nil
+}
+
+// verifyChainLink returns why the link following the one with prevSeq and prevHash is broken,
+// or nothing if it verifies.
+func (s *Service) verifyChainLink(ctx context.Context, tenantID string, link *model.ChainLink, prevSeq int64, prevHash string) (string, error) {
+	if link.Seq != prevSeq+1 {
+		return "links before this one are missing", nil
+	}
+	if link.PrevHash != prevHash {
+		return "prev_hash does not match the previous link", nil
+	}
+	if model.ChainHash(link.PrevHash, link.Content) != link.Hash {
+		return "hash does not match the link content", nil
+	}
+	// Earlier links of a reopened bill sealed a close that was undone, only its last close must
+	// still match. A reopened bill is sealed again when it closes.
+	if !link.Latest || link.BillStatus == model.BillStatusOpen {
+		return "", nil
+	}
+	if link.BillStatus == "" {
+		return "bill was deleted", nil
+	}
+	record, err := s.db.GetBillChainRecord(ctx, tenantID, link.BillID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "bill was deleted", nil
+		}
+		rlog.Error("failed to read bill for chain", "error", err, "bill_id", link.BillID)
+		return "", err
+	}
+	if record.Content() != link.Content {
+		return "bill or line items changed after close", nil
+	}
+	return "", nil
+}
@@ -0,0 +1,131 @@
+package fee
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"encore.app/fee/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// sealedChain links closed bills the way CloseBill appends them.
+func sealedChain(records ...model.ChainRecord) []*model.ChainLink {
+	var links []*model.ChainLink
+	prevHash := ""
+	for i, record := range records {
+		content := record.Content()
+		hash := model.ChainHash(prevHash, content)
+		links = append(links, &model.ChainLink{
+			Seq:        int64(i + 1),
+			BillID:     record.BillID,
+			Content:    content,
+			PrevHash:   prevHash,
+			Hash:       hash,
+			Latest:     true,
+			BillStatus: model.BillStatusClosed,
+		})
+		prevHash = hash
+	}
+	return links
+}
+
+func chainRecord(billID string, amounts ...int64) model.ChainRecord {
+	record := model.ChainRecord{BillID: billID, Currency: "USD", ClosedAt: time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)}
+	for i, amount := range amounts {
+		record.LineItems = append(record.LineItems, model.ChainLineItem{LineItemID: string(rune('a' + i)), Amount: amount, Status: "ACTIVE"})
+		record.TotalAmount += amount
+	}
+	return record
+}
+
+func TestVerifyBillChain_Valid(t *testing.T) {
+	service, mockDB, _ := setup(t)
+	first, second := chainRecord("bill-1", 100, 50), chainRecord("bill-2", 70)
+	links := sealedChain(first, second)
+
+	mockDB.On("ListBillChain", mock.Anything, model.DefaultTenant, int64(0), billChainPage).Return(links, nil).Once()
+	mockDB.On("GetBillChainRecord", mock.Anything, model.DefaultTenant, "bill-1").Return(&first, nil).Once()
+	mockDB.On("GetBillChainRecord", mock.Anything, model.DefaultTenant, "bill-2").Return(&second, nil).Once()
+
+	resp, err := service.VerifyBillChain(context.Background())
+
+	assert.NoError(t, err)
+	assert.True(t, resp.Valid)
+	assert.Equal(t, int64(2), resp.Links)
+	assert.Equal(t, links[1].Hash, resp.Head)
+	assert.Nil(t, resp.Broken)
+	mockDB.AssertExpectations(t)
+}
+
+func TestVerifyBillChain_LineItemAmountChanged(t *testing.T) {
+	service, mockDB, _ := setup(t)
+	first := chainRecord("bill-1", 100, 50)
+	links := sealedChain(first)
+	edited := chainRecord("bill-1", 100, 5)
+	edited.TotalAmount = first.TotalAmount
+
+	mockDB.On("ListBillChain", mock.Anything, model.DefaultTenant, int64(0), billChainPage).Return(links, nil).Once()
+	mockDB.On("GetBillChainRecord", mock.Anything, model.DefaultTenant, "bill-1").Return(&edited, nil).Once()
+
+	resp, err := service.VerifyBillChain(context.Background())
+
+	assert.NoError(t, err)
+	assert.False(t, resp.Valid)
+	assert.Equal(t, &model.ChainBreak{Seq: 1, BillID: "bill-1", Reason: "bill or line items changed after close"}, resp.Broken)
+	mockDB.AssertExpectations(t)
+}
+
+func TestVerifyBillChain_LinkChanged(t *testing.T) {
+	service, mockDB, _ := setup(t)
+	first, second := chainRecord("bill-1", 100), chainRecord("bill-2", 70)
+	links := sealedChain(first, second)
+	// Rewriting a link and its hash still breaks the next link.
+	links[0].Content = chainRecord("bill-1", 10).Content()
+	links[0].Hash = model.ChainHash("", links[0].Content)
+	tampered := chainRecord("bill-1", 10)
+
+	mockDB.On("ListBillChain", mock.Anything, model.DefaultTenant, int64(0), billChainPage).Return(links, nil).Once()
+	mockDB.On("GetBillChainRecord", mock.Anything, model.DefaultTenant, "bill-1").Return(&tampered, nil).Once()
+
+	resp, err := service.VerifyBillChain(context.Background())
+
+	assert.NoError(t, err)
+	assert.False(t, resp.Valid)
+	assert.Equal(t, int64(2), resp.Broken.Seq)
+	assert.Equal(t, "prev_hash does not match the previous link", resp.Broken.Reason)
+	mockDB.AssertExpectations(t)
+}
+
+func TestVerifyBillChain_ReopenedBillSkipsDataCheck(t *testing.T) {
+	service, mockDB, _ := setup(t)
+	links := sealedChain(chainRecord("bill-1", 100), chainRecord("bill-2", 70))
+	links[0].Latest = false
+	links[1].BillStatus = model.BillStatusOpen
+
+	mockDB.On("ListBillChain", mock.Anything, model.DefaultTenant, int64(0), billChainPage).Return(links, nil).Once()
+
+	resp, err := service.VerifyBillChain(context.Background())
+
+	assert.NoError(t, err)
+	assert.True(t, resp.Valid)
+	mockDB.AssertNotCalled(t, "GetBillChainRecord", mock.Anything, mock.Anything, mock.Anything)
+	mockDB.AssertExpectations(t)
+}
+
+func TestVerifyBillChain_MissingLink(t *testing.T) {
+	service, mockDB, _ := setup(t)
+	links := sealedChain(chainRecord("bill-1", 100), chainRecord("bill-2", 70), chainRecord("bill-3", 30))
+	first := chainRecord("bill-1", 100)
+
+	mockDB.On("ListBillChain", mock.Anything, model.DefaultTenant, int64(0), billChainPage).Return([]*model.ChainLink{links[0], links[2]}, nil).Once()
+	mockDB.On("GetBillChainRecord", mock.Anything, model.DefaultTenant, "bill-1").Return(&first, nil).Once()
+
+	resp, err := service.VerifyBillChain(context.Background())
+
+	assert.NoError(t, err)
+	assert.False(t, resp.Valid)
+	assert.Equal(t, &model.ChainBreak{Seq: 3, BillID: "bill-3", Reason: "links before this one are missing"}, resp.Broken)
+	mockDB.AssertExpectations(t)
+}
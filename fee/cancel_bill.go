@@ -0,0 +1,71 @@
+package fee
+
+import (
+	"context"
+	"errors"
+
+	"encore.app/fee/dao"
+	"encore.app/fee/model"
+	temporal "encore.app/fee/workflow"
+	"encore.dev/beta/errs"
+	"encore.dev/rlog"
+)
+
+type CancelBillParams struct {
+	IdempotencyKey string `header:"X-Idempotency-Key"`
+	// Actor optionally names the operator behind the API key, recorded next to the key as closed_by.
+	Actor string `header:"X-Actor"`
+	// Reason is one of CUSTOMER_REQUEST, FRAUD, MIGRATION, ERROR_CORRECTION or OTHER (the default).
+	Reason string `json:"reason"`
+	Note   string `json:"note"`
+}
+
+func (p *CancelBillParams) Validate() error {
+	return validateClosure(&p.Reason, p.Note, p.Actor)
+}
+
+// CancelBill abandons a scheduled or open bill, e.g. one opened in error. The bill is
+// moved to CANCELLED, is never charged and is not post-processed.
+//
+//encore:api auth method=POST path=/api/bills/:billID/cancel tag:idempotency tag:scope_bills_close
+func (s *Service) CancelBill(ctx context.Context, billID string, params *CancelBillParams) (*temporal.BillResponse, error) {
+	if err := params.Validate(); err != nil {
+		return nil, &errs.Error{
+			Code:    errs.InvalidArgument,
+			Message: err.Error(),
+		}
+	}
+
+	status, err := s.db.GetBillStatus(ctx, requestTenant(), billID)
+	if err != nil {
+		if errors.Is(err, dao.ErrBillNotFound) {
+			return nil, &errs.Error{
+				Code:    errs.NotFound,
+				Message: "bill not found",
+			}
+		}
+		rlog.Error("failed to get bill status", "error", err, "bill_id", billID)
+		return nil, err
+	}
+	if status != model.BillStatusScheduled && status != model.BillStatusOpen {
+		return nil, &errs.Error{
+			Code:    errs.FailedPrecondition,
+			Message: "only scheduled or open bills can be cancelled",
+		}
+	}
+
+	// The workflow decides whether the bill is still scheduled, so a cancel that races the start
+	// of the billing period is applied to the bill as it is then.
+	var billDetail temporal.BillResponse
+	err = s.updateBill(ctx, billID, temporal.CancelBillUpdate, temporal.CancelBillRequest{
+		BillID:    billID,
+		Reason:    model.CloseReason(params.Reason),
+		Note:      params.Note,
+		Actor:     requestActor(params.Actor),
+		RequestID: requestID(),
+	}, &billDetail)
+	if err != nil {
+		return nil, err
+	}
+	return &billDetail, nil
+}
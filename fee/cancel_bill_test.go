@@ -0,0 +1,77 @@
+package fee
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"encore.app/fee/dao"
+	"encore.app/fee/model"
+	temporal "encore.app/fee/workflow"
+	"encore.dev/beta/errs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.temporal.io/sdk/client"
+)
+
+func TestCancelBill_Closed(t *testing.T) {
+	service, mockDB, mockTemporalClient := setup(t)
+
+	mockDB.On("GetBillStatus", mock.Anything, model.DefaultTenant, "closed-bill").Return(model.BillStatusClosed, nil).Once()
+
+	_, err := service.CancelBill(context.Background(), "closed-bill", &CancelBillParams{})
+
+	var errsErr *errs.Error
+	assert.True(t, errors.As(err, &errsErr))
+	assert.Equal(t, errs.FailedPrecondition, errsErr.Code)
+	mockDB.AssertExpectations(t)
+	mockTemporalClient.AssertNotCalled(t, "SignalWorkflow", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockTemporalClient.AssertNotCalled(t, "UpdateWorkflow", mock.Anything, mock.Anything)
+}
+
+func TestCancelBill_Open(t *testing.T) {
+	service, mockDB, mockTemporalClient := setup(t)
+
+	mockDB.On("GetBillStatus", mock.Anything, model.DefaultTenant, "open-bill").Return(model.BillStatusOpen, nil).Once()
+	mockTemporalClient.On("UpdateWorkflow", mock.Anything, mock.MatchedBy(func(options client.UpdateWorkflowOptions) bool {
+		req, ok := options.Args[0].(temporal.CancelBillRequest)
+		return ok && options.UpdateName == temporal.CancelBillUpdate && req.Reason == model.CloseReasonErrorCorrection
+	})).Return(&mockUpdateHandle{result: temporal.BillResponse{BillID: "open-bill", Status: "CANCELLED"}}, nil).Once()
+
+	resp, err := service.CancelBill(context.Background(), "open-bill", &CancelBillParams{Reason: "error_correction"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "CANCELLED", resp.Status)
+	mockTemporalClient.AssertExpectations(t)
+	mockTemporalClient.AssertNotCalled(t, "SignalWorkflow", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCancelBill_NotFound(t *testing.T) {
+	service, mockDB, _ := setup(t)
+
+	mockDB.On("GetBillStatus", mock.Anything, model.DefaultTenant, "missing-bill").Return(model.BillStatus(""), dao.ErrBillNotFound).Once()
+
+	_, err := service.CancelBill(context.Background(), "missing-bill", &CancelBillParams{})
+
+	var errsErr *errs.Error
+	assert.True(t, errors.As(err, &errsErr))
+	assert.Equal(t, errs.NotFound, errsErr.Code)
+	mockDB.AssertExpectations(t)
+}
+
+func TestCancelBill_Scheduled(t *testing.T) {
+	service, mockDB, mockTemporalClient := setup(t)
+
+	// A scheduled bill is cancelled through the update too, the workflow knows whether it started meanwhile.
+	mockDB.On("GetBillStatus", mock.Anything, model.DefaultTenant, "scheduled-bill").Return(model.BillStatusScheduled, nil).Once()
+	mockTemporalClient.On("UpdateWorkflow", mock.Anything, mock.MatchedBy(func(options client.UpdateWorkflowOptions) bool {
+		return options.UpdateName == temporal.CancelBillUpdate
+	})).Return(&mockUpdateHandle{result: temporal.BillResponse{BillID: "scheduled-bill", Status: "CANCELLED"}}, nil).Once()
+
+	resp, err := service.CancelBill(context.Background(), "scheduled-bill", &CancelBillParams{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "CANCELLED", resp.Status)
+	mockTemporalClient.AssertExpectations(t)
+	mockTemporalClient.AssertNotCalled(t, "SignalWorkflow", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -2,22 +2,53 @@ package fee
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 
+	"encore.app/fee/dao"
+	"encore.app/fee/model"
 	temporal "encore.app/fee/workflow"
 	"encore.dev/beta/errs"
 	"encore.dev/rlog"
-	"go.temporal.io/api/serviceerror"
-	"go.temporal.io/sdk/client"
 )
 
 type CloseBillParams struct {
 	IdempotencyKey string `header:"X-Idempotency-Key"`
+	// Actor optionally names the operator behind the API key, recorded next to the key as closed_by.
+	Actor string `header:"X-Actor"`
+	// Force closes the bill even though it has unresolved failed line items.
+	Force bool `json:"force"`
+	// Reason is one of CUSTOMER_REQUEST, FRAUD, MIGRATION, ERROR_CORRECTION or OTHER (the default).
+	Reason string `json:"reason"`
+	Note   string `json:"note"`
 }
 
-//encore:api public method=POST path=/api/bills/:billID/close tag:idempotency
+func (p *CloseBillParams) Validate() error {
+	return validateClosure(&p.Reason, p.Note, p.Actor)
+}
+
+// validateClosure checks what a manual close or cancel records on the bill. The reason is
+// normalized to upper case and defaults to OTHER.
+func validateClosure(reason *string, note, actor string) error {
+	if *reason == "" {
+		*reason = string(model.CloseReasonOther)
+	}
+	*reason = strings.ToUpper(*reason)
+	r, err := model.ToCloseReason(*reason)
+	if err != nil || r == model.CloseReasonPeriodEnd {
+		return fmt.Errorf("invalid reason")
+	}
+	if len(note) > 500 {
+		return fmt.Errorf("note must be at most 500 characters")
+	}
+	if len(actor) > 64 {
+		return fmt.Errorf("actor must be at most 64 characters")
+	}
+	return nil
+}
+
+//encore:api auth method=POST path=/api/bills/:billID/close tag:idempotency tag:scope_bills_close
 func (s *Service) CloseBill(ctx context.Context, billID string, params *CloseBillParams) (*temporal.BillResponse, error) {
 	if billID == "" {
 		return nil, &errs.Error{
@@ -26,36 +57,44 @@ func (s *Service) CloseBill(ctx context.Context, billID string, params *CloseBil
 		}
 	}
 
-	signal := temporal.ClosedBillRequest{
-		BillID: billID,
+	if err := params.Validate(); err != nil {
+		return nil, &errs.Error{
+			Code:    errs.InvalidArgument,
+			Message: err.Error(),
+		}
 	}
 
-	workflowID := temporal.BillCycleWorkflowID(billID)
-	// Signal the workflow to close the bill
-	err := s.client.SignalWorkflow(ctx, workflowID, "", temporal.CloseBillSignal, signal)
+	status, err := s.db.GetBillStatus(ctx, requestTenant(), billID)
 	if err != nil {
-		var notFound *serviceerror.NotFound
-		if errors.As(err, &notFound) || errors.Is(err, sql.ErrNoRows) {
+		if errors.Is(err, dao.ErrBillNotFound) {
 			return nil, &errs.Error{
 				Code:    errs.NotFound,
 				Message: "bill not found or already closed",
 			}
 		}
-		rlog.Error("failed to signal workflow to close bill", "error", err, "bill_id", billID)
-		return nil, fmt.Errorf("failed to signal workflow: %w", err)
+		rlog.Error("failed to get bill status", "error", err, "bill_id", billID)
+		return nil, err
+	}
+	if status == model.BillStatusScheduled {
+		return nil, &errs.Error{
+			Code:    errs.FailedPrecondition,
+			Message: "bill has not started yet, cancel it instead",
+		}
 	}
 
-	// Get a handle to the workflow run
-	we := s.client.GetWorkflow(ctx, workflowID, "")
+	signal := temporal.ClosedBillRequest{
+		BillID:    billID,
+		Force:     params.Force,
+		Reason:    model.CloseReason(params.Reason),
+		Note:      params.Note,
+		Actor:     requestActor(params.Actor),
+		RequestID: requestID(),
+	}
 
-	// Wait for the workflow to complete and retrieve the result
+	// The update completes once the workflow has closed the bill and returns the final bill.
 	var billDetail temporal.BillResponse
-	err = we.GetWithOptions(ctx, &billDetail, client.WorkflowRunGetOptions{
-		DisableFollowingRuns: false,
-	})
-	if err != nil {
-		rlog.Error("failed to get workflow result", "error", err, "bill_id", billID)
-		return nil, fmt.Errorf("failed to get workflow result: %w", err)
+	if err := s.updateBill(ctx, billID, temporal.CloseBillUpdate, signal, &billDetail); err != nil {
+		return nil, err
 	}
 
 	return &billDetail, nil
@@ -0,0 +1,57 @@
+package fee
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"encore.app/fee/model"
+	temporal "encore.app/fee/workflow"
+	"encore.dev/beta/errs"
+	"encore.dev/et"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.temporal.io/sdk/client"
+)
+
+func TestCloseBill_RecordsReason(t *testing.T) {
+	service, mockDB, mockTemporalClient := setup(t)
+	et.OverrideAuthInfo("0123456789abcdef", &AuthData{KeyID: "0123456789abcdef", Scopes: []model.Scope{model.ScopeBillsClose}})
+
+	mockDB.On("GetBillStatus", mock.Anything, model.DefaultTenant, "bill-1").Return(model.BillStatusOpen, nil).Once()
+	mockTemporalClient.On("UpdateWorkflow", mock.Anything, mock.MatchedBy(func(options client.UpdateWorkflowOptions) bool {
+		req, ok := options.Args[0].(temporal.ClosedBillRequest)
+		return ok && options.UpdateName == temporal.CloseBillUpdate &&
+			req.Reason == model.CloseReasonFraud && req.Note == "chargeback" && req.Actor == "key:0123456789abcdef (ops@example.com)"
+	})).Return(&mockUpdateHandle{result: temporal.BillResponse{BillID: "bill-1", Status: "CLOSED"}}, nil).Once()
+
+	resp, err := service.CloseBill(context.Background(), "bill-1", &CloseBillParams{
+		Actor:  "ops@example.com",
+		Reason: "fraud",
+		Note:   "chargeback",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "CLOSED", resp.Status)
+	mockTemporalClient.AssertExpectations(t)
+}
+
+func TestCloseBill_DefaultsReason(t *testing.T) {
+	params := &CloseBillParams{}
+
+	assert.NoError(t, params.Validate())
+	assert.Equal(t, string(model.CloseReasonOther), params.Reason)
+}
+
+func TestCloseBill_InvalidReason(t *testing.T) {
+	for _, reason := range []string{"bored", "PERIOD_END"} {
+		service, mockDB, _ := setup(t)
+
+		_, err := service.CloseBill(context.Background(), "bill-1", &CloseBillParams{Reason: reason})
+
+		var errsErr *errs.Error
+		assert.True(t, errors.As(err, &errsErr), reason)
+		assert.Equal(t, errs.InvalidArgument, errsErr.Code, reason)
+		mockDB.AssertNotCalled(t, "GetBillStatus", mock.Anything, model.DefaultTenant, mock.Anything)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"encore.app/fee/model"
@@ -18,15 +19,18 @@ type Recurring struct {
 	Amount      int64          `json:"amount"`
 	Interval    utils.Duration `json:"interval"`
 	Description string         `json:"description"`
+	PriceID     string         `json:"price_id"` // when set, amount and interval come from the catalog
 }
 
 type CreateBillParams struct {
 	BillID             string     `json:"bill_id"`
 	PolicyType         string     `json:"policy_type"`
 	Currency           string     `json:"currency"`
+	CustomerID         string     `json:"customer_id"` // optional, bills can be listed by customer
 	Recurring          *Recurring `json:"recurring"`
-	BillingPeriodStart time.Time  `json:"billing_period_start"`
-	BillingPeriodEnd   time.Time  `json:"billing_period_end"` // eg: 2025-09-25T21:25:00+08:00
+	BillingPeriodStart time.Time  `json:"billing_period_start"` // a future start creates a SCHEDULED bill
+	BillingPeriodEnd   time.Time  `json:"billing_period_end"`   // eg: 2025-09-25T21:25:00+08:00
+	ScheduledLineItems string     `json:"scheduled_line_items"` // REJECT (default) or QUEUE line items sent before the start
 	IdempotencyKey     string     `header:"X-Idempotency-Key"`
 }
 
@@ -34,19 +38,35 @@ func (p *CreateBillParams) Validate() error {
 	if p.BillID == "" {
 		return fmt.Errorf("bill_id is a required field")
 	}
+	// The workflow ID of a bill outside the default tenant is "bill-<tenant>/<bill_id>".
+	if strings.Contains(p.BillID, "/") {
+		return fmt.Errorf("bill_id must not contain '/'")
+	}
 	_, err := model.ToCurrency(p.Currency)
 	if err != nil {
 		return err
 	}
+	if len(p.CustomerID) > 64 {
+		return fmt.Errorf("customer_id must be at most 64 characters")
+	}
 	if p.BillingPeriodEnd.IsZero() {
 		return fmt.Errorf("billing_period_end is a required field")
 	}
 	if p.BillingPeriodStart.IsZero() {
 		p.BillingPeriodStart = time.Now()
 	}
-	if p.BillingPeriodEnd.Before(time.Now().Add(1 * time.Minute)) {
+	if p.BillingPeriodEnd.Before(time.Now().Add(temporal.MinPeriodLead)) {
 		return fmt.Errorf("billing_period_end is too short, must be at least 1 minutes ahead")
 	}
+	if p.BillingPeriodEnd.Before(p.BillingPeriodStart.Add(temporal.MinPeriodLead)) {
+		return fmt.Errorf("billing_period_end must be at least 1 minutes after billing_period_start")
+	}
+	if p.ScheduledLineItems == "" {
+		p.ScheduledLineItems = string(model.ScheduledLineItemsReject)
+	}
+	if _, err := model.ToScheduledLineItemMode(strings.ToUpper(p.ScheduledLineItems)); err != nil {
+		return fmt.Errorf("invalid scheduled_line_items")
+	}
 	policy, err := model.ToPolicyType(p.PolicyType)
 	if err != nil {
 		return fmt.Errorf("invalid policy")
@@ -56,6 +76,12 @@ func (p *CreateBillParams) Validate() error {
 		if p.Recurring == nil {
 			return fmt.Errorf("recurring is mandatory for policy=SUBSCRIPTION")
 		}
+		if p.Recurring.PriceID != "" {
+			if p.Recurring.Amount != 0 {
+				return fmt.Errorf("recurring.amount must not be provided together with recurring.price_id")
+			}
+			return nil
+		}
 		if p.Recurring.Amount <= 0 {
 			return fmt.Errorf("recurring.amount must be more than zero")
 		}
@@ -73,7 +99,7 @@ type CreateBillResponse struct {
 	Status     string `json:"status"`
 }
 
-//encore:api public method=POST path=/api/bills tag:idempotency
+//encore:api auth method=POST path=/api/bills tag:idempotency tag:scope_bills_write
 func (s *Service) CreateBill(ctx context.Context, params *CreateBillParams) (*CreateBillResponse, error) {
 	if err := params.Validate(); err != nil {
 		return nil, &errs.Error{
@@ -82,7 +108,8 @@ func (s *Service) CreateBill(ctx context.Context, params *CreateBillParams) (*Cr
 		}
 	}
 
-	exists, err := s.db.IsBillExists(ctx, params.BillID)
+	tenantID := requestTenant()
+	exists, err := s.db.IsBillExists(ctx, tenantID, params.BillID)
 	if err != nil {
 		rlog.Error("failed in checking bill exists in db", "error", err)
 		return nil, err
@@ -95,11 +122,20 @@ func (s *Service) CreateBill(ctx context.Context, params *CreateBillParams) (*Cr
 	}
 
 	req := &temporal.BillLifecycleWorkflowRequest{
+		TenantID:          tenantID,
 		BillID:            params.BillID,
 		PolicyType:        model.PolicyType(params.PolicyType),
 		BillingPeriodEnd:  params.BillingPeriodEnd,
 		BilingPeriodStart: params.BillingPeriodStart,
 		Currency:          params.Currency,
+		CustomerID:        params.CustomerID,
+		Actor:             requestActor(""),
+		RequestID:         requestID(),
+	}
+	status := model.BillStatusOpen
+	if params.BillingPeriodStart.After(time.Now()) {
+		status = model.BillStatusScheduled
+		req.ScheduledLineItems = model.ScheduledLineItemMode(strings.ToUpper(params.ScheduledLineItems))
 	}
 	if params.Recurring != nil {
 		recurring := params.Recurring
@@ -108,10 +144,15 @@ func (s *Service) CreateBill(ctx context.Context, params *CreateBillParams) (*Cr
 			Interval:    recurring.Interval,
 			Description: recurring.Description,
 		}
+		if recurring.PriceID != "" {
+			if err := s.applyRecurringPrice(ctx, params, &req.Recurring); err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	w, err := s.client.ExecuteWorkflow(ctx, client.StartWorkflowOptions{
-		ID:        temporal.BillCycleWorkflowID(params.BillID),
+		ID:        temporal.BillCycleWorkflowID(tenantID, params.BillID),
 		TaskQueue: temporal.BillCycleTaskQueue,
 	}, temporal.BillLifecycleWorkflow, req)
 	if err != nil {
@@ -119,5 +160,36 @@ func (s *Service) CreateBill(ctx context.Context, params *CreateBillParams) (*Cr
 		return nil, errors.New("failed to start workflow: " + params.BillID)
 	}
 
-	return &CreateBillResponse{BillID: params.BillID, Status: string(model.BillStatusOpen), WorkflowID: w.GetID()}, nil
+	return &CreateBillResponse{BillID: params.BillID, Status: string(status), WorkflowID: w.GetID()}, nil
+}
+
+// applyRecurringPrice fills the recurring policy from a RECURRING catalog price, whose interval
+// a caller can't override. The workflow keeps the price_id and re-prices each charge against the catalog.
+func (s *Service) applyRecurringPrice(ctx context.Context, params *CreateBillParams, recurring *temporal.RecurringPolicy) error {
+	price, err := s.resolvePrice(ctx, params.Recurring.PriceID, model.PriceTypeRecurring, params.BillingPeriodStart)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(price.Currency, params.Currency) {
+		return &errs.Error{
+			Code:    errs.InvalidArgument,
+			Message: "recurring.price_id currency does not match bill currency",
+		}
+	}
+	interval, err := time.ParseDuration(price.RecurringInterval)
+	if err != nil {
+		rlog.Error("invalid recurring interval on price", "error", err, "price_id", price.PriceID)
+		return err
+	}
+	// The catalog sets the interval, a caller may only repeat it.
+	if recurring.Interval.Duration > 0 && recurring.Interval.Duration != interval {
+		return &errs.Error{
+			Code:    errs.InvalidArgument,
+			Message: fmt.Sprintf("recurring.interval does not match the recurring.price_id interval %s", interval),
+		}
+	}
+	recurring.Interval = utils.Duration{Duration: interval}
+	recurring.Amount = price.UnitAmount
+	recurring.PriceID = price.PriceID
+	return nil
 }
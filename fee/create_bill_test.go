@@ -19,6 +19,7 @@ import (
 func setup(t *testing.T) (*Service, *mocks.DB, *mockTemporalClient) {
 	mockTemporalClient := &mockTemporalClient{}
 	mockDB := &mocks.DB{}
+	secrets.CursorSigningKey = testCursorKey
 
 	service := &Service{
 		db:     mockDB,
@@ -137,7 +138,7 @@ func TestCreateBill_DuplicateBillID(t *testing.T) {
 		BillingPeriodEnd: time.Now().Add(5 * time.Minute),
 	}
 
-	mockDB.On("IsBillExists", mock.Anything, params.BillID).Return(true, nil).Once()
+	mockDB.On("IsBillExists", mock.Anything, model.DefaultTenant, params.BillID).Return(true, nil).Once()
 
 	_, err := service.CreateBill(context.Background(), params)
 
@@ -159,7 +160,7 @@ func TestCreateBill_DBError(t *testing.T) {
 	}
 	dbErr := errors.New("database connection failed")
 
-	mockDB.On("IsBillExists", mock.Anything, params.BillID).Return(false, dbErr).Once()
+	mockDB.On("IsBillExists", mock.Anything, model.DefaultTenant, params.BillID).Return(false, dbErr).Once()
 
 	_, err := service.CreateBill(context.Background(), params)
 
@@ -178,7 +179,7 @@ func TestCreateBill_TemporalError(t *testing.T) {
 	}
 	temporalErr := errors.New("temporal connection failed")
 
-	mockDB.On("IsBillExists", mock.Anything, params.BillID).Return(false, nil).Once()
+	mockDB.On("IsBillExists", mock.Anything, model.DefaultTenant, params.BillID).Return(false, nil).Once()
 	mockTemporalClient.On("ExecuteWorkflow", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, temporalErr).Once()
 
 	_, err := service.CreateBill(context.Background(), params)
@@ -199,10 +200,13 @@ func TestCreateBill_Success_UsageBased(t *testing.T) {
 		BillingPeriodStart: time.Now(),
 	}
 
-	mockDB.On("IsBillExists", mock.Anything, params.BillID).Return(false, nil).Once()
+	mockDB.On("IsBillExists", mock.Anything, model.DefaultTenant, params.BillID).Return(false, nil).Once()
 
 	expectedWorkflowReq := &temporal.BillLifecycleWorkflowRequest{
+		TenantID:          model.DefaultTenant,
 		BillID:            params.BillID,
+		Actor:             "api",
+		RequestID:         requestID(),
 		PolicyType:        model.UsageBased,
 		BillingPeriodEnd:  params.BillingPeriodEnd,
 		BilingPeriodStart: params.BillingPeriodStart,
@@ -243,10 +247,13 @@ func TestCreateBill_Success_Subscription(t *testing.T) {
 		},
 	}
 
-	mockDB.On("IsBillExists", mock.Anything, params.BillID).Return(false, nil).Once()
+	mockDB.On("IsBillExists", mock.Anything, model.DefaultTenant, params.BillID).Return(false, nil).Once()
 
 	expectedWorkflowReq := &temporal.BillLifecycleWorkflowRequest{
+		TenantID:          model.DefaultTenant,
 		BillID:            params.BillID,
+		Actor:             "api",
+		RequestID:         requestID(),
 		PolicyType:        model.Subscription,
 		BillingPeriodEnd:  params.BillingPeriodEnd,
 		BilingPeriodStart: params.BillingPeriodStart,
@@ -276,3 +283,111 @@ func TestCreateBill_Success_Subscription(t *testing.T) {
 	mockDB.AssertExpectations(t)
 	mockTemporalClient.AssertExpectations(t)
 }
+
+func TestCreateBill_RecurringPriceInterval(t *testing.T) {
+	price := &model.Price{
+		PriceID:           "pro-monthly-usd",
+		Currency:          "USD",
+		Type:              string(model.PriceTypeRecurring),
+		RecurringInterval: "720h",
+		Active:            true,
+		UnitAmount:        5000,
+	}
+
+	tests := []struct {
+		name     string
+		interval time.Duration
+		wantErr  bool
+	}{
+		{"FromCatalog", 0, false},
+		{"SameAsCatalog", 720 * time.Hour, false},
+		{"Conflicting", time.Hour, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, mockDB, mockTemporalClient := setup(t)
+			params := &CreateBillParams{
+				BillID:             "test-subscription-bill",
+				PolicyType:         string(model.Subscription),
+				Currency:           "USD",
+				BillingPeriodEnd:   time.Now().Add(10 * time.Minute),
+				BillingPeriodStart: time.Now(),
+				Recurring: &Recurring{
+					PriceID:  price.PriceID,
+					Interval: utils.Duration{Duration: tt.interval},
+				},
+			}
+			mockDB.On("IsBillExists", mock.Anything, model.DefaultTenant, params.BillID).Return(false, nil).Once()
+			mockDB.On("GetPrice", mock.Anything, model.DefaultTenant, price.PriceID, mock.Anything).Return(price, nil).Once()
+			if !tt.wantErr {
+				mockTemporalClient.On("ExecuteWorkflow", mock.Anything, mock.Anything, mock.Anything,
+					mock.MatchedBy(func(req *temporal.BillLifecycleWorkflowRequest) bool {
+						return req.Recurring.Interval.Duration == 720*time.Hour && req.Recurring.Amount == 5000
+					})).Return(&mockWorkflowRun{}, nil).Once()
+			}
+
+			_, err := service.CreateBill(context.Background(), params)
+
+			if tt.wantErr {
+				var errsErr *errs.Error
+				assert.True(t, errors.As(err, &errsErr))
+				assert.Equal(t, errs.InvalidArgument, errsErr.Code)
+				mockTemporalClient.AssertNotCalled(t, "ExecuteWorkflow", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			} else {
+				assert.NoError(t, err)
+			}
+			mockDB.AssertExpectations(t)
+			mockTemporalClient.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCreateBill_Success_Scheduled(t *testing.T) {
+	service, mockDB, mockTemporalClient := setup(t)
+	params := &CreateBillParams{
+		BillID:             "test-scheduled-bill",
+		PolicyType:         string(model.UsageBased),
+		Currency:           "USD",
+		BillingPeriodStart: time.Now().Add(1 * time.Hour),
+		BillingPeriodEnd:   time.Now().Add(2 * time.Hour),
+		ScheduledLineItems: "queue",
+	}
+
+	mockDB.On("IsBillExists", mock.Anything, model.DefaultTenant, params.BillID).Return(false, nil).Once()
+	mockTemporalClient.On(
+		"ExecuteWorkflow",
+		mock.Anything,
+		mock.Anything,
+		mock.Anything,
+		mock.MatchedBy(func(req *temporal.BillLifecycleWorkflowRequest) bool {
+			return req.ScheduledLineItems == model.ScheduledLineItemsQueue
+		}),
+	).Return(&mockWorkflowRun{}, nil).Once()
+
+	resp, err := service.CreateBill(context.Background(), params)
+
+	assert.NoError(t, err)
+	assert.Equal(t, string(model.BillStatusScheduled), resp.Status)
+
+	mockDB.AssertExpectations(t)
+	mockTemporalClient.AssertExpectations(t)
+}
+
+func TestCreateBill_EndBeforeScheduledStart(t *testing.T) {
+	service, _, _ := setup(t)
+	params := &CreateBillParams{
+		BillID:             "test-scheduled-bill",
+		PolicyType:         string(model.UsageBased),
+		Currency:           "USD",
+		BillingPeriodStart: time.Now().Add(2 * time.Hour),
+		BillingPeriodEnd:   time.Now().Add(1 * time.Hour),
+	}
+
+	_, err := service.CreateBill(context.Background(), params)
+
+	var errsErr *errs.Error
+	assert.True(t, errors.As(err, &errsErr))
+	assert.Equal(t, errs.InvalidArgument, errsErr.Code)
+	assert.Equal(t, "billing_period_end must be at least 1 minutes after billing_period_start", errsErr.Message)
+}
@@ -42,9 +42,10 @@ func StartMonthlyBilling(ctx context.Context) error {
 	billingPeriodEnd := startOfNextMonth.Add(-1 * time.Second)
 
 	for _, customerID := range customerIDs {
-		workflowID := temporal.BillCycleWorkflowID(customerID)
+		workflowID := temporal.BillCycleWorkflowID(model.DefaultTenant, customerID)
 
 		req := &temporal.BillLifecycleWorkflowRequest{
+			TenantID:         model.DefaultTenant,
 			BillID:           customerID,
 			PolicyType:       model.Subscription,
 			BillingPeriodEnd: billingPeriodEnd,
@@ -0,0 +1,75 @@
+package fee
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Cursor directions, a cursor reads the page after or before the item it was made from.
+const (
+	cursorNext = "next"
+	cursorPrev = "prev"
+)
+
+// checkCursorKey keeps the service from starting without CursorSigningKey, a cursor signed with
+// an empty key could be forged by anyone.
+func checkCursorKey() error {
+	if secrets.CursorSigningKey == "" {
+		return fmt.Errorf("the CursorSigningKey secret is not set, pagination cursors can't be signed")
+	}
+	return nil
+}
+
+// encodeCursor turns a cursor of the kind of list into an opaque, signed string.
+func encodeCursor(kind string, cursor any) string {
+	payload, _ := json.Marshal(cursor)
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(payload) + "." + enc.EncodeToString(signCursor(kind, payload))
+}
+
+// decodeCursor verifies a cursor of the kind of list and decodes it into cursor.
+func decodeCursor(kind, value string, cursor any) error {
+	enc := base64.RawURLEncoding
+	payloadPart, sigPart, ok := strings.Cut(value, ".")
+	if !ok {
+		return fmt.Errorf("invalid cursor")
+	}
+	payload, err := enc.DecodeString(payloadPart)
+	if err != nil {
+		return fmt.Errorf("invalid cursor")
+	}
+	sig, err := enc.DecodeString(sigPart)
+	if err != nil || !hmac.Equal(sig, signCursor(kind, payload)) {
+		return fmt.Errorf("invalid cursor")
+	}
+	if err := json.Unmarshal(payload, cursor); err != nil {
+		return fmt.Errorf("invalid cursor")
+	}
+	return nil
+}
+
+// signCursor binds the kind into the signature, a bill cursor is never accepted for line items.
+func signCursor(kind string, payload []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secrets.CursorSigningKey))
+	mac.Write([]byte(kind))
+	mac.Write([]byte{0})
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// pageLinks tells which of the next and previous cursors a page of n items has.
+// A forward page has a next page when the query found more, and a previous page unless it is the
+// first one. A backward page is the other way around.
+func pageLinks(n int, continued, backward, hasMore bool) (next, prev bool) {
+	if n == 0 {
+		return false, false
+	}
+	if backward {
+		return true, hasMore
+	}
+	return hasMore, continued
+}
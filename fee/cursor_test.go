@@ -0,0 +1,27 @@
+package fee
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testCursorKey signs the cursors of the tests, setup sets it.
+const testCursorKey = "cursor-key"
+
+// testCursor signs a cursor with the test key, also before setup ran.
+func testCursor(t *testing.T, kind string, cursor any) string {
+	t.Helper()
+	secrets.CursorSigningKey = testCursorKey
+	return encodeCursor(kind, cursor)
+}
+
+func TestCheckCursorKey(t *testing.T) {
+	secrets.CursorSigningKey = ""
+	t.Cleanup(func() { secrets.CursorSigningKey = testCursorKey })
+
+	assert.Error(t, checkCursorKey())
+
+	secrets.CursorSigningKey = testCursorKey
+	assert.NoError(t, checkCursorKey())
+}
@@ -0,0 +1,62 @@
+package fee
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"encore.app/fee/model"
+	"encore.dev/beta/errs"
+	"encore.dev/rlog"
+)
+
+type PutCustomerParams struct {
+	Name    string `json:"name"`
+	Email   string `json:"email"`
+	Address string `json:"address"`
+	TaxID   string `json:"tax_id"`
+	// BillingEmails receive the bill emails instead of Email, at most 10.
+	BillingEmails []string `json:"billing_emails"`
+}
+
+// PutCustomer creates a customer or replaces its billing details. Invoices keep the details the
+// customer had when they were issued.
+//
+//encore:api auth method=PUT path=/api/customers/:customerID tag:scope_bills_write
+func (s *Service) PutCustomer(ctx context.Context, customerID string, params *PutCustomerParams) (*model.Customer, error) {
+	customer := &model.Customer{
+		CustomerID:    customerID,
+		Name:          params.Name,
+		Email:         params.Email,
+		Address:       params.Address,
+		TaxID:         params.TaxID,
+		BillingEmails: params.BillingEmails,
+	}
+	if err := customer.Validate(); err != nil {
+		return nil, &errs.Error{
+			Code:    errs.InvalidArgument,
+			Message: err.Error(),
+		}
+	}
+	if err := s.db.UpsertCustomer(ctx, requestTenant(), customer); err != nil {
+		rlog.Error("failed to put customer", "error", err)
+		return nil, err
+	}
+	return customer, nil
+}
+
+//encore:api auth method=GET path=/api/customers/:customerID tag:scope_bills_read
+func (s *Service) GetCustomer(ctx context.Context, customerID string) (*model.Customer, error) {
+	customer, err := s.db.GetCustomer(ctx, requestTenant(), customerID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &errs.Error{
+				Code:    errs.NotFound,
+				Message: "customer not found",
+			}
+		}
+		rlog.Error("failed to get customer", "error", err)
+		return nil, err
+	}
+	return customer, nil
+}
@@ -0,0 +1,154 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"encore.app/fee/model"
+	"encore.dev/rlog"
+	"github.com/jackc/pgx/v5"
+)
+
+const apiKeyColumns = `key_id, tenant_id, name, key_hash, scopes, created_by, COALESCE(rotated_from, ''), created_at, expires_at, revoked_at`
+
+// scanAPIKey reads the apiKeyColumns of a row.
+func scanAPIKey(row pgx.Row) (*model.APIKey, error) {
+	var key model.APIKey
+	var scopes []string
+	if err := row.Scan(&key.KeyID, &key.TenantID, &key.Name, &key.KeyHash, &scopes, &key.CreatedBy, &key.RotatedFrom,
+		&key.CreatedAt, &key.ExpiresAt, &key.RevokedAt); err != nil {
+		return nil, err
+	}
+	key.Scopes = make([]model.Scope, len(scopes))
+	for i, s := range scopes {
+		key.Scopes[i] = model.Scope(s)
+	}
+	return &key, nil
+}
+
+func scopeStrings(scopes []model.Scope) []string {
+	out := make([]string, len(scopes))
+	for i, s := range scopes {
+		out[i] = string(s)
+	}
+	return out
+}
+
+// CreateAPIKey stores a new API key of the tenant and fills its created_at.
+func (d *dbStore) CreateAPIKey(ctx context.Context, tenantID string, key *model.APIKey) error {
+	err := d.db.QueryRow(ctx, `
+		INSERT INTO api_keys (key_id, tenant_id, name, key_hash, scopes, created_by, rotated_from)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''))
+		RETURNING created_at
+	`, key.KeyID, tenantID, key.Name, key.KeyHash, scopeStrings(key.Scopes), key.CreatedBy, key.RotatedFrom).Scan(&key.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create api key: %w", err)
+	}
+	key.TenantID = tenantID
+	return nil
+}
+
+// GetAPIKey returns sql.ErrNoRows if the tenant has no API key with that ID.
+func (d *dbStore) GetAPIKey(ctx context.Context, tenantID, keyID string) (*model.APIKey, error) {
+	return d.getAPIKey(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE key_id = $1 AND tenant_id = $2`, keyID, tenantID)
+}
+
+// LookupAPIKey finds an API key of any tenant by its ID. It is how the tenant of a request is
+// found in the first place, everything else only reads the keys of a known tenant.
+func (d *dbStore) LookupAPIKey(ctx context.Context, keyID string) (*model.APIKey, error) {
+	return d.getAPIKey(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE key_id = $1`, keyID)
+}
+
+func (d *dbStore) getAPIKey(ctx context.Context, query string, args ...any) (*model.APIKey, error) {
+	key, err := scanAPIKey(d.db.QueryRow(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || errors.Is(err, pgx.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+	return key, nil
+}
+
+// ListAPIKeys lists the API keys of the tenant, newest first. Revoked keys are only included when asked for.
+func (d *dbStore) ListAPIKeys(ctx context.Context, tenantID string, includeRevoked bool) ([]*model.APIKey, error) {
+	rows, err := d.db.Query(ctx, `
+		SELECT `+apiKeyColumns+`
+		FROM api_keys
+		WHERE tenant_id = $2 AND ($1 OR revoked_at IS NULL)
+		ORDER BY created_at DESC
+	`, includeRevoked, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []*model.APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// RotateAPIKey stores the replacement of an API key and lets the old key expire at oldExpiresAt,
+// or earlier if it already expires sooner. It returns sql.ErrNoRows if the old key is revoked or unknown.
+func (d *dbStore) RotateAPIKey(ctx context.Context, tenantID string, next *model.APIKey, oldExpiresAt time.Time) (err error) {
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("could not begin transaction: %w", err)
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			rlog.Error("failed to rollback rotate api key", "error", rbErr)
+		}
+	}()
+
+	res, err := tx.Exec(ctx, `
+		UPDATE api_keys
+		SET expires_at = LEAST(COALESCE(expires_at, $2), $2)
+		WHERE key_id = $1 AND tenant_id = $3 AND revoked_at IS NULL
+	`, next.RotatedFrom, oldExpiresAt, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to expire rotated api key: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return sql.ErrNoRows
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO api_keys (key_id, tenant_id, name, key_hash, scopes, created_by, rotated_from)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING created_at
+	`, next.KeyID, tenantID, next.Name, next.KeyHash, scopeStrings(next.Scopes), next.CreatedBy, next.RotatedFrom).Scan(&next.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert rotated api key: %w", err)
+	}
+	next.TenantID = tenantID
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit failed: %w", err)
+	}
+	return nil
+}
+
+// RevokeAPIKey revokes an API key at once. It returns sql.ErrNoRows if the key is unknown or already revoked.
+func (d *dbStore) RevokeAPIKey(ctx context.Context, tenantID, keyID string) error {
+	res, err := d.db.Exec(ctx, `
+		UPDATE api_keys SET revoked_at = now()
+		WHERE key_id = $1 AND tenant_id = $2 AND revoked_at IS NULL
+	`, keyID, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"encore.app/fee/model"
+	"encore.dev/rlog"
+	"encore.dev/storage/sqldb"
+)
+
+// audited runs a change in a transaction together with the audit entries it returns, so a
+// change is never committed without its entries. Entries whose ID is already in the log, written
+// by an earlier attempt of the same activity, are skipped. The domain events of the entries are
+// written to the outbox, and those of webhook event types queued for the tenant's webhook
+// subscriptions, in the same transaction.
+func (d *dbStore) audited(ctx context.Context, tenantID, op string, change func(tx *sqldb.Tx) ([]model.AuditEntry, error)) (err error) {
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("could not begin transaction: %w", err)
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			rlog.Error("failed to rollback "+op, "error", rbErr)
+		}
+	}()
+
+	entries, err := change(tx)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO audit_log (tenant_id, entry_id, bill_id, line_item_id, event, actor, request_id, before, after)
+			VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, NULLIF($7, ''), $8::jsonb, $9::jsonb)
+			ON CONFLICT (tenant_id, entry_id) DO NOTHING
+		`, tenantID, entry.EntryID, entry.BillID, entry.LineItemID, entry.Event, entry.Actor, entry.RequestID,
+			nullJSON(entry.Before), nullJSON(entry.After))
+		if err != nil {
+			return fmt.Errorf("failed to write audit entry: %w", err)
+		}
+		if event, ok := model.WebhookEventOf(entry); ok {
+			if err := enqueueWebhookEvent(ctx, tx, tenantID, event); err != nil {
+				return err
+			}
+		}
+		events, err := model.OutboxEventsOf(tenantID, entry, time.Now())
+		if err != nil {
+			return fmt.Errorf("failed to encode outbox events: %w", err)
+		}
+		if err := enqueueOutboxEvents(ctx, tx, tenantID, events); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit failed: %w", err)
+	}
+	return nil
+}
+
+// auditEntry starts the audit entry of an event about subject, the bill or one of its line items.
+func auditEntry(source model.AuditSource, event model.AuditEvent, billID, lineItemID string, before, after any) model.AuditEntry {
+	subject := billID
+	if lineItemID != "" {
+		subject = lineItemID
+	}
+	return model.AuditEntry{
+		EntryID:    source.EntryID(event, subject),
+		BillID:     billID,
+		LineItemID: lineItemID,
+		Event:      event,
+		Actor:      source.Actor,
+		RequestID:  source.RequestID,
+		Before:     auditValues(before),
+		After:      auditValues(after),
+	}
+}
+
+// auditValues encodes the before or after values of an entry, nil stays empty.
+func auditValues(values any) json.RawMessage {
+	if values == nil {
+		return nil
+	}
+	b, _ := json.Marshal(values)
+	return b
+}
+
+func nullJSON(b json.RawMessage) *string {
+	if len(b) == 0 {
+		return nil
+	}
+	s := string(b)
+	return &s
+}
+
+// ListAuditEntries pages through the audit log in the order it was written, starting after the
+// entry with sequence number afterSeq.
+func (d *dbStore) ListAuditEntries(ctx context.Context, tenantID string, filter model.AuditFilter, limit int, afterSeq int64) ([]*model.AuditEntry, bool, error) {
+	q := &sqlQuery{}
+	q.where("tenant_id = %s", tenantID)
+	q.where("id > %s", afterSeq)
+	if filter.BillID != "" {
+		q.where("bill_id = %s", filter.BillID)
+	}
+	if len(filter.Events) > 0 {
+		events := make([]string, len(filter.Events))
+		for i, event := range filter.Events {
+			events[i] = string(event)
+		}
+		q.where("event = ANY(%s::varchar[])", events)
+	}
+	if filter.Actor != "" {
+		q.where("actor = %s", filter.Actor)
+	}
+	if filter.RequestID != "" {
+		q.where("request_id = %s", filter.RequestID)
+	}
+	if filter.From != nil {
+		q.where("created_at >= %s", *filter.From)
+	}
+	if filter.To != nil {
+		q.where("created_at < %s", *filter.To)
+	}
+
+	query := `
+		SELECT id, entry_id, bill_id, COALESCE(line_item_id, ''), event, actor, COALESCE(request_id, ''),
+			before::text, after::text, created_at
+		FROM audit_log
+		WHERE ` + strings.Join(q.conds, " AND ") + `
+		ORDER BY id
+		LIMIT ` + q.arg(limit+1)
+	rows, err := d.db.Query(ctx, query, q.args...)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to list audit entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*model.AuditEntry
+	for rows.Next() {
+		var entry model.AuditEntry
+		var before, after *string
+		if err := rows.Scan(&entry.Seq, &entry.EntryID, &entry.BillID, &entry.LineItemID, &entry.Event, &entry.Actor,
+			&entry.RequestID, &before, &after, &entry.CreatedAt); err != nil {
+			return nil, false, err
+		}
+		if before != nil {
+			entry.Before = json.RawMessage(*before)
+		}
+		if after != nil {
+			entry.After = json.RawMessage(*after)
+		}
+		entries = append(entries, &entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+
+	hasMore := len(entries) > limit
+	if hasMore {
+		entries = entries[:limit]
+	}
+	return entries, hasMore, nil
+}
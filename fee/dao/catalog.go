@@ -0,0 +1,251 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"encore.app/fee/model"
+	"encore.dev/rlog"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrProductExists = errors.New("product already exists")
+	ErrPriceExists   = errors.New("price already exists")
+)
+
+// CreateProduct inserts a new product into the catalog.
+func (d *dbStore) CreateProduct(ctx context.Context, tenantID string, product *model.Product) error {
+	err := d.db.QueryRow(ctx, `
+		INSERT INTO products (tenant_id, product_id, name, description, active, updated_at)
+		VALUES ($1, $2, $3, $4, TRUE, now())
+		ON CONFLICT (tenant_id, product_id) DO NOTHING
+		RETURNING active, created_at, updated_at;
+	`, tenantID, product.ProductID, product.Name, product.Description).Scan(&product.Active, &product.CreatedAt, &product.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || errors.Is(err, pgx.ErrNoRows) {
+			return ErrProductExists
+		}
+		return fmt.Errorf("failed to insert product: %w", err)
+	}
+	return nil
+}
+
+// GetProduct retrieves a product by its ID.
+func (d *dbStore) GetProduct(ctx context.Context, tenantID, productID string) (*model.Product, error) {
+	var product model.Product
+	err := d.db.QueryRow(ctx, `
+		SELECT product_id, name, description, active, created_at, updated_at
+		FROM products
+		WHERE tenant_id = $1 AND product_id = $2
+	`, tenantID, productID).Scan(&product.ProductID, &product.Name, &product.Description, &product.Active, &product.CreatedAt, &product.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+// ListProducts retrieves a page of products, newest first.
+func (d *dbStore) ListProducts(ctx context.Context, tenantID string, activeOnly bool, limit int, cursor time.Time) ([]*model.Product, bool, error) {
+	rows, err := d.db.Query(ctx, `
+		SELECT product_id, name, description, active, created_at, updated_at
+		FROM products
+		WHERE tenant_id = $4 AND created_at < $1 AND (active OR NOT $2)
+		ORDER BY created_at DESC LIMIT $3
+	`, cursor, activeOnly, limit+1, tenantID)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	var products []*model.Product
+	for rows.Next() {
+		var product model.Product
+		if err := rows.Scan(&product.ProductID, &product.Name, &product.Description, &product.Active, &product.CreatedAt, &product.UpdatedAt); err != nil {
+			return nil, false, err
+		}
+		products = append(products, &product)
+	}
+
+	hasMore := len(products) > limit
+	if hasMore {
+		products = products[:limit]
+	}
+	return products, hasMore, nil
+}
+
+// UpdateProduct applies the non-nil fields to a product and returns the updated row.
+func (d *dbStore) UpdateProduct(ctx context.Context, tenantID, productID string, name, description *string, active *bool) (*model.Product, error) {
+	var product model.Product
+	err := d.db.QueryRow(ctx, `
+		UPDATE products
+		SET name = COALESCE($2, name),
+			description = COALESCE($3, description),
+			active = COALESCE($4, active),
+			updated_at = now()
+		WHERE tenant_id = $5 AND product_id = $1
+		RETURNING product_id, name, description, active, created_at, updated_at;
+	`, productID, name, description, active, tenantID).Scan(&product.ProductID, &product.Name, &product.Description, &product.Active, &product.CreatedAt, &product.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+// CreatePrice inserts a new price together with its first plan version.
+func (d *dbStore) CreatePrice(ctx context.Context, tenantID string, price *model.Price) (err error) {
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("could not begin transaction: %w", err)
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			rlog.Error("failed to rollback create price", "error", rbErr)
+		}
+	}()
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO prices (tenant_id, price_id, product_id, currency, unit, type, recurring_interval, active, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, TRUE, now())
+		ON CONFLICT (tenant_id, price_id) DO NOTHING
+		RETURNING active, created_at, updated_at;
+	`, tenantID, price.PriceID, price.ProductID, price.Currency, price.Unit, price.Type, price.RecurringInterval).Scan(&price.Active, &price.CreatedAt, &price.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || errors.Is(err, pgx.ErrNoRows) {
+			return ErrPriceExists
+		}
+		return fmt.Errorf("failed to insert price: %w", err)
+	}
+
+	price.Version = 1
+	_, err = tx.Exec(ctx, `
+		INSERT INTO price_versions (tenant_id, price_id, version, unit_amount, effective_from)
+		VALUES ($1, $2, $3, $4, $5)
+	`, tenantID, price.PriceID, price.Version, price.UnitAmount, price.EffectiveFrom)
+	if err != nil {
+		return fmt.Errorf("failed to insert price version: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit failed: %w", err)
+	}
+	return nil
+}
+
+// GetPrice retrieves a price resolved to the plan version effective at the given time.
+// It returns sql.ErrNoRows if the price does not exist or has no version effective yet.
+func (d *dbStore) GetPrice(ctx context.Context, tenantID, priceID string, at time.Time) (*model.Price, error) {
+	var price model.Price
+	err := d.db.QueryRow(ctx, `
+		SELECT p.price_id, p.product_id, p.currency, p.unit, p.type, p.recurring_interval, p.active,
+			p.created_at, p.updated_at, v.version, v.unit_amount, v.effective_from
+		FROM prices p
+		JOIN price_versions v ON v.tenant_id = p.tenant_id AND v.price_id = p.price_id
+		WHERE p.tenant_id = $3 AND p.price_id = $1 AND v.effective_from <= $2
+		ORDER BY v.effective_from DESC, v.version DESC
+		LIMIT 1
+	`, priceID, at, tenantID).Scan(&price.PriceID, &price.ProductID, &price.Currency, &price.Unit, &price.Type, &price.RecurringInterval, &price.Active,
+		&price.CreatedAt, &price.UpdatedAt, &price.Version, &price.UnitAmount, &price.EffectiveFrom)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, err
+	}
+	return &price, nil
+}
+
+// ListPrices retrieves every price of a product resolved to the version effective at the given time.
+func (d *dbStore) ListPrices(ctx context.Context, tenantID, productID string, at time.Time) ([]*model.Price, error) {
+	rows, err := d.db.Query(ctx, `
+		SELECT DISTINCT ON (p.price_id)
+			p.price_id, p.product_id, p.currency, p.unit, p.type, p.recurring_interval, p.active,
+			p.created_at, p.updated_at, v.version, v.unit_amount, v.effective_from
+		FROM prices p
+		JOIN price_versions v ON v.tenant_id = p.tenant_id AND v.price_id = p.price_id
+		WHERE p.tenant_id = $3 AND p.product_id = $1 AND v.effective_from <= $2
+		ORDER BY p.price_id, v.effective_from DESC, v.version DESC
+	`, productID, at, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var prices []*model.Price
+	for rows.Next() {
+		var price model.Price
+		if err := rows.Scan(&price.PriceID, &price.ProductID, &price.Currency, &price.Unit, &price.Type, &price.RecurringInterval, &price.Active,
+			&price.CreatedAt, &price.UpdatedAt, &price.Version, &price.UnitAmount, &price.EffectiveFrom); err != nil {
+			return nil, err
+		}
+		prices = append(prices, &price)
+	}
+	return prices, nil
+}
+
+// SetPriceActive archives or restores a price.
+func (d *dbStore) SetPriceActive(ctx context.Context, tenantID, priceID string, active bool) error {
+	result, err := d.db.Exec(ctx, `
+		UPDATE prices
+		SET active = $2, updated_at = now()
+		WHERE tenant_id = $3 AND price_id = $1
+	`, priceID, active, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to update price: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// AddPriceVersion schedules a new plan version of a price from the given effective date.
+func (d *dbStore) AddPriceVersion(ctx context.Context, tenantID, priceID string, unitAmount int64, effectiveFrom time.Time) (*model.PriceVersion, error) {
+	version := model.PriceVersion{PriceID: priceID, UnitAmount: unitAmount, EffectiveFrom: effectiveFrom}
+	// Lock the price row so concurrent writers can't allocate the same version number.
+	err := d.db.QueryRow(ctx, `
+		WITH locked AS (
+			SELECT tenant_id, price_id FROM prices WHERE tenant_id = $4 AND price_id = $1 FOR UPDATE
+		)
+		INSERT INTO price_versions (tenant_id, price_id, version, unit_amount, effective_from)
+		SELECT l.tenant_id, l.price_id,
+			(SELECT COALESCE(MAX(version), 0) + 1 FROM price_versions WHERE tenant_id = l.tenant_id AND price_id = l.price_id),
+			$2, $3
+		FROM locked l
+		RETURNING version, created_at;
+	`, priceID, unitAmount, effectiveFrom, tenantID).Scan(&version.Version, &version.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to insert price version: %w", err)
+	}
+	return &version, nil
+}
+
+// ListPriceVersions retrieves every plan version of a price, newest first.
+func (d *dbStore) ListPriceVersions(ctx context.Context, tenantID, priceID string) ([]model.PriceVersion, error) {
+	rows, err := d.db.Query(ctx, `
+		SELECT price_id, version, unit_amount, effective_from, created_at
+		FROM price_versions
+		WHERE tenant_id = $1 AND price_id = $2
+		ORDER BY version DESC
+	`, tenantID, priceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []model.PriceVersion
+	for rows.Next() {
+		var version model.PriceVersion
+		if err := rows.Scan(&version.PriceID, &version.Version, &version.UnitAmount, &version.EffectiveFrom, &version.CreatedAt); err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+	return versions, nil
+}
@@ -0,0 +1,131 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"encore.app/fee/model"
+	"encore.dev/storage/sqldb"
+	"github.com/jackc/pgx/v5"
+)
+
+// querier reads within a transaction or outside of one.
+type querier interface {
+	QueryRow(ctx context.Context, query string, args ...any) *sqldb.Row
+	Query(ctx context.Context, query string, args ...any) (*sqldb.Rows, error)
+}
+
+// appendBillChain seals the bill as it is in tx into the next link of the tenant's chain.
+// Nothing is appended if the link with linkID already exists.
+func appendBillChain(ctx context.Context, tx *sqldb.Tx, tenantID, billID, linkID string) error {
+	// Links of a tenant are appended one at a time, each one names the hash of the one before.
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext('bill_chain/' || $1))", tenantID); err != nil {
+		return fmt.Errorf("failed to lock bill chain: %w", err)
+	}
+	var exists bool
+	err := tx.QueryRow(ctx, `
+		SELECT EXISTS(SELECT 1 FROM bill_chain WHERE tenant_id = $1 AND link_id = $2)
+	`, tenantID, linkID).Scan(&exists)
+	if err != nil || exists {
+		return err
+	}
+
+	record, err := billChainRecord(ctx, tx, tenantID, billID)
+	if err != nil {
+		return err
+	}
+	var seq int64
+	var prevHash string
+	err = tx.QueryRow(ctx, `
+		SELECT seq, hash FROM bill_chain WHERE tenant_id = $1 ORDER BY seq DESC LIMIT 1
+	`, tenantID).Scan(&seq, &prevHash)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) && !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("failed to read bill chain head: %w", err)
+	}
+
+	content := record.Content()
+	_, err = tx.Exec(ctx, `
+		INSERT INTO bill_chain (tenant_id, seq, link_id, bill_id, content, prev_hash, hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, tenantID, seq+1, linkID, billID, content, prevHash, model.ChainHash(prevHash, content))
+	if err != nil {
+		return fmt.Errorf("failed to append bill chain: %w", err)
+	}
+	return nil
+}
+
+// billChainRecord reads the sealed values of a bill and its line items. It returns sql.ErrNoRows
+// if the bill doesn't exist.
+func billChainRecord(ctx context.Context, q querier, tenantID, billID string) (*model.ChainRecord, error) {
+	record := model.ChainRecord{BillID: billID, LineItems: []model.ChainLineItem{}}
+	var closedAt *time.Time
+	err := q.QueryRow(ctx, `
+		SELECT currency, total_amount, closed_at FROM bills WHERE tenant_id = $1 AND bill_id = $2
+	`, tenantID, billID).Scan(&record.Currency, &record.TotalAmount, &closedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || errors.Is(err, pgx.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to read bill for chain: %w", err)
+	}
+	if closedAt != nil {
+		record.ClosedAt = *closedAt
+	}
+
+	rows, err := q.Query(ctx, `
+		SELECT line_item_id, amount, status
+		FROM line_items
+		WHERE tenant_id = $1 AND bill_id = $2
+		ORDER BY line_item_id
+	`, tenantID, billID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read line items for chain: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var item model.ChainLineItem
+		if err := rows.Scan(&item.LineItemID, &item.Amount, &item.Status); err != nil {
+			return nil, err
+		}
+		record.LineItems = append(record.LineItems, item)
+	}
+	return &record, rows.Err()
+}
+
+// GetBillChainRecord returns the values the chain would seal for the bill as it is now.
+func (d *dbStore) GetBillChainRecord(ctx context.Context, tenantID, billID string) (*model.ChainRecord, error) {
+	return billChainRecord(ctx, d.db, tenantID, billID)
+}
+
+// ListBillChain reads the links of the tenant's chain after afterSeq, in chain order.
+func (d *dbStore) ListBillChain(ctx context.Context, tenantID string, afterSeq int64, limit int) ([]*model.ChainLink, error) {
+	rows, err := d.db.Query(ctx, `
+		SELECT c.seq, c.bill_id, c.content, c.prev_hash, c.hash,
+			NOT EXISTS (
+				SELECT 1 FROM bill_chain n WHERE n.tenant_id = c.tenant_id AND n.bill_id = c.bill_id AND n.seq > c.seq
+			),
+			COALESCE(b.status, '')
+		FROM bill_chain c
+		LEFT JOIN bills b ON b.tenant_id = c.tenant_id AND b.bill_id = c.bill_id
+		WHERE c.tenant_id = $1 AND c.seq > $2
+		ORDER BY c.seq
+		LIMIT $3
+	`, tenantID, afterSeq, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list bill chain: %w", err)
+	}
+	defer rows.Close()
+
+	var links []*model.ChainLink
+	for rows.Next() {
+		var link model.ChainLink
+		if err := rows.Scan(&link.Seq, &link.BillID, &link.Content, &link.PrevHash, &link.Hash, &link.Latest, &link.BillStatus); err != nil {
+			return nil, err
+		}
+		links = append(links, &link)
+	}
+	return links, rows.Err()
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"slices"
+	"strings"
 	"time"
 
 	"encoding/json" // Import for JSON unmarshaling
@@ -33,46 +35,181 @@ func New() DB {
 }
 
 // CreateBill inserts a new bill into the database.
-func (d *dbStore) CreateBill(ctx context.Context, billID, policyType, currency string, startAt time.Time, metadata model.BillMetadata) error {
+func (d *dbStore) CreateBill(ctx context.Context, tenantID, billID, policyType, currency, customerID string, status model.BillStatus, periodStart, periodEnd time.Time, metadata model.BillMetadata, source model.AuditSource) error {
 	metadataBytes, err := json.Marshal(metadata)
 	if err != nil {
 		return err
 	}
-	_, err = d.db.Exec(ctx, `
-		INSERT INTO bills (bill_id, policy_type, currency, status, started_at, metadata, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, now())
-		ON CONFLICT (bill_id) DO NOTHING;
-	`, billID, policyType, currency, model.BillStatusOpen, startAt, metadataBytes)
-	if err != nil {
-		return err
-	}
-	return nil
+	return d.audited(ctx, tenantID, "create bill", func(tx *sqldb.Tx) ([]model.AuditEntry, error) {
+		tag, err := tx.Exec(ctx, `
+			INSERT INTO bills (tenant_id, bill_id, policy_type, currency, customer_id, status, period_start, period_end, metadata, updated_at)
+			VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8, $9, now())
+			ON CONFLICT (tenant_id, bill_id) DO NOTHING;
+		`, tenantID, billID, policyType, currency, customerID, status, periodStart, periodEnd, metadataBytes)
+		if err != nil || tag.RowsAffected() == 0 {
+			return nil, err
+		}
+		return []model.AuditEntry{auditEntry(source, model.AuditBillCreated, billID, "", nil, map[string]any{
+			"status":       status,
+			"policy_type":  policyType,
+			"currency":     currency,
+			"customer_id":  customerID,
+			"period_start": periodStart,
+			"period_end":   periodEnd,
+			"metadata":     metadata,
+		})}, nil
+	})
 }
 
 // GetBillStatus retrieves the status of a bill.
-func (d *dbStore) GetBillStatus(ctx context.Context, billID string) (model.BillStatus, error) {
+func (d *dbStore) GetBillStatus(ctx context.Context, tenantID, billID string) (model.BillStatus, error) {
 	var status model.BillStatus
-	err := d.db.QueryRow(ctx, "SELECT status FROM bills WHERE bill_id = $1", billID).Scan(&status)
+	err := d.db.QueryRow(ctx, "SELECT status FROM bills WHERE tenant_id = $1 AND bill_id = $2", tenantID, billID).Scan(&status)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return "", errors.New("bill not found")
+			return "", ErrBillNotFound
 		}
 		return "", err
 	}
 	return status, nil
 }
 
+// ActivateBill moves a scheduled bill to open once its billing period starts.
+func (d *dbStore) ActivateBill(ctx context.Context, tenantID, billID string) error {
+	_, err := d.db.Exec(ctx, `
+		UPDATE bills
+		SET status = $1, updated_at = now()
+		WHERE tenant_id = $4 AND bill_id = $2 AND status = $3
+	`, model.BillStatusOpen, billID, model.BillStatusScheduled, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to activate bill: %w", err)
+	}
+	return nil
+}
+
+// SetBillPeriodEnd changes when an open bill closes. It returns sql.ErrNoRows if the bill is not open.
+func (d *dbStore) SetBillPeriodEnd(ctx context.Context, tenantID, billID string, periodEnd time.Time) error {
+	tag, err := d.db.Exec(ctx, `
+		UPDATE bills
+		SET period_end = $2, updated_at = now()
+		WHERE tenant_id = $4 AND bill_id = $1 AND status = $3
+	`, billID, periodEnd, model.BillStatusOpen, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to set bill period end: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// CancelBill abandons a bill that has not been closed yet. Cancelled bills are never charged.
+func (d *dbStore) CancelBill(ctx context.Context, tenantID, billID string, closure model.BillClosure, source model.AuditSource) error {
+	err := d.audited(ctx, tenantID, "cancel bill", func(tx *sqldb.Tx) ([]model.AuditEntry, error) {
+		var before model.BillStatus
+		err := tx.QueryRow(ctx, `
+			SELECT status FROM bills WHERE tenant_id = $1 AND bill_id = $2 AND status IN ($3, $4) FOR UPDATE
+		`, tenantID, billID, model.BillStatusScheduled, model.BillStatusOpen).Scan(&before)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) || errors.Is(err, pgx.ErrNoRows) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		_, err = tx.Exec(ctx, `
+			UPDATE bills
+			SET status = $1, closed_at = now(), updated_at = now(),
+				close_reason = $3, close_note = NULLIF($4, ''), closed_by = $5, close_trigger = $6
+			WHERE tenant_id = $7 AND bill_id = $2
+		`, model.BillStatusCancelled, billID, closure.Reason, closure.Note, closure.Actor, closure.Trigger, tenantID)
+		if err != nil {
+			return nil, err
+		}
+		return []model.AuditEntry{auditEntry(source, model.AuditBillCancelled, billID, "",
+			map[string]any{"status": before},
+			map[string]any{"status": model.BillStatusCancelled, "closure": closure})}, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to cancel bill: %w", err)
+	}
+	rlog.Debug("CancelBill success", "billID", billID)
+	return nil
+}
+
+// ReopenBill moves a closed bill back to open, clears how it was closed and voids its invoice.
+// Reopening an open bill again is a no-op, any other status returns sql.ErrNoRows.
+func (d *dbStore) ReopenBill(ctx context.Context, tenantID, billID string, source model.AuditSource) error {
+	err := d.audited(ctx, tenantID, "reopen bill", func(tx *sqldb.Tx) ([]model.AuditEntry, error) {
+		var status model.BillStatus
+		var total int64
+		var closure closureColumns
+		err := tx.QueryRow(ctx, `
+			SELECT status, total_amount, close_reason, close_note, closed_by, close_trigger
+			FROM bills
+			WHERE tenant_id = $1 AND bill_id = $2 AND status IN ($3, $4)
+			FOR UPDATE
+		`, tenantID, billID, model.BillStatusOpen, model.BillStatusClosed).Scan(&status, &total,
+			&closure.reason, &closure.note, &closure.actor, &closure.trigger)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) || errors.Is(err, pgx.ErrNoRows) {
+				return nil, sql.ErrNoRows
+			}
+			return nil, err
+		}
+		if status == model.BillStatusOpen {
+			return nil, nil
+		}
+		_, err = tx.Exec(ctx, `
+			UPDATE bills
+			SET status = $1, closed_at = NULL, updated_at = now(),
+				close_reason = NULL, close_note = NULL, closed_by = NULL, close_trigger = NULL, invoice_pdf_key = NULL
+			WHERE tenant_id = $3 AND bill_id = $2
+		`, model.BillStatusOpen, billID, tenantID)
+		if err != nil {
+			return nil, err
+		}
+		// The closed bill's link must not settle the reopened one, the next close creates a new one.
+		_, err = tx.Exec(ctx, `
+			UPDATE payment_links SET status = $1, updated_at = now()
+			WHERE tenant_id = $2 AND bill_id = $3 AND status = $4
+		`, model.PaymentLinkCancelled, tenantID, billID, model.PaymentLinkPending)
+		if err != nil {
+			return nil, err
+		}
+		// The closed bill's invoice no longer stands, the next close issues the one that supersedes it.
+		voided, err := voidInvoices(ctx, tx, tenantID, billID, model.InvoiceVoidBillReopened)
+		if err != nil {
+			return nil, err
+		}
+		after := map[string]any{"status": model.BillStatusOpen}
+		if len(voided) > 0 {
+			after["voided_invoice_ids"] = voided
+		}
+		return []model.AuditEntry{auditEntry(source, model.AuditBillReopened, billID, "",
+			map[string]any{"status": status, "total_amount": total, "closure": closure.toModel()},
+			after)}, nil
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to reopen bill: %w", err)
+	}
+	rlog.Debug("ReopenBill success", "billID", billID)
+	return nil
+}
+
 // InsertLineItem inserts a new line item for a bill.
-func (d *dbStore) InsertLineItem(ctx context.Context, billID, currency string, amount int64, metadata *model.LineItemMetadata) error {
+func (d *dbStore) InsertLineItem(ctx context.Context, tenantID, billID, currency string, amount int64, metadata *model.LineItemMetadata) error {
 	metadataBytes, err := json.Marshal(metadata)
 	if err != nil {
 		return fmt.Errorf("failed to marshal line item metadata: %w", err)
 	}
 
 	_, err = d.db.Exec(ctx, `
-		INSERT INTO line_items (bill_id, currency, amount, metadata)
-		VALUES ($1, $2, $3, $4)
-	`, billID, currency, amount, metadataBytes)
+		INSERT INTO line_items (tenant_id, bill_id, currency, amount, metadata)
+		VALUES ($1, $2, $3, $4, $5)
+	`, tenantID, billID, currency, amount, metadataBytes)
 	if err != nil {
 		return err
 	}
@@ -136,13 +273,46 @@ func (d *dbStore) InsertLineItem(ctx context.Context, billID, currency string, a
 // 	return nil
 // }
 
-// CloseBill updates the status and metadata of a bill to closed.
-func (d *dbStore) CloseBill(ctx context.Context, billID string, total int64) error {
-	_, err := d.db.Exec(ctx, `
-		UPDATE bills
-		SET status = $1, total_amount = $2, closed_at = now()
-		WHERE bill_id = $3
-	`, model.BillStatusClosed, total, billID)
+// CloseBill updates the status and metadata of a bill to closed and records why it was closed.
+// The closed bill and its line items are sealed into the bill chain of the tenant.
+func (d *dbStore) CloseBill(ctx context.Context, tenantID, billID string, total int64, closure model.BillClosure, source model.AuditSource) error {
+	err := d.audited(ctx, tenantID, "close bill", func(tx *sqldb.Tx) ([]model.AuditEntry, error) {
+		var status model.BillStatus
+		var before int64
+		err := tx.QueryRow(ctx, `
+			SELECT status, total_amount FROM bills WHERE tenant_id = $1 AND bill_id = $2 FOR UPDATE
+		`, tenantID, billID).Scan(&status, &before)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) || errors.Is(err, pgx.ErrNoRows) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		_, err = tx.Exec(ctx, `
+			UPDATE bills
+			SET status = $1, total_amount = $2, closed_at = COALESCE(closed_at, now()),
+				close_reason = $4, close_note = NULLIF($5, ''), closed_by = $6, close_trigger = $7
+			WHERE tenant_id = $8 AND bill_id = $3
+		`, model.BillStatusClosed, total, billID, closure.Reason, closure.Note, closure.Actor, closure.Trigger, tenantID)
+		if err != nil {
+			return nil, err
+		}
+		entry := auditEntry(source, model.AuditBillClosed, billID, "",
+			map[string]any{"status": status, "total_amount": before},
+			map[string]any{"status": model.BillStatusClosed, "total_amount": total, "closure": closure})
+		// The link shares the ID of the audit entry, a retried close seals the bill once.
+		if err := appendBillChain(ctx, tx, tenantID, billID, entry.EntryID); err != nil {
+			return nil, err
+		}
+		// Only a bill with charges is invoiced, like its post process. The invoice is issued with
+		// the close so the two can't disagree, and shares its ID for the same reason as the link.
+		if total > 0 {
+			if err := issueInvoice(ctx, tx, tenantID, billID, entry.EntryID); err != nil {
+				return nil, err
+			}
+		}
+		return []model.AuditEntry{entry}, nil
+	})
 	if err != nil {
 		return err
 	}
@@ -150,18 +320,32 @@ func (d *dbStore) CloseBill(ctx context.Context, billID string, total int64) err
 	return nil
 }
 
+// GetBillCurrency retrieves the currency of a bill.
+func (d *dbStore) GetBillCurrency(ctx context.Context, tenantID, billID string) (string, error) {
+	var currency string
+	err := d.db.QueryRow(ctx, "SELECT currency FROM bills WHERE tenant_id = $1 AND bill_id = $2", tenantID, billID).Scan(&currency)
+	if err != nil {
+		return "", err
+	}
+	return currency, nil
+}
+
 // GetBill retrieves a bill's main details.
-func (d *dbStore) GetBill(ctx context.Context, billID string) (*model.BillDetail, error) {
+func (d *dbStore) GetBill(ctx context.Context, tenantID, billID string) (*model.BillDetail, error) {
 	var bill model.BillDetail
+	var closure closureColumns
 	err := d.db.QueryRow(ctx, `
-		SELECT bill_id, status, policy_type, created_at, closed_at, currency, total_amount
+		SELECT bill_id, status, policy_type, created_at, closed_at, currency, total_amount,
+			close_reason, close_note, closed_by, close_trigger, period_start, period_end, metadata, COALESCE(customer_id, '')
 		FROM bills
-		WHERE bill_id = $1 
-	`, billID).Scan(&bill.BillID, &bill.Status, &bill.PolicyType, &bill.CreatedAt, &bill.ClosedAt, &bill.Currency, &bill.TotalAmount)
+		WHERE tenant_id = $1 AND bill_id = $2
+	`, tenantID, billID).Scan(&bill.BillID, &bill.Status, &bill.PolicyType, &bill.CreatedAt, &bill.ClosedAt, &bill.Currency, &bill.TotalAmount,
+		&closure.reason, &closure.note, &closure.actor, &closure.trigger, &bill.PeriodStart, &bill.PeriodEnd, &bill.Metadata, &bill.CustomerID)
 	if err != nil {
 		return nil, err
 	}
-	lineItems, err := d.GetLineItemsForBill(ctx, billID)
+	bill.Closure = closure.toModel()
+	lineItems, err := d.GetLineItemsForBill(ctx, tenantID, billID)
 	if err != nil {
 		return nil, err
 	}
@@ -170,13 +354,13 @@ func (d *dbStore) GetBill(ctx context.Context, billID string) (*model.BillDetail
 }
 
 // GetLineItemsForBill retrieves all line items for a given bill.
-func (d *dbStore) GetLineItemsForBill(ctx context.Context, billID string) ([]model.LineItem, error) {
+func (d *dbStore) GetLineItemsForBill(ctx context.Context, tenantID, billID string) ([]model.LineItem, error) {
 	rows, err := d.db.Query(ctx, `
 		SELECT amount, metadata, created_at, status, line_item_id
 		FROM line_items
-		WHERE bill_id = $1
+		WHERE tenant_id = $1 AND bill_id = $2
 		ORDER BY created_at DESC
-	`, billID)
+	`, tenantID, billID)
 	if err != nil {
 		return nil, err
 	}
@@ -193,16 +377,120 @@ func (d *dbStore) GetLineItemsForBill(ctx context.Context, billID string) ([]mod
 	return lineItems, nil
 }
 
-// GetBills retrieves a list of bills filtered by status.
-func (d *dbStore) GetBills(ctx context.Context, status model.BillStatus, limit int, cursor time.Time) ([]*model.BillDetail, bool, error) {
+// billSortColumns whitelists the columns GetBills can order by.
+var billSortColumns = map[model.BillSort]string{
+	model.BillSortCreatedAt: "created_at",
+	model.BillSortClosedAt:  "closed_at",
+	model.BillSortTotal:     "total_amount",
+}
+
+// sqlQuery collects the conditions of a list query, values are always bound as placeholders.
+type sqlQuery struct {
+	conds []string
+	args  []any
+}
+
+// arg binds a value and returns its placeholder.
+func (q *sqlQuery) arg(v any) string {
+	q.args = append(q.args, v)
+	return fmt.Sprintf("$%d", len(q.args))
+}
+
+func (q *sqlQuery) where(cond string, args ...any) {
+	placeholders := make([]any, len(args))
+	for i, v := range args {
+		placeholders[i] = q.arg(v)
+	}
+	q.conds = append(q.conds, fmt.Sprintf(cond, placeholders...))
+}
+
+// GetBills retrieves a page of bills matching the filter, in the order of the page.
+func (d *dbStore) GetBills(ctx context.Context, tenantID string, filter model.BillFilter, page model.BillPage) ([]*model.BillDetail, bool, error) {
+	if page.Sort == "" {
+		page.Sort = model.BillSortCreatedAt
+	}
+	column, ok := billSortColumns[page.Sort]
+	if !ok {
+		return nil, false, fmt.Errorf("invalid BillSort: %s", page.Sort)
+	}
+
+	q := &sqlQuery{}
+	q.where("tenant_id = %s", tenantID)
+	if len(filter.Statuses) > 0 {
+		statuses := make([]string, len(filter.Statuses))
+		for i, status := range filter.Statuses {
+			statuses[i] = string(status)
+		}
+		q.where("status = ANY(%s)", statuses)
+	}
+	if filter.PolicyType != "" {
+		q.where("policy_type = %s", filter.PolicyType)
+	}
+	if filter.Currency != "" {
+		q.where("currency = %s", filter.Currency)
+	}
+	if filter.CustomerID != "" {
+		q.where("customer_id = %s", filter.CustomerID)
+	}
+	if filter.CloseReason != "" {
+		q.where("close_reason = %s", filter.CloseReason)
+	}
+	if filter.CreatedFrom != nil {
+		q.where("created_at >= %s", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		q.where("created_at < %s", *filter.CreatedTo)
+	}
+	if filter.ClosedFrom != nil {
+		q.where("closed_at >= %s", *filter.ClosedFrom)
+	}
+	if filter.ClosedTo != nil {
+		q.where("closed_at < %s", *filter.ClosedTo)
+	}
+	if filter.PeriodEndFrom != nil {
+		q.where("period_end >= %s", *filter.PeriodEndFrom)
+	}
+	if filter.PeriodEndTo != nil {
+		q.where("period_end < %s", *filter.PeriodEndTo)
+	}
+	if filter.MinTotal != nil {
+		q.where("total_amount >= %s", *filter.MinTotal)
+	}
+	if filter.MaxTotal != nil {
+		q.where("total_amount <= %s", *filter.MaxTotal)
+	}
+	if page.Sort == model.BillSortClosedAt {
+		q.where("closed_at IS NOT NULL")
+	}
+
+	direction, cmp := keysetOrder(page.Asc, page.Backward && page.After != nil)
+	if after := page.After; after != nil {
+		var key any
+		switch page.Sort {
+		case model.BillSortClosedAt:
+			if after.ClosedAt == nil {
+				return nil, false, errors.New("cursor has no closed_at")
+			}
+			key = *after.ClosedAt
+		case model.BillSortTotal:
+			key = after.Total
+		default:
+			if after.CreatedAt == nil {
+				return nil, false, errors.New("cursor has no created_at")
+			}
+			key = *after.CreatedAt
+		}
+		q.where("("+column+", bill_id) "+cmp+" (%s, %s)", key, after.BillID)
+	}
+
 	query := `
-		SELECT bill_id, status, policy_type, created_at, metadata, closed_at, currency, total_amount
+		SELECT bill_id, status, policy_type, created_at, metadata, closed_at, currency, total_amount,
+			close_reason, close_note, closed_by, close_trigger, period_start, period_end, COALESCE(customer_id, '')
 		FROM bills
-		WHERE created_at < $1 AND status = $2 
-		ORDER BY created_at DESC LIMIT $3
-	`
-	args := []any{cursor, status, limit + 1}
-	rows, err := d.db.Query(ctx, query, args...)
+		WHERE ` + strings.Join(q.conds, " AND ")
+	query += fmt.Sprintf("\n\t\tORDER BY %s %s, bill_id %s LIMIT %s", column, direction, direction, q.arg(page.Limit+1))
+
+	rows, err := d.db.Query(ctx, query, q.args...)
 	if err != nil {
 		return nil, false, err
 	}
@@ -212,29 +500,89 @@ func (d *dbStore) GetBills(ctx context.Context, status model.BillStatus, limit i
 	for rows.Next() {
 		var bill model.BillDetail
 		var jsonData []byte
-		if err := rows.Scan(&bill.BillID, &bill.Status, &bill.PolicyType, &bill.CreatedAt, &jsonData, &bill.ClosedAt, &bill.Currency, &bill.TotalAmount); err != nil {
+		var closure closureColumns
+		if err := rows.Scan(&bill.BillID, &bill.Status, &bill.PolicyType, &bill.CreatedAt, &jsonData, &bill.ClosedAt, &bill.Currency, &bill.TotalAmount,
+			&closure.reason, &closure.note, &closure.actor, &closure.trigger, &bill.PeriodStart, &bill.PeriodEnd, &bill.CustomerID); err != nil {
 			return nil, false, err
 		}
+		bill.Closure = closure.toModel()
 		bills = append(bills, &bill)
 	}
 
-	hasMore := len(bills) > limit
+	hasMore := len(bills) > page.Limit
 	if hasMore {
-		bills = bills[:limit]
+		bills = bills[:page.Limit]
+	}
+	if page.Backward && page.After != nil {
+		slices.Reverse(bills)
 	}
 
 	return bills, hasMore, nil
 }
 
+// keysetOrder returns the ORDER BY direction and the comparison with the cursor of a keyset page.
+// A backward page is read in the opposite order from the cursor and reversed by the caller.
+func keysetOrder(asc, backward bool) (direction, cmp string) {
+	if asc != backward {
+		return "ASC", ">"
+	}
+	return "DESC", "<"
+}
+
+// ListLineItems retrieves a page of the line items of a bill, optionally only those with the status.
+func (d *dbStore) ListLineItems(ctx context.Context, tenantID, billID string, status model.LineItemStatus, page model.LineItemPage) ([]model.LineItem, bool, error) {
+	q := &sqlQuery{}
+	q.where("tenant_id = %s", tenantID)
+	q.where("bill_id = %s", billID)
+	if status != "" {
+		q.where("status = %s", status)
+	}
+	backward := page.Backward && page.After != nil
+	direction, cmp := keysetOrder(page.Asc, backward)
+	if after := page.After; after != nil {
+		q.where("(created_at, line_item_id) "+cmp+" (%s, %s)", after.CreatedAt, after.LineItemID)
+	}
+	query := `
+		SELECT amount, metadata, created_at, status, line_item_id
+		FROM line_items
+		WHERE ` + strings.Join(q.conds, " AND ")
+	query += fmt.Sprintf("\n\t\tORDER BY created_at %s, line_item_id %s LIMIT %s", direction, direction, q.arg(page.Limit+1))
+
+	rows, err := d.db.Query(ctx, query, q.args...)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	var lineItems []model.LineItem
+	for rows.Next() {
+		var item model.LineItem
+		if err := rows.Scan(&item.Amount, &item.Metadata, &item.CreatedAt, &item.Status, &item.LineItemID); err != nil {
+			return nil, false, err
+		}
+		lineItems = append(lineItems, item)
+	}
+
+	hasMore := len(lineItems) > page.Limit
+	if hasMore {
+		lineItems = lineItems[:page.Limit]
+	}
+	if backward {
+		slices.Reverse(lineItems)
+	}
+
+	return lineItems, hasMore, nil
+}
+
 // GetBillIDs retrieves a bill id from status policy type
-func (d *dbStore) GetBillIDs(ctx context.Context, status model.BillStatus, policyType model.PolicyType, limit int, cursor time.Time) ([]string, bool, error) {
+func (d *dbStore) GetBillIDs(ctx context.Context, tenantID string, status model.BillStatus, policyType model.PolicyType, limit int, cursor time.Time) ([]string, bool, error) {
 	query := `
 		SELECT bill_id
 		FROM bills
-		WHERE created_at < $1 AND status = $2 AND policy_type = $3
+		WHERE tenant_id = $5 AND created_at < $1 AND status = $2 AND policy_type = $3
 		ORDER BY created_at DESC LIMIT $4
 	`
-	args := []interface{}{cursor, status, policyType, limit + 1}
+	args := []interface{}{cursor, status, policyType, limit + 1, tenantID}
 	rows, err := d.db.Query(ctx, query, args...)
 	if err != nil {
 		return nil, false, err
@@ -258,33 +606,271 @@ func (d *dbStore) GetBillIDs(ctx context.Context, status model.BillStatus, polic
 	return billIDs, hasMore, nil
 }
 
-func (d *dbStore) AddLineItem(ctx context.Context, billID string, amount int64, metadata *model.LineItemMetadata, lineItemID string) error {
+// AddLineItem inserts a line item and returns the line item that represents it. That is
+// lineItemID itself, unless another line item of the bill already carries the same external_ref,
+// in which case nothing is inserted and the original line item is returned. The result is only
+// Inserted when this change added the line item, a retry of the same change included, so a
+// racing request with the same line item ID is not counted twice.
+func (d *dbStore) AddLineItem(ctx context.Context, tenantID, billID string, amount int64, metadata *model.LineItemMetadata, lineItemID string, source model.AuditSource) (model.AddedLineItem, error) {
+	added := model.AddedLineItem{LineItemID: lineItemID}
 	metadataBytes, err := json.Marshal(metadata)
 	if err != nil {
-		return fmt.Errorf("failed to marshal line item metadata: %w", err)
+		return added, fmt.Errorf("failed to marshal line item metadata: %w", err)
 	}
-	_, err = d.db.Exec(ctx, `
-		INSERT INTO line_items (bill_id, amount, metadata, line_item_id, updated_at)
-		VALUES ($1, $2, $3, $4, now())
-		ON CONFLICT (line_item_id) DO NOTHING;
-	`, billID, amount, metadataBytes, lineItemID)
+	var externalRef string
+	if metadata != nil {
+		externalRef = metadata.ExternalRef
+	}
+	err = d.audited(ctx, tenantID, "add line item", func(tx *sqldb.Tx) ([]model.AuditEntry, error) {
+		tag, err := tx.Exec(ctx, `
+			INSERT INTO line_items (tenant_id, bill_id, amount, metadata, line_item_id, external_ref, updated_at)
+			VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), now())
+			ON CONFLICT DO NOTHING;
+		`, tenantID, billID, amount, metadataBytes, lineItemID, externalRef)
+		if err != nil {
+			return nil, err
+		}
+		entry := lineItemAddedEntry(source, billID, lineItemID, amount, metadata)
+		if tag.RowsAffected() == 0 {
+			retried, err := auditedBefore(ctx, tx, tenantID, source, []string{entry.EntryID})
+			if err != nil || !retried[entry.EntryID] {
+				return nil, err
+			}
+		} else if err := addBillTotal(ctx, tx, tenantID, billID, amount); err != nil {
+			return nil, err
+		}
+		added.Inserted = true
+		return []model.AuditEntry{entry}, nil
+	})
+	if err != nil {
+		return added, fmt.Errorf("failed to insert line item: %w", err)
+	}
+	if added.Inserted || externalRef == "" {
+		return added, nil
+	}
+	original, err := d.lineItemIDsByExternalRef(ctx, tenantID, billID, []string{externalRef})
+	if err != nil {
+		return added, err
+	}
+	if id, ok := original[externalRef]; ok {
+		added.LineItemID = id
+	}
+	return added, nil
+}
+
+// AddLineItems inserts a batch of line items for a bill with a single multi-row insert.
+// It returns the outcome of every item, in order, with the same semantics as AddLineItem.
+func (d *dbStore) AddLineItems(ctx context.Context, tenantID, billID string, items []model.LineItemInput, source model.AuditSource) ([]model.AddedLineItem, error) {
+	if len(items) == 0 {
+		return nil, nil
+	}
+	lineItemIDs := make([]string, len(items))
+	amounts := make([]int64, len(items))
+	metadata := make([]string, len(items))
+	externalRefs := make([]string, len(items))
+	for i, item := range items {
+		metadataBytes, err := json.Marshal(item.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal line item metadata: %w", err)
+		}
+		lineItemIDs[i] = item.LineItemID
+		amounts[i] = item.Amount
+		metadata[i] = string(metadataBytes)
+		if item.Metadata != nil {
+			externalRefs[i] = item.Metadata.ExternalRef
+		}
+	}
+	added := make([]model.AddedLineItem, len(items))
+	err := d.audited(ctx, tenantID, "add line items", func(tx *sqldb.Tx) ([]model.AuditEntry, error) {
+		rows, err := tx.Query(ctx, `
+			INSERT INTO line_items (tenant_id, bill_id, line_item_id, amount, metadata, external_ref, updated_at)
+			SELECT $6, $1, t.line_item_id, t.amount, t.metadata::jsonb, NULLIF(t.external_ref, ''), now()
+			FROM unnest($2::varchar[], $3::bigint[], $4::text[], $5::varchar[]) AS t(line_item_id, amount, metadata, external_ref)
+			ON CONFLICT DO NOTHING
+			RETURNING line_item_id;
+		`, billID, lineItemIDs, amounts, metadata, externalRefs, tenantID)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		inserted := make(map[string]bool, len(items))
+		for rows.Next() {
+			var lineItemID string
+			if err := rows.Scan(&lineItemID); err != nil {
+				return nil, err
+			}
+			inserted[lineItemID] = true
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		rows.Close()
+
+		entries := make([]model.AuditEntry, len(items))
+		var conflicts []string
+		for i, item := range items {
+			entries[i] = lineItemAddedEntry(source, billID, item.LineItemID, item.Amount, item.Metadata)
+			if !inserted[item.LineItemID] {
+				conflicts = append(conflicts, entries[i].EntryID)
+			}
+		}
+		retried, err := auditedBefore(ctx, tx, tenantID, source, conflicts)
+		if err != nil {
+			return nil, err
+		}
+		var audit []model.AuditEntry
+		var total int64
+		for i, item := range items {
+			added[i].LineItemID = item.LineItemID
+			if inserted[item.LineItemID] {
+				total += item.Amount
+			}
+			if inserted[item.LineItemID] || retried[entries[i].EntryID] {
+				added[i].Inserted = true
+				audit = append(audit, entries[i])
+			}
+		}
+		if err := addBillTotal(ctx, tx, tenantID, billID, total); err != nil {
+			return nil, err
+		}
+		return audit, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert line items: %w", err)
+	}
+
+	var refs []string
+	for i, ref := range externalRefs {
+		if ref != "" && !added[i].Inserted {
+			refs = append(refs, ref)
+		}
+	}
+	original, err := d.lineItemIDsByExternalRef(ctx, tenantID, billID, refs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range added {
+		if id, ok := original[externalRefs[i]]; ok && !added[i].Inserted {
+			added[i].LineItemID = id
+		}
+	}
+	return added, nil
+}
+
+// addBillTotal keeps the stored total of an open bill in step with its active line items, so
+// the bill list filters and sorts it by the amount it shows. Closing a bill stores the final total.
+func addBillTotal(ctx context.Context, tx *sqldb.Tx, tenantID, billID string, amount int64) error {
+	if amount == 0 {
+		return nil
+	}
+	_, err := tx.Exec(ctx, `
+		UPDATE bills SET total_amount = total_amount + $3, updated_at = now()
+		WHERE tenant_id = $1 AND bill_id = $2
+	`, tenantID, billID, amount)
 	if err != nil {
-		return fmt.Errorf("failed to insert line item: %w", err)
+		return fmt.Errorf("failed to update bill total: %w", err)
 	}
 	return nil
 }
 
-func (d *dbStore) UpdateLineItem(ctx context.Context, billID, lineItemID string, status string) (*model.LineItem, error) {
+// auditedBefore returns which of the audit entries are already in the log. The entry of a change
+// is keyed by the attempt to make it, so one that is there was written by an earlier attempt of
+// the same change that committed but did not report back. Changes without a key are never retried.
+func auditedBefore(ctx context.Context, tx *sqldb.Tx, tenantID string, source model.AuditSource, entryIDs []string) (map[string]bool, error) {
+	found := make(map[string]bool, len(entryIDs))
+	if source.Key == "" || len(entryIDs) == 0 {
+		return found, nil
+	}
+	rows, err := tx.Query(ctx, `
+		SELECT entry_id FROM audit_log WHERE tenant_id = $1 AND entry_id = ANY($2::varchar[])
+	`, tenantID, entryIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up audit entries: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var entryID string
+		if err := rows.Scan(&entryID); err != nil {
+			return nil, err
+		}
+		found[entryID] = true
+	}
+	return found, rows.Err()
+}
+
+func lineItemAddedEntry(source model.AuditSource, billID, lineItemID string, amount int64, metadata *model.LineItemMetadata) model.AuditEntry {
+	return auditEntry(source, model.AuditLineItemAdded, billID, lineItemID, nil, map[string]any{
+		"status":   model.LineItemStatusActive,
+		"amount":   amount,
+		"metadata": metadata,
+	})
+}
+
+// GetLineItemByExternalRef returns sql.ErrNoRows if the bill has no line item with the given external_ref.
+func (d *dbStore) GetLineItemByExternalRef(ctx context.Context, tenantID, billID, externalRef string) (*model.LineItem, error) {
 	var lineItem model.LineItem
 	err := d.db.QueryRow(ctx, `
-		UPDATE line_items li
-		SET status = $1,
-			updated_at = now()
-		WHERE li.line_item_id = $2
-		AND li.bill_id = $3
-		AND li.status = 'ACTIVE' 
-		RETURNING li.amount;
-	`, status, lineItemID, billID).Scan(&lineItem.Amount)
+		SELECT line_item_id, amount, metadata, status, created_at
+		FROM line_items
+		WHERE tenant_id = $3 AND bill_id = $1 AND external_ref = $2
+	`, billID, externalRef, tenantID).Scan(&lineItem.LineItemID, &lineItem.Amount, &lineItem.Metadata, &lineItem.Status, &lineItem.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get line item by external ref: %w", err)
+	}
+	return &lineItem, nil
+}
+
+func (d *dbStore) lineItemIDsByExternalRef(ctx context.Context, tenantID, billID string, externalRefs []string) (map[string]string, error) {
+	lineItemIDs := make(map[string]string, len(externalRefs))
+	if len(externalRefs) == 0 {
+		return lineItemIDs, nil
+	}
+	rows, err := d.db.Query(ctx, `
+		SELECT external_ref, line_item_id
+		FROM line_items
+		WHERE tenant_id = $3 AND bill_id = $1 AND external_ref = ANY($2::varchar[])
+	`, billID, externalRefs, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up line items by external ref: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var ref, lineItemID string
+		if err := rows.Scan(&ref, &lineItemID); err != nil {
+			return nil, err
+		}
+		lineItemIDs[ref] = lineItemID
+	}
+	return lineItemIDs, rows.Err()
+}
+
+// UpdateLineItem changes the status of an active line item, only voids are audited.
+func (d *dbStore) UpdateLineItem(ctx context.Context, tenantID, billID, lineItemID string, status string, source model.AuditSource) (*model.LineItem, error) {
+	var lineItem model.LineItem
+	err := d.audited(ctx, tenantID, "update line item", func(tx *sqldb.Tx) ([]model.AuditEntry, error) {
+		err := tx.QueryRow(ctx, `
+			UPDATE line_items li
+			SET status = $1,
+				updated_at = now()
+			WHERE li.tenant_id = $4
+			AND li.line_item_id = $2
+			AND li.bill_id = $3
+			AND li.status = 'ACTIVE'
+			RETURNING li.amount;
+		`, status, lineItemID, billID, tenantID).Scan(&lineItem.Amount)
+		if err != nil || status != string(model.LineItemStatusVoided) {
+			return nil, err
+		}
+		if err := addBillTotal(ctx, tx, tenantID, billID, -lineItem.Amount); err != nil {
+			return nil, err
+		}
+		return []model.AuditEntry{auditEntry(source, model.AuditLineItemVoided, billID, lineItemID,
+			map[string]any{"status": model.LineItemStatusActive, "amount": lineItem.Amount},
+			map[string]any{"status": status})}, nil
+	})
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) || errors.Is(err, pgx.ErrNoRows) {
@@ -296,10 +882,10 @@ func (d *dbStore) UpdateLineItem(ctx context.Context, billID, lineItemID string,
 }
 
 // IsBillExists checks if a bill with the given ID exists in the database.
-func (d *dbStore) IsBillExists(ctx context.Context, billID string) (bool, error) {
+func (d *dbStore) IsBillExists(ctx context.Context, tenantID, billID string) (bool, error) {
 	var exists bool
-	query := "SELECT EXISTS(SELECT 1 FROM bills WHERE bill_id = $1)"
-	err := d.db.QueryRow(ctx, query, billID).Scan(&exists)
+	query := "SELECT EXISTS(SELECT 1 FROM bills WHERE tenant_id = $1 AND bill_id = $2)"
+	err := d.db.QueryRow(ctx, query, tenantID, billID).Scan(&exists)
 	if err != nil && err != sql.ErrNoRows {
 		rlog.Error("failed to check if bill exists", "error", err, "bill_id", billID)
 		return false, err
@@ -308,17 +894,18 @@ func (d *dbStore) IsBillExists(ctx context.Context, billID string) (bool, error)
 }
 
 // IsLineItemExists checks if a specific line item exists with a given status.
-func (d *dbStore) IsLineItemExists(ctx context.Context, billID, lineItemID, status string) (bool, error) {
+func (d *dbStore) IsLineItemExists(ctx context.Context, tenantID, billID, lineItemID, status string) (bool, error) {
 	var exists bool
 	query := `
 		SELECT EXISTS (
 			SELECT 1
 			FROM line_items
-			WHERE line_item_id = $1
+			WHERE tenant_id = $4
+			  AND line_item_id = $1
 			  AND bill_id = $2
 			  AND status = $3
 		)`
-	err := d.db.QueryRow(ctx, query, lineItemID, billID, status).Scan(&exists)
+	err := d.db.QueryRow(ctx, query, lineItemID, billID, status, tenantID).Scan(&exists)
 	if err != nil {
 		return false, fmt.Errorf("failed to check for line item: %w", err)
 	}
@@ -396,3 +983,26 @@ func (d *dbStore) IsLineItemExists(ctx context.Context, billID, lineItemID, stat
 
 // 	return nil
 // }
+
+// closureColumns scans the nullable close_* columns of a bill.
+type closureColumns struct {
+	reason, note, actor, trigger *string
+}
+
+// toModel returns nil for bills that are not closed or were closed before reasons were recorded.
+func (c closureColumns) toModel() *model.BillClosure {
+	if c.reason == nil {
+		return nil
+	}
+	closure := &model.BillClosure{Reason: model.CloseReason(*c.reason)}
+	if c.note != nil {
+		closure.Note = *c.note
+	}
+	if c.actor != nil {
+		closure.Actor = *c.actor
+	}
+	if c.trigger != nil {
+		closure.Trigger = model.CloseTrigger(*c.trigger)
+	}
+	return closure
+}
@@ -0,0 +1,109 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"encore.app/fee/model"
+	"encore.dev/rlog"
+	"github.com/jackc/pgx/v5"
+)
+
+// BeginEmailDelivery records an attempt to send the delivery and tells whether to send it. A
+// delivery is sent again only after the mailer refused it. One that was sent or skipped isn't,
+// nor one whose earlier attempt stopped while sending, which is marked UNKNOWN.
+func (d *dbStore) BeginEmailDelivery(ctx context.Context, tenantID string, delivery *model.EmailDelivery) (bool, error) {
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			rlog.Error("failed to rollback begin email delivery", "error", rbErr)
+		}
+	}()
+
+	var status model.EmailDeliveryStatus
+	err = tx.QueryRow(ctx, `
+		SELECT status FROM email_deliveries WHERE tenant_id = $1 AND delivery_id = $2 FOR UPDATE
+	`, tenantID, delivery.DeliveryID).Scan(&status)
+	switch {
+	case errors.Is(err, sql.ErrNoRows) || errors.Is(err, pgx.ErrNoRows):
+		_, err = tx.Exec(ctx, `
+			INSERT INTO email_deliveries (tenant_id, delivery_id, bill_id, invoice_id, template, recipients, subject, status)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		`, tenantID, delivery.DeliveryID, delivery.BillID, delivery.InvoiceID, delivery.Template, nonNil(delivery.Recipients), delivery.Subject,
+			model.EmailDeliverySending)
+	case err != nil:
+		return false, fmt.Errorf("failed to read email delivery: %w", err)
+	case status == model.EmailDeliveryFailed:
+		_, err = tx.Exec(ctx, `
+			UPDATE email_deliveries
+			SET status = $1, attempts = attempts + 1, recipients = $2, subject = $3, updated_at = now()
+			WHERE tenant_id = $4 AND delivery_id = $5
+		`, model.EmailDeliverySending, nonNil(delivery.Recipients), delivery.Subject, tenantID, delivery.DeliveryID)
+	case status == model.EmailDeliverySending:
+		_, err = tx.Exec(ctx, `
+			UPDATE email_deliveries SET status = $1, updated_at = now() WHERE tenant_id = $2 AND delivery_id = $3
+		`, model.EmailDeliveryUnknown, tenantID, delivery.DeliveryID)
+		if err == nil {
+			err = tx.Commit()
+		}
+		return false, err
+	default:
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to record email delivery: %w", err)
+	}
+	return true, tx.Commit()
+}
+
+// FinishEmailDelivery records the outcome of the attempt BeginEmailDelivery began.
+func (d *dbStore) FinishEmailDelivery(ctx context.Context, tenantID, deliveryID string, status model.EmailDeliveryStatus, lastError string) error {
+	_, err := d.db.Exec(ctx, `
+		UPDATE email_deliveries
+		SET status = $1, last_error = NULLIF($2, ''), sent_at = CASE WHEN $1 = $3 THEN now() END, updated_at = now()
+		WHERE tenant_id = $4 AND delivery_id = $5
+	`, status, lastError, model.EmailDeliverySent, tenantID, deliveryID)
+	if err != nil {
+		return fmt.Errorf("failed to record email delivery outcome: %w", err)
+	}
+	return nil
+}
+
+// ListEmailDeliveries retrieves the emails sent for a bill, oldest first.
+func (d *dbStore) ListEmailDeliveries(ctx context.Context, tenantID, billID string) ([]*model.EmailDelivery, error) {
+	rows, err := d.db.Query(ctx, `
+		SELECT delivery_id, bill_id, invoice_id, template, recipients, subject, status, attempts, COALESCE(last_error, ''),
+			created_at, updated_at, sent_at
+		FROM email_deliveries
+		WHERE tenant_id = $1 AND bill_id = $2
+		ORDER BY created_at, id
+	`, tenantID, billID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list email deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []*model.EmailDelivery
+	for rows.Next() {
+		var delivery model.EmailDelivery
+		if err := rows.Scan(&delivery.DeliveryID, &delivery.BillID, &delivery.InvoiceID, &delivery.Template, &delivery.Recipients,
+			&delivery.Subject, &delivery.Status, &delivery.Attempts, &delivery.LastError, &delivery.CreatedAt, &delivery.UpdatedAt,
+			&delivery.SentAt); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, &delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
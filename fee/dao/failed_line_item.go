@@ -0,0 +1,113 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"encore.app/fee/model"
+	"github.com/jackc/pgx/v5"
+)
+
+// RecordFailedLineItem stores a line item that could not be persisted. Recording the same
+// line item again only refreshes its error, so the activity can safely be retried.
+func (d *dbStore) RecordFailedLineItem(ctx context.Context, tenantID string, item *model.FailedLineItem) error {
+	_, err := d.db.Exec(ctx, `
+		INSERT INTO failed_line_items (tenant_id, line_item_id, bill_id, source, amount, payload, error, status, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6::jsonb, $7, $8, now())
+		ON CONFLICT (tenant_id, line_item_id) DO UPDATE
+		SET error = EXCLUDED.error, updated_at = now()
+	`, tenantID, item.LineItemID, item.BillID, item.Source, item.Amount, item.Payload, item.Error, model.FailedLineItemPending)
+	if err != nil {
+		return fmt.Errorf("failed to record failed line item: %w", err)
+	}
+	return nil
+}
+
+// GetFailedLineItem returns sql.ErrNoRows if there is no failed line item with that ID.
+func (d *dbStore) GetFailedLineItem(ctx context.Context, tenantID, lineItemID string) (*model.FailedLineItem, error) {
+	var item model.FailedLineItem
+	err := d.db.QueryRow(ctx, `
+		SELECT line_item_id, bill_id, source, amount, payload::text, error, status, created_at, updated_at, resolved_at
+		FROM failed_line_items
+		WHERE tenant_id = $1 AND line_item_id = $2
+	`, tenantID, lineItemID).Scan(&item.LineItemID, &item.BillID, &item.Source, &item.Amount, &item.Payload, &item.Error,
+		&item.Status, &item.CreatedAt, &item.UpdatedAt, &item.ResolvedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get failed line item: %w", err)
+	}
+	return &item, nil
+}
+
+// ListFailedLineItems pages through failed line items, newest first, after the (created_at, line_item_id)
+// of the last item of the previous page. An empty billID lists all bills, a nil after reads the first page.
+func (d *dbStore) ListFailedLineItems(ctx context.Context, tenantID, billID string, status model.FailedLineItemStatus, limit int, after *model.LineItemCursor) ([]*model.FailedLineItem, bool, error) {
+	query := `
+		SELECT line_item_id, bill_id, source, amount, payload::text, error, status, created_at, updated_at, resolved_at
+		FROM failed_line_items
+		WHERE tenant_id = $1 AND status = $2 AND ($3 = '' OR bill_id = $3)`
+	args := []any{tenantID, status, billID}
+	if after != nil {
+		query += ` AND (created_at, line_item_id) < ($4, $5)`
+		args = append(args, after.CreatedAt, after.LineItemID)
+	}
+	args = append(args, limit+1)
+	query += fmt.Sprintf(` ORDER BY created_at DESC, line_item_id DESC LIMIT $%d`, len(args))
+	rows, err := d.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to list failed line items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []*model.FailedLineItem
+	for rows.Next() {
+		var item model.FailedLineItem
+		if err := rows.Scan(&item.LineItemID, &item.BillID, &item.Source, &item.Amount, &item.Payload, &item.Error,
+			&item.Status, &item.CreatedAt, &item.UpdatedAt, &item.ResolvedAt); err != nil {
+			return nil, false, err
+		}
+		items = append(items, &item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+
+	hasMore := len(items) > limit
+	if hasMore {
+		items = items[:limit]
+	}
+	return items, hasMore, nil
+}
+
+// ResolveFailedLineItem moves a pending failed line item to its final status.
+// It returns sql.ErrNoRows if the line item is not pending.
+func (d *dbStore) ResolveFailedLineItem(ctx context.Context, tenantID, lineItemID string, status model.FailedLineItemStatus) error {
+	tag, err := d.db.Exec(ctx, `
+		UPDATE failed_line_items
+		SET status = $2, resolved_at = now(), updated_at = now()
+		WHERE tenant_id = $4 AND line_item_id = $1 AND status = $3
+	`, lineItemID, status, model.FailedLineItemPending, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to resolve failed line item: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// CountFailedLineItems returns how many failed line items of a bill have the given status.
+func (d *dbStore) CountFailedLineItems(ctx context.Context, tenantID, billID string, status model.FailedLineItemStatus) (int, error) {
+	var count int
+	err := d.db.QueryRow(ctx, `
+		SELECT count(*) FROM failed_line_items WHERE tenant_id = $3 AND bill_id = $1 AND status = $2
+	`, billID, status, tenantID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count failed line items: %w", err)
+	}
+	return count, nil
+}
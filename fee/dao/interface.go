@@ -8,18 +8,99 @@ import (
 )
 
 type DB interface {
-	CreateBill(ctx context.Context, billID, policyType, currency string, startAt time.Time, metadata model.BillMetadata) error
-	GetBillStatus(ctx context.Context, billID string) (model.BillStatus, error)
-	InsertLineItem(ctx context.Context, billID, currency string, amount int64, metadata *model.LineItemMetadata) error
-	CloseBill(ctx context.Context, billID string, total int64) error
-	GetBill(ctx context.Context, billID string) (*model.BillDetail, error)
-	GetLineItemsForBill(ctx context.Context, billID string) ([]model.LineItem, error)
-	GetBills(ctx context.Context, status model.BillStatus, limit int, cursor time.Time) ([]*model.BillDetail, bool, error)
-	GetBillIDs(ctx context.Context, status model.BillStatus, policyType model.PolicyType, limit int, cursor time.Time) ([]string, bool, error)
-	AddLineItem(ctx context.Context, billID string, amount int64, metadata *model.LineItemMetadata, lineItemID string) error
-	UpdateLineItem(ctx context.Context, billID, lineItemID string, status string) (*model.LineItem, error)
-	IsBillExists(ctx context.Context, billID string) (bool, error)
-	IsLineItemExists(ctx context.Context, billID, lineItemID, status string) (bool, error)
+	CreateBill(ctx context.Context, tenantID, billID, policyType, currency, customerID string, status model.BillStatus, periodStart, periodEnd time.Time, metadata model.BillMetadata, source model.AuditSource) error
+	GetBillStatus(ctx context.Context, tenantID, billID string) (model.BillStatus, error)
+	ActivateBill(ctx context.Context, tenantID, billID string) error
+	SetBillPeriodEnd(ctx context.Context, tenantID, billID string, periodEnd time.Time) error
+	CancelBill(ctx context.Context, tenantID, billID string, closure model.BillClosure, source model.AuditSource) error
+	ReopenBill(ctx context.Context, tenantID, billID string, source model.AuditSource) error
+	InsertLineItem(ctx context.Context, tenantID, billID, currency string, amount int64, metadata *model.LineItemMetadata) error
+	CloseBill(ctx context.Context, tenantID, billID string, total int64, closure model.BillClosure, source model.AuditSource) error
+	GetBill(ctx context.Context, tenantID, billID string) (*model.BillDetail, error)
+	GetBillCurrency(ctx context.Context, tenantID, billID string) (string, error)
+	GetLineItemsForBill(ctx context.Context, tenantID, billID string) ([]model.LineItem, error)
+	ListLineItems(ctx context.Context, tenantID, billID string, status model.LineItemStatus, page model.LineItemPage) ([]model.LineItem, bool, error)
+	GetBills(ctx context.Context, tenantID string, filter model.BillFilter, page model.BillPage) ([]*model.BillDetail, bool, error)
+	GetBillIDs(ctx context.Context, tenantID string, status model.BillStatus, policyType model.PolicyType, limit int, cursor time.Time) ([]string, bool, error)
+	AddLineItem(ctx context.Context, tenantID, billID string, amount int64, metadata *model.LineItemMetadata, lineItemID string, source model.AuditSource) (model.AddedLineItem, error)
+	AddLineItems(ctx context.Context, tenantID, billID string, items []model.LineItemInput, source model.AuditSource) ([]model.AddedLineItem, error)
+	GetLineItemByExternalRef(ctx context.Context, tenantID, billID, externalRef string) (*model.LineItem, error)
+	UpdateLineItem(ctx context.Context, tenantID, billID, lineItemID string, status string, source model.AuditSource) (*model.LineItem, error)
+	IsBillExists(ctx context.Context, tenantID, billID string) (bool, error)
+	IsLineItemExists(ctx context.Context, tenantID, billID, lineItemID, status string) (bool, error)
+
+	RecordFailedLineItem(ctx context.Context, tenantID string, item *model.FailedLineItem) error
+	GetFailedLineItem(ctx context.Context, tenantID, lineItemID string) (*model.FailedLineItem, error)
+	ListFailedLineItems(ctx context.Context, tenantID, billID string, status model.FailedLineItemStatus, limit int, after *model.LineItemCursor) ([]*model.FailedLineItem, bool, error)
+	ResolveFailedLineItem(ctx context.Context, tenantID, lineItemID string, status model.FailedLineItemStatus) error
+	CountFailedLineItems(ctx context.Context, tenantID, billID string, status model.FailedLineItemStatus) (int, error)
+
+	CreateAPIKey(ctx context.Context, tenantID string, key *model.APIKey) error
+	GetAPIKey(ctx context.Context, tenantID, keyID string) (*model.APIKey, error)
+	LookupAPIKey(ctx context.Context, keyID string) (*model.APIKey, error)
+	ListAPIKeys(ctx context.Context, tenantID string, includeRevoked bool) ([]*model.APIKey, error)
+	RotateAPIKey(ctx context.Context, tenantID string, next *model.APIKey, oldExpiresAt time.Time) error
+	RevokeAPIKey(ctx context.Context, tenantID, keyID string) error
+
+	CreateProduct(ctx context.Context, tenantID string, product *model.Product) error
+	GetProduct(ctx context.Context, tenantID, productID string) (*model.Product, error)
+	ListProducts(ctx context.Context, tenantID string, activeOnly bool, limit int, cursor time.Time) ([]*model.Product, bool, error)
+	UpdateProduct(ctx context.Context, tenantID, productID string, name, description *string, active *bool) (*model.Product, error)
+	CreatePrice(ctx context.Context, tenantID string, price *model.Price) error
+	GetPrice(ctx context.Context, tenantID, priceID string, at time.Time) (*model.Price, error)
+	ListPrices(ctx context.Context, tenantID, productID string, at time.Time) ([]*model.Price, error)
+	SetPriceActive(ctx context.Context, tenantID, priceID string, active bool) error
+	AddPriceVersion(ctx context.Context, tenantID, priceID string, unitAmount int64, effectiveFrom time.Time) (*model.PriceVersion, error)
+	ListPriceVersions(ctx context.Context, tenantID, priceID string) ([]model.PriceVersion, error)
+
+	CreateUsageImport(ctx context.Context, tenantID string, usageImport *model.UsageImport, rows []model.UsageImportRow) (bool, error)
+	GetUsageImport(ctx context.Context, tenantID, importID string) (*model.UsageImport, error)
+	ListUsageImportRows(ctx context.Context, tenantID, importID string, afterRow, limit int, failedOnly bool) ([]model.UsageImportRow, error)
+	RecordUsageImportResults(ctx context.Context, tenantID, importID string, results []model.UsageImportRowResult) error
+	SetUsageImportStatus(ctx context.Context, tenantID, importID string, status model.UsageImportStatus) error
+
+	ListAuditEntries(ctx context.Context, tenantID string, filter model.AuditFilter, limit int, afterSeq int64) ([]*model.AuditEntry, bool, error)
+	ListBillChain(ctx context.Context, tenantID string, afterSeq int64, limit int) ([]*model.ChainLink, error)
+	GetBillChainRecord(ctx context.Context, tenantID, billID string) (*model.ChainRecord, error)
+
+	GetInvoice(ctx context.Context, tenantID, invoiceID string) (*model.Invoice, error)
+	ListBillInvoices(ctx context.Context, tenantID, billID string) ([]*model.Invoice, error)
+	UpsertCustomer(ctx context.Context, tenantID string, customer *model.Customer) error
+	GetCustomer(ctx context.Context, tenantID, customerID string) (*model.Customer, error)
+	SetInvoiceIssuer(ctx context.Context, tenantID string, issuer *model.InvoiceIssuer) error
+	GetInvoiceIssuer(ctx context.Context, tenantID string) (*model.InvoiceIssuer, error)
+	SetBillInvoicePDF(ctx context.Context, tenantID, billID, key string) error
+	GetBillInvoicePDF(ctx context.Context, tenantID, billID string) (string, error)
+
+	BeginEmailDelivery(ctx context.Context, tenantID string, delivery *model.EmailDelivery) (bool, error)
+	FinishEmailDelivery(ctx context.Context, tenantID, deliveryID string, status model.EmailDeliveryStatus, lastError string) error
+	ListEmailDeliveries(ctx context.Context, tenantID, billID string) ([]*model.EmailDelivery, error)
+
+	CreatePaymentLink(ctx context.Context, tenantID string, link *model.PaymentLink) error
+	GetPaymentLink(ctx context.Context, tenantID, linkID string) (*model.PaymentLink, error)
+	LookupPaymentLink(ctx context.Context, provider, providerLinkID string) (*model.PaymentLink, error)
+	ListBillPaymentLinks(ctx context.Context, tenantID, billID string) ([]*model.PaymentLink, error)
+	ExpirePaymentLink(ctx context.Context, tenantID, linkID string) error
+	SettleBill(ctx context.Context, tenantID, linkID, paymentID string, source model.AuditSource) error
+	RecordUnappliedPayment(ctx context.Context, tenantID string, p *model.UnappliedPayment) error
+
+	CreateWebhookSubscription(ctx context.Context, tenantID string, sub *model.WebhookSubscription) error
+	GetWebhookSubscription(ctx context.Context, tenantID, subscriptionID string) (*model.WebhookSubscription, error)
+	ListWebhookSubscriptions(ctx context.Context, tenantID string) ([]*model.WebhookSubscription, error)
+	UpdateWebhookSubscription(ctx context.Context, tenantID string, sub *model.WebhookSubscription) error
+	AddEvent(ctx context.Context, tenantID string, event *model.WebhookEvent, outbox []model.OutboxEvent) error
+	GetWebhookEvent(ctx context.Context, tenantID, eventID string) (*model.WebhookEvent, error)
+	ListUndispatchedWebhookDeliveries(ctx context.Context, tenantID string, limit int) ([]string, error)
+	ListWebhookDispatchTenants(ctx context.Context) ([]string, error)
+	MarkWebhookDeliveriesDispatched(ctx context.Context, tenantID string, deliveryIDs []string) error
+	GetWebhookDelivery(ctx context.Context, tenantID, deliveryID string) (*model.WebhookDelivery, error)
+	ListWebhookDeliveries(ctx context.Context, tenantID, subscriptionID string, status model.WebhookDeliveryStatus, limit int) ([]*model.WebhookDelivery, error)
+	RecordWebhookAttempt(ctx context.Context, tenantID, deliveryID string, attempt model.WebhookAttempt) error
+	FailWebhookDelivery(ctx context.Context, tenantID, deliveryID string) error
+	RedeliverWebhook(ctx context.Context, tenantID, redeliveryOf, deliveryID string) (*model.WebhookDelivery, error)
+
+	ListOutboxTenants(ctx context.Context) ([]string, error)
+	PublishOutboxEvents(ctx context.Context, tenantID string, limit int, publish func(context.Context, *model.OutboxEvent) error) (int, error)
 }
 
 //go:generate mockery --name=DB --output=./mocks --outpkg=mocks
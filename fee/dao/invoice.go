@@ -0,0 +1,345 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"encore.app/fee/model"
+	"encore.dev/storage/sqldb"
+	"github.com/jackc/pgx/v5"
+)
+
+// issueInvoice freezes the bill as it is in tx into a new invoice with the tenant's next invoice
+// number. Nothing is issued if the invoice with invoiceID already exists.
+func issueInvoice(ctx context.Context, tx *sqldb.Tx, tenantID, billID, invoiceID string) error {
+	var exists bool
+	err := tx.QueryRow(ctx, `
+		SELECT EXISTS(SELECT 1 FROM invoices WHERE tenant_id = $1 AND invoice_id = $2)
+	`, tenantID, invoiceID).Scan(&exists)
+	if err != nil || exists {
+		return err
+	}
+
+	snapshot, customerID, err := invoiceSnapshot(ctx, tx, tenantID, billID)
+	if err != nil {
+		return err
+	}
+	issuer, err := invoiceIssuer(ctx, tx, tenantID)
+	if err != nil {
+		return err
+	}
+	var customer json.RawMessage
+	if customerID != "" {
+		c, err := getCustomer(ctx, tx, tenantID, customerID)
+		switch {
+		case err == nil:
+			customer, _ = json.Marshal(c.Party())
+		case errors.Is(err, sql.ErrNoRows):
+			customer, _ = json.Marshal(model.Party{ID: customerID, Name: customerID})
+		default:
+			return err
+		}
+	}
+
+	// The sequence row stays locked until tx ends, so invoices of a tenant are numbered one at a
+	// time and a rolled back close gives its number back.
+	var number int64
+	err = tx.QueryRow(ctx, `
+		INSERT INTO invoice_sequences (tenant_id, last_number) VALUES ($1, 1)
+		ON CONFLICT (tenant_id) DO UPDATE SET last_number = invoice_sequences.last_number + 1
+		RETURNING last_number
+	`, tenantID).Scan(&number)
+	if err != nil {
+		return fmt.Errorf("failed to take invoice number: %w", err)
+	}
+
+	issuedAt := snapshot.ClosedAt
+	dueAt := issuedAt.AddDate(0, 0, issuer.PaymentTermsDays)
+	// A bill closed again after a reopen supersedes the invoice the reopen voided.
+	_, err = tx.Exec(ctx, `
+		INSERT INTO invoices (tenant_id, invoice_id, invoice_number, number, bill_id, currency, total_amount,
+			issued_at, due_at, issuer, customer, snapshot, supersedes_invoice_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, (
+			SELECT v.invoice_id FROM invoice_voids v
+			JOIN invoices i ON i.tenant_id = v.tenant_id AND i.invoice_id = v.invoice_id
+			WHERE v.tenant_id = $1 AND v.bill_id = $5
+			ORDER BY i.invoice_number DESC
+			LIMIT 1
+		))
+	`, tenantID, invoiceID, number, model.FormatInvoiceNumber(issuer.NumberPrefix, number), billID, snapshot.Currency, snapshot.TotalAmount,
+		issuedAt, dueAt, jsonText(issuer.Party()), nullJSON(customer), jsonText(snapshot))
+	if err != nil {
+		return fmt.Errorf("failed to insert invoice: %w", err)
+	}
+	return nil
+}
+
+// voidInvoices voids the bill's invoices that aren't voided yet, when the bill is reopened. It
+// returns the IDs of the invoices it voided.
+func voidInvoices(ctx context.Context, tx *sqldb.Tx, tenantID, billID, reason string) ([]string, error) {
+	rows, err := tx.Query(ctx, `
+		INSERT INTO invoice_voids (tenant_id, invoice_id, bill_id, reason)
+		SELECT tenant_id, invoice_id, bill_id, $3 FROM invoices
+		WHERE tenant_id = $1 AND bill_id = $2
+		ORDER BY invoice_number
+		ON CONFLICT (tenant_id, invoice_id) DO NOTHING
+		RETURNING invoice_id
+	`, tenantID, billID, reason)
+	if err != nil {
+		return nil, fmt.Errorf("failed to void invoices: %w", err)
+	}
+	defer rows.Close()
+
+	var invoiceIDs []string
+	for rows.Next() {
+		var invoiceID string
+		if err := rows.Scan(&invoiceID); err != nil {
+			return nil, err
+		}
+		invoiceIDs = append(invoiceIDs, invoiceID)
+	}
+	return invoiceIDs, rows.Err()
+}
+
+// invoiceSnapshot reads the bill and its active line items as they are in tx, and the bill's
+// customer ID.
+func invoiceSnapshot(ctx context.Context, tx *sqldb.Tx, tenantID, billID string) (*model.InvoiceSnapshot, string, error) {
+	snapshot := model.InvoiceSnapshot{BillID: billID, LineItems: []model.InvoiceLineItem{}}
+	var closedAt *time.Time
+	var customerID string
+	err := tx.QueryRow(ctx, `
+		SELECT policy_type, currency, total_amount, period_start, period_end, closed_at, COALESCE(customer_id, '')
+		FROM bills
+		WHERE tenant_id = $1 AND bill_id = $2
+	`, tenantID, billID).Scan(&snapshot.PolicyType, &snapshot.Currency, &snapshot.TotalAmount,
+		&snapshot.PeriodStart, &snapshot.PeriodEnd, &closedAt, &customerID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read bill for invoice: %w", err)
+	}
+	if closedAt != nil {
+		snapshot.ClosedAt = *closedAt
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT line_item_id, amount, metadata, created_at
+		FROM line_items
+		WHERE tenant_id = $1 AND bill_id = $2 AND status = 'ACTIVE'
+		ORDER BY created_at, line_item_id
+	`, tenantID, billID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read line items for invoice: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var item model.InvoiceLineItem
+		var metadata []byte
+		if err := rows.Scan(&item.LineItemID, &item.Amount, &metadata, &item.CreatedAt); err != nil {
+			return nil, "", err
+		}
+		var m model.LineItemMetadata
+		if len(metadata) > 0 {
+			if err := json.Unmarshal(metadata, &m); err != nil {
+				return nil, "", fmt.Errorf("failed to decode line item %s metadata: %w", item.LineItemID, err)
+			}
+		}
+		item.Description, item.PriceID, item.Quantity, item.UnitAmount = m.Description, m.PriceID, m.Quantity, m.UnitAmount
+		snapshot.LineItems = append(snapshot.LineItems, item)
+	}
+	return &snapshot, customerID, rows.Err()
+}
+
+// invoiceIssuer returns the tenant's issuer, or one named after the tenant with the default
+// numbering and payment terms if it has none.
+func invoiceIssuer(ctx context.Context, q querier, tenantID string) (*model.InvoiceIssuer, error) {
+	issuer, err := getInvoiceIssuer(ctx, q, tenantID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &model.InvoiceIssuer{Name: tenantID, NumberPrefix: model.DefaultInvoicePrefix, PaymentTermsDays: model.DefaultPaymentTermsDays,
+			Locale: model.LocaleEN}, nil
+	}
+	return issuer, err
+}
+
+func jsonText(v any) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// GetInvoice retrieves an issued invoice.
+func (d *dbStore) GetInvoice(ctx context.Context, tenantID, invoiceID string) (*model.Invoice, error) {
+	invoices, err := d.queryInvoices(ctx, "tenant_id = $1 AND invoice_id = $2", tenantID, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	if len(invoices) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return invoices[0], nil
+}
+
+// ListBillInvoices retrieves the invoices issued for a bill, oldest first. A bill has more than one
+// if it was reopened and closed again.
+func (d *dbStore) ListBillInvoices(ctx context.Context, tenantID, billID string) ([]*model.Invoice, error) {
+	return d.queryInvoices(ctx, "tenant_id = $1 AND bill_id = $2", tenantID, billID)
+}
+
+func (d *dbStore) queryInvoices(ctx context.Context, where string, args ...any) ([]*model.Invoice, error) {
+	rows, err := d.db.Query(ctx, `
+		SELECT invoice_id, invoice_number, number, bill_id, currency, total_amount, issued_at, due_at,
+			issuer, customer, snapshot, created_at, COALESCE(supersedes_invoice_id, ''),
+			(SELECT v.voided_at FROM invoice_voids v WHERE v.tenant_id = invoices.tenant_id AND v.invoice_id = invoices.invoice_id),
+			COALESCE((SELECT n.invoice_id FROM invoices n
+				WHERE n.tenant_id = invoices.tenant_id AND n.supersedes_invoice_id = invoices.invoice_id), '')
+		FROM invoices
+		WHERE `+where+`
+		ORDER BY invoice_number
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query invoices: %w", err)
+	}
+	defer rows.Close()
+
+	var invoices []*model.Invoice
+	for rows.Next() {
+		var invoice model.Invoice
+		var issuer, customer, snapshot []byte
+		if err := rows.Scan(&invoice.InvoiceID, &invoice.InvoiceNumber, &invoice.Number, &invoice.BillID, &invoice.Currency,
+			&invoice.TotalAmount, &invoice.IssuedAt, &invoice.DueAt, &issuer, &customer, &snapshot, &invoice.CreatedAt,
+			&invoice.SupersedesInvoiceID, &invoice.VoidedAt, &invoice.SupersededBy); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(issuer, &invoice.Issuer); err != nil {
+			return nil, fmt.Errorf("failed to decode invoice issuer: %w", err)
+		}
+		if customer != nil {
+			if err := json.Unmarshal(customer, &invoice.Customer); err != nil {
+				return nil, fmt.Errorf("failed to decode invoice customer: %w", err)
+			}
+		}
+		if err := json.Unmarshal(snapshot, &invoice.Snapshot); err != nil {
+			return nil, fmt.Errorf("failed to decode invoice snapshot: %w", err)
+		}
+		invoice.DisplayAmount = model.FormatAmount(invoice.TotalAmount)
+		invoices = append(invoices, &invoice)
+	}
+	return invoices, rows.Err()
+}
+
+// UpsertCustomer creates the customer or replaces its details. Invoices already issued keep the
+// details they were issued with.
+func (d *dbStore) UpsertCustomer(ctx context.Context, tenantID string, customer *model.Customer) error {
+	billingEmails := customer.BillingEmails
+	if billingEmails == nil {
+		billingEmails = []string{}
+	}
+	err := d.db.QueryRow(ctx, `
+		INSERT INTO customers (tenant_id, customer_id, name, email, address, tax_id, billing_emails)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), $7)
+		ON CONFLICT (tenant_id, customer_id) DO UPDATE
+		SET name = EXCLUDED.name, email = EXCLUDED.email, address = EXCLUDED.address, tax_id = EXCLUDED.tax_id,
+			billing_emails = EXCLUDED.billing_emails, updated_at = now()
+		RETURNING created_at, updated_at
+	`, tenantID, customer.CustomerID, customer.Name, customer.Email, customer.Address, customer.TaxID, billingEmails).Scan(&customer.CreatedAt, &customer.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to upsert customer: %w", err)
+	}
+	return nil
+}
+
+// GetCustomer retrieves a customer, sql.ErrNoRows if it doesn't exist.
+func (d *dbStore) GetCustomer(ctx context.Context, tenantID, customerID string) (*model.Customer, error) {
+	return getCustomer(ctx, d.db, tenantID, customerID)
+}
+
+func getCustomer(ctx context.Context, q querier, tenantID, customerID string) (*model.Customer, error) {
+	customer := model.Customer{CustomerID: customerID}
+	err := q.QueryRow(ctx, `
+		SELECT name, COALESCE(email, ''), COALESCE(address, ''), COALESCE(tax_id, ''), billing_emails, created_at, updated_at
+		FROM customers
+		WHERE tenant_id = $1 AND customer_id = $2
+	`, tenantID, customerID).Scan(&customer.Name, &customer.Email, &customer.Address, &customer.TaxID, &customer.BillingEmails,
+		&customer.CreatedAt, &customer.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || errors.Is(err, pgx.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to read customer: %w", err)
+	}
+	return &customer, nil
+}
+
+// SetInvoiceIssuer sets the tenant's issuer details. Invoices already issued keep the details and
+// number they were issued with.
+func (d *dbStore) SetInvoiceIssuer(ctx context.Context, tenantID string, issuer *model.InvoiceIssuer) error {
+	err := d.db.QueryRow(ctx, `
+		INSERT INTO invoice_issuers (tenant_id, name, email, address, tax_id, number_prefix, payment_terms_days,
+			locale, payment_instructions, logo)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), NULLIF($5, ''), $6, $7, $8, NULLIF($9, ''), $10)
+		ON CONFLICT (tenant_id) DO UPDATE
+		SET name = EXCLUDED.name, email = EXCLUDED.email, address = EXCLUDED.address, tax_id = EXCLUDED.tax_id,
+			number_prefix = EXCLUDED.number_prefix, payment_terms_days = EXCLUDED.payment_terms_days,
+			locale = EXCLUDED.locale, payment_instructions = EXCLUDED.payment_instructions, logo = EXCLUDED.logo,
+			updated_at = now()
+		RETURNING updated_at
+	`, tenantID, issuer.Name, issuer.Email, issuer.Address, issuer.TaxID, issuer.NumberPrefix, issuer.PaymentTermsDays,
+		issuer.Locale, issuer.PaymentInstructions, issuer.Logo).Scan(&issuer.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to set invoice issuer: %w", err)
+	}
+	return nil
+}
+
+// GetInvoiceIssuer retrieves the tenant's issuer details, sql.ErrNoRows if they aren't set.
+func (d *dbStore) GetInvoiceIssuer(ctx context.Context, tenantID string) (*model.InvoiceIssuer, error) {
+	return getInvoiceIssuer(ctx, d.db, tenantID)
+}
+
+func getInvoiceIssuer(ctx context.Context, q querier, tenantID string) (*model.InvoiceIssuer, error) {
+	var issuer model.InvoiceIssuer
+	err := q.QueryRow(ctx, `
+		SELECT name, COALESCE(email, ''), COALESCE(address, ''), COALESCE(tax_id, ''), number_prefix, payment_terms_days,
+			locale, COALESCE(payment_instructions, ''), logo, updated_at
+		FROM invoice_issuers
+		WHERE tenant_id = $1
+	`, tenantID).Scan(&issuer.Name, &issuer.Email, &issuer.Address, &issuer.TaxID, &issuer.NumberPrefix, &issuer.PaymentTermsDays,
+		&issuer.Locale, &issuer.PaymentInstructions, &issuer.Logo, &issuer.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || errors.Is(err, pgx.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to read invoice issuer: %w", err)
+	}
+	return &issuer, nil
+}
+
+// SetBillInvoicePDF records the object key of the rendered PDF of the bill's latest invoice. A bill
+// reopened in the meantime keeps none.
+func (d *dbStore) SetBillInvoicePDF(ctx context.Context, tenantID, billID, key string) error {
+	_, err := d.db.Exec(ctx, `
+		UPDATE bills SET invoice_pdf_key = $1, updated_at = now()
+		WHERE tenant_id = $2 AND bill_id = $3 AND status = $4
+	`, key, tenantID, billID, model.BillStatusClosed)
+	if err != nil {
+		return fmt.Errorf("failed to set invoice pdf: %w", err)
+	}
+	return nil
+}
+
+// GetBillInvoicePDF returns the object key of the bill's invoice PDF, empty if none was rendered
+// since the bill last closed, or sql.ErrNoRows if the bill doesn't exist.
+func (d *dbStore) GetBillInvoicePDF(ctx context.Context, tenantID, billID string) (string, error) {
+	var key string
+	err := d.db.QueryRow(ctx, `
+		SELECT COALESCE(invoice_pdf_key, '') FROM bills WHERE tenant_id = $1 AND bill_id = $2
+	`, tenantID, billID).Scan(&key)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || errors.Is(err, pgx.ErrNoRows) {
+			return "", sql.ErrNoRows
+		}
+		return "", fmt.Errorf("failed to read invoice pdf: %w", err)
+	}
+	return key, nil
+}
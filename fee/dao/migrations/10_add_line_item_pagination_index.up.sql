@@ -0,0 +1,4 @@
+--
+-- Keyset pagination of the line items of a bill
+--
+CREATE INDEX idx_line_items_bill_id_created_at ON line_items (bill_id, created_at, line_item_id);
@@ -0,0 +1,16 @@
+--
+-- Create api_keys table
+-- Service API keys, only the SHA-256 hash of the secret part is stored.
+--
+CREATE TABLE IF NOT EXISTS api_keys (
+    key_id VARCHAR(32) PRIMARY KEY,
+    name VARCHAR(128) NOT NULL,
+    key_hash CHAR(64) NOT NULL,
+    scopes TEXT[] NOT NULL,
+    created_by VARCHAR(128) NOT NULL,
+    rotated_from VARCHAR(32),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ
+);
+CREATE INDEX idx_api_keys_created_at_desc ON api_keys (created_at DESC);
@@ -0,0 +1,74 @@
+--
+-- Tenants
+-- Every row belongs to a tenant and IDs are only unique within their tenant.
+-- Rows written before tenants existed belong to the 'default' tenant.
+--
+ALTER TABLE bills ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE line_items ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE failed_line_items ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE products ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE prices ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE price_versions ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE usage_events ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE usage_imports ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE usage_import_rows ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE api_keys ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+
+-- New rows must name their tenant
+ALTER TABLE bills ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE line_items ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE failed_line_items ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE products ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE prices ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE price_versions ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE usage_events ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE usage_imports ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE usage_import_rows ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE api_keys ALTER COLUMN tenant_id DROP DEFAULT;
+
+-- Foreign keys reference the unique constraints replaced below
+ALTER TABLE prices DROP CONSTRAINT prices_product_id_fkey;
+ALTER TABLE price_versions DROP CONSTRAINT price_versions_price_id_fkey;
+ALTER TABLE usage_import_rows DROP CONSTRAINT usage_import_rows_import_id_fkey;
+
+ALTER TABLE bills DROP CONSTRAINT bills_bill_id_key;
+ALTER TABLE bills ADD CONSTRAINT bills_tenant_id_bill_id_key UNIQUE (tenant_id, bill_id);
+
+ALTER TABLE line_items DROP CONSTRAINT line_items_line_item_id_key;
+ALTER TABLE line_items ADD CONSTRAINT line_items_tenant_id_line_item_id_key UNIQUE (tenant_id, line_item_id);
+DROP INDEX IF EXISTS idx_line_items_bill_external_ref;
+CREATE UNIQUE INDEX idx_line_items_tenant_bill_external_ref ON line_items (tenant_id, bill_id, external_ref) WHERE external_ref IS NOT NULL;
+
+ALTER TABLE failed_line_items DROP CONSTRAINT failed_line_items_line_item_id_key;
+ALTER TABLE failed_line_items ADD CONSTRAINT failed_line_items_tenant_id_line_item_id_key UNIQUE (tenant_id, line_item_id);
+
+ALTER TABLE products DROP CONSTRAINT products_product_id_key;
+ALTER TABLE products ADD CONSTRAINT products_tenant_id_product_id_key UNIQUE (tenant_id, product_id);
+
+ALTER TABLE prices DROP CONSTRAINT prices_price_id_key;
+ALTER TABLE prices ADD CONSTRAINT prices_tenant_id_price_id_key UNIQUE (tenant_id, price_id);
+ALTER TABLE prices ADD CONSTRAINT prices_product_id_fkey FOREIGN KEY (tenant_id, product_id) REFERENCES products (tenant_id, product_id);
+
+ALTER TABLE price_versions DROP CONSTRAINT price_versions_price_id_version_key;
+ALTER TABLE price_versions ADD CONSTRAINT price_versions_tenant_id_price_id_version_key UNIQUE (tenant_id, price_id, version);
+ALTER TABLE price_versions ADD CONSTRAINT price_versions_price_id_fkey FOREIGN KEY (tenant_id, price_id) REFERENCES prices (tenant_id, price_id);
+
+ALTER TABLE usage_events DROP CONSTRAINT usage_events_event_id_key;
+ALTER TABLE usage_events ADD CONSTRAINT usage_events_tenant_id_event_id_key UNIQUE (tenant_id, event_id);
+
+ALTER TABLE usage_imports DROP CONSTRAINT usage_imports_import_id_key;
+ALTER TABLE usage_imports DROP CONSTRAINT usage_imports_file_hash_key;
+ALTER TABLE usage_imports ADD CONSTRAINT usage_imports_tenant_id_import_id_key UNIQUE (tenant_id, import_id);
+ALTER TABLE usage_imports ADD CONSTRAINT usage_imports_tenant_id_file_hash_key UNIQUE (tenant_id, file_hash);
+
+ALTER TABLE usage_import_rows DROP CONSTRAINT usage_import_rows_import_id_row_number_key;
+ALTER TABLE usage_import_rows ADD CONSTRAINT usage_import_rows_tenant_id_import_id_row_number_key UNIQUE (tenant_id, import_id, row_number);
+ALTER TABLE usage_import_rows ADD CONSTRAINT usage_import_rows_import_id_fkey FOREIGN KEY (tenant_id, import_id) REFERENCES usage_imports (tenant_id, import_id);
+
+-- Lists are always read within a tenant
+CREATE INDEX idx_bills_tenant_created_at_desc ON bills (tenant_id, created_at DESC, bill_id DESC);
+CREATE INDEX idx_bills_tenant_status_created_at_desc ON bills (tenant_id, status, created_at DESC);
+CREATE INDEX idx_line_items_tenant_bill_created_at ON line_items (tenant_id, bill_id, created_at, line_item_id);
+CREATE INDEX idx_failed_line_items_tenant_status_created_at ON failed_line_items (tenant_id, status, created_at DESC);
+CREATE INDEX idx_products_tenant_created_at_desc ON products (tenant_id, created_at DESC);
+CREATE INDEX idx_api_keys_tenant_created_at_desc ON api_keys (tenant_id, created_at DESC);
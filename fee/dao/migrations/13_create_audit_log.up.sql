@@ -0,0 +1,35 @@
+--
+-- Audit log
+-- One row per financial event, written in the same transaction as the change it records.
+-- entry_id is derived from the activity attempt, so a retried activity doesn't add a second row.
+--
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL,
+    entry_id VARCHAR(64) NOT NULL,
+    bill_id VARCHAR(64) NOT NULL,
+    line_item_id VARCHAR(64),
+    event VARCHAR(32) NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    request_id VARCHAR(128),
+    before JSONB,
+    after JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT audit_log_tenant_id_entry_id_key UNIQUE (tenant_id, entry_id)
+);
+CREATE INDEX idx_audit_log_tenant_bill ON audit_log (tenant_id, bill_id, id);
+CREATE INDEX idx_audit_log_tenant_created_at ON audit_log (tenant_id, created_at, id);
+
+-- The log is append-only, even for the application's own role
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_no_update_delete
+    BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+CREATE TRIGGER audit_log_no_truncate
+    BEFORE TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
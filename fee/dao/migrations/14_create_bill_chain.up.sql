@@ -0,0 +1,33 @@
+--
+-- Bill chain
+-- Every close of a bill appends a link sealing the bill and its line items. A link's hash covers
+-- its content and the previous link's hash, chains are per tenant.
+--
+CREATE TABLE IF NOT EXISTS bill_chain (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL,
+    seq BIGINT NOT NULL,
+    link_id VARCHAR(64) NOT NULL,
+    bill_id VARCHAR(64) NOT NULL,
+    content TEXT NOT NULL, -- hashed as is, never re-encode it as JSONB
+    prev_hash VARCHAR(64) NOT NULL,
+    hash VARCHAR(64) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT bill_chain_tenant_id_seq_key UNIQUE (tenant_id, seq),
+    CONSTRAINT bill_chain_tenant_id_link_id_key UNIQUE (tenant_id, link_id)
+);
+CREATE INDEX idx_bill_chain_tenant_bill_seq ON bill_chain (tenant_id, bill_id, seq);
+
+-- The chain is append-only like the audit log, the trigger function now names the table
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION '% is append-only', TG_TABLE_NAME;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER bill_chain_no_update_delete
+    BEFORE UPDATE OR DELETE ON bill_chain
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+CREATE TRIGGER bill_chain_no_truncate
+    BEFORE TRUNCATE ON bill_chain
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
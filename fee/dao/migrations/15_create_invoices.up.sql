@@ -0,0 +1,72 @@
+--
+-- Customers
+-- Billing details of the customers bills refer to by customer_id.
+--
+CREATE TABLE IF NOT EXISTS customers (
+    id SERIAL PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL,
+    customer_id VARCHAR(64) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    email VARCHAR(255),
+    address TEXT,
+    tax_id VARCHAR(64),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT customers_tenant_id_customer_id_key UNIQUE (tenant_id, customer_id)
+);
+
+--
+-- Invoice issuers
+-- The tenant's own details printed on its invoices.
+--
+CREATE TABLE IF NOT EXISTS invoice_issuers (
+    tenant_id VARCHAR(64) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    email VARCHAR(255),
+    address TEXT,
+    tax_id VARCHAR(64),
+    number_prefix VARCHAR(16) NOT NULL DEFAULT 'INV-',
+    payment_terms_days INT NOT NULL DEFAULT 30,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+--
+-- Invoice sequences
+-- The last invoice number of each tenant. It is taken in the transaction that issues the invoice,
+-- so the row stays locked until commit and a rolled back close doesn't leave a gap.
+--
+CREATE TABLE IF NOT EXISTS invoice_sequences (
+    tenant_id VARCHAR(64) PRIMARY KEY,
+    last_number BIGINT NOT NULL
+);
+
+--
+-- Invoices
+-- Issued when a bill with charges closes, a frozen copy of the bill that is never changed.
+--
+CREATE TABLE IF NOT EXISTS invoices (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL,
+    invoice_id VARCHAR(64) NOT NULL,
+    invoice_number BIGINT NOT NULL,
+    number VARCHAR(96) NOT NULL,
+    bill_id VARCHAR(64) NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    total_amount BIGINT NOT NULL,
+    issued_at TIMESTAMPTZ NOT NULL,
+    due_at TIMESTAMPTZ NOT NULL,
+    issuer JSONB NOT NULL,
+    customer JSONB,
+    snapshot JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT invoices_tenant_id_invoice_number_key UNIQUE (tenant_id, invoice_number),
+    CONSTRAINT invoices_tenant_id_invoice_id_key UNIQUE (tenant_id, invoice_id)
+);
+CREATE INDEX idx_invoices_tenant_bill ON invoices (tenant_id, bill_id, invoice_number);
+
+CREATE TRIGGER invoices_no_update_delete
+    BEFORE UPDATE OR DELETE ON invoices
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+CREATE TRIGGER invoices_no_truncate
+    BEFORE TRUNCATE ON invoices
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
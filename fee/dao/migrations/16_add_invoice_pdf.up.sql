@@ -0,0 +1,9 @@
+--
+-- Invoice PDF template on the issuer, and the rendered PDF of a bill's latest invoice
+--
+ALTER TABLE invoice_issuers ADD COLUMN locale VARCHAR(8) NOT NULL DEFAULT 'en';
+ALTER TABLE invoice_issuers ADD COLUMN payment_instructions TEXT;
+ALTER TABLE invoice_issuers ADD COLUMN logo BYTEA;
+
+-- Object key in the invoices bucket, cleared when the bill is reopened
+ALTER TABLE bills ADD COLUMN invoice_pdf_key TEXT;
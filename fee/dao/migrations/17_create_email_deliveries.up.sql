@@ -0,0 +1,28 @@
+--
+-- Billing contacts of a customer, the invoice emails go to them
+--
+ALTER TABLE customers ADD COLUMN billing_emails TEXT[] NOT NULL DEFAULT '{}';
+
+--
+-- Email deliveries
+-- One row per email a bill's post-processing sends, with its attempts and outcome. A retried
+-- activity finds the row and doesn't send the email again.
+--
+CREATE TABLE IF NOT EXISTS email_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL,
+    delivery_id VARCHAR(64) NOT NULL,
+    bill_id VARCHAR(64) NOT NULL,
+    invoice_id VARCHAR(64) NOT NULL,
+    template VARCHAR(64) NOT NULL,
+    recipients TEXT[] NOT NULL,
+    subject TEXT NOT NULL,
+    status VARCHAR(16) NOT NULL, -- SENDING, SENT, FAILED, SKIPPED or UNKNOWN
+    attempts INT NOT NULL DEFAULT 1,
+    last_error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    sent_at TIMESTAMPTZ,
+    CONSTRAINT email_deliveries_tenant_id_delivery_id_key UNIQUE (tenant_id, delivery_id)
+);
+CREATE INDEX idx_email_deliveries_tenant_bill ON email_deliveries (tenant_id, bill_id, created_at);
@@ -0,0 +1,27 @@
+--
+-- Payment links
+-- One link per invoice, created by the payment provider in the bill's post-processing. Paying it
+-- settles the bill.
+--
+CREATE TABLE IF NOT EXISTS payment_links (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL,
+    link_id VARCHAR(64) NOT NULL,
+    bill_id VARCHAR(64) NOT NULL,
+    invoice_id VARCHAR(64) NOT NULL,
+    provider VARCHAR(32) NOT NULL,
+    provider_link_id VARCHAR(128) NOT NULL,
+    url TEXT NOT NULL,
+    amount BIGINT NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    status VARCHAR(16) NOT NULL, -- PENDING, PAID, EXPIRED or CANCELLED
+    expires_at TIMESTAMPTZ NOT NULL,
+    payment_id VARCHAR(128),
+    paid_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT payment_links_tenant_id_link_id_key UNIQUE (tenant_id, link_id),
+    -- callbacks find the link by the provider's ID, whatever its tenant
+    CONSTRAINT payment_links_provider_link_id_key UNIQUE (provider, provider_link_id)
+);
+CREATE INDEX idx_payment_links_tenant_bill ON payment_links (tenant_id, bill_id, created_at);
@@ -0,0 +1,75 @@
+--
+-- Webhook subscriptions
+-- Endpoints of a tenant that receive its bill lifecycle events, signed with the secret.
+--
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL,
+    subscription_id VARCHAR(64) NOT NULL,
+    url TEXT NOT NULL,
+    event_types TEXT[] NOT NULL DEFAULT '{}', -- every event type when empty
+    description TEXT NOT NULL DEFAULT '',
+    secret TEXT NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT webhook_subscriptions_tenant_id_subscription_id_key UNIQUE (tenant_id, subscription_id)
+);
+
+--
+-- Webhook events
+-- Written in the transaction of the change they describe, an audited event shares the ID of its
+-- audit entry.
+--
+CREATE TABLE IF NOT EXISTS webhook_events (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL,
+    event_id VARCHAR(64) NOT NULL,
+    event_type VARCHAR(64) NOT NULL,
+    bill_id VARCHAR(64) NOT NULL,
+    line_item_id VARCHAR(64),
+    data JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT webhook_events_tenant_id_event_id_key UNIQUE (tenant_id, event_id)
+);
+
+--
+-- Webhook deliveries
+-- One row per event and subscription, plus one per manual redelivery. dispatched_at is set once
+-- the delivery workflow is started, deliveries without it are picked up by the next dispatch.
+--
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL,
+    delivery_id VARCHAR(64) NOT NULL,
+    subscription_id VARCHAR(64) NOT NULL,
+    event_id VARCHAR(64) NOT NULL,
+    redelivery_of VARCHAR(64),
+    status VARCHAR(16) NOT NULL DEFAULT 'PENDING', -- PENDING, DELIVERED or FAILED
+    attempts INT NOT NULL DEFAULT 0,
+    last_status_code INT,
+    last_error TEXT,
+    dispatched_at TIMESTAMPTZ,
+    delivered_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT webhook_deliveries_tenant_id_delivery_id_key UNIQUE (tenant_id, delivery_id)
+);
+CREATE INDEX idx_webhook_deliveries_tenant_subscription ON webhook_deliveries (tenant_id, subscription_id, created_at);
+CREATE INDEX idx_webhook_deliveries_undispatched ON webhook_deliveries (tenant_id, created_at) WHERE dispatched_at IS NULL;
+
+--
+-- Webhook attempts
+-- Every request of a delivery to its endpoint, with the response status or the error.
+--
+CREATE TABLE IF NOT EXISTS webhook_attempts (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL,
+    delivery_id VARCHAR(64) NOT NULL,
+    attempt INT NOT NULL,
+    status_code INT,
+    error TEXT,
+    duration_ms BIGINT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT webhook_attempts_tenant_id_delivery_id_attempt_key UNIQUE (tenant_id, delivery_id, attempt)
+);
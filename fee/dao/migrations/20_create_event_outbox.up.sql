@@ -0,0 +1,16 @@
+--
+-- Event outbox
+-- Bill domain events, written in the transaction of the change they describe and published to
+-- their Pub/Sub topic afterwards. published_at is set once the topic accepted the event.
+--
+CREATE TABLE IF NOT EXISTS event_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL,
+    event_id VARCHAR(64) NOT NULL,
+    topic VARCHAR(64) NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    published_at TIMESTAMPTZ,
+    CONSTRAINT event_outbox_tenant_id_event_id_topic_key UNIQUE (tenant_id, event_id, topic)
+);
+CREATE INDEX idx_event_outbox_unpublished ON event_outbox (tenant_id, id) WHERE published_at IS NULL;
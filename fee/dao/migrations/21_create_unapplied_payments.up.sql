@@ -0,0 +1,18 @@
+--
+-- Unapplied payments
+-- Payments a provider reported for a link whose bill can't be settled any more, e.g. because it was
+-- reopened. They are kept for reconciliation instead of being dropped.
+--
+CREATE TABLE IF NOT EXISTS unapplied_payments (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL,
+    provider VARCHAR(32) NOT NULL,
+    payment_id VARCHAR(128) NOT NULL,
+    link_id VARCHAR(64) NOT NULL,
+    bill_id VARCHAR(64) NOT NULL,
+    amount BIGINT NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT unapplied_payments_provider_payment_id_key UNIQUE (provider, payment_id)
+);
+CREATE INDEX idx_unapplied_payments_tenant_bill ON unapplied_payments (tenant_id, bill_id, created_at);
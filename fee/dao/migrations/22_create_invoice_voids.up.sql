@@ -0,0 +1,24 @@
+--
+-- Invoice voids
+-- An invoice is voided when its bill is reopened, the invoice issued when the bill closes again
+-- supersedes it. Like invoices, voids are never changed.
+--
+CREATE TABLE IF NOT EXISTS invoice_voids (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL,
+    invoice_id VARCHAR(64) NOT NULL,
+    bill_id VARCHAR(64) NOT NULL,
+    reason VARCHAR(32) NOT NULL, -- BILL_REOPENED
+    voided_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT invoice_voids_tenant_id_invoice_id_key UNIQUE (tenant_id, invoice_id)
+);
+
+CREATE TRIGGER invoice_voids_no_update_delete
+    BEFORE UPDATE OR DELETE ON invoice_voids
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+CREATE TRIGGER invoice_voids_no_truncate
+    BEFORE TRUNCATE ON invoice_voids
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
+
+-- Set when the invoice is issued, the invoice it replaces.
+ALTER TABLE invoices ADD COLUMN supersedes_invoice_id VARCHAR(64);
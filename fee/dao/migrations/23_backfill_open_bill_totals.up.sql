@@ -0,0 +1,12 @@
+--
+-- Open bill totals
+-- total_amount of a bill that is not closed yet is its running total, kept up to date as line
+-- items are added and voided. Bills that were already open held 0, they are set from their active
+-- line items.
+--
+UPDATE bills b
+SET total_amount = COALESCE((
+    SELECT SUM(li.amount) FROM line_items li
+    WHERE li.tenant_id = b.tenant_id AND li.bill_id = b.bill_id AND li.status = 'ACTIVE'
+), 0)
+WHERE b.status IN ('OPEN', 'SCHEDULED');
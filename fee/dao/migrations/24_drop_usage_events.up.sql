@@ -0,0 +1,7 @@
+--
+-- Drop usage_events
+-- Redelivered usage events are no longer claimed here: their line item IDs derive from the event
+-- ID, so the workflow skips the ones it already added. The table, with its tenant constraint and
+-- bill index, has been unused since.
+--
+DROP TABLE IF EXISTS usage_events;
@@ -0,0 +1,14 @@
+--
+-- Event outbox attempts
+-- A relay claims events with a lease, claimed_until, and publishes them outside its transaction.
+-- Failed publishes are counted, an event that keeps failing is dead-lettered so the later events
+-- of its tenant are published without it.
+--
+ALTER TABLE event_outbox ADD COLUMN claimed_until TIMESTAMPTZ;
+ALTER TABLE event_outbox ADD COLUMN attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE event_outbox ADD COLUMN last_error TEXT;
+ALTER TABLE event_outbox ADD COLUMN dead_lettered_at TIMESTAMPTZ;
+
+DROP INDEX idx_event_outbox_unpublished;
+CREATE INDEX idx_event_outbox_unpublished ON event_outbox (tenant_id, id) WHERE published_at IS NULL AND dead_lettered_at IS NULL;
+CREATE INDEX idx_event_outbox_dead_lettered ON event_outbox (tenant_id, id) WHERE dead_lettered_at IS NOT NULL;
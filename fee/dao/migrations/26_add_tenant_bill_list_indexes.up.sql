@@ -0,0 +1,17 @@
+--
+-- Tenant prefixed bill list indexes
+-- Every bill list query filters on tenant_id, the filter and sort indexes of the bill list start
+-- with it like the created_at index does. The indexes they replace are dropped, together with the
+-- created_at and status ones that tenant prefixed indexes already replaced.
+--
+CREATE INDEX idx_bills_tenant_customer_id_created_at_desc ON bills (tenant_id, customer_id, created_at DESC, bill_id DESC) WHERE customer_id IS NOT NULL;
+CREATE INDEX idx_bills_tenant_closed_at_desc ON bills (tenant_id, closed_at DESC, bill_id DESC) WHERE closed_at IS NOT NULL;
+CREATE INDEX idx_bills_tenant_total_amount_desc ON bills (tenant_id, total_amount DESC, bill_id DESC);
+CREATE INDEX idx_bills_tenant_close_reason_created_at_desc ON bills (tenant_id, close_reason, created_at DESC) WHERE close_reason IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_bills_customer_id_created_at_desc;
+DROP INDEX IF EXISTS idx_bills_closed_at_desc;
+DROP INDEX IF EXISTS idx_bills_total_amount_desc;
+DROP INDEX IF EXISTS idx_bills_close_reason_created_at_desc;
+DROP INDEX IF EXISTS idx_bills_created_at_desc;
+DROP INDEX IF EXISTS idx_bills_status_created_at_desc;
@@ -0,0 +1,48 @@
+--
+-- Create products table
+--
+CREATE TABLE IF NOT EXISTS products (
+    id SERIAL PRIMARY KEY,
+    product_id VARCHAR(64) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL,
+    UNIQUE(product_id)
+);
+CREATE INDEX idx_products_created_at_desc ON products (created_at DESC);
+
+--
+-- Create prices table
+--
+CREATE TABLE IF NOT EXISTS prices (
+    id SERIAL PRIMARY KEY,
+    price_id VARCHAR(64) NOT NULL,
+    product_id VARCHAR(64) NOT NULL REFERENCES products (product_id),
+    currency VARCHAR(3) NOT NULL,
+    unit VARCHAR(30) NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    recurring_interval VARCHAR(30) NOT NULL DEFAULT '',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL,
+    UNIQUE(price_id)
+);
+CREATE INDEX idx_prices_product_id ON prices (product_id);
+
+--
+-- Create price_versions table
+-- Each row is a plan version of a price. The amount charged at a point in time
+-- is the latest version whose effective_from is not in the future.
+--
+CREATE TABLE IF NOT EXISTS price_versions (
+    id SERIAL PRIMARY KEY,
+    price_id VARCHAR(64) NOT NULL REFERENCES prices (price_id),
+    version INT NOT NULL,
+    unit_amount BIGINT NOT NULL,
+    effective_from TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE(price_id, version)
+);
+CREATE INDEX idx_price_versions_effective_from_desc ON price_versions (price_id, effective_from DESC);
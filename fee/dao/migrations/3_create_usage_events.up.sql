@@ -0,0 +1,15 @@
+--
+-- Create usage_events table
+-- Records every usage event received from Pub/Sub so redeliveries are not billed twice.
+--
+CREATE TABLE IF NOT EXISTS usage_events (
+    id SERIAL PRIMARY KEY,
+    event_id VARCHAR(128) NOT NULL,
+    bill_id VARCHAR(64) NOT NULL,
+    line_item_id VARCHAR(64) NOT NULL,
+    amount BIGINT NOT NULL,
+    occurred_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE(event_id)
+);
+CREATE INDEX idx_usage_events_bill_id ON usage_events (bill_id);
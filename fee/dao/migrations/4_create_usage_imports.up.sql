@@ -0,0 +1,37 @@
+--
+-- Create usage_imports table
+-- import_id is derived from the file hash, so re-uploading the same file maps to the same import.
+--
+CREATE TABLE IF NOT EXISTS usage_imports (
+    id SERIAL PRIMARY KEY,
+    import_id VARCHAR(64) NOT NULL,
+    file_hash VARCHAR(64) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    total_rows INT NOT NULL,
+    processed_rows INT NOT NULL DEFAULT 0,
+    failed_rows INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL,
+    completed_at TIMESTAMPTZ,
+    UNIQUE(import_id),
+    UNIQUE(file_hash)
+);
+
+--
+-- Create usage_import_rows table
+--
+CREATE TABLE IF NOT EXISTS usage_import_rows (
+    id SERIAL PRIMARY KEY,
+    import_id VARCHAR(64) NOT NULL REFERENCES usage_imports (import_id),
+    row_number INT NOT NULL,
+    bill_id VARCHAR(64) NOT NULL,
+    amount BIGINT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    external_ref VARCHAR(128) NOT NULL DEFAULT '',
+    occurred_at TIMESTAMPTZ,
+    line_item_id VARCHAR(64) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMPTZ NOT NULL,
+    UNIQUE(import_id, row_number)
+);
@@ -0,0 +1,17 @@
+--
+-- Add external_ref to line_items
+-- Callers pass their own transaction or event ID, which must be unique per bill.
+--
+ALTER TABLE line_items ADD COLUMN IF NOT EXISTS external_ref VARCHAR(128);
+
+-- Line items imported before the column existed carry the reference in their metadata.
+-- Only the first line item per reference is backfilled so the unique index can be built.
+UPDATE line_items li
+SET external_ref = li.metadata->>'external_ref'
+WHERE li.metadata->>'external_ref' <> ''
+AND li.id = (
+    SELECT min(d.id) FROM line_items d
+    WHERE d.bill_id = li.bill_id AND d.metadata->>'external_ref' = li.metadata->>'external_ref'
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_line_items_bill_external_ref ON line_items (bill_id, external_ref) WHERE external_ref IS NOT NULL;
@@ -0,0 +1,20 @@
+--
+-- Create failed_line_items table
+-- Line items whose activity failed after all retries, kept until an admin retries or discards them.
+--
+CREATE TABLE IF NOT EXISTS failed_line_items (
+    id SERIAL PRIMARY KEY,
+    line_item_id VARCHAR(64) NOT NULL,
+    bill_id VARCHAR(64) NOT NULL,
+    source VARCHAR(20) NOT NULL,
+    amount BIGINT NOT NULL,
+    payload JSONB NOT NULL,
+    error TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL,
+    resolved_at TIMESTAMPTZ,
+    UNIQUE(line_item_id)
+);
+CREATE INDEX idx_failed_line_items_bill_id ON failed_line_items (bill_id);
+CREATE INDEX idx_failed_line_items_status_created_at ON failed_line_items (status, created_at DESC);
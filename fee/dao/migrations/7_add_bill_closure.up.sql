@@ -0,0 +1,9 @@
+--
+-- Record why, how and by whom a bill was closed
+--
+ALTER TABLE bills ADD COLUMN close_reason VARCHAR(32);
+ALTER TABLE bills ADD COLUMN close_note TEXT;
+ALTER TABLE bills ADD COLUMN closed_by VARCHAR(128);
+ALTER TABLE bills ADD COLUMN close_trigger VARCHAR(16);
+
+CREATE INDEX idx_bills_close_reason_created_at_desc ON bills (close_reason, created_at DESC) WHERE close_reason IS NOT NULL;
@@ -0,0 +1,17 @@
+--
+-- Store both billing period boundaries on the bill
+--
+ALTER TABLE bills RENAME COLUMN started_at TO period_start;
+ALTER TABLE bills ADD COLUMN period_end TIMESTAMPTZ;
+
+-- The period end was kept in the metadata until now
+UPDATE bills
+SET period_end = (metadata->>'billing_period_end')::timestamptz,
+    metadata = metadata - 'billing_period_end'
+WHERE metadata ? 'billing_period_end';
+
+-- Older closed bills: the period effectively ended when the bill was closed.
+-- Older open bills keep a NULL period end, only their workflow knows it.
+UPDATE bills SET period_end = closed_at WHERE period_end IS NULL AND closed_at IS NOT NULL;
+
+CREATE INDEX idx_bills_status_period_end ON bills (status, period_end);
@@ -0,0 +1,9 @@
+--
+-- Customer of a bill and indexes for filtering and sorting the bill list
+--
+ALTER TABLE bills ADD COLUMN customer_id VARCHAR(64);
+
+CREATE INDEX idx_bills_customer_id_created_at_desc ON bills (customer_id, created_at DESC) WHERE customer_id IS NOT NULL;
+CREATE INDEX idx_bills_closed_at_desc ON bills (closed_at DESC, bill_id DESC) WHERE closed_at IS NOT NULL;
+CREATE INDEX idx_bills_total_amount_desc ON bills (total_amount DESC, bill_id DESC);
+CREATE INDEX idx_bills_created_at_desc ON bills (created_at DESC, bill_id DESC);
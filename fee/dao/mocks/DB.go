@@ -17,17 +17,17 @@ type DB struct {
 	mock.Mock
 }
 
-// AddLineItem provides a mock function with given fields: ctx, billID, amount, metadata, lineItemID
-func (_m *DB) AddLineItem(ctx context.Context, billID string, amount int64, metadata *model.LineItemMetadata, lineItemID string) error {
-	ret := _m.Called(ctx, billID, amount, metadata, lineItemID)
+// ActivateBill provides a mock function with given fields: ctx, tenantID, billID
+func (_m *DB) ActivateBill(ctx context.Context, tenantID string, billID string) error {
+	ret := _m.Called(ctx, tenantID, billID)
 
 	if len(ret) == 0 {
-		panic("no return value specified for AddLineItem")
+		panic("no return value specified for ActivateBill")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, int64, *model.LineItemMetadata, string) error); ok {
-		r0 = rf(ctx, billID, amount, metadata, lineItemID)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, tenantID, billID)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -35,17 +35,17 @@ func (_m *DB) AddLineItem(ctx context.Context, billID string, amount int64, meta
 	return r0
 }
 
-// CloseBill provides a mock function with given fields: ctx, billID, total
-func (_m *DB) CloseBill(ctx context.Context, billID string, total int64) error {
-	ret := _m.Called(ctx, billID, total)
+// AddEvent provides a mock function with given fields: ctx, tenantID, event, outbox
+func (_m *DB) AddEvent(ctx context.Context, tenantID string, event *model.WebhookEvent, outbox []model.OutboxEvent) error {
+	ret := _m.Called(ctx, tenantID, event, outbox)
 
 	if len(ret) == 0 {
-		panic("no return value specified for CloseBill")
+		panic("no return value specified for AddEvent")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, int64) error); ok {
-		r0 = rf(ctx, billID, total)
+	if rf, ok := ret.Get(0).(func(context.Context, string, *model.WebhookEvent, []model.OutboxEvent) error); ok {
+		r0 = rf(ctx, tenantID, event, outbox)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -53,47 +53,57 @@ func (_m *DB) CloseBill(ctx context.Context, billID string, total int64) error {
 	return r0
 }
 
-// CreateBill provides a mock function with given fields: ctx, billID, policyType, currency, startAt, metadata
-func (_m *DB) CreateBill(ctx context.Context, billID string, policyType string, currency string, startAt time.Time, metadata model.BillMetadata) error {
-	ret := _m.Called(ctx, billID, policyType, currency, startAt, metadata)
+// AddLineItem provides a mock function with given fields: ctx, tenantID, billID, amount, metadata, lineItemID, source
+func (_m *DB) AddLineItem(ctx context.Context, tenantID string, billID string, amount int64, metadata *model.LineItemMetadata, lineItemID string, source model.AuditSource) (model.AddedLineItem, error) {
+	ret := _m.Called(ctx, tenantID, billID, amount, metadata, lineItemID, source)
 
 	if len(ret) == 0 {
-		panic("no return value specified for CreateBill")
+		panic("no return value specified for AddLineItem")
 	}
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, time.Time, model.BillMetadata) error); ok {
-		r0 = rf(ctx, billID, policyType, currency, startAt, metadata)
+	var r0 model.AddedLineItem
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int64, *model.LineItemMetadata, string, model.AuditSource) (model.AddedLineItem, error)); ok {
+		return rf(ctx, tenantID, billID, amount, metadata, lineItemID, source)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int64, *model.LineItemMetadata, string, model.AuditSource) model.AddedLineItem); ok {
+		r0 = rf(ctx, tenantID, billID, amount, metadata, lineItemID, source)
 	} else {
-		r0 = ret.Error(0)
+		r0 = ret.Get(0).(model.AddedLineItem)
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, int64, *model.LineItemMetadata, string, model.AuditSource) error); ok {
+		r1 = rf(ctx, tenantID, billID, amount, metadata, lineItemID, source)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
-// GetBill provides a mock function with given fields: ctx, billID
-func (_m *DB) GetBill(ctx context.Context, billID string) (*model.BillDetail, error) {
-	ret := _m.Called(ctx, billID)
+// AddLineItems provides a mock function with given fields: ctx, tenantID, billID, items, source
+func (_m *DB) AddLineItems(ctx context.Context, tenantID string, billID string, items []model.LineItemInput, source model.AuditSource) ([]model.AddedLineItem, error) {
+	ret := _m.Called(ctx, tenantID, billID, items, source)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetBill")
+		panic("no return value specified for AddLineItems")
 	}
 
-	var r0 *model.BillDetail
+	var r0 []model.AddedLineItem
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) (*model.BillDetail, error)); ok {
-		return rf(ctx, billID)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, []model.LineItemInput, model.AuditSource) ([]model.AddedLineItem, error)); ok {
+		return rf(ctx, tenantID, billID, items, source)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) *model.BillDetail); ok {
-		r0 = rf(ctx, billID)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, []model.LineItemInput, model.AuditSource) []model.AddedLineItem); ok {
+		r0 = rf(ctx, tenantID, billID, items, source)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*model.BillDetail)
+			r0 = ret.Get(0).([]model.AddedLineItem)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, billID)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, []model.LineItemInput, model.AuditSource) error); ok {
+		r1 = rf(ctx, tenantID, billID, items, source)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -101,64 +111,121 @@ func (_m *DB) GetBill(ctx context.Context, billID string) (*model.BillDetail, er
 	return r0, r1
 }
 
-// GetBillIDs provides a mock function with given fields: ctx, status, policyType, limit, cursor
-func (_m *DB) GetBillIDs(ctx context.Context, status model.BillStatus, policyType model.PolicyType, limit int, cursor time.Time) ([]string, bool, error) {
-	ret := _m.Called(ctx, status, policyType, limit, cursor)
+// AddPriceVersion provides a mock function with given fields: ctx, tenantID, priceID, unitAmount, effectiveFrom
+func (_m *DB) AddPriceVersion(ctx context.Context, tenantID string, priceID string, unitAmount int64, effectiveFrom time.Time) (*model.PriceVersion, error) {
+	ret := _m.Called(ctx, tenantID, priceID, unitAmount, effectiveFrom)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetBillIDs")
+		panic("no return value specified for AddPriceVersion")
 	}
 
-	var r0 []string
-	var r1 bool
-	var r2 error
-	if rf, ok := ret.Get(0).(func(context.Context, model.BillStatus, model.PolicyType, int, time.Time) ([]string, bool, error)); ok {
-		return rf(ctx, status, policyType, limit, cursor)
+	var r0 *model.PriceVersion
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int64, time.Time) (*model.PriceVersion, error)); ok {
+		return rf(ctx, tenantID, priceID, unitAmount, effectiveFrom)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, model.BillStatus, model.PolicyType, int, time.Time) []string); ok {
-		r0 = rf(ctx, status, policyType, limit, cursor)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int64, time.Time) *model.PriceVersion); ok {
+		r0 = rf(ctx, tenantID, priceID, unitAmount, effectiveFrom)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]string)
+			r0 = ret.Get(0).(*model.PriceVersion)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, model.BillStatus, model.PolicyType, int, time.Time) bool); ok {
-		r1 = rf(ctx, status, policyType, limit, cursor)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, int64, time.Time) error); ok {
+		r1 = rf(ctx, tenantID, priceID, unitAmount, effectiveFrom)
 	} else {
-		r1 = ret.Get(1).(bool)
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// BeginEmailDelivery provides a mock function with given fields: ctx, tenantID, delivery
+func (_m *DB) BeginEmailDelivery(ctx context.Context, tenantID string, delivery *model.EmailDelivery) (bool, error) {
+	ret := _m.Called(ctx, tenantID, delivery)
+
+	if len(ret) == 0 {
+		panic("no return value specified for BeginEmailDelivery")
 	}
 
-	if rf, ok := ret.Get(2).(func(context.Context, model.BillStatus, model.PolicyType, int, time.Time) error); ok {
-		r2 = rf(ctx, status, policyType, limit, cursor)
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *model.EmailDelivery) (bool, error)); ok {
+		return rf(ctx, tenantID, delivery)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, *model.EmailDelivery) bool); ok {
+		r0 = rf(ctx, tenantID, delivery)
 	} else {
-		r2 = ret.Error(2)
+		r0 = ret.Get(0).(bool)
 	}
 
-	return r0, r1, r2
+	if rf, ok := ret.Get(1).(func(context.Context, string, *model.EmailDelivery) error); ok {
+		r1 = rf(ctx, tenantID, delivery)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
-// GetBillStatus provides a mock function with given fields: ctx, billID
-func (_m *DB) GetBillStatus(ctx context.Context, billID string) (model.BillStatus, error) {
-	ret := _m.Called(ctx, billID)
+// CancelBill provides a mock function with given fields: ctx, tenantID, billID, closure, source
+func (_m *DB) CancelBill(ctx context.Context, tenantID string, billID string, closure model.BillClosure, source model.AuditSource) error {
+	ret := _m.Called(ctx, tenantID, billID, closure, source)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetBillStatus")
+		panic("no return value specified for CancelBill")
 	}
 
-	var r0 model.BillStatus
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, model.BillClosure, model.AuditSource) error); ok {
+		r0 = rf(ctx, tenantID, billID, closure, source)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CloseBill provides a mock function with given fields: ctx, tenantID, billID, total, closure, source
+func (_m *DB) CloseBill(ctx context.Context, tenantID string, billID string, total int64, closure model.BillClosure, source model.AuditSource) error {
+	ret := _m.Called(ctx, tenantID, billID, total, closure, source)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CloseBill")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int64, model.BillClosure, model.AuditSource) error); ok {
+		r0 = rf(ctx, tenantID, billID, total, closure, source)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CountFailedLineItems provides a mock function with given fields: ctx, tenantID, billID, status
+func (_m *DB) CountFailedLineItems(ctx context.Context, tenantID string, billID string, status model.FailedLineItemStatus) (int, error) {
+	ret := _m.Called(ctx, tenantID, billID, status)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CountFailedLineItems")
+	}
+
+	var r0 int
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) (model.BillStatus, error)); ok {
-		return rf(ctx, billID)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, model.FailedLineItemStatus) (int, error)); ok {
+		return rf(ctx, tenantID, billID, status)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) model.BillStatus); ok {
-		r0 = rf(ctx, billID)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, model.FailedLineItemStatus) int); ok {
+		r0 = rf(ctx, tenantID, billID, status)
 	} else {
-		r0 = ret.Get(0).(model.BillStatus)
+		r0 = ret.Get(0).(int)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, billID)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, model.FailedLineItemStatus) error); ok {
+		r1 = rf(ctx, tenantID, billID, status)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -166,66 +233,117 @@ func (_m *DB) GetBillStatus(ctx context.Context, billID string) (model.BillStatu
 	return r0, r1
 }
 
-// GetBills provides a mock function with given fields: ctx, status, limit, cursor
-func (_m *DB) GetBills(ctx context.Context, status model.BillStatus, limit int, cursor time.Time) ([]*model.BillDetail, bool, error) {
-	ret := _m.Called(ctx, status, limit, cursor)
+// CreateAPIKey provides a mock function with given fields: ctx, tenantID, key
+func (_m *DB) CreateAPIKey(ctx context.Context, tenantID string, key *model.APIKey) error {
+	ret := _m.Called(ctx, tenantID, key)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetBills")
+		panic("no return value specified for CreateAPIKey")
 	}
 
-	var r0 []*model.BillDetail
-	var r1 bool
-	var r2 error
-	if rf, ok := ret.Get(0).(func(context.Context, model.BillStatus, int, time.Time) ([]*model.BillDetail, bool, error)); ok {
-		return rf(ctx, status, limit, cursor)
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *model.APIKey) error); ok {
+		r0 = rf(ctx, tenantID, key)
+	} else {
+		r0 = ret.Error(0)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, model.BillStatus, int, time.Time) []*model.BillDetail); ok {
-		r0 = rf(ctx, status, limit, cursor)
+
+	return r0
+}
+
+// CreateBill provides a mock function with given fields: ctx, tenantID, billID, policyType, currency, customerID, status, periodStart, periodEnd, metadata, source
+func (_m *DB) CreateBill(ctx context.Context, tenantID string, billID string, policyType string, currency string, customerID string, status model.BillStatus, periodStart time.Time, periodEnd time.Time, metadata model.BillMetadata, source model.AuditSource) error {
+	ret := _m.Called(ctx, tenantID, billID, policyType, currency, customerID, status, periodStart, periodEnd, metadata, source)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateBill")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, string, string, model.BillStatus, time.Time, time.Time, model.BillMetadata, model.AuditSource) error); ok {
+		r0 = rf(ctx, tenantID, billID, policyType, currency, customerID, status, periodStart, periodEnd, metadata, source)
 	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]*model.BillDetail)
-		}
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreatePaymentLink provides a mock function with given fields: ctx, tenantID, link
+func (_m *DB) CreatePaymentLink(ctx context.Context, tenantID string, link *model.PaymentLink) error {
+	ret := _m.Called(ctx, tenantID, link)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreatePaymentLink")
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, model.BillStatus, int, time.Time) bool); ok {
-		r1 = rf(ctx, status, limit, cursor)
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *model.PaymentLink) error); ok {
+		r0 = rf(ctx, tenantID, link)
 	} else {
-		r1 = ret.Get(1).(bool)
+		r0 = ret.Error(0)
 	}
 
-	if rf, ok := ret.Get(2).(func(context.Context, model.BillStatus, int, time.Time) error); ok {
-		r2 = rf(ctx, status, limit, cursor)
+	return r0
+}
+
+// CreatePrice provides a mock function with given fields: ctx, tenantID, price
+func (_m *DB) CreatePrice(ctx context.Context, tenantID string, price *model.Price) error {
+	ret := _m.Called(ctx, tenantID, price)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreatePrice")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *model.Price) error); ok {
+		r0 = rf(ctx, tenantID, price)
 	} else {
-		r2 = ret.Error(2)
+		r0 = ret.Error(0)
 	}
 
-	return r0, r1, r2
+	return r0
 }
 
-// GetLineItemsForBill provides a mock function with given fields: ctx, billID
-func (_m *DB) GetLineItemsForBill(ctx context.Context, billID string) ([]model.LineItem, error) {
-	ret := _m.Called(ctx, billID)
+// CreateProduct provides a mock function with given fields: ctx, tenantID, product
+func (_m *DB) CreateProduct(ctx context.Context, tenantID string, product *model.Product) error {
+	ret := _m.Called(ctx, tenantID, product)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetLineItemsForBill")
+		panic("no return value specified for CreateProduct")
 	}
 
-	var r0 []model.LineItem
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *model.Product) error); ok {
+		r0 = rf(ctx, tenantID, product)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateUsageImport provides a mock function with given fields: ctx, tenantID, usageImport, rows
+func (_m *DB) CreateUsageImport(ctx context.Context, tenantID string, usageImport *model.UsageImport, rows []model.UsageImportRow) (bool, error) {
+	ret := _m.Called(ctx, tenantID, usageImport, rows)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateUsageImport")
+	}
+
+	var r0 bool
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) ([]model.LineItem, error)); ok {
-		return rf(ctx, billID)
+	if rf, ok := ret.Get(0).(func(context.Context, string, *model.UsageImport, []model.UsageImportRow) (bool, error)); ok {
+		return rf(ctx, tenantID, usageImport, rows)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) []model.LineItem); ok {
-		r0 = rf(ctx, billID)
+	if rf, ok := ret.Get(0).(func(context.Context, string, *model.UsageImport, []model.UsageImportRow) bool); ok {
+		r0 = rf(ctx, tenantID, usageImport, rows)
 	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]model.LineItem)
-		}
+		r0 = ret.Get(0).(bool)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, billID)
+	if rf, ok := ret.Get(1).(func(context.Context, string, *model.UsageImport, []model.UsageImportRow) error); ok {
+		r1 = rf(ctx, tenantID, usageImport, rows)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -233,17 +351,17 @@ func (_m *DB) GetLineItemsForBill(ctx context.Context, billID string) ([]model.L
 	return r0, r1
 }
 
-// InsertLineItem provides a mock function with given fields: ctx, billID, currency, amount, metadata
-func (_m *DB) InsertLineItem(ctx context.Context, billID string, currency string, amount int64, metadata *model.LineItemMetadata) error {
-	ret := _m.Called(ctx, billID, currency, amount, metadata)
+// CreateWebhookSubscription provides a mock function with given fields: ctx, tenantID, sub
+func (_m *DB) CreateWebhookSubscription(ctx context.Context, tenantID string, sub *model.WebhookSubscription) error {
+	ret := _m.Called(ctx, tenantID, sub)
 
 	if len(ret) == 0 {
-		panic("no return value specified for InsertLineItem")
+		panic("no return value specified for CreateWebhookSubscription")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, string, int64, *model.LineItemMetadata) error); ok {
-		r0 = rf(ctx, billID, currency, amount, metadata)
+	if rf, ok := ret.Get(0).(func(context.Context, string, *model.WebhookSubscription) error); ok {
+		r0 = rf(ctx, tenantID, sub)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -251,27 +369,83 @@ func (_m *DB) InsertLineItem(ctx context.Context, billID string, currency string
 	return r0
 }
 
-// IsBillExists provides a mock function with given fields: ctx, billID
-func (_m *DB) IsBillExists(ctx context.Context, billID string) (bool, error) {
-	ret := _m.Called(ctx, billID)
+// ExpirePaymentLink provides a mock function with given fields: ctx, tenantID, linkID
+func (_m *DB) ExpirePaymentLink(ctx context.Context, tenantID string, linkID string) error {
+	ret := _m.Called(ctx, tenantID, linkID)
 
 	if len(ret) == 0 {
-		panic("no return value specified for IsBillExists")
+		panic("no return value specified for ExpirePaymentLink")
 	}
 
-	var r0 bool
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, tenantID, linkID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FailWebhookDelivery provides a mock function with given fields: ctx, tenantID, deliveryID
+func (_m *DB) FailWebhookDelivery(ctx context.Context, tenantID string, deliveryID string) error {
+	ret := _m.Called(ctx, tenantID, deliveryID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FailWebhookDelivery")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, tenantID, deliveryID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FinishEmailDelivery provides a mock function with given fields: ctx, tenantID, deliveryID, status, lastError
+func (_m *DB) FinishEmailDelivery(ctx context.Context, tenantID string, deliveryID string, status model.EmailDeliveryStatus, lastError string) error {
+	ret := _m.Called(ctx, tenantID, deliveryID, status, lastError)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FinishEmailDelivery")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, model.EmailDeliveryStatus, string) error); ok {
+		r0 = rf(ctx, tenantID, deliveryID, status, lastError)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetAPIKey provides a mock function with given fields: ctx, tenantID, keyID
+func (_m *DB) GetAPIKey(ctx context.Context, tenantID string, keyID string) (*model.APIKey, error) {
+	ret := _m.Called(ctx, tenantID, keyID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAPIKey")
+	}
+
+	var r0 *model.APIKey
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) (bool, error)); ok {
-		return rf(ctx, billID)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*model.APIKey, error)); ok {
+		return rf(ctx, tenantID, keyID)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) bool); ok {
-		r0 = rf(ctx, billID)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *model.APIKey); ok {
+		r0 = rf(ctx, tenantID, keyID)
 	} else {
-		r0 = ret.Get(0).(bool)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.APIKey)
+		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, billID)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, tenantID, keyID)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -279,27 +453,29 @@ func (_m *DB) IsBillExists(ctx context.Context, billID string) (bool, error) {
 	return r0, r1
 }
 
-// IsLineItemExists provides a mock function with given fields: ctx, billID, lineItemID, status
-func (_m *DB) IsLineItemExists(ctx context.Context, billID string, lineItemID string, status string) (bool, error) {
-	ret := _m.Called(ctx, billID, lineItemID, status)
+// GetBill provides a mock function with given fields: ctx, tenantID, billID
+func (_m *DB) GetBill(ctx context.Context, tenantID string, billID string) (*model.BillDetail, error) {
+	ret := _m.Called(ctx, tenantID, billID)
 
 	if len(ret) == 0 {
-		panic("no return value specified for IsLineItemExists")
+		panic("no return value specified for GetBill")
 	}
 
-	var r0 bool
+	var r0 *model.BillDetail
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) (bool, error)); ok {
-		return rf(ctx, billID, lineItemID, status)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*model.BillDetail, error)); ok {
+		return rf(ctx, tenantID, billID)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) bool); ok {
-		r0 = rf(ctx, billID, lineItemID, status)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *model.BillDetail); ok {
+		r0 = rf(ctx, tenantID, billID)
 	} else {
-		r0 = ret.Get(0).(bool)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.BillDetail)
+		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string, string, string) error); ok {
-		r1 = rf(ctx, billID, lineItemID, status)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, tenantID, billID)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -307,29 +483,122 @@ func (_m *DB) IsLineItemExists(ctx context.Context, billID string, lineItemID st
 	return r0, r1
 }
 
-// UpdateLineItem provides a mock function with given fields: ctx, billID, lineItemID, status
-func (_m *DB) UpdateLineItem(ctx context.Context, billID string, lineItemID string, status string) (*model.LineItem, error) {
-	ret := _m.Called(ctx, billID, lineItemID, status)
+// GetBillChainRecord provides a mock function with given fields: ctx, tenantID, billID
+func (_m *DB) GetBillChainRecord(ctx context.Context, tenantID string, billID string) (*model.ChainRecord, error) {
+	ret := _m.Called(ctx, tenantID, billID)
 
 	if len(ret) == 0 {
-		panic("no return value specified for UpdateLineItem")
+		panic("no return value specified for GetBillChainRecord")
 	}
 
-	var r0 *model.LineItem
+	var r0 *model.ChainRecord
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) (*model.LineItem, error)); ok {
-		return rf(ctx, billID, lineItemID, status)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*model.ChainRecord, error)); ok {
+		return rf(ctx, tenantID, billID)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) *model.LineItem); ok {
-		r0 = rf(ctx, billID, lineItemID, status)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *model.ChainRecord); ok {
+		r0 = rf(ctx, tenantID, billID)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*model.LineItem)
+			r0 = ret.Get(0).(*model.ChainRecord)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string, string, string) error); ok {
-		r1 = rf(ctx, billID, lineItemID, status)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, tenantID, billID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetBillCurrency provides a mock function with given fields: ctx, tenantID, billID
+func (_m *DB) GetBillCurrency(ctx context.Context, tenantID string, billID string) (string, error) {
+	ret := _m.Called(ctx, tenantID, billID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetBillCurrency")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (string, error)); ok {
+		return rf(ctx, tenantID, billID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) string); ok {
+		r0 = rf(ctx, tenantID, billID)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, tenantID, billID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetBillIDs provides a mock function with given fields: ctx, tenantID, status, policyType, limit, cursor
+func (_m *DB) GetBillIDs(ctx context.Context, tenantID string, status model.BillStatus, policyType model.PolicyType, limit int, cursor time.Time) ([]string, bool, error) {
+	ret := _m.Called(ctx, tenantID, status, policyType, limit, cursor)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetBillIDs")
+	}
+
+	var r0 []string
+	var r1 bool
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, model.BillStatus, model.PolicyType, int, time.Time) ([]string, bool, error)); ok {
+		return rf(ctx, tenantID, status, policyType, limit, cursor)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, model.BillStatus, model.PolicyType, int, time.Time) []string); ok {
+		r0 = rf(ctx, tenantID, status, policyType, limit, cursor)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, model.BillStatus, model.PolicyType, int, time.Time) bool); ok {
+		r1 = rf(ctx, tenantID, status, policyType, limit, cursor)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, string, model.BillStatus, model.PolicyType, int, time.Time) error); ok {
+		r2 = rf(ctx, tenantID, status, policyType, limit, cursor)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// GetBillInvoicePDF provides a mock function with given fields: ctx, tenantID, billID
+func (_m *DB) GetBillInvoicePDF(ctx context.Context, tenantID string, billID string) (string, error) {
+	ret := _m.Called(ctx, tenantID, billID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetBillInvoicePDF")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (string, error)); ok {
+		return rf(ctx, tenantID, billID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) string); ok {
+		r0 = rf(ctx, tenantID, billID)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, tenantID, billID)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -337,6 +606,1557 @@ func (_m *DB) UpdateLineItem(ctx context.Context, billID string, lineItemID stri
 	return r0, r1
 }
 
+// GetBillStatus provides a mock function with given fields: ctx, tenantID, billID
+func (_m *DB) GetBillStatus(ctx context.Context, tenantID string, billID string) (model.BillStatus, error) {
+	ret := _m.Called(ctx, tenantID, billID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetBillStatus")
+	}
+
+	var r0 model.BillStatus
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (model.BillStatus, error)); ok {
+		return rf(ctx, tenantID, billID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) model.BillStatus); ok {
+		r0 = rf(ctx, tenantID, billID)
+	} else {
+		r0 = ret.Get(0).(model.BillStatus)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, tenantID, billID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetBills provides a mock function with given fields: ctx, tenantID, filter, page
+func (_m *DB) GetBills(ctx context.Context, tenantID string, filter model.BillFilter, page model.BillPage) ([]*model.BillDetail, bool, error) {
+	ret := _m.Called(ctx, tenantID, filter, page)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetBills")
+	}
+
+	var r0 []*model.BillDetail
+	var r1 bool
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, model.BillFilter, model.BillPage) ([]*model.BillDetail, bool, error)); ok {
+		return rf(ctx, tenantID, filter, page)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, model.BillFilter, model.BillPage) []*model.BillDetail); ok {
+		r0 = rf(ctx, tenantID, filter, page)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.BillDetail)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, model.BillFilter, model.BillPage) bool); ok {
+		r1 = rf(ctx, tenantID, filter, page)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, string, model.BillFilter, model.BillPage) error); ok {
+		r2 = rf(ctx, tenantID, filter, page)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// GetCustomer provides a mock function with given fields: ctx, tenantID, customerID
+func (_m *DB) GetCustomer(ctx context.Context, tenantID string, customerID string) (*model.Customer, error) {
+	ret := _m.Called(ctx, tenantID, customerID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetCustomer")
+	}
+
+	var r0 *model.Customer
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*model.Customer, error)); ok {
+		return rf(ctx, tenantID, customerID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *model.Customer); ok {
+		r0 = rf(ctx, tenantID, customerID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Customer)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, tenantID, customerID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetFailedLineItem provides a mock function with given fields: ctx, tenantID, lineItemID
+func (_m *DB) GetFailedLineItem(ctx context.Context, tenantID string, lineItemID string) (*model.FailedLineItem, error) {
+	ret := _m.Called(ctx, tenantID, lineItemID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetFailedLineItem")
+	}
+
+	var r0 *model.FailedLineItem
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*model.FailedLineItem, error)); ok {
+		return rf(ctx, tenantID, lineItemID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *model.FailedLineItem); ok {
+		r0 = rf(ctx, tenantID, lineItemID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.FailedLineItem)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, tenantID, lineItemID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetInvoice provides a mock function with given fields: ctx, tenantID, invoiceID
+func (_m *DB) GetInvoice(ctx context.Context, tenantID string, invoiceID string) (*model.Invoice, error) {
+	ret := _m.Called(ctx, tenantID, invoiceID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetInvoice")
+	}
+
+	var r0 *model.Invoice
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*model.Invoice, error)); ok {
+		return rf(ctx, tenantID, invoiceID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *model.Invoice); ok {
+		r0 = rf(ctx, tenantID, invoiceID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Invoice)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, tenantID, invoiceID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetInvoiceIssuer provides a mock function with given fields: ctx, tenantID
+func (_m *DB) GetInvoiceIssuer(ctx context.Context, tenantID string) (*model.InvoiceIssuer, error) {
+	ret := _m.Called(ctx, tenantID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetInvoiceIssuer")
+	}
+
+	var r0 *model.InvoiceIssuer
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*model.InvoiceIssuer, error)); ok {
+		return rf(ctx, tenantID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *model.InvoiceIssuer); ok {
+		r0 = rf(ctx, tenantID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.InvoiceIssuer)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, tenantID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetLineItemByExternalRef provides a mock function with given fields: ctx, tenantID, billID, externalRef
+func (_m *DB) GetLineItemByExternalRef(ctx context.Context, tenantID string, billID string, externalRef string) (*model.LineItem, error) {
+	ret := _m.Called(ctx, tenantID, billID, externalRef)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetLineItemByExternalRef")
+	}
+
+	var r0 *model.LineItem
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) (*model.LineItem, error)); ok {
+		return rf(ctx, tenantID, billID, externalRef)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) *model.LineItem); ok {
+		r0 = rf(ctx, tenantID, billID, externalRef)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.LineItem)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string) error); ok {
+		r1 = rf(ctx, tenantID, billID, externalRef)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetLineItemsForBill provides a mock function with given fields: ctx, tenantID, billID
+func (_m *DB) GetLineItemsForBill(ctx context.Context, tenantID string, billID string) ([]model.LineItem, error) {
+	ret := _m.Called(ctx, tenantID, billID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetLineItemsForBill")
+	}
+
+	var r0 []model.LineItem
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) ([]model.LineItem, error)); ok {
+		return rf(ctx, tenantID, billID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) []model.LineItem); ok {
+		r0 = rf(ctx, tenantID, billID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.LineItem)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, tenantID, billID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPaymentLink provides a mock function with given fields: ctx, tenantID, linkID
+func (_m *DB) GetPaymentLink(ctx context.Context, tenantID string, linkID string) (*model.PaymentLink, error) {
+	ret := _m.Called(ctx, tenantID, linkID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPaymentLink")
+	}
+
+	var r0 *model.PaymentLink
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*model.PaymentLink, error)); ok {
+		return rf(ctx, tenantID, linkID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *model.PaymentLink); ok {
+		r0 = rf(ctx, tenantID, linkID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.PaymentLink)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, tenantID, linkID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPrice provides a mock function with given fields: ctx, tenantID, priceID, at
+func (_m *DB) GetPrice(ctx context.Context, tenantID string, priceID string, at time.Time) (*model.Price, error) {
+	ret := _m.Called(ctx, tenantID, priceID, at)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPrice")
+	}
+
+	var r0 *model.Price
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Time) (*model.Price, error)); ok {
+		return rf(ctx, tenantID, priceID, at)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Time) *model.Price); ok {
+		r0 = rf(ctx, tenantID, priceID, at)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Price)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, time.Time) error); ok {
+		r1 = rf(ctx, tenantID, priceID, at)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetProduct provides a mock function with given fields: ctx, tenantID, productID
+func (_m *DB) GetProduct(ctx context.Context, tenantID string, productID string) (*model.Product, error) {
+	ret := _m.Called(ctx, tenantID, productID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetProduct")
+	}
+
+	var r0 *model.Product
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*model.Product, error)); ok {
+		return rf(ctx, tenantID, productID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *model.Product); ok {
+		r0 = rf(ctx, tenantID, productID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Product)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, tenantID, productID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUsageImport provides a mock function with given fields: ctx, tenantID, importID
+func (_m *DB) GetUsageImport(ctx context.Context, tenantID string, importID string) (*model.UsageImport, error) {
+	ret := _m.Called(ctx, tenantID, importID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUsageImport")
+	}
+
+	var r0 *model.UsageImport
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*model.UsageImport, error)); ok {
+		return rf(ctx, tenantID, importID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *model.UsageImport); ok {
+		r0 = rf(ctx, tenantID, importID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.UsageImport)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, tenantID, importID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetWebhookDelivery provides a mock function with given fields: ctx, tenantID, deliveryID
+func (_m *DB) GetWebhookDelivery(ctx context.Context, tenantID string, deliveryID string) (*model.WebhookDelivery, error) {
+	ret := _m.Called(ctx, tenantID, deliveryID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetWebhookDelivery")
+	}
+
+	var r0 *model.WebhookDelivery
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*model.WebhookDelivery, error)); ok {
+		return rf(ctx, tenantID, deliveryID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *model.WebhookDelivery); ok {
+		r0 = rf(ctx, tenantID, deliveryID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.WebhookDelivery)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, tenantID, deliveryID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetWebhookEvent provides a mock function with given fields: ctx, tenantID, eventID
+func (_m *DB) GetWebhookEvent(ctx context.Context, tenantID string, eventID string) (*model.WebhookEvent, error) {
+	ret := _m.Called(ctx, tenantID, eventID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetWebhookEvent")
+	}
+
+	var r0 *model.WebhookEvent
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*model.WebhookEvent, error)); ok {
+		return rf(ctx, tenantID, eventID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *model.WebhookEvent); ok {
+		r0 = rf(ctx, tenantID, eventID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.WebhookEvent)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, tenantID, eventID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetWebhookSubscription provides a mock function with given fields: ctx, tenantID, subscriptionID
+func (_m *DB) GetWebhookSubscription(ctx context.Context, tenantID string, subscriptionID string) (*model.WebhookSubscription, error) {
+	ret := _m.Called(ctx, tenantID, subscriptionID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetWebhookSubscription")
+	}
+
+	var r0 *model.WebhookSubscription
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*model.WebhookSubscription, error)); ok {
+		return rf(ctx, tenantID, subscriptionID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *model.WebhookSubscription); ok {
+		r0 = rf(ctx, tenantID, subscriptionID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.WebhookSubscription)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, tenantID, subscriptionID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// InsertLineItem provides a mock function with given fields: ctx, tenantID, billID, currency, amount, metadata
+func (_m *DB) InsertLineItem(ctx context.Context, tenantID string, billID string, currency string, amount int64, metadata *model.LineItemMetadata) error {
+	ret := _m.Called(ctx, tenantID, billID, currency, amount, metadata)
+
+	if len(ret) == 0 {
+		panic("no return value specified for InsertLineItem")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, int64, *model.LineItemMetadata) error); ok {
+		r0 = rf(ctx, tenantID, billID, currency, amount, metadata)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// IsBillExists provides a mock function with given fields: ctx, tenantID, billID
+func (_m *DB) IsBillExists(ctx context.Context, tenantID string, billID string) (bool, error) {
+	ret := _m.Called(ctx, tenantID, billID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IsBillExists")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (bool, error)); ok {
+		return rf(ctx, tenantID, billID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) bool); ok {
+		r0 = rf(ctx, tenantID, billID)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, tenantID, billID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// IsLineItemExists provides a mock function with given fields: ctx, tenantID, billID, lineItemID, status
+func (_m *DB) IsLineItemExists(ctx context.Context, tenantID string, billID string, lineItemID string, status string) (bool, error) {
+	ret := _m.Called(ctx, tenantID, billID, lineItemID, status)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IsLineItemExists")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, string) (bool, error)); ok {
+		return rf(ctx, tenantID, billID, lineItemID, status)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, string) bool); ok {
+		r0 = rf(ctx, tenantID, billID, lineItemID, status)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string, string) error); ok {
+		r1 = rf(ctx, tenantID, billID, lineItemID, status)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListAPIKeys provides a mock function with given fields: ctx, tenantID, includeRevoked
+func (_m *DB) ListAPIKeys(ctx context.Context, tenantID string, includeRevoked bool) ([]*model.APIKey, error) {
+	ret := _m.Called(ctx, tenantID, includeRevoked)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListAPIKeys")
+	}
+
+	var r0 []*model.APIKey
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, bool) ([]*model.APIKey, error)); ok {
+		return rf(ctx, tenantID, includeRevoked)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, bool) []*model.APIKey); ok {
+		r0 = rf(ctx, tenantID, includeRevoked)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.APIKey)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, bool) error); ok {
+		r1 = rf(ctx, tenantID, includeRevoked)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListAuditEntries provides a mock function with given fields: ctx, tenantID, filter, limit, afterSeq
+func (_m *DB) ListAuditEntries(ctx context.Context, tenantID string, filter model.AuditFilter, limit int, afterSeq int64) ([]*model.AuditEntry, bool, error) {
+	ret := _m.Called(ctx, tenantID, filter, limit, afterSeq)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListAuditEntries")
+	}
+
+	var r0 []*model.AuditEntry
+	var r1 bool
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, model.AuditFilter, int, int64) ([]*model.AuditEntry, bool, error)); ok {
+		return rf(ctx, tenantID, filter, limit, afterSeq)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, model.AuditFilter, int, int64) []*model.AuditEntry); ok {
+		r0 = rf(ctx, tenantID, filter, limit, afterSeq)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.AuditEntry)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, model.AuditFilter, int, int64) bool); ok {
+		r1 = rf(ctx, tenantID, filter, limit, afterSeq)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, string, model.AuditFilter, int, int64) error); ok {
+		r2 = rf(ctx, tenantID, filter, limit, afterSeq)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// ListBillChain provides a mock function with given fields: ctx, tenantID, afterSeq, limit
+func (_m *DB) ListBillChain(ctx context.Context, tenantID string, afterSeq int64, limit int) ([]*model.ChainLink, error) {
+	ret := _m.Called(ctx, tenantID, afterSeq, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListBillChain")
+	}
+
+	var r0 []*model.ChainLink
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64, int) ([]*model.ChainLink, error)); ok {
+		return rf(ctx, tenantID, afterSeq, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64, int) []*model.ChainLink); ok {
+		r0 = rf(ctx, tenantID, afterSeq, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.ChainLink)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int64, int) error); ok {
+		r1 = rf(ctx, tenantID, afterSeq, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListBillInvoices provides a mock function with given fields: ctx, tenantID, billID
+func (_m *DB) ListBillInvoices(ctx context.Context, tenantID string, billID string) ([]*model.Invoice, error) {
+	ret := _m.Called(ctx, tenantID, billID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListBillInvoices")
+	}
+
+	var r0 []*model.Invoice
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) ([]*model.Invoice, error)); ok {
+		return rf(ctx, tenantID, billID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) []*model.Invoice); ok {
+		r0 = rf(ctx, tenantID, billID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Invoice)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, tenantID, billID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListBillPaymentLinks provides a mock function with given fields: ctx, tenantID, billID
+func (_m *DB) ListBillPaymentLinks(ctx context.Context, tenantID string, billID string) ([]*model.PaymentLink, error) {
+	ret := _m.Called(ctx, tenantID, billID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListBillPaymentLinks")
+	}
+
+	var r0 []*model.PaymentLink
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) ([]*model.PaymentLink, error)); ok {
+		return rf(ctx, tenantID, billID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) []*model.PaymentLink); ok {
+		r0 = rf(ctx, tenantID, billID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.PaymentLink)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, tenantID, billID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListEmailDeliveries provides a mock function with given fields: ctx, tenantID, billID
+func (_m *DB) ListEmailDeliveries(ctx context.Context, tenantID string, billID string) ([]*model.EmailDelivery, error) {
+	ret := _m.Called(ctx, tenantID, billID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListEmailDeliveries")
+	}
+
+	var r0 []*model.EmailDelivery
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) ([]*model.EmailDelivery, error)); ok {
+		return rf(ctx, tenantID, billID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) []*model.EmailDelivery); ok {
+		r0 = rf(ctx, tenantID, billID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.EmailDelivery)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, tenantID, billID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListFailedLineItems provides a mock function with given fields: ctx, tenantID, billID, status, limit, after
+func (_m *DB) ListFailedLineItems(ctx context.Context, tenantID string, billID string, status model.FailedLineItemStatus, limit int, after *model.LineItemCursor) ([]*model.FailedLineItem, bool, error) {
+	ret := _m.Called(ctx, tenantID, billID, status, limit, after)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListFailedLineItems")
+	}
+
+	var r0 []*model.FailedLineItem
+	var r1 bool
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, model.FailedLineItemStatus, int, *model.LineItemCursor) ([]*model.FailedLineItem, bool, error)); ok {
+		return rf(ctx, tenantID, billID, status, limit, after)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, model.FailedLineItemStatus, int, *model.LineItemCursor) []*model.FailedLineItem); ok {
+		r0 = rf(ctx, tenantID, billID, status, limit, after)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.FailedLineItem)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, model.FailedLineItemStatus, int, *model.LineItemCursor) bool); ok {
+		r1 = rf(ctx, tenantID, billID, status, limit, after)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, string, string, model.FailedLineItemStatus, int, *model.LineItemCursor) error); ok {
+		r2 = rf(ctx, tenantID, billID, status, limit, after)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// ListLineItems provides a mock function with given fields: ctx, tenantID, billID, status, page
+func (_m *DB) ListLineItems(ctx context.Context, tenantID string, billID string, status model.LineItemStatus, page model.LineItemPage) ([]model.LineItem, bool, error) {
+	ret := _m.Called(ctx, tenantID, billID, status, page)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListLineItems")
+	}
+
+	var r0 []model.LineItem
+	var r1 bool
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, model.LineItemStatus, model.LineItemPage) ([]model.LineItem, bool, error)); ok {
+		return rf(ctx, tenantID, billID, status, page)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, model.LineItemStatus, model.LineItemPage) []model.LineItem); ok {
+		r0 = rf(ctx, tenantID, billID, status, page)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.LineItem)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, model.LineItemStatus, model.LineItemPage) bool); ok {
+		r1 = rf(ctx, tenantID, billID, status, page)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, string, string, model.LineItemStatus, model.LineItemPage) error); ok {
+		r2 = rf(ctx, tenantID, billID, status, page)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// ListOutboxTenants provides a mock function with given fields: ctx
+func (_m *DB) ListOutboxTenants(ctx context.Context) ([]string, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListOutboxTenants")
+	}
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]string, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []string); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListPriceVersions provides a mock function with given fields: ctx, tenantID, priceID
+func (_m *DB) ListPriceVersions(ctx context.Context, tenantID string, priceID string) ([]model.PriceVersion, error) {
+	ret := _m.Called(ctx, tenantID, priceID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListPriceVersions")
+	}
+
+	var r0 []model.PriceVersion
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) ([]model.PriceVersion, error)); ok {
+		return rf(ctx, tenantID, priceID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) []model.PriceVersion); ok {
+		r0 = rf(ctx, tenantID, priceID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.PriceVersion)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, tenantID, priceID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListPrices provides a mock function with given fields: ctx, tenantID, productID, at
+func (_m *DB) ListPrices(ctx context.Context, tenantID string, productID string, at time.Time) ([]*model.Price, error) {
+	ret := _m.Called(ctx, tenantID, productID, at)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListPrices")
+	}
+
+	var r0 []*model.Price
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Time) ([]*model.Price, error)); ok {
+		return rf(ctx, tenantID, productID, at)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Time) []*model.Price); ok {
+		r0 = rf(ctx, tenantID, productID, at)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Price)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, time.Time) error); ok {
+		r1 = rf(ctx, tenantID, productID, at)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListProducts provides a mock function with given fields: ctx, tenantID, activeOnly, limit, cursor
+func (_m *DB) ListProducts(ctx context.Context, tenantID string, activeOnly bool, limit int, cursor time.Time) ([]*model.Product, bool, error) {
+	ret := _m.Called(ctx, tenantID, activeOnly, limit, cursor)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListProducts")
+	}
+
+	var r0 []*model.Product
+	var r1 bool
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, bool, int, time.Time) ([]*model.Product, bool, error)); ok {
+		return rf(ctx, tenantID, activeOnly, limit, cursor)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, bool, int, time.Time) []*model.Product); ok {
+		r0 = rf(ctx, tenantID, activeOnly, limit, cursor)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.Product)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, bool, int, time.Time) bool); ok {
+		r1 = rf(ctx, tenantID, activeOnly, limit, cursor)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, string, bool, int, time.Time) error); ok {
+		r2 = rf(ctx, tenantID, activeOnly, limit, cursor)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// ListUndispatchedWebhookDeliveries provides a mock function with given fields: ctx, tenantID, limit
+func (_m *DB) ListUndispatchedWebhookDeliveries(ctx context.Context, tenantID string, limit int) ([]string, error) {
+	ret := _m.Called(ctx, tenantID, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListUndispatchedWebhookDeliveries")
+	}
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) ([]string, error)); ok {
+		return rf(ctx, tenantID, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) []string); ok {
+		r0 = rf(ctx, tenantID, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int) error); ok {
+		r1 = rf(ctx, tenantID, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListUsageImportRows provides a mock function with given fields: ctx, tenantID, importID, afterRow, limit, failedOnly
+func (_m *DB) ListUsageImportRows(ctx context.Context, tenantID string, importID string, afterRow int, limit int, failedOnly bool) ([]model.UsageImportRow, error) {
+	ret := _m.Called(ctx, tenantID, importID, afterRow, limit, failedOnly)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListUsageImportRows")
+	}
+
+	var r0 []model.UsageImportRow
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int, int, bool) ([]model.UsageImportRow, error)); ok {
+		return rf(ctx, tenantID, importID, afterRow, limit, failedOnly)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int, int, bool) []model.UsageImportRow); ok {
+		r0 = rf(ctx, tenantID, importID, afterRow, limit, failedOnly)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.UsageImportRow)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, int, int, bool) error); ok {
+		r1 = rf(ctx, tenantID, importID, afterRow, limit, failedOnly)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListWebhookDeliveries provides a mock function with given fields: ctx, tenantID, subscriptionID, status, limit
+func (_m *DB) ListWebhookDeliveries(ctx context.Context, tenantID string, subscriptionID string, status model.WebhookDeliveryStatus, limit int) ([]*model.WebhookDelivery, error) {
+	ret := _m.Called(ctx, tenantID, subscriptionID, status, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListWebhookDeliveries")
+	}
+
+	var r0 []*model.WebhookDelivery
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, model.WebhookDeliveryStatus, int) ([]*model.WebhookDelivery, error)); ok {
+		return rf(ctx, tenantID, subscriptionID, status, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, model.WebhookDeliveryStatus, int) []*model.WebhookDelivery); ok {
+		r0 = rf(ctx, tenantID, subscriptionID, status, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.WebhookDelivery)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, model.WebhookDeliveryStatus, int) error); ok {
+		r1 = rf(ctx, tenantID, subscriptionID, status, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListWebhookDispatchTenants provides a mock function with given fields: ctx
+func (_m *DB) ListWebhookDispatchTenants(ctx context.Context) ([]string, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListWebhookDispatchTenants")
+	}
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]string, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []string); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListWebhookSubscriptions provides a mock function with given fields: ctx, tenantID
+func (_m *DB) ListWebhookSubscriptions(ctx context.Context, tenantID string) ([]*model.WebhookSubscription, error) {
+	ret := _m.Called(ctx, tenantID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListWebhookSubscriptions")
+	}
+
+	var r0 []*model.WebhookSubscription
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]*model.WebhookSubscription, error)); ok {
+		return rf(ctx, tenantID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*model.WebhookSubscription); ok {
+		r0 = rf(ctx, tenantID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.WebhookSubscription)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, tenantID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// LookupAPIKey provides a mock function with given fields: ctx, keyID
+func (_m *DB) LookupAPIKey(ctx context.Context, keyID string) (*model.APIKey, error) {
+	ret := _m.Called(ctx, keyID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for LookupAPIKey")
+	}
+
+	var r0 *model.APIKey
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*model.APIKey, error)); ok {
+		return rf(ctx, keyID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *model.APIKey); ok {
+		r0 = rf(ctx, keyID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.APIKey)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, keyID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// LookupPaymentLink provides a mock function with given fields: ctx, provider, providerLinkID
+func (_m *DB) LookupPaymentLink(ctx context.Context, provider string, providerLinkID string) (*model.PaymentLink, error) {
+	ret := _m.Called(ctx, provider, providerLinkID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for LookupPaymentLink")
+	}
+
+	var r0 *model.PaymentLink
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*model.PaymentLink, error)); ok {
+		return rf(ctx, provider, providerLinkID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *model.PaymentLink); ok {
+		r0 = rf(ctx, provider, providerLinkID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.PaymentLink)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, provider, providerLinkID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MarkWebhookDeliveriesDispatched provides a mock function with given fields: ctx, tenantID, deliveryIDs
+func (_m *DB) MarkWebhookDeliveriesDispatched(ctx context.Context, tenantID string, deliveryIDs []string) error {
+	ret := _m.Called(ctx, tenantID, deliveryIDs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MarkWebhookDeliveriesDispatched")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, []string) error); ok {
+		r0 = rf(ctx, tenantID, deliveryIDs)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// PublishOutboxEvents provides a mock function with given fields: ctx, tenantID, limit, publish
+func (_m *DB) PublishOutboxEvents(ctx context.Context, tenantID string, limit int, publish func(context.Context, *model.OutboxEvent) error) (int, error) {
+	ret := _m.Called(ctx, tenantID, limit, publish)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PublishOutboxEvents")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int, func(context.Context, *model.OutboxEvent) error) (int, error)); ok {
+		return rf(ctx, tenantID, limit, publish)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int, func(context.Context, *model.OutboxEvent) error) int); ok {
+		r0 = rf(ctx, tenantID, limit, publish)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int, func(context.Context, *model.OutboxEvent) error) error); ok {
+		r1 = rf(ctx, tenantID, limit, publish)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RecordFailedLineItem provides a mock function with given fields: ctx, tenantID, item
+func (_m *DB) RecordFailedLineItem(ctx context.Context, tenantID string, item *model.FailedLineItem) error {
+	ret := _m.Called(ctx, tenantID, item)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RecordFailedLineItem")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *model.FailedLineItem) error); ok {
+		r0 = rf(ctx, tenantID, item)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RecordUnappliedPayment provides a mock function with given fields: ctx, tenantID, p
+func (_m *DB) RecordUnappliedPayment(ctx context.Context, tenantID string, p *model.UnappliedPayment) error {
+	ret := _m.Called(ctx, tenantID, p)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RecordUnappliedPayment")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *model.UnappliedPayment) error); ok {
+		r0 = rf(ctx, tenantID, p)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RecordUsageImportResults provides a mock function with given fields: ctx, tenantID, importID, results
+func (_m *DB) RecordUsageImportResults(ctx context.Context, tenantID string, importID string, results []model.UsageImportRowResult) error {
+	ret := _m.Called(ctx, tenantID, importID, results)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RecordUsageImportResults")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, []model.UsageImportRowResult) error); ok {
+		r0 = rf(ctx, tenantID, importID, results)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RecordWebhookAttempt provides a mock function with given fields: ctx, tenantID, deliveryID, attempt
+func (_m *DB) RecordWebhookAttempt(ctx context.Context, tenantID string, deliveryID string, attempt model.WebhookAttempt) error {
+	ret := _m.Called(ctx, tenantID, deliveryID, attempt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RecordWebhookAttempt")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, model.WebhookAttempt) error); ok {
+		r0 = rf(ctx, tenantID, deliveryID, attempt)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RedeliverWebhook provides a mock function with given fields: ctx, tenantID, redeliveryOf, deliveryID
+func (_m *DB) RedeliverWebhook(ctx context.Context, tenantID string, redeliveryOf string, deliveryID string) (*model.WebhookDelivery, error) {
+	ret := _m.Called(ctx, tenantID, redeliveryOf, deliveryID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RedeliverWebhook")
+	}
+
+	var r0 *model.WebhookDelivery
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) (*model.WebhookDelivery, error)); ok {
+		return rf(ctx, tenantID, redeliveryOf, deliveryID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) *model.WebhookDelivery); ok {
+		r0 = rf(ctx, tenantID, redeliveryOf, deliveryID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.WebhookDelivery)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string) error); ok {
+		r1 = rf(ctx, tenantID, redeliveryOf, deliveryID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ReopenBill provides a mock function with given fields: ctx, tenantID, billID, source
+func (_m *DB) ReopenBill(ctx context.Context, tenantID string, billID string, source model.AuditSource) error {
+	ret := _m.Called(ctx, tenantID, billID, source)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReopenBill")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, model.AuditSource) error); ok {
+		r0 = rf(ctx, tenantID, billID, source)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ResolveFailedLineItem provides a mock function with given fields: ctx, tenantID, lineItemID, status
+func (_m *DB) ResolveFailedLineItem(ctx context.Context, tenantID string, lineItemID string, status model.FailedLineItemStatus) error {
+	ret := _m.Called(ctx, tenantID, lineItemID, status)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ResolveFailedLineItem")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, model.FailedLineItemStatus) error); ok {
+		r0 = rf(ctx, tenantID, lineItemID, status)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RevokeAPIKey provides a mock function with given fields: ctx, tenantID, keyID
+func (_m *DB) RevokeAPIKey(ctx context.Context, tenantID string, keyID string) error {
+	ret := _m.Called(ctx, tenantID, keyID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RevokeAPIKey")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, tenantID, keyID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RotateAPIKey provides a mock function with given fields: ctx, tenantID, next, oldExpiresAt
+func (_m *DB) RotateAPIKey(ctx context.Context, tenantID string, next *model.APIKey, oldExpiresAt time.Time) error {
+	ret := _m.Called(ctx, tenantID, next, oldExpiresAt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RotateAPIKey")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *model.APIKey, time.Time) error); ok {
+		r0 = rf(ctx, tenantID, next, oldExpiresAt)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetBillInvoicePDF provides a mock function with given fields: ctx, tenantID, billID, key
+func (_m *DB) SetBillInvoicePDF(ctx context.Context, tenantID string, billID string, key string) error {
+	ret := _m.Called(ctx, tenantID, billID, key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetBillInvoicePDF")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) error); ok {
+		r0 = rf(ctx, tenantID, billID, key)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetBillPeriodEnd provides a mock function with given fields: ctx, tenantID, billID, periodEnd
+func (_m *DB) SetBillPeriodEnd(ctx context.Context, tenantID string, billID string, periodEnd time.Time) error {
+	ret := _m.Called(ctx, tenantID, billID, periodEnd)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetBillPeriodEnd")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Time) error); ok {
+		r0 = rf(ctx, tenantID, billID, periodEnd)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetInvoiceIssuer provides a mock function with given fields: ctx, tenantID, issuer
+func (_m *DB) SetInvoiceIssuer(ctx context.Context, tenantID string, issuer *model.InvoiceIssuer) error {
+	ret := _m.Called(ctx, tenantID, issuer)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetInvoiceIssuer")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *model.InvoiceIssuer) error); ok {
+		r0 = rf(ctx, tenantID, issuer)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetPriceActive provides a mock function with given fields: ctx, tenantID, priceID, active
+func (_m *DB) SetPriceActive(ctx context.Context, tenantID string, priceID string, active bool) error {
+	ret := _m.Called(ctx, tenantID, priceID, active)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetPriceActive")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, bool) error); ok {
+		r0 = rf(ctx, tenantID, priceID, active)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetUsageImportStatus provides a mock function with given fields: ctx, tenantID, importID, status
+func (_m *DB) SetUsageImportStatus(ctx context.Context, tenantID string, importID string, status model.UsageImportStatus) error {
+	ret := _m.Called(ctx, tenantID, importID, status)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetUsageImportStatus")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, model.UsageImportStatus) error); ok {
+		r0 = rf(ctx, tenantID, importID, status)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SettleBill provides a mock function with given fields: ctx, tenantID, linkID, paymentID, source
+func (_m *DB) SettleBill(ctx context.Context, tenantID string, linkID string, paymentID string, source model.AuditSource) error {
+	ret := _m.Called(ctx, tenantID, linkID, paymentID, source)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SettleBill")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, model.AuditSource) error); ok {
+		r0 = rf(ctx, tenantID, linkID, paymentID, source)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateLineItem provides a mock function with given fields: ctx, tenantID, billID, lineItemID, status, source
+func (_m *DB) UpdateLineItem(ctx context.Context, tenantID string, billID string, lineItemID string, status string, source model.AuditSource) (*model.LineItem, error) {
+	ret := _m.Called(ctx, tenantID, billID, lineItemID, status, source)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateLineItem")
+	}
+
+	var r0 *model.LineItem
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, string, model.AuditSource) (*model.LineItem, error)); ok {
+		return rf(ctx, tenantID, billID, lineItemID, status, source)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, string, model.AuditSource) *model.LineItem); ok {
+		r0 = rf(ctx, tenantID, billID, lineItemID, status, source)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.LineItem)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string, string, model.AuditSource) error); ok {
+		r1 = rf(ctx, tenantID, billID, lineItemID, status, source)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateProduct provides a mock function with given fields: ctx, tenantID, productID, name, description, active
+func (_m *DB) UpdateProduct(ctx context.Context, tenantID string, productID string, name *string, description *string, active *bool) (*model.Product, error) {
+	ret := _m.Called(ctx, tenantID, productID, name, description, active)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateProduct")
+	}
+
+	var r0 *model.Product
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, *string, *string, *bool) (*model.Product, error)); ok {
+		return rf(ctx, tenantID, productID, name, description, active)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, *string, *string, *bool) *model.Product); ok {
+		r0 = rf(ctx, tenantID, productID, name, description, active)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.Product)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, *string, *string, *bool) error); ok {
+		r1 = rf(ctx, tenantID, productID, name, description, active)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateWebhookSubscription provides a mock function with given fields: ctx, tenantID, sub
+func (_m *DB) UpdateWebhookSubscription(ctx context.Context, tenantID string, sub *model.WebhookSubscription) error {
+	ret := _m.Called(ctx, tenantID, sub)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateWebhookSubscription")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *model.WebhookSubscription) error); ok {
+		r0 = rf(ctx, tenantID, sub)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpsertCustomer provides a mock function with given fields: ctx, tenantID, customer
+func (_m *DB) UpsertCustomer(ctx context.Context, tenantID string, customer *model.Customer) error {
+	ret := _m.Called(ctx, tenantID, customer)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpsertCustomer")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *model.Customer) error); ok {
+		r0 = rf(ctx, tenantID, customer)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // NewDB creates a new instance of DB. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewDB(t interface {
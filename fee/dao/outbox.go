@@ -0,0 +1,212 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"encore.app/fee/model"
+	"encore.dev/rlog"
+	"encore.dev/storage/sqldb"
+)
+
+// enqueueOutboxEvents stores events in the outbox in the transaction of the change they describe.
+// An event that is already stored, by an earlier attempt of the same change, is kept as it is.
+func enqueueOutboxEvents(ctx context.Context, tx *sqldb.Tx, tenantID string, events []model.OutboxEvent) error {
+	for _, event := range events {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO event_outbox (tenant_id, event_id, topic, payload)
+			VALUES ($1, $2, $3, $4::jsonb)
+			ON CONFLICT (tenant_id, event_id, topic) DO NOTHING
+		`, tenantID, event.EventID, event.Topic, string(event.Payload))
+		if err != nil {
+			return fmt.Errorf("failed to write outbox event: %w", err)
+		}
+	}
+	return nil
+}
+
+// ListOutboxTenants returns the tenants that have events that weren't published yet.
+func (d *dbStore) ListOutboxTenants(ctx context.Context) ([]string, error) {
+	rows, err := d.db.Query(ctx, `
+		SELECT DISTINCT tenant_id
+		FROM event_outbox
+		WHERE published_at IS NULL AND dead_lettered_at IS NULL
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list outbox tenants: %w", err)
+	}
+	defer rows.Close()
+
+	var tenantIDs []string
+	for rows.Next() {
+		var tenantID string
+		if err := rows.Scan(&tenantID); err != nil {
+			return nil, err
+		}
+		tenantIDs = append(tenantIDs, tenantID)
+	}
+	return tenantIDs, rows.Err()
+}
+
+// outboxLease is how long a relay holds the events it claimed. A relay that stopped before it
+// marked them is replaced once the lease ends, its events are published again.
+const outboxLease = time.Minute
+
+// outboxMaxAttempts is how often an event is tried before it is dead-lettered, so an event that
+// can never be published doesn't hold back the later events of its tenant forever.
+const outboxMaxAttempts = 10
+
+// PublishOutboxEvents publishes up to limit events of the tenant that weren't published yet, in the
+// order they were written, and marks them published. It stops at the first event publish fails so
+// none overtakes an earlier one, and returns how many were published. An event that failed
+// outboxMaxAttempts times is dead-lettered and no longer holds back the events after it.
+//
+// The events are claimed with a lease in a short transaction and published outside of it, so no
+// lock is held over the network. Relays running at the same time neither publish an event twice
+// nor overtake each other: a relay that finds older events of the tenant claimed leaves the tenant
+// to the relay holding them.
+func (d *dbStore) PublishOutboxEvents(ctx context.Context, tenantID string, limit int, publish func(context.Context, *model.OutboxEvent) error) (int, error) {
+	events, err := d.claimOutboxEvents(ctx, tenantID, limit)
+	if err != nil || len(events) == 0 {
+		return 0, err
+	}
+
+	claimed := make([]int64, len(events))
+	published := make([]int64, 0, len(events))
+	var failed *model.OutboxEvent
+	var publishErr error
+	for i, event := range events {
+		claimed[i] = event.Seq
+		if failed != nil {
+			continue
+		}
+		if publishErr = publish(ctx, event); publishErr != nil {
+			rlog.Error("failed to publish outbox event", "error", publishErr, "event_id", event.EventID, "topic", event.Topic)
+			failed = event
+			continue
+		}
+		published = append(published, event.Seq)
+	}
+
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("could not begin transaction: %w", err)
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			rlog.Error("failed to rollback outbox publish", "error", rbErr)
+		}
+	}()
+	_, err = tx.Exec(ctx, `
+		UPDATE event_outbox SET published_at = now(), claimed_until = NULL
+		WHERE tenant_id = $1 AND id = ANY($2::bigint[])
+	`, tenantID, published)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark outbox events published: %w", err)
+	}
+	if failed != nil {
+		var deadLettered bool
+		err = tx.QueryRow(ctx, `
+			UPDATE event_outbox
+			SET attempts = attempts + 1, last_error = $3,
+				dead_lettered_at = CASE WHEN attempts + 1 >= $4 THEN now() END
+			WHERE tenant_id = $1 AND id = $2
+			RETURNING dead_lettered_at IS NOT NULL
+		`, tenantID, failed.Seq, publishErr.Error(), outboxMaxAttempts).Scan(&deadLettered)
+		if err != nil {
+			return 0, fmt.Errorf("failed to record outbox publish failure: %w", err)
+		}
+		if deadLettered {
+			rlog.Error("dead-lettered outbox event", "event_id", failed.EventID, "topic", failed.Topic, "tenant_id", tenantID)
+		}
+	}
+	// The events after a failed one are tried again by the next relay.
+	_, err = tx.Exec(ctx, `
+		UPDATE event_outbox SET claimed_until = NULL
+		WHERE tenant_id = $1 AND id = ANY($2::bigint[]) AND published_at IS NULL
+	`, tenantID, claimed)
+	if err != nil {
+		return 0, fmt.Errorf("failed to release outbox events: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit failed: %w", err)
+	}
+	return len(published), nil
+}
+
+// claimOutboxEvents leases the next events of the tenant to the caller. It claims none when an
+// older event is claimed by another relay, which must publish it first.
+func (d *dbStore) claimOutboxEvents(ctx context.Context, tenantID string, limit int) ([]*model.OutboxEvent, error) {
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not begin transaction: %w", err)
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			rlog.Error("failed to rollback outbox claim", "error", rbErr)
+		}
+	}()
+
+	rows, err := tx.Query(ctx, `
+		SELECT id, event_id, topic, payload::text
+		FROM event_outbox
+		WHERE tenant_id = $1 AND published_at IS NULL AND dead_lettered_at IS NULL
+			AND (claimed_until IS NULL OR claimed_until < now())
+		ORDER BY id
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	`, tenantID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock outbox events: %w", err)
+	}
+	var events []*model.OutboxEvent
+	ids := make([]int64, 0, limit)
+	for rows.Next() {
+		var event model.OutboxEvent
+		var payload string
+		if err := rows.Scan(&event.Seq, &event.EventID, &event.Topic, &payload); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		event.Payload = []byte(payload)
+		events = append(events, &event)
+		ids = append(ids, event.Seq)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, nil
+	}
+
+	// Older events were skipped because another relay is claiming or holds them.
+	var behind bool
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM event_outbox
+			WHERE tenant_id = $1 AND published_at IS NULL AND dead_lettered_at IS NULL AND id < $2
+		)
+	`, tenantID, events[0].Seq).Scan(&behind)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check outbox order: %w", err)
+	}
+	if behind {
+		return nil, nil
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE event_outbox SET claimed_until = now() + make_interval(secs => $3)
+		WHERE tenant_id = $1 AND id = ANY($2::bigint[])
+	`, tenantID, ids, outboxLease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim outbox events: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit failed: %w", err)
+	}
+	return events, nil
+}
@@ -0,0 +1,165 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"encore.app/fee/model"
+	"encore.dev/storage/sqldb"
+	"github.com/jackc/pgx/v5"
+)
+
+const paymentLinkColumns = `tenant_id, link_id, bill_id, invoice_id, provider, provider_link_id, url, amount, currency, status,
+	expires_at, COALESCE(payment_id, ''), paid_at, created_at`
+
+// scanPaymentLink reads the paymentLinkColumns of a row.
+func scanPaymentLink(row pgx.Row) (*model.PaymentLink, error) {
+	var link model.PaymentLink
+	if err := row.Scan(&link.TenantID, &link.LinkID, &link.BillID, &link.InvoiceID, &link.Provider, &link.ProviderLinkID,
+		&link.URL, &link.Amount, &link.Currency, &link.Status, &link.ExpiresAt, &link.PaymentID, &link.PaidAt, &link.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// CreatePaymentLink stores a new payment link, a link that is already stored is kept as it is.
+func (d *dbStore) CreatePaymentLink(ctx context.Context, tenantID string, link *model.PaymentLink) error {
+	_, err := d.db.Exec(ctx, `
+		INSERT INTO payment_links (tenant_id, link_id, bill_id, invoice_id, provider, provider_link_id, url, amount, currency, status, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (tenant_id, link_id) DO NOTHING
+	`, tenantID, link.LinkID, link.BillID, link.InvoiceID, link.Provider, link.ProviderLinkID, link.URL, link.Amount, link.Currency,
+		link.Status, link.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to create payment link: %w", err)
+	}
+	return nil
+}
+
+// GetPaymentLink returns sql.ErrNoRows if the tenant has no payment link with that ID.
+func (d *dbStore) GetPaymentLink(ctx context.Context, tenantID, linkID string) (*model.PaymentLink, error) {
+	return d.getPaymentLink(ctx, `SELECT `+paymentLinkColumns+` FROM payment_links WHERE tenant_id = $1 AND link_id = $2`, tenantID, linkID)
+}
+
+// LookupPaymentLink finds a payment link of any tenant by the provider's ID of the link. Provider
+// callbacks carry no API key, the link tells which tenant they are for.
+func (d *dbStore) LookupPaymentLink(ctx context.Context, provider, providerLinkID string) (*model.PaymentLink, error) {
+	return d.getPaymentLink(ctx, `SELECT `+paymentLinkColumns+` FROM payment_links WHERE provider = $1 AND provider_link_id = $2`,
+		provider, providerLinkID)
+}
+
+func (d *dbStore) getPaymentLink(ctx context.Context, query string, args ...any) (*model.PaymentLink, error) {
+	link, err := scanPaymentLink(d.db.QueryRow(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || errors.Is(err, pgx.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get payment link: %w", err)
+	}
+	return link, nil
+}
+
+// ListBillPaymentLinks retrieves the payment links of a bill, oldest first.
+func (d *dbStore) ListBillPaymentLinks(ctx context.Context, tenantID, billID string) ([]*model.PaymentLink, error) {
+	rows, err := d.db.Query(ctx, `
+		SELECT `+paymentLinkColumns+`
+		FROM payment_links
+		WHERE tenant_id = $1 AND bill_id = $2
+		ORDER BY created_at, id
+	`, tenantID, billID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list payment links: %w", err)
+	}
+	defer rows.Close()
+
+	var links []*model.PaymentLink
+	for rows.Next() {
+		link, err := scanPaymentLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
+
+// ExpirePaymentLink marks a link that wasn't paid by its expiry as EXPIRED.
+func (d *dbStore) ExpirePaymentLink(ctx context.Context, tenantID, linkID string) error {
+	_, err := d.db.Exec(ctx, `
+		UPDATE payment_links SET status = $1, updated_at = now()
+		WHERE tenant_id = $2 AND link_id = $3 AND status = $4
+	`, model.PaymentLinkExpired, tenantID, linkID, model.PaymentLinkPending)
+	if err != nil {
+		return fmt.Errorf("failed to expire payment link: %w", err)
+	}
+	return nil
+}
+
+// SettleBill records the payment of a link and settles its closed bill, audited as
+// PAYMENT_RECORDED. A link that is already paid is left as it is. It returns sql.ErrNoRows if
+// the link doesn't exist or its bill isn't closed, e.g. because it was reopened.
+func (d *dbStore) SettleBill(ctx context.Context, tenantID, linkID, paymentID string, source model.AuditSource) error {
+	err := d.audited(ctx, tenantID, "settle bill", func(tx *sqldb.Tx) ([]model.AuditEntry, error) {
+		var billID string
+		var amount int64
+		var status model.PaymentLinkStatus
+		err := tx.QueryRow(ctx, `
+			SELECT bill_id, amount, status FROM payment_links WHERE tenant_id = $1 AND link_id = $2 FOR UPDATE
+		`, tenantID, linkID).Scan(&billID, &amount, &status)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) || errors.Is(err, pgx.ErrNoRows) {
+				return nil, sql.ErrNoRows
+			}
+			return nil, err
+		}
+		if status == model.PaymentLinkPaid {
+			return nil, nil
+		}
+		if status == model.PaymentLinkCancelled {
+			return nil, sql.ErrNoRows
+		}
+
+		res, err := tx.Exec(ctx, `
+			UPDATE bills SET status = $1, updated_at = now() WHERE tenant_id = $2 AND bill_id = $3 AND status = $4
+		`, model.BillStatusSettled, tenantID, billID, model.BillStatusClosed)
+		if err != nil {
+			return nil, err
+		}
+		if res.RowsAffected() == 0 {
+			return nil, sql.ErrNoRows
+		}
+		_, err = tx.Exec(ctx, `
+			UPDATE payment_links SET status = $1, payment_id = $2, paid_at = now(), updated_at = now()
+			WHERE tenant_id = $3 AND link_id = $4
+		`, model.PaymentLinkPaid, paymentID, tenantID, linkID)
+		if err != nil {
+			return nil, err
+		}
+		return []model.AuditEntry{auditEntry(source, model.AuditPaymentRecorded, billID, "",
+			map[string]any{"status": model.BillStatusClosed},
+			map[string]any{"status": model.BillStatusSettled, "amount": amount, "link_id": linkID, "payment_id": paymentID})}, nil
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to settle bill: %w", err)
+	}
+	return nil
+}
+
+// RecordUnappliedPayment keeps a payment that couldn't settle its bill, a payment that is already
+// recorded is kept as it is.
+func (d *dbStore) RecordUnappliedPayment(ctx context.Context, tenantID string, p *model.UnappliedPayment) error {
+	_, err := d.db.Exec(ctx, `
+		INSERT INTO unapplied_payments (tenant_id, provider, payment_id, link_id, bill_id, amount, currency)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (provider, payment_id) DO NOTHING
+	`, tenantID, p.Provider, p.PaymentID, p.LinkID, p.BillID, p.Amount, p.Currency)
+	if err != nil {
+		return fmt.Errorf("failed to record unapplied payment: %w", err)
+	}
+	return nil
+}
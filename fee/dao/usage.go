@@ -0,0 +1,182 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"encore.app/fee/model"
+	"encore.dev/rlog"
+	"github.com/jackc/pgx/v5"
+)
+
+// CreateUsageImport stores a validated import together with all of its rows. It returns false
+// without writing anything if an import with the same file hash already exists.
+func (d *dbStore) CreateUsageImport(ctx context.Context, tenantID string, usageImport *model.UsageImport, importRows []model.UsageImportRow) (created bool, err error) {
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("could not begin transaction: %w", err)
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			rlog.Error("failed to rollback create usage import", "error", rbErr)
+		}
+	}()
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO usage_imports (tenant_id, import_id, file_hash, status, total_rows, updated_at)
+		VALUES ($1, $2, $3, $4, $5, now())
+		ON CONFLICT DO NOTHING
+		RETURNING status, created_at, updated_at;
+	`, tenantID, usageImport.ImportID, usageImport.FileHash, model.UsageImportStatusPending, len(importRows)).Scan(&usageImport.Status, &usageImport.CreatedAt, &usageImport.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to insert usage import: %w", err)
+	}
+	usageImport.TotalRows = len(importRows)
+
+	rowNumbers := make([]int, len(importRows))
+	billIDs := make([]string, len(importRows))
+	amounts := make([]int64, len(importRows))
+	descriptions := make([]string, len(importRows))
+	externalRefs := make([]string, len(importRows))
+	occurredAts := make([]*time.Time, len(importRows))
+	lineItemIDs := make([]string, len(importRows))
+	for i, row := range importRows {
+		rowNumbers[i] = row.RowNumber
+		billIDs[i] = row.BillID
+		amounts[i] = row.Amount
+		descriptions[i] = row.Description
+		externalRefs[i] = row.ExternalRef
+		occurredAts[i] = row.OccurredAt
+		lineItemIDs[i] = row.LineItemID
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO usage_import_rows (tenant_id, import_id, row_number, bill_id, amount, description, external_ref, occurred_at, line_item_id, status, updated_at)
+		SELECT $10, $1, t.row_number, t.bill_id, t.amount, t.description, t.external_ref, t.occurred_at, t.line_item_id, $2, now()
+		FROM unnest($3::int[], $4::varchar[], $5::bigint[], $6::text[], $7::varchar[], $8::timestamptz[], $9::varchar[])
+			AS t(row_number, bill_id, amount, description, external_ref, occurred_at, line_item_id)
+	`, usageImport.ImportID, model.UsageImportRowPending, rowNumbers, billIDs, amounts, descriptions, externalRefs, occurredAts, lineItemIDs, tenantID)
+	if err != nil {
+		return false, fmt.Errorf("failed to insert usage import rows: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("commit failed: %w", err)
+	}
+	return true, nil
+}
+
+// GetUsageImport returns sql.ErrNoRows if the import does not exist.
+func (d *dbStore) GetUsageImport(ctx context.Context, tenantID, importID string) (*model.UsageImport, error) {
+	var usageImport model.UsageImport
+	err := d.db.QueryRow(ctx, `
+		SELECT import_id, file_hash, status, total_rows, processed_rows, failed_rows, created_at, updated_at, completed_at
+		FROM usage_imports
+		WHERE tenant_id = $1 AND import_id = $2
+	`, tenantID, importID).Scan(&usageImport.ImportID, &usageImport.FileHash, &usageImport.Status, &usageImport.TotalRows,
+		&usageImport.ProcessedRows, &usageImport.FailedRows, &usageImport.CreatedAt, &usageImport.UpdatedAt, &usageImport.CompletedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get usage import: %w", err)
+	}
+	return &usageImport, nil
+}
+
+// ListUsageImportRows pages through the rows of an import in file order, starting after the given row number.
+// When failedOnly is set, only rows that could not be delivered are returned.
+func (d *dbStore) ListUsageImportRows(ctx context.Context, tenantID, importID string, afterRow, limit int, failedOnly bool) ([]model.UsageImportRow, error) {
+	rows, err := d.db.Query(ctx, `
+		SELECT row_number, bill_id, amount, description, external_ref, occurred_at, line_item_id, status, error
+		FROM usage_import_rows
+		WHERE tenant_id = $6 AND import_id = $1 AND row_number > $2 AND (NOT $3 OR status = $4)
+		ORDER BY row_number
+		LIMIT $5
+	`, importID, afterRow, failedOnly, model.UsageImportRowFailed, limit, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list usage import rows: %w", err)
+	}
+	defer rows.Close()
+
+	var importRows []model.UsageImportRow
+	for rows.Next() {
+		var row model.UsageImportRow
+		if err := rows.Scan(&row.RowNumber, &row.BillID, &row.Amount, &row.Description, &row.ExternalRef,
+			&row.OccurredAt, &row.LineItemID, &row.Status, &row.Error); err != nil {
+			return nil, err
+		}
+		importRows = append(importRows, row)
+	}
+	return importRows, rows.Err()
+}
+
+// RecordUsageImportResults marks rows as submitted or failed and refreshes the import progress.
+// The counters are recomputed from the rows, so recording the same results twice is harmless.
+func (d *dbStore) RecordUsageImportResults(ctx context.Context, tenantID, importID string, results []model.UsageImportRowResult) (err error) {
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("could not begin transaction: %w", err)
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			rlog.Error("failed to rollback record usage import results", "error", rbErr)
+		}
+	}()
+
+	rowNumbers := make([]int, len(results))
+	statuses := make([]string, len(results))
+	errorMessages := make([]string, len(results))
+	for i, result := range results {
+		rowNumbers[i] = result.RowNumber
+		statuses[i] = string(model.UsageImportRowSubmitted)
+		if result.Error != "" {
+			statuses[i] = string(model.UsageImportRowFailed)
+		}
+		errorMessages[i] = result.Error
+	}
+	_, err = tx.Exec(ctx, `
+		UPDATE usage_import_rows r
+		SET status = t.status, error = t.error, updated_at = now()
+		FROM unnest($2::int[], $3::varchar[], $4::text[]) AS t(row_number, status, error)
+		WHERE r.tenant_id = $5 AND r.import_id = $1 AND r.row_number = t.row_number
+	`, importID, rowNumbers, statuses, errorMessages, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to update usage import rows: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE usage_imports
+		SET processed_rows = (SELECT count(*) FROM usage_import_rows WHERE tenant_id = $4 AND import_id = $1 AND status <> $2),
+			failed_rows = (SELECT count(*) FROM usage_import_rows WHERE tenant_id = $4 AND import_id = $1 AND status = $3),
+			updated_at = now()
+		WHERE tenant_id = $4 AND import_id = $1
+	`, importID, model.UsageImportRowPending, model.UsageImportRowFailed, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to update usage import progress: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit failed: %w", err)
+	}
+	return nil
+}
+
+func (d *dbStore) SetUsageImportStatus(ctx context.Context, tenantID, importID string, status model.UsageImportStatus) error {
+	_, err := d.db.Exec(ctx, `
+		UPDATE usage_imports
+		SET status = $2,
+			completed_at = CASE WHEN $2 = $3 THEN now() ELSE completed_at END,
+			updated_at = now()
+		WHERE tenant_id = $4 AND import_id = $1
+	`, importID, status, model.UsageImportStatusCompleted, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to update usage import status: %w", err)
+	}
+	return nil
+}
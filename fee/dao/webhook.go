@@ -0,0 +1,396 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"encore.app/fee/model"
+	"encore.dev/rlog"
+	"encore.dev/storage/sqldb"
+	"github.com/jackc/pgx/v5"
+)
+
+const webhookSubscriptionColumns = `subscription_id, url, event_types, description, secret, active, created_at, updated_at`
+
+// scanWebhookSubscription reads the webhookSubscriptionColumns of a row.
+func scanWebhookSubscription(row pgx.Row) (*model.WebhookSubscription, error) {
+	var sub model.WebhookSubscription
+	var eventTypes []string
+	if err := row.Scan(&sub.SubscriptionID, &sub.URL, &eventTypes, &sub.Description, &sub.Secret, &sub.Active,
+		&sub.CreatedAt, &sub.UpdatedAt); err != nil {
+		return nil, err
+	}
+	sub.EventTypes = make([]model.WebhookEventType, len(eventTypes))
+	for i, t := range eventTypes {
+		sub.EventTypes[i] = model.WebhookEventType(t)
+	}
+	return &sub, nil
+}
+
+func eventTypeStrings(eventTypes []model.WebhookEventType) []string {
+	out := make([]string, len(eventTypes))
+	for i, t := range eventTypes {
+		out[i] = string(t)
+	}
+	return out
+}
+
+// CreateWebhookSubscription stores a new subscription of the tenant and fills its timestamps.
+func (d *dbStore) CreateWebhookSubscription(ctx context.Context, tenantID string, sub *model.WebhookSubscription) error {
+	err := d.db.QueryRow(ctx, `
+		INSERT INTO webhook_subscriptions (tenant_id, subscription_id, url, event_types, description, secret, active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING created_at, updated_at
+	`, tenantID, sub.SubscriptionID, sub.URL, eventTypeStrings(sub.EventTypes), sub.Description, sub.Secret, sub.Active).
+		Scan(&sub.CreatedAt, &sub.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+	return nil
+}
+
+// GetWebhookSubscription returns sql.ErrNoRows if the tenant has no subscription with that ID.
+func (d *dbStore) GetWebhookSubscription(ctx context.Context, tenantID, subscriptionID string) (*model.WebhookSubscription, error) {
+	sub, err := scanWebhookSubscription(d.db.QueryRow(ctx, `
+		SELECT `+webhookSubscriptionColumns+` FROM webhook_subscriptions WHERE tenant_id = $1 AND subscription_id = $2
+	`, tenantID, subscriptionID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || errors.Is(err, pgx.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get webhook subscription: %w", err)
+	}
+	return sub, nil
+}
+
+// ListWebhookSubscriptions lists the subscriptions of the tenant, newest first.
+func (d *dbStore) ListWebhookSubscriptions(ctx context.Context, tenantID string) ([]*model.WebhookSubscription, error) {
+	rows, err := d.db.Query(ctx, `
+		SELECT `+webhookSubscriptionColumns+`
+		FROM webhook_subscriptions
+		WHERE tenant_id = $1
+		ORDER BY created_at DESC, id DESC
+	`, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	var subs []*model.WebhookSubscription
+	for rows.Next() {
+		sub, err := scanWebhookSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+// UpdateWebhookSubscription changes the endpoint, event types, description and active flag of a
+// subscription, its secret stays. It returns sql.ErrNoRows if the subscription doesn't exist.
+func (d *dbStore) UpdateWebhookSubscription(ctx context.Context, tenantID string, sub *model.WebhookSubscription) error {
+	err := d.db.QueryRow(ctx, `
+		UPDATE webhook_subscriptions
+		SET url = $1, event_types = $2, description = $3, active = $4, updated_at = now()
+		WHERE tenant_id = $5 AND subscription_id = $6
+		RETURNING created_at, updated_at
+	`, sub.URL, eventTypeStrings(sub.EventTypes), sub.Description, sub.Active, tenantID, sub.SubscriptionID).
+		Scan(&sub.CreatedAt, &sub.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || errors.Is(err, pgx.ErrNoRows) {
+			return sql.ErrNoRows
+		}
+		return fmt.Errorf("failed to update webhook subscription: %w", err)
+	}
+	return nil
+}
+
+// enqueueWebhookEvent stores an event with a delivery to every active subscription of the tenant
+// that receives its type, in the transaction of the change it describes. Delivery IDs derive from
+// the event and subscription, so an event stored again gets no second delivery.
+func enqueueWebhookEvent(ctx context.Context, tx *sqldb.Tx, tenantID string, event *model.WebhookEvent) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO webhook_events (tenant_id, event_id, event_type, bill_id, line_item_id, data)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6::jsonb)
+		ON CONFLICT (tenant_id, event_id) DO NOTHING
+	`, tenantID, event.EventID, event.Type, event.BillID, event.LineItemID, nullJSON(event.Data))
+	if err != nil {
+		return fmt.Errorf("failed to write webhook event: %w", err)
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO webhook_deliveries (tenant_id, delivery_id, subscription_id, event_id)
+		SELECT tenant_id, md5($2::text || '/' || subscription_id), subscription_id, $2
+		FROM webhook_subscriptions
+		WHERE tenant_id = $1 AND active AND (cardinality(event_types) = 0 OR $3::text = ANY(event_types))
+		ON CONFLICT (tenant_id, delivery_id) DO NOTHING
+	`, tenantID, event.EventID, event.Type)
+	if err != nil {
+		return fmt.Errorf("failed to write webhook deliveries: %w", err)
+	}
+	return nil
+}
+
+// AddEvent stores an event that isn't part of an audited change: the webhook event with its
+// deliveries and the domain events in the outbox.
+func (d *dbStore) AddEvent(ctx context.Context, tenantID string, event *model.WebhookEvent, outbox []model.OutboxEvent) error {
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("could not begin transaction: %w", err)
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			rlog.Error("failed to rollback add event", "error", rbErr)
+		}
+	}()
+
+	if err := enqueueWebhookEvent(ctx, tx, tenantID, event); err != nil {
+		return err
+	}
+	if err := enqueueOutboxEvents(ctx, tx, tenantID, outbox); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit failed: %w", err)
+	}
+	return nil
+}
+
+// GetWebhookEvent returns sql.ErrNoRows if the tenant has no event with that ID.
+func (d *dbStore) GetWebhookEvent(ctx context.Context, tenantID, eventID string) (*model.WebhookEvent, error) {
+	var event model.WebhookEvent
+	var data *string
+	err := d.db.QueryRow(ctx, `
+		SELECT event_id, event_type, bill_id, COALESCE(line_item_id, ''), data::text, created_at
+		FROM webhook_events
+		WHERE tenant_id = $1 AND event_id = $2
+	`, tenantID, eventID).Scan(&event.EventID, &event.Type, &event.BillID, &event.LineItemID, &data, &event.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || errors.Is(err, pgx.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get webhook event: %w", err)
+	}
+	if data != nil {
+		event.Data = []byte(*data)
+	}
+	return &event, nil
+}
+
+// ListUndispatchedWebhookDeliveries returns the IDs of the tenant's deliveries whose workflow
+// wasn't started yet, oldest first.
+func (d *dbStore) ListUndispatchedWebhookDeliveries(ctx context.Context, tenantID string, limit int) ([]string, error) {
+	rows, err := d.db.Query(ctx, `
+		SELECT delivery_id
+		FROM webhook_deliveries
+		WHERE tenant_id = $1 AND dispatched_at IS NULL
+		ORDER BY created_at, id
+		LIMIT $2
+	`, tenantID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list undispatched webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveryIDs []string
+	for rows.Next() {
+		var deliveryID string
+		if err := rows.Scan(&deliveryID); err != nil {
+			return nil, err
+		}
+		deliveryIDs = append(deliveryIDs, deliveryID)
+	}
+	return deliveryIDs, rows.Err()
+}
+
+// ListWebhookDispatchTenants returns the tenants that have deliveries whose workflow wasn't started yet.
+func (d *dbStore) ListWebhookDispatchTenants(ctx context.Context) ([]string, error) {
+	rows, err := d.db.Query(ctx, `
+		SELECT DISTINCT tenant_id
+		FROM webhook_deliveries
+		WHERE dispatched_at IS NULL
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook dispatch tenants: %w", err)
+	}
+	defer rows.Close()
+
+	var tenantIDs []string
+	for rows.Next() {
+		var tenantID string
+		if err := rows.Scan(&tenantID); err != nil {
+			return nil, err
+		}
+		tenantIDs = append(tenantIDs, tenantID)
+	}
+	return tenantIDs, rows.Err()
+}
+
+// MarkWebhookDeliveriesDispatched records that the workflows of the deliveries were started.
+func (d *dbStore) MarkWebhookDeliveriesDispatched(ctx context.Context, tenantID string, deliveryIDs []string) error {
+	_, err := d.db.Exec(ctx, `
+		UPDATE webhook_deliveries SET dispatched_at = now()
+		WHERE tenant_id = $1 AND delivery_id = ANY($2::varchar[]) AND dispatched_at IS NULL
+	`, tenantID, deliveryIDs)
+	if err != nil {
+		return fmt.Errorf("failed to mark webhook deliveries dispatched: %w", err)
+	}
+	return nil
+}
+
+const webhookDeliveryColumns = `d.delivery_id, d.subscription_id, d.event_id, e.event_type, e.bill_id, COALESCE(d.redelivery_of, ''),
+	d.status, d.attempts, COALESCE(d.last_status_code, 0), COALESCE(d.last_error, ''), d.created_at, d.updated_at, d.delivered_at`
+
+const webhookDeliveryTables = `webhook_deliveries d JOIN webhook_events e ON e.tenant_id = d.tenant_id AND e.event_id = d.event_id`
+
+// scanWebhookDelivery reads the webhookDeliveryColumns of a row.
+func scanWebhookDelivery(row pgx.Row) (*model.WebhookDelivery, error) {
+	var delivery model.WebhookDelivery
+	if err := row.Scan(&delivery.DeliveryID, &delivery.SubscriptionID, &delivery.EventID, &delivery.EventType, &delivery.BillID,
+		&delivery.RedeliveryOf, &delivery.Status, &delivery.Attempts, &delivery.LastStatusCode, &delivery.LastError,
+		&delivery.CreatedAt, &delivery.UpdatedAt, &delivery.DeliveredAt); err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// GetWebhookDelivery returns a delivery with its attempts, or sql.ErrNoRows if the tenant has
+// no delivery with that ID.
+func (d *dbStore) GetWebhookDelivery(ctx context.Context, tenantID, deliveryID string) (*model.WebhookDelivery, error) {
+	delivery, err := scanWebhookDelivery(d.db.QueryRow(ctx, `
+		SELECT `+webhookDeliveryColumns+` FROM `+webhookDeliveryTables+` WHERE d.tenant_id = $1 AND d.delivery_id = $2
+	`, tenantID, deliveryID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || errors.Is(err, pgx.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+
+	rows, err := d.db.Query(ctx, `
+		SELECT attempt, COALESCE(status_code, 0), COALESCE(error, ''), duration_ms, created_at
+		FROM webhook_attempts
+		WHERE tenant_id = $1 AND delivery_id = $2
+		ORDER BY attempt
+	`, tenantID, deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook attempts: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var attempt model.WebhookAttempt
+		if err := rows.Scan(&attempt.Attempt, &attempt.StatusCode, &attempt.Error, &attempt.DurationMs, &attempt.CreatedAt); err != nil {
+			return nil, err
+		}
+		delivery.AttemptLog = append(delivery.AttemptLog, attempt)
+	}
+	return delivery, rows.Err()
+}
+
+// ListWebhookDeliveries lists up to limit deliveries of a subscription, newest first, only those
+// with the status unless it is empty.
+func (d *dbStore) ListWebhookDeliveries(ctx context.Context, tenantID, subscriptionID string, status model.WebhookDeliveryStatus, limit int) ([]*model.WebhookDelivery, error) {
+	q := &sqlQuery{}
+	q.where("d.tenant_id = %s", tenantID)
+	q.where("d.subscription_id = %s", subscriptionID)
+	if status != "" {
+		q.where("d.status = %s", status)
+	}
+	rows, err := d.db.Query(ctx, `
+		SELECT `+webhookDeliveryColumns+`
+		FROM `+webhookDeliveryTables+`
+		WHERE `+strings.Join(q.conds, " AND ")+`
+		ORDER BY d.created_at DESC, d.id DESC
+		LIMIT `+q.arg(limit), q.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []*model.WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+// RecordWebhookAttempt logs an attempt of a delivery, which is DELIVERED if the attempt has no
+// error. An attempt that is already logged, by an earlier try to record it, is kept as it is.
+func (d *dbStore) RecordWebhookAttempt(ctx context.Context, tenantID, deliveryID string, attempt model.WebhookAttempt) error {
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("could not begin transaction: %w", err)
+	}
+	defer func() {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			rlog.Error("failed to rollback record webhook attempt", "error", rbErr)
+		}
+	}()
+
+	res, err := tx.Exec(ctx, `
+		INSERT INTO webhook_attempts (tenant_id, delivery_id, attempt, status_code, error, duration_ms)
+		VALUES ($1, $2, $3, NULLIF($4, 0), NULLIF($5, ''), $6)
+		ON CONFLICT (tenant_id, delivery_id, attempt) DO NOTHING
+	`, tenantID, deliveryID, attempt.Attempt, attempt.StatusCode, attempt.Error, attempt.DurationMs)
+	if err != nil {
+		return fmt.Errorf("failed to record webhook attempt: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return nil
+	}
+	status := model.WebhookDeliveryPending
+	if attempt.Error == "" {
+		status = model.WebhookDeliveryDelivered
+	}
+	_, err = tx.Exec(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $1, attempts = attempts + 1, last_status_code = NULLIF($2, 0), last_error = NULLIF($3, ''),
+			delivered_at = CASE WHEN $1 = $4 THEN now() END, updated_at = now()
+		WHERE tenant_id = $5 AND delivery_id = $6
+	`, status, attempt.StatusCode, attempt.Error, model.WebhookDeliveryDelivered, tenantID, deliveryID)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook delivery: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit failed: %w", err)
+	}
+	return nil
+}
+
+// FailWebhookDelivery marks a delivery whose every attempt failed as FAILED.
+func (d *dbStore) FailWebhookDelivery(ctx context.Context, tenantID, deliveryID string) error {
+	_, err := d.db.Exec(ctx, `
+		UPDATE webhook_deliveries SET status = $1, updated_at = now()
+		WHERE tenant_id = $2 AND delivery_id = $3 AND status = $4
+	`, model.WebhookDeliveryFailed, tenantID, deliveryID, model.WebhookDeliveryPending)
+	if err != nil {
+		return fmt.Errorf("failed to fail webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// RedeliverWebhook stores a new delivery of the event of a delivery to the same subscription,
+// with the ID deliveryID. It returns sql.ErrNoRows if the tenant has no delivery with the ID
+// redeliveryOf.
+func (d *dbStore) RedeliverWebhook(ctx context.Context, tenantID, redeliveryOf, deliveryID string) (*model.WebhookDelivery, error) {
+	res, err := d.db.Exec(ctx, `
+		INSERT INTO webhook_deliveries (tenant_id, delivery_id, subscription_id, event_id, redelivery_of)
+		SELECT tenant_id, $3, subscription_id, event_id, delivery_id
+		FROM webhook_deliveries
+		WHERE tenant_id = $1 AND delivery_id = $2
+	`, tenantID, redeliveryOf, deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to redeliver webhook: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return d.GetWebhookDelivery(ctx, tenantID, deliveryID)
+}
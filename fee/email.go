@@ -0,0 +1,41 @@
+package fee
+
+import (
+	"context"
+
+	"encore.app/fee/model"
+	"encore.dev/beta/errs"
+	"encore.dev/rlog"
+)
+
+type ListBillEmailsResponse struct {
+	Emails []*model.EmailDelivery `json:"emails"`
+}
+
+// ListBillEmails returns the emails sent for a bill's invoices, oldest first, with whether they
+// were delivered to the mailer.
+//
+//encore:api auth method=GET path=/api/bills/:billID/emails tag:scope_bills_read
+func (s *Service) ListBillEmails(ctx context.Context, billID string) (*ListBillEmailsResponse, error) {
+	tenantID := requestTenant()
+	exists, err := s.db.IsBillExists(ctx, tenantID, billID)
+	if err != nil {
+		rlog.Error("failed in checking bill exists in db", "error", err)
+		return nil, err
+	}
+	if !exists {
+		return nil, &errs.Error{
+			Code:    errs.NotFound,
+			Message: "bill not found",
+		}
+	}
+	emails, err := s.db.ListEmailDeliveries(ctx, tenantID, billID)
+	if err != nil {
+		rlog.Error("failed to list bill emails", "error", err)
+		return nil, err
+	}
+	if emails == nil {
+		emails = []*model.EmailDelivery{}
+	}
+	return &ListBillEmailsResponse{Emails: emails}, nil
+}
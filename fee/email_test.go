@@ -0,0 +1,36 @@
+package fee
+
+import (
+	"context"
+	"testing"
+
+	"encore.app/fee/model"
+	"encore.dev/beta/errs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestListBillEmails(t *testing.T) {
+	service, mockDB, _ := setup(t)
+	emails := []*model.EmailDelivery{
+		{DeliveryID: "d-1", BillID: "bill-1", InvoiceID: "inv-1", Template: "bill_closed", Recipients: []string{"ap@globex.example"}, Status: model.EmailDeliverySent, Attempts: 1},
+	}
+	mockDB.On("IsBillExists", mock.Anything, model.DefaultTenant, "bill-1").Return(true, nil).Once()
+	mockDB.On("ListEmailDeliveries", mock.Anything, model.DefaultTenant, "bill-1").Return(emails, nil).Once()
+
+	resp, err := service.ListBillEmails(context.Background(), "bill-1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, emails, resp.Emails)
+	mockDB.AssertExpectations(t)
+}
+
+func TestListBillEmails_BillNotFound(t *testing.T) {
+	service, mockDB, _ := setup(t)
+	mockDB.On("IsBillExists", mock.Anything, model.DefaultTenant, "bill-1").Return(false, nil).Once()
+
+	_, err := service.ListBillEmails(context.Background(), "bill-1")
+
+	assert.Equal(t, errs.NotFound, errs.Code(err))
+	mockDB.AssertNotCalled(t, "ListEmailDeliveries", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,80 @@
+// Package events declares the Pub/Sub topics bill domain events are published to, one per event
+// type and schema version, see the schemas in model. Other services subscribe to them to react
+// to bills in-process. Events are published at least once: subscribers skip event IDs they have
+// already handled.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"encore.app/fee/model"
+	"encore.dev/pubsub"
+)
+
+var (
+	BillCreatedV1 = pubsub.NewTopic[*model.BillCreatedV1]("bill-created-v1", pubsub.TopicConfig{
+		DeliveryGuarantee: pubsub.AtLeastOnce,
+	})
+	LineItemAddedV1 = pubsub.NewTopic[*model.LineItemAddedV1]("line-item-added-v1", pubsub.TopicConfig{
+		DeliveryGuarantee: pubsub.AtLeastOnce,
+	})
+	LineItemVoidedV1 = pubsub.NewTopic[*model.LineItemVoidedV1]("line-item-voided-v1", pubsub.TopicConfig{
+		DeliveryGuarantee: pubsub.AtLeastOnce,
+	})
+	BillClosedV1 = pubsub.NewTopic[*model.BillClosedV1]("bill-closed-v1", pubsub.TopicConfig{
+		DeliveryGuarantee: pubsub.AtLeastOnce,
+	})
+	BillCancelledV1 = pubsub.NewTopic[*model.BillCancelledV1]("bill-cancelled-v1", pubsub.TopicConfig{
+		DeliveryGuarantee: pubsub.AtLeastOnce,
+	})
+	BillReopenedV1 = pubsub.NewTopic[*model.BillReopenedV1]("bill-reopened-v1", pubsub.TopicConfig{
+		DeliveryGuarantee: pubsub.AtLeastOnce,
+	})
+	BillSettledV1 = pubsub.NewTopic[*model.BillSettledV1]("bill-settled-v1", pubsub.TopicConfig{
+		DeliveryGuarantee: pubsub.AtLeastOnce,
+	})
+	PostProcessFailedV1 = pubsub.NewTopic[*model.PostProcessFailedV1]("post-process-failed-v1", pubsub.TopicConfig{
+		DeliveryGuarantee: pubsub.AtLeastOnce,
+	})
+)
+
+// Publisher publishes the events of the outbox to their topics.
+type Publisher struct{}
+
+// Publish publishes an outbox event to the topic it was written for.
+func (Publisher) Publish(ctx context.Context, event *model.OutboxEvent) error {
+	switch event.Topic {
+	case model.TopicBillCreatedV1:
+		return publish(ctx, pubsub.TopicRef[pubsub.Publisher[*model.BillCreatedV1]](BillCreatedV1), event)
+	case model.TopicLineItemAddedV1:
+		return publish(ctx, pubsub.TopicRef[pubsub.Publisher[*model.LineItemAddedV1]](LineItemAddedV1), event)
+	case model.TopicLineItemVoidedV1:
+		return publish(ctx, pubsub.TopicRef[pubsub.Publisher[*model.LineItemVoidedV1]](LineItemVoidedV1), event)
+	case model.TopicBillClosedV1:
+		return publish(ctx, pubsub.TopicRef[pubsub.Publisher[*model.BillClosedV1]](BillClosedV1), event)
+	case model.TopicBillCancelledV1:
+		return publish(ctx, pubsub.TopicRef[pubsub.Publisher[*model.BillCancelledV1]](BillCancelledV1), event)
+	case model.TopicBillReopenedV1:
+		return publish(ctx, pubsub.TopicRef[pubsub.Publisher[*model.BillReopenedV1]](BillReopenedV1), event)
+	case model.TopicBillSettledV1:
+		return publish(ctx, pubsub.TopicRef[pubsub.Publisher[*model.BillSettledV1]](BillSettledV1), event)
+	case model.TopicPostProcessFailedV1:
+		return publish(ctx, pubsub.TopicRef[pubsub.Publisher[*model.PostProcessFailedV1]](PostProcessFailedV1), event)
+	default:
+		return fmt.Errorf("unknown event topic: %s", event.Topic)
+	}
+}
+
+// publish decodes the payload of an event as the message of its topic and publishes it.
+func publish[T any](ctx context.Context, topic pubsub.Publisher[*T], event *model.OutboxEvent) error {
+	var msg T
+	if err := json.Unmarshal(event.Payload, &msg); err != nil {
+		return fmt.Errorf("invalid %s event %s: %w", event.Topic, event.EventID, err)
+	}
+	if _, err := topic.Publish(ctx, &msg); err != nil {
+		return fmt.Errorf("failed to publish %s event %s: %w", event.Topic, event.EventID, err)
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package fee
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"encore.app/fee/model"
+	temporal "encore.app/fee/workflow"
+	"encore.dev/beta/errs"
+	"encore.dev/rlog"
+)
+
+type ListFailedLineItemsParams struct {
+	BillID string `query:"bill_id"`
+	Status string `query:"status"` // PENDING by default
+	Limit  int    `query:"limit"`
+	Cursor string `query:"cursor"`
+}
+
+type ListFailedLineItemsResponse struct {
+	FailedLineItems []*model.FailedLineItem `json:"failed_line_items"`
+	HasMore         bool                    `json:"has_more"`
+	NextCursor      string                  `json:"next_cursor,omitempty"` // pass as cursor, with the same bill_id and status, for the next page
+}
+
+const failedLineItemCursorKind = "failed-line-items"
+
+// failedLineItemCursor is the signed cursor of a failed line item list, it only continues a page of the same bill and status.
+type failedLineItemCursor struct {
+	BillID string `json:"bill,omitempty"`
+	Status string `json:"status"`
+	model.LineItemCursor
+}
+
+type ResolveFailedLineItemResponse struct {
+	LineItemID      string `json:"line_item_id"`
+	BillID          string `json:"bill_id"`
+	Status          string `json:"status"`
+	Total           int64  `json:"total"`             // running total of the bill, 0 once the bill is closed
+	FailedLineItems int    `json:"failed_line_items"` // unresolved failed line items left on an open bill
+}
+
+// ListFailedLineItems lists line items that could not be persisted after all retries.
+//
+//encore:api auth method=GET path=/api/admin/failed-line-items tag:scope_admin
+func (s *Service) ListFailedLineItems(ctx context.Context, params *ListFailedLineItemsParams) (*ListFailedLineItemsResponse, error) {
+	status := model.FailedLineItemPending
+	if params.Status != "" {
+		var err error
+		status, err = model.ToFailedLineItemStatus(strings.ToUpper(params.Status))
+		if err != nil {
+			return nil, &errs.Error{
+				Code:    errs.InvalidArgument,
+				Message: "invalid status",
+			}
+		}
+	}
+
+	if params.Limit == 0 {
+		params.Limit = 10
+	}
+
+	var after *model.LineItemCursor
+	if params.Cursor != "" {
+		var cursor failedLineItemCursor
+		if err := decodeCursor(failedLineItemCursorKind, params.Cursor, &cursor); err != nil {
+			return nil, &errs.Error{
+				Code:    errs.InvalidArgument,
+				Message: err.Error(),
+			}
+		}
+		if cursor.BillID != params.BillID || cursor.Status != string(status) {
+			return nil, &errs.Error{
+				Code:    errs.InvalidArgument,
+				Message: "cursor does not match bill_id and status",
+			}
+		}
+		after = &cursor.LineItemCursor
+	}
+
+	items, hasMore, err := s.db.ListFailedLineItems(ctx, requestTenant(), params.BillID, status, params.Limit, after)
+	if err != nil {
+		rlog.Error("failed to list failed line items", "error", err)
+		return nil, err
+	}
+	if items == nil {
+		items = []*model.FailedLineItem{}
+	}
+	resp := &ListFailedLineItemsResponse{FailedLineItems: items, HasMore: hasMore}
+	if hasMore {
+		last := items[len(items)-1]
+		resp.NextCursor = encodeCursor(failedLineItemCursorKind, failedLineItemCursor{
+			BillID:         params.BillID,
+			Status:         string(status),
+			LineItemCursor: model.LineItemCursor{CreatedAt: last.CreatedAt, LineItemID: last.LineItemID},
+		})
+	}
+	return resp, nil
+}
+
+// RetryFailedLineItem adds a failed line item to its bill again.
+//
+//encore:api auth method=POST path=/api/admin/failed-line-items/:lineItemID/retry tag:idempotency tag:scope_admin
+func (s *Service) RetryFailedLineItem(ctx context.Context, lineItemID string) (*ResolveFailedLineItemResponse, error) {
+	return s.resolveFailedLineItem(ctx, lineItemID, model.FailedLineItemRetried)
+}
+
+// DiscardFailedLineItem gives up on a failed line item, it is never billed.
+//
+//encore:api auth method=POST path=/api/admin/failed-line-items/:lineItemID/discard tag:idempotency tag:scope_admin
+func (s *Service) DiscardFailedLineItem(ctx context.Context, lineItemID string) (*ResolveFailedLineItemResponse, error) {
+	return s.resolveFailedLineItem(ctx, lineItemID, model.FailedLineItemDiscarded)
+}
+
+// resolveFailedLineItem goes through the bill workflow while the bill is open, so its total and
+// failure count stay in step. Failures left on a bill closed with force can only be discarded.
+func (s *Service) resolveFailedLineItem(ctx context.Context, lineItemID string, status model.FailedLineItemStatus) (*ResolveFailedLineItemResponse, error) {
+	item, err := s.db.GetFailedLineItem(ctx, requestTenant(), lineItemID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &errs.Error{
+				Code:    errs.NotFound,
+				Message: "failed line item not found",
+			}
+		}
+		rlog.Error("failed to get failed line item", "error", err, "line_item_id", lineItemID)
+		return nil, err
+	}
+	if item.Status != string(model.FailedLineItemPending) {
+		return nil, &errs.Error{
+			Code:    errs.FailedPrecondition,
+			Message: "failed line item is already resolved",
+		}
+	}
+
+	billStatus, err := s.db.GetBillStatus(ctx, requestTenant(), item.BillID)
+	if err != nil {
+		rlog.Error("failed to get bill status", "error", err, "bill_id", item.BillID)
+		return nil, err
+	}
+	resp := &ResolveFailedLineItemResponse{
+		LineItemID: lineItemID,
+		BillID:     item.BillID,
+		Status:     string(status),
+	}
+
+	if billStatus != model.BillStatusOpen {
+		if status == model.FailedLineItemRetried {
+			return nil, &errs.Error{
+				Code:    errs.FailedPrecondition,
+				Message: "bill is no longer open, the failed line item can only be discarded",
+			}
+		}
+		if err := s.db.ResolveFailedLineItem(ctx, requestTenant(), lineItemID, status); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, &errs.Error{
+					Code:    errs.FailedPrecondition,
+					Message: "failed line item is already resolved",
+				}
+			}
+			rlog.Error("failed to resolve failed line item", "error", err, "line_item_id", lineItemID)
+			return nil, err
+		}
+		return resp, nil
+	}
+
+	req := temporal.ResolveFailedLineItemRequest{
+		LineItemID: lineItemID,
+		Status:     status,
+		Actor:      requestActor(""),
+		RequestID:  requestID(),
+	}
+	var result temporal.ResolveFailedLineItemResult
+	if err := s.updateBill(ctx, item.BillID, temporal.ResolveFailedLineItemUpdate, req, &result); err != nil {
+		return nil, err
+	}
+	resp.Total = result.Total
+	resp.FailedLineItems = result.FailedLineItems
+	return resp, nil
+}
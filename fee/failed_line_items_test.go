@@ -0,0 +1,173 @@
+package fee
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"encore.app/fee/model"
+	temporal "encore.app/fee/workflow"
+	"encore.dev/beta/errs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.temporal.io/sdk/client"
+	temporalsdk "go.temporal.io/sdk/temporal"
+)
+
+func pendingFailedLineItem(lineItemID, billID string) *model.FailedLineItem {
+	return &model.FailedLineItem{
+		LineItemID: lineItemID,
+		BillID:     billID,
+		Source:     string(model.FailedLineItemSourceSignal),
+		Amount:     100,
+		Status:     string(model.FailedLineItemPending),
+	}
+}
+
+func TestRetryFailedLineItem(t *testing.T) {
+	service, mockDB, mockTemporalClient := setup(t)
+
+	mockDB.On("GetFailedLineItem", mock.Anything, model.DefaultTenant, "line-item-1").Return(pendingFailedLineItem("line-item-1", "bill-1"), nil).Once()
+	mockDB.On("GetBillStatus", mock.Anything, model.DefaultTenant, "bill-1").Return(model.BillStatusOpen, nil).Once()
+	mockTemporalClient.On("UpdateWorkflow", mock.Anything, mock.MatchedBy(func(options client.UpdateWorkflowOptions) bool {
+		req, ok := options.Args[0].(temporal.ResolveFailedLineItemRequest)
+		return ok && options.WorkflowID == temporal.BillCycleWorkflowID(model.DefaultTenant, "bill-1") &&
+			options.UpdateName == temporal.ResolveFailedLineItemUpdate && req.Status == model.FailedLineItemRetried
+	})).Return(&mockUpdateHandle{result: temporal.ResolveFailedLineItemResult{
+		LineItemID: "line-item-1",
+		Status:     model.FailedLineItemRetried,
+		Total:      300,
+	}}, nil).Once()
+
+	resp, err := service.RetryFailedLineItem(context.Background(), "line-item-1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "RETRIED", resp.Status)
+	assert.Equal(t, int64(300), resp.Total)
+	assert.Equal(t, 0, resp.FailedLineItems)
+	mockDB.AssertExpectations(t)
+	mockTemporalClient.AssertExpectations(t)
+}
+
+func TestRetryFailedLineItem_BillClosed(t *testing.T) {
+	service, mockDB, mockTemporalClient := setup(t)
+
+	mockDB.On("GetFailedLineItem", mock.Anything, model.DefaultTenant, "line-item-1").Return(pendingFailedLineItem("line-item-1", "bill-1"), nil).Once()
+	mockDB.On("GetBillStatus", mock.Anything, model.DefaultTenant, "bill-1").Return(model.BillStatusClosed, nil).Once()
+
+	_, err := service.RetryFailedLineItem(context.Background(), "line-item-1")
+
+	var errsErr *errs.Error
+	assert.True(t, errors.As(err, &errsErr))
+	assert.Equal(t, errs.FailedPrecondition, errsErr.Code)
+	mockTemporalClient.AssertNotCalled(t, "UpdateWorkflow", mock.Anything, mock.Anything)
+}
+
+func TestDiscardFailedLineItem_BillClosed(t *testing.T) {
+	service, mockDB, mockTemporalClient := setup(t)
+
+	mockDB.On("GetFailedLineItem", mock.Anything, model.DefaultTenant, "line-item-1").Return(pendingFailedLineItem("line-item-1", "bill-1"), nil).Once()
+	mockDB.On("GetBillStatus", mock.Anything, model.DefaultTenant, "bill-1").Return(model.BillStatusClosed, nil).Once()
+	mockDB.On("ResolveFailedLineItem", mock.Anything, model.DefaultTenant, "line-item-1", model.FailedLineItemDiscarded).Return(nil).Once()
+
+	resp, err := service.DiscardFailedLineItem(context.Background(), "line-item-1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "DISCARDED", resp.Status)
+	mockDB.AssertExpectations(t)
+	mockTemporalClient.AssertNotCalled(t, "UpdateWorkflow", mock.Anything, mock.Anything)
+}
+
+func TestDiscardFailedLineItem_AlreadyResolved(t *testing.T) {
+	service, mockDB, _ := setup(t)
+
+	item := pendingFailedLineItem("line-item-1", "bill-1")
+	item.Status = string(model.FailedLineItemRetried)
+	mockDB.On("GetFailedLineItem", mock.Anything, model.DefaultTenant, "line-item-1").Return(item, nil).Once()
+
+	_, err := service.DiscardFailedLineItem(context.Background(), "line-item-1")
+
+	var errsErr *errs.Error
+	assert.True(t, errors.As(err, &errsErr))
+	assert.Equal(t, errs.FailedPrecondition, errsErr.Code)
+	mockDB.AssertNotCalled(t, "ResolveFailedLineItem", mock.Anything, model.DefaultTenant, mock.Anything, mock.Anything)
+}
+
+func TestCloseBill_UnresolvedFailures(t *testing.T) {
+	service, mockDB, mockTemporalClient := setup(t)
+
+	mockDB.On("GetBillStatus", mock.Anything, model.DefaultTenant, "bill-1").Return(model.BillStatusOpen, nil).Once()
+	mockTemporalClient.On("UpdateWorkflow", mock.Anything, mock.Anything).Return(&mockUpdateHandle{
+		err: temporalsdk.NewApplicationError("bill has 2 unresolved failed line items", temporal.ErrTypeUnresolvedFailures),
+	}, nil).Once()
+
+	_, err := service.CloseBill(context.Background(), "bill-1", &CloseBillParams{})
+
+	var errsErr *errs.Error
+	assert.True(t, errors.As(err, &errsErr))
+	assert.Equal(t, errs.FailedPrecondition, errsErr.Code)
+}
+
+func TestListFailedLineItems_InvalidStatus(t *testing.T) {
+	service, mockDB, _ := setup(t)
+
+	_, err := service.ListFailedLineItems(context.Background(), &ListFailedLineItemsParams{Status: "LOST"})
+
+	var errsErr *errs.Error
+	assert.True(t, errors.As(err, &errsErr))
+	assert.Equal(t, errs.InvalidArgument, errsErr.Code)
+	mockDB.AssertNotCalled(t, "ListFailedLineItems", mock.Anything, model.DefaultTenant, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestListFailedLineItems_InvalidCursor(t *testing.T) {
+	tests := []struct {
+		name   string
+		params *ListFailedLineItemsParams
+	}{
+		{"RawTimestamp", &ListFailedLineItemsParams{Cursor: "2025-01-01T00:00:00Z"}},
+		{"AnotherBill", &ListFailedLineItemsParams{BillID: "bill-2", Cursor: testCursor(t, failedLineItemCursorKind, failedLineItemCursor{
+			BillID: "bill-1",
+			Status: string(model.FailedLineItemPending),
+		})}},
+		{"AnotherStatus", &ListFailedLineItemsParams{Status: "DISCARDED", Cursor: testCursor(t, failedLineItemCursorKind, failedLineItemCursor{
+			Status: string(model.FailedLineItemPending),
+		})}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			service, mockDB, _ := setup(t)
+
+			_, err := service.ListFailedLineItems(context.Background(), tc.params)
+
+			var errsErr *errs.Error
+			assert.True(t, errors.As(err, &errsErr))
+			assert.Equal(t, errs.InvalidArgument, errsErr.Code)
+			mockDB.AssertNotCalled(t, "ListFailedLineItems", mock.Anything, model.DefaultTenant, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestListFailedLineItems_NextCursor(t *testing.T) {
+	service, mockDB, _ := setup(t)
+
+	last := pendingFailedLineItem("line-item-2", "bill-1")
+	last.CreatedAt = time.Now()
+	mockDB.On("ListFailedLineItems", mock.Anything, model.DefaultTenant, "bill-1", model.FailedLineItemPending, 1, (*model.LineItemCursor)(nil)).
+		Return([]*model.FailedLineItem{last}, true, nil).Once()
+
+	resp, err := service.ListFailedLineItems(context.Background(), &ListFailedLineItemsParams{BillID: "bill-1", Limit: 1})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, resp.NextCursor)
+
+	mockDB.On("ListFailedLineItems", mock.Anything, model.DefaultTenant, "bill-1", model.FailedLineItemPending, 1, mock.MatchedBy(func(after *model.LineItemCursor) bool {
+		return after != nil && after.LineItemID == "line-item-2" && after.CreatedAt.Equal(last.CreatedAt)
+	})).Return([]*model.FailedLineItem{}, false, nil).Once()
+
+	resp, err = service.ListFailedLineItems(context.Background(), &ListFailedLineItemsParams{BillID: "bill-1", Limit: 1, Cursor: resp.NextCursor})
+	assert.NoError(t, err)
+	assert.False(t, resp.HasMore)
+	assert.Empty(t, resp.NextCursor)
+	mockDB.AssertExpectations(t)
+}
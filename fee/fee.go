@@ -5,20 +5,53 @@ import (
 	"fmt"
 
 	"encore.app/fee/dao"
+	"encore.app/fee/events"
+	"encore.app/fee/mail"
+	"encore.app/fee/payment"
+	"encore.app/fee/webhook"
 	temporal "encore.app/fee/workflow"
+	"encore.dev"
+	"encore.dev/storage/objects"
 	"go.temporal.io/sdk/client"
 	"go.temporal.io/sdk/worker"
 )
 
+var secrets struct {
+	// CursorSigningKey signs the pagination cursors, so clients can't forge a position in a list.
+	CursorSigningKey string
+	// BootstrapAdminKey authenticates as admin without a stored key, to issue the first API keys.
+	BootstrapAdminKey string
+	// MailerURL configures how bill emails are sent, see mail.New. They are only logged when empty.
+	MailerURL string
+	// PaymentProviderURL configures the payment provider, see payment.New. Bills get no payment
+	// links when it is empty outside local development and tests.
+	PaymentProviderURL string
+	// PaymentCallbackSecret signs the payment provider's callbacks, none are accepted when empty.
+	PaymentCallbackSecret string
+}
+
+// devEnv tells whether the service runs locally or in tests, where development shortcuts such as
+// plain http webhooks and the fake payment provider are allowed.
+func devEnv() bool {
+	meta := encore.Meta()
+	return meta.Environment.Cloud == encore.CloudLocal || meta.Environment.Type == encore.EnvTest
+}
+
 //encore:service
 type Service struct {
 	client   client.Client
 	workers  []worker.Worker
 	db       dao.DB
 	activity *temporal.Activities
+	payments payment.Provider
+	// stopRelay stops the relay of the events changes left behind.
+	stopRelay context.CancelFunc
 }
 
 func initService() (*Service, error) {
+	if err := checkCursorKey(); err != nil {
+		return nil, err
+	}
 	// For local development, we can use the default in-memory client.
 	// For production, we would configure this with the actual Temporal cluster address.
 	tc, err := client.NewLazyClient(client.Options{
@@ -30,11 +63,22 @@ func initService() (*Service, error) {
 	}
 	db := dao.New()
 
-	activity := temporal.NewActivity(db)
+	mailer, err := mail.New(secrets.MailerURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create mailer: %w", err)
+	}
+
+	payments, err := payment.New(secrets.PaymentProviderURL, []byte(secrets.PaymentCallbackSecret), devEnv())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create payment provider: %w", err)
+	}
+
+	activity := temporal.NewActivity(db, objects.BucketRef[temporal.InvoiceStore](invoiceBucket), mailer, payments, webhook.NewSender(), tc, events.Publisher{})
 
 	// Initialize and start the worker using the created client
 	billCycleWorker := worker.New(tc, temporal.BillCycleTaskQueue, worker.Options{})
 	billCycleWorker.RegisterWorkflow(temporal.BillLifecycleWorkflow)
+	billCycleWorker.RegisterWorkflow(temporal.UsageImportWorkflow)
 	billCycleWorker.RegisterActivity(activity)
 	err = billCycleWorker.Start()
 	if err != nil {
@@ -51,12 +95,27 @@ func initService() (*Service, error) {
 		billCycleWorker.Stop()
 		return nil, fmt.Errorf("failed to start closed bill worker: %v", err)
 	}
-	allWorkers := []worker.Worker{billCycleWorker, closedBillWorker}
 
-	return &Service{client: tc, workers: allWorkers, db: db, activity: activity}, nil
+	webhookWorker := worker.New(tc, temporal.WebhookTaskQueue, worker.Options{})
+	webhookWorker.RegisterWorkflow(temporal.WebhookDeliveryWorkflow)
+	webhookWorker.RegisterActivity(activity)
+	err = webhookWorker.Start()
+	if err != nil {
+		tc.Close()
+		billCycleWorker.Stop()
+		closedBillWorker.Stop()
+		return nil, fmt.Errorf("failed to start webhook worker: %v", err)
+	}
+	allWorkers := []worker.Worker{billCycleWorker, closedBillWorker, webhookWorker}
+
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	s := &Service{client: tc, workers: allWorkers, db: db, activity: activity, payments: payments, stopRelay: stopRelay}
+	go s.relay(relayCtx)
+	return s, nil
 }
 
 func (s *Service) Shutdown(force context.Context) {
+	s.stopRelay()
 	s.client.Close()
 	for _, w := range s.workers {
 		w.Stop()
@@ -5,14 +5,19 @@ import (
 	"database/sql"
 	"errors"
 
+	"encore.app/fee/model"
 	temporal "encore.app/fee/workflow"
 	"encore.dev/beta/errs"
 	"encore.dev/rlog"
 )
 
-//encore:api public method=GET path=/api/bills/:billID
+// GetBill returns a bill with its line items. For open bills the totals come from the
+// running workflow, which is authoritative until the bill is closed, and the response
+// carries the workflow's live state.
+//
+//encore:api auth method=GET path=/api/bills/:billID tag:scope_bills_read
 func (s *Service) GetBill(ctx context.Context, billID string) (*temporal.BillResponse, error) {
-	resp, err := s.activity.GetBillDetail(ctx, billID)
+	resp, err := s.activity.GetBillDetail(ctx, requestTenant(), billID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, &errs.Error{
@@ -23,5 +28,30 @@ func (s *Service) GetBill(ctx context.Context, billID string) (*temporal.BillRes
 		rlog.Error("failed to get bill", "error", err)
 		return nil, err
 	}
+
+	if resp.Status == string(model.BillStatusOpen) {
+		live, err := s.queryBillState(ctx, billID)
+		if err != nil {
+			// The stored view is still useful, e.g. while the workflow is closing the bill.
+			rlog.Warn("failed to query live bill state, returning stored bill", "error", err, "bill_id", billID)
+			return resp, nil
+		}
+		resp.TotalAmount = live.Total
+		resp.DisplayAmount = model.FormatAmount(live.Total)
+		resp.PeriodEnd = &live.PendingPeriodEnd
+		resp.Live = live
+	}
 	return resp, nil
 }
+
+func (s *Service) queryBillState(ctx context.Context, billID string) (*temporal.LiveBillState, error) {
+	queryResult, err := s.client.QueryWorkflow(ctx, temporal.BillCycleWorkflowID(requestTenant(), billID), "", temporal.QueryBillState)
+	if err != nil {
+		return nil, err
+	}
+	var live temporal.LiveBillState
+	if err := queryResult.Get(&live); err != nil {
+		return nil, err
+	}
+	return &live, nil
+}
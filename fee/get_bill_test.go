@@ -0,0 +1,124 @@
+package fee
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"encore.app/fee/model"
+	temporal "encore.app/fee/workflow"
+	"encore.dev/et"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetBill_OpenMergesLiveState(t *testing.T) {
+	service, mockDB, mockTemporalClient := setup(t)
+	service.activity = temporal.NewActivity(mockDB, nil, nil, nil, nil, nil, nil)
+
+	bill := &model.BillDetail{
+		BillID:      "test-bill-id",
+		Status:      string(model.BillStatusOpen),
+		PolicyType:  string(model.UsageBased),
+		Currency:    "USD",
+		CreatedAt:   time.Now(),
+		TotalAmount: 0,
+		LineItems: []model.LineItem{
+			{LineItemID: "line-item-1", Amount: 250, Metadata: `{"description":"API calls"}`, Status: "ACTIVE"},
+		},
+	}
+	live := temporal.LiveBillState{
+		Total:            250,
+		EventCount:       1,
+		PendingPeriodEnd: time.Now().Add(time.Hour),
+		Policy:           temporal.PolicyState{Type: model.UsageBased},
+	}
+
+	mockDB.On("GetBill", mock.Anything, model.DefaultTenant, "test-bill-id").Return(bill, nil).Once()
+	mockDB.On("ListBillPaymentLinks", mock.Anything, model.DefaultTenant, "test-bill-id").Return(nil, nil).Once()
+	mockTemporalClient.On("QueryWorkflow", mock.Anything, "bill-test-bill-id", "", temporal.QueryBillState, mock.Anything).
+		Return(&mockValue{val: live}, nil).Once()
+
+	resp, err := service.GetBill(context.Background(), "test-bill-id")
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(250), resp.TotalAmount)
+	assert.Len(t, resp.LineItems, 1)
+	if assert.NotNil(t, resp.Live) {
+		assert.Equal(t, 1, resp.Live.EventCount)
+	}
+	mockDB.AssertExpectations(t)
+	mockTemporalClient.AssertExpectations(t)
+}
+
+func TestGetBill_QueryFailureFallsBackToStoredBill(t *testing.T) {
+	service, mockDB, mockTemporalClient := setup(t)
+	service.activity = temporal.NewActivity(mockDB, nil, nil, nil, nil, nil, nil)
+
+	bill := &model.BillDetail{
+		BillID:      "test-bill-id",
+		Status:      string(model.BillStatusOpen),
+		Currency:    "USD",
+		TotalAmount: 100,
+	}
+	mockDB.On("GetBill", mock.Anything, model.DefaultTenant, "test-bill-id").Return(bill, nil).Once()
+	mockDB.On("ListBillPaymentLinks", mock.Anything, model.DefaultTenant, "test-bill-id").Return(nil, nil).Once()
+	mockTemporalClient.On("QueryWorkflow", mock.Anything, "bill-test-bill-id", "", temporal.QueryBillState, mock.Anything).
+		Return(nil, errors.New("workflow is closing")).Once()
+
+	resp, err := service.GetBill(context.Background(), "test-bill-id")
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100), resp.TotalAmount)
+	assert.Nil(t, resp.Live)
+}
+
+func TestGetBill_ScopedToCallerTenant(t *testing.T) {
+	service, mockDB, mockTemporalClient := setup(t)
+	service.activity = temporal.NewActivity(mockDB, nil, nil, nil, nil, nil, nil)
+	et.OverrideAuthInfo("0123456789abcdef", &AuthData{KeyID: "0123456789abcdef", TenantID: "acme", Scopes: []model.Scope{model.ScopeBillsRead}})
+
+	bill := &model.BillDetail{
+		BillID:   "test-bill-id",
+		Status:   string(model.BillStatusOpen),
+		Currency: "USD",
+	}
+	mockDB.On("GetBill", mock.Anything, "acme", "test-bill-id").Return(bill, nil).Once()
+	mockDB.On("ListBillPaymentLinks", mock.Anything, "acme", "test-bill-id").Return(nil, nil).Once()
+	mockTemporalClient.On("QueryWorkflow", mock.Anything, "bill-acme/test-bill-id", "", temporal.QueryBillState, mock.Anything).
+		Return(&mockValue{val: temporal.LiveBillState{}}, nil).Once()
+
+	_, err := service.GetBill(context.Background(), "test-bill-id")
+
+	assert.NoError(t, err)
+	mockDB.AssertExpectations(t)
+	mockTemporalClient.AssertExpectations(t)
+}
+
+func TestGetBill_ClosedWithPaymentLinks(t *testing.T) {
+	service, mockDB, mockTemporalClient := setup(t)
+	service.activity = temporal.NewActivity(mockDB, nil, nil, nil, nil, nil, nil)
+
+	closedAt := time.Now().Add(-time.Hour)
+	bill := &model.BillDetail{
+		BillID:      "test-bill-id",
+		Status:      string(model.BillStatusClosed),
+		Currency:    "USD",
+		TotalAmount: 1500,
+		ClosedAt:    &closedAt,
+	}
+	links := []*model.PaymentLink{
+		{LinkID: "link-1", BillID: "test-bill-id", URL: "https://pay.fake.invalid/fake_1", Amount: 1500, Currency: "USD",
+			Status: model.PaymentLinkPending, ExpiresAt: closedAt.Add(30 * 24 * time.Hour)},
+	}
+	mockDB.On("GetBill", mock.Anything, model.DefaultTenant, "test-bill-id").Return(bill, nil).Once()
+	mockDB.On("ListBillPaymentLinks", mock.Anything, model.DefaultTenant, "test-bill-id").Return(links, nil).Once()
+
+	resp, err := service.GetBill(context.Background(), "test-bill-id")
+
+	assert.NoError(t, err)
+	assert.Equal(t, links, resp.PaymentLinks)
+	mockDB.AssertExpectations(t)
+	mockTemporalClient.AssertNotCalled(t, "QueryWorkflow", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
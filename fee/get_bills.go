@@ -2,6 +2,9 @@ package fee
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -18,70 +21,211 @@ type Amount struct {
 }
 
 type Bill struct {
-	BillID      string     `json:"bill_id"`
-	Status      string     `json:"status"`
-	PolicyType  string     `json:"policy_type"`
-	CreatedAt   time.Time  `json:"created_at"`
-	ClosedAt    *time.Time `json:"closed_at,omitempty"`
-	TotalCharge Amount     `json:"total_charge"`
+	BillID      string             `json:"bill_id"`
+	Status      string             `json:"status"`
+	PolicyType  string             `json:"policy_type"`
+	CustomerID  string             `json:"customer_id,omitempty"`
+	CreatedAt   time.Time          `json:"created_at"`
+	ClosedAt    *time.Time         `json:"closed_at,omitempty"`
+	PeriodStart time.Time          `json:"period_start"`
+	PeriodEnd   *time.Time         `json:"period_end,omitempty"`
+	Closure     *model.BillClosure `json:"closure,omitempty"`
+	TotalCharge Amount             `json:"total_charge"`
 }
 
 type GetBillsResponse struct {
-	Bills   []Bill `json:"bills"`
-	HasMore bool   `json:"has_more"`
+	Bills      []Bill `json:"bills"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"` // pass as cursor, with the same sort and order, for the next page
+	PrevCursor string `json:"prev_cursor,omitempty"` // likewise for the previous page
 }
 
+// GetBillsParams filters, sorts and pages the bill list. All filters are optional and combine
+// with AND. Time ranges are [from, to) as RFC 3339 timestamps, total ranges are inclusive.
 type GetBillsParams struct {
-	Status string `query:"status"`
-	Limit  int    `query:"limit"`
-	Cursor string `query:"cursor"`
+	Status        []string `query:"status"` // one or more, also comma separated; all statuses when empty
+	PolicyType    string   `query:"policy_type"`
+	Currency      string   `query:"currency"`
+	CustomerID    string   `query:"customer_id"`
+	Reason        string   `query:"reason"` // close reason, only closed bills have one
+	CreatedFrom   string   `query:"created_from"`
+	CreatedTo     string   `query:"created_to"`
+	ClosedFrom    string   `query:"closed_from"`
+	ClosedTo      string   `query:"closed_to"`
+	PeriodEndFrom string   `query:"period_end_from"`
+	PeriodEndTo   string   `query:"period_end_to"`
+	MinTotal      string   `query:"min_total"` // in minor units
+	MaxTotal      string   `query:"max_total"`
+	Sort          string   `query:"sort"`  // created_at (default), closed_at or total
+	Order         string   `query:"order"` // desc (default) or asc
+	Limit         int      `query:"limit"`
+	Cursor        string   `query:"cursor"`
 }
 
-//encore:api public method=GET path=/api/bills
-func (s *Service) GetBills(ctx context.Context, params *GetBillsParams) (*GetBillsResponse, error) {
-	status, err := model.ToBillStatus(strings.ToUpper(params.Status))
-	if err != nil {
-		rlog.Error("invalid status", params.Status, "error", err)
-		return nil, &errs.Error{
-			Code:    errs.InvalidArgument,
-			Message: "invalid status",
+// filter validates the filter parameters.
+func (p *GetBillsParams) filter() (model.BillFilter, error) {
+	var filter model.BillFilter
+	for _, value := range p.Status {
+		for _, s := range strings.Split(value, ",") {
+			if s = strings.TrimSpace(s); s == "" {
+				continue
+			}
+			status, err := model.ToBillStatus(strings.ToUpper(s))
+			if err != nil {
+				return filter, fmt.Errorf("invalid status")
+			}
+			filter.Statuses = append(filter.Statuses, status)
+		}
+	}
+	if p.PolicyType != "" {
+		policy, err := model.ToPolicyType(strings.ToUpper(p.PolicyType))
+		if err != nil {
+			return filter, fmt.Errorf("invalid policy_type")
+		}
+		filter.PolicyType = policy
+	}
+	if p.Currency != "" {
+		currency, err := model.ToCurrency(strings.ToUpper(p.Currency))
+		if err != nil {
+			return filter, fmt.Errorf("invalid currency")
+		}
+		filter.Currency = string(currency)
+	}
+	filter.CustomerID = p.CustomerID
+	if p.Reason != "" {
+		reason, err := model.ToCloseReason(strings.ToUpper(p.Reason))
+		if err != nil {
+			return filter, fmt.Errorf("invalid reason")
+		}
+		filter.CloseReason = reason
+	}
+
+	ranges := []struct {
+		name  string
+		value string
+		dst   **time.Time
+	}{
+		{"created_from", p.CreatedFrom, &filter.CreatedFrom},
+		{"created_to", p.CreatedTo, &filter.CreatedTo},
+		{"closed_from", p.ClosedFrom, &filter.ClosedFrom},
+		{"closed_to", p.ClosedTo, &filter.ClosedTo},
+		{"period_end_from", p.PeriodEndFrom, &filter.PeriodEndFrom},
+		{"period_end_to", p.PeriodEndTo, &filter.PeriodEndTo},
+	}
+	for _, r := range ranges {
+		t, err := parseTimeParam(r.name, r.value)
+		if err != nil {
+			return filter, err
 		}
+		*r.dst = t
 	}
 
-	if params.Limit == 0 {
-		params.Limit = 10
+	var err error
+	if filter.MinTotal, err = parseAmountParam("min_total", p.MinTotal); err != nil {
+		return filter, err
+	}
+	if filter.MaxTotal, err = parseAmountParam("max_total", p.MaxTotal); err != nil {
+		return filter, err
+	}
+	if filter.MinTotal != nil && filter.MaxTotal != nil && *filter.MinTotal > *filter.MaxTotal {
+		return filter, fmt.Errorf("min_total must not be more than max_total")
 	}
+	return filter, nil
+}
 
-	cursor := time.Now()
-	if params.Cursor != "" {
-		var err error
-		cursor, err = time.Parse(time.RFC3339, params.Cursor)
+// page validates the sort, order, limit and cursor parameters.
+func (p *GetBillsParams) page() (model.BillPage, error) {
+	page := model.BillPage{Sort: model.BillSortCreatedAt, Limit: p.Limit}
+	if p.Sort != "" {
+		sort, err := model.ToBillSort(strings.ToUpper(p.Sort))
 		if err != nil {
-			return nil, err
+			return page, fmt.Errorf("invalid sort")
+		}
+		page.Sort = sort
+	}
+	switch strings.ToLower(p.Order) {
+	case "", "desc":
+	case "asc":
+		page.Asc = true
+	default:
+		return page, fmt.Errorf("invalid order")
+	}
+	if page.Limit == 0 {
+		page.Limit = 10
+	}
+	if page.Limit < 0 || page.Limit > 100 {
+		return page, fmt.Errorf("limit must be between 1 and 100")
+	}
+	if p.Cursor != "" {
+		var cursor billCursor
+		if err := decodeCursor(billCursorKind, p.Cursor, &cursor); err != nil {
+			return page, err
 		}
+		if cursor.Sort != page.Sort || cursor.Asc != page.Asc {
+			return page, fmt.Errorf("cursor does not match sort and order")
+		}
+		page.After = &cursor.BillCursor
+		page.Backward = cursor.Dir == cursorPrev
+	}
+	return page, nil
+}
+
+const billCursorKind = "bills"
+
+// billCursor is the signed cursor of the bill list, it only continues a page of the same order.
+type billCursor struct {
+	Sort model.BillSort `json:"sort"`
+	Asc  bool           `json:"asc,omitempty"`
+	Dir  string         `json:"dir"` // next or prev
+	model.BillCursor
+}
+
+func encodeBillCursor(page model.BillPage, dir string, bill *model.BillDetail) string {
+	return encodeCursor(billCursorKind, billCursor{Sort: page.Sort, Asc: page.Asc, Dir: dir, BillCursor: *bill.CursorOf(page.Sort)})
+}
+
+//encore:api auth method=GET path=/api/bills tag:scope_bills_read
+func (s *Service) GetBills(ctx context.Context, params *GetBillsParams) (*GetBillsResponse, error) {
+	filter, err := params.filter()
+	if err != nil {
+		return nil, invalidArgument(err)
+	}
+	page, err := params.page()
+	if err != nil {
+		return nil, invalidArgument(err)
 	}
 
-	bills, hasMore, err := s.db.GetBills(ctx, status, params.Limit, cursor)
+	bills, hasMore, err := s.db.GetBills(ctx, requestTenant(), filter, page)
 	if err != nil {
 		rlog.Error("failed to get bills", "error", err)
 		return nil, err
 	}
-	resp := &GetBillsResponse{
-		HasMore: hasMore,
+	resp := &GetBillsResponse{}
+	next, prev := pageLinks(len(bills), page.After != nil, page.Backward, hasMore)
+	if next {
+		resp.NextCursor = encodeBillCursor(page, cursorNext, bills[len(bills)-1])
+	}
+	if prev {
+		resp.PrevCursor = encodeBillCursor(page, cursorPrev, bills[0])
 	}
+	resp.HasMore = next
 	resp.Bills = make([]Bill, len(bills))
 	for i, bill := range bills {
 		resp.Bills[i] = Bill{
-			BillID:     bill.BillID,
-			Status:     string(bill.Status),
-			PolicyType: bill.PolicyType,
-			CreatedAt:  bill.CreatedAt,
-			ClosedAt:   bill.ClosedAt,
+			BillID:      bill.BillID,
+			Status:      string(bill.Status),
+			PolicyType:  bill.PolicyType,
+			CustomerID:  bill.CustomerID,
+			CreatedAt:   bill.CreatedAt,
+			ClosedAt:    bill.ClosedAt,
+			PeriodStart: bill.PeriodStart,
+			PeriodEnd:   bill.PeriodEnd,
+			Closure:     bill.Closure,
 		}
 		totalAmount := bill.TotalAmount
 		// Query from temporal state if bills is still open
 		if bill.Status == string(model.BillStatusOpen) {
-			workflowID := "bill-" + bill.BillID
+			workflowID := temporal.BillCycleWorkflowID(requestTenant(), bill.BillID)
 			queryResult, err := s.client.QueryWorkflow(ctx, workflowID, "", temporal.QueryBillTotal)
 			if err != nil {
 				rlog.Error("failed to query workflow for live totals", "error", err, "bill_id", bill.BillID)
@@ -103,3 +247,45 @@ func (s *Service) GetBills(ctx context.Context, params *GetBillsParams) (*GetBil
 
 	return resp, nil
 }
+
+// invalidArgument reports a validation error of the request parameters.
+func invalidArgument(err error) error {
+	var errsErr *errs.Error
+	if errors.As(err, &errsErr) {
+		return err
+	}
+	return &errs.Error{
+		Code:    errs.InvalidArgument,
+		Message: err.Error(),
+	}
+}
+
+// parseTimeParam parses an optional RFC 3339 query parameter.
+func parseTimeParam(name, value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, &errs.Error{
+			Code:    errs.InvalidArgument,
+			Message: "invalid " + name,
+		}
+	}
+	return &t, nil
+}
+
+// parseAmountParam parses an optional amount query parameter in minor units.
+func parseAmountParam(name, value string) (*int64, error) {
+	if value == "" {
+		return nil, nil
+	}
+	amount, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, &errs.Error{
+			Code:    errs.InvalidArgument,
+			Message: "invalid " + name,
+		}
+	}
+	return &amount, nil
+}
@@ -2,12 +2,15 @@ package fee
 
 import (
 	"context"
+	"errors"
+	"reflect"
 	"testing"
 	"time"
 
 	"encore.app/fee/dao/mocks"
 	"encore.app/fee/model"
 	temporal "encore.app/fee/workflow"
+	"encore.dev/beta/errs"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -22,7 +25,7 @@ func TestGetBills_OPEN(t *testing.T) {
 	}
 
 	params := &GetBillsParams{
-		Status: "OPEN",
+		Status: []string{"OPEN"},
 		Limit:  10,
 	}
 
@@ -36,7 +39,7 @@ func TestGetBills_OPEN(t *testing.T) {
 	}
 
 	// Mock expectations
-	mockDB.On("GetBills", mock.Anything, model.BillStatusOpen, params.Limit, mock.Anything).Return(bills, false, nil)
+	mockDB.On("GetBills", mock.Anything, model.DefaultTenant, model.BillFilter{Statuses: []model.BillStatus{model.BillStatusOpen}}, model.BillPage{Sort: model.BillSortCreatedAt, Limit: params.Limit}).Return(bills, false, nil)
 	mockTemporalClient.On(
 		"QueryWorkflow",
 		mock.Anything,
@@ -70,6 +73,13 @@ func (v *mockValue) Get(valPtr interface{}) error {
 		if ok {
 			*p = val
 		}
+		return nil
+	}
+	if v.val != nil {
+		target := reflect.ValueOf(valPtr).Elem()
+		if value := reflect.ValueOf(v.val); value.Type().AssignableTo(target.Type()) {
+			target.Set(value)
+		}
 	}
 	return nil
 }
@@ -88,7 +98,7 @@ func TestGetBills_CLOSED(t *testing.T) {
 	}
 
 	params := &GetBillsParams{
-		Status: "OPEN",
+		Status: []string{"OPEN"},
 		Limit:  10,
 	}
 
@@ -104,7 +114,7 @@ func TestGetBills_CLOSED(t *testing.T) {
 	}
 
 	// Mock expectations
-	mockDB.On("GetBills", mock.Anything, model.BillStatusOpen, params.Limit, mock.Anything).Return(bills, false, nil)
+	mockDB.On("GetBills", mock.Anything, model.DefaultTenant, model.BillFilter{Statuses: []model.BillStatus{model.BillStatusOpen}}, model.BillPage{Sort: model.BillSortCreatedAt, Limit: params.Limit}).Return(bills, false, nil)
 
 	resp, err := service.GetBills(context.Background(), params)
 
@@ -119,3 +129,167 @@ func TestGetBills_CLOSED(t *testing.T) {
 	mockDB.AssertExpectations(t)
 	mockTemporalClient.AssertExpectations(t)
 }
+
+func TestGetBills_FilterByReason(t *testing.T) {
+	service, mockDB, _ := setup(t)
+
+	closedAt := time.Now()
+	bills := []*model.BillDetail{
+		{
+			BillID:   "fraud-bill",
+			Status:   "CLOSED",
+			ClosedAt: &closedAt,
+			Closure:  &model.BillClosure{Reason: model.CloseReasonFraud, Actor: "ops", Trigger: model.CloseTriggerManual},
+		},
+	}
+	mockDB.On("GetBills", mock.Anything, model.DefaultTenant, model.BillFilter{Statuses: []model.BillStatus{model.BillStatusClosed}, CloseReason: model.CloseReasonFraud}, mock.Anything).Return(bills, false, nil).Once()
+
+	resp, err := service.GetBills(context.Background(), &GetBillsParams{Status: []string{"closed"}, Reason: "fraud"})
+
+	assert.NoError(t, err)
+	assert.Len(t, resp.Bills, 1)
+	assert.Equal(t, model.CloseReasonFraud, resp.Bills[0].Closure.Reason)
+	mockDB.AssertExpectations(t)
+}
+
+func TestGetBills_ClosingSoon(t *testing.T) {
+	service, mockDB, _ := setup(t)
+
+	to := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	mockDB.On("GetBills", mock.Anything, model.DefaultTenant, mock.MatchedBy(func(filter model.BillFilter) bool {
+		return reflect.DeepEqual(filter.Statuses, []model.BillStatus{model.BillStatusOpen}) && filter.PeriodEndFrom == nil && filter.PeriodEndTo.Equal(to)
+	}), mock.Anything).Return([]*model.BillDetail{}, false, nil).Once()
+
+	_, err := service.GetBills(context.Background(), &GetBillsParams{Status: []string{"OPEN"}, PeriodEndTo: to.Format(time.RFC3339)})
+
+	assert.NoError(t, err)
+	mockDB.AssertExpectations(t)
+}
+
+func TestGetBills_InvalidPeriodEnd(t *testing.T) {
+	service, mockDB, _ := setup(t)
+
+	_, err := service.GetBills(context.Background(), &GetBillsParams{Status: []string{"OPEN"}, PeriodEndFrom: "tomorrow"})
+
+	assert.Error(t, err)
+	mockDB.AssertNotCalled(t, "GetBills", mock.Anything, model.DefaultTenant, mock.Anything, mock.Anything)
+}
+
+func TestGetBills_CombinedFilters(t *testing.T) {
+	service, mockDB, _ := setup(t)
+
+	mockDB.On("GetBills", mock.Anything, model.DefaultTenant, mock.MatchedBy(func(filter model.BillFilter) bool {
+		return reflect.DeepEqual(filter.Statuses, []model.BillStatus{model.BillStatusClosed, model.BillStatusCancelled, model.BillStatusOpen}) &&
+			filter.PolicyType == model.Subscription && filter.Currency == "GEL" && filter.CustomerID == "cus-1" &&
+			filter.ClosedFrom != nil && filter.ClosedTo == nil && *filter.MinTotal == 100 && *filter.MaxTotal == 5000
+	}), model.BillPage{Sort: model.BillSortTotal, Asc: true, Limit: 10}).Return([]*model.BillDetail{}, false, nil).Once()
+
+	_, err := service.GetBills(context.Background(), &GetBillsParams{
+		Status:     []string{"closed,cancelled", "open"},
+		PolicyType: "subscription",
+		Currency:   "gel",
+		CustomerID: "cus-1",
+		ClosedFrom: "2025-01-01T00:00:00Z",
+		MinTotal:   "100",
+		MaxTotal:   "5000",
+		Sort:       "total",
+		Order:      "asc",
+	})
+
+	assert.NoError(t, err)
+	mockDB.AssertExpectations(t)
+}
+
+func TestGetBills_NoStatus(t *testing.T) {
+	service, mockDB, _ := setup(t)
+
+	mockDB.On("GetBills", mock.Anything, model.DefaultTenant, model.BillFilter{}, mock.Anything).Return([]*model.BillDetail{}, false, nil).Once()
+
+	resp, err := service.GetBills(context.Background(), &GetBillsParams{})
+
+	assert.NoError(t, err)
+	assert.Empty(t, resp.Bills)
+	mockDB.AssertExpectations(t)
+}
+
+func TestGetBills_NextCursor(t *testing.T) {
+	service, mockDB, _ := setup(t)
+
+	closedAt := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	bills := []*model.BillDetail{
+		{BillID: "bill-1", Status: "CLOSED", ClosedAt: &closedAt},
+	}
+	page := model.BillPage{Sort: model.BillSortClosedAt, Limit: 1}
+	mockDB.On("GetBills", mock.Anything, model.DefaultTenant, mock.Anything, page).Return(bills, true, nil).Once()
+
+	resp, err := service.GetBills(context.Background(), &GetBillsParams{Sort: "closed_at", Limit: 1})
+	assert.NoError(t, err)
+	assert.True(t, resp.HasMore)
+	assert.NotEmpty(t, resp.NextCursor)
+
+	mockDB.On("GetBills", mock.Anything, model.DefaultTenant, mock.Anything, mock.MatchedBy(func(p model.BillPage) bool {
+		return p.Sort == model.BillSortClosedAt && p.After != nil && p.After.BillID == "bill-1" && p.After.ClosedAt.Equal(closedAt)
+	})).Return([]*model.BillDetail{}, false, nil).Once()
+
+	_, err = service.GetBills(context.Background(), &GetBillsParams{Sort: "closed_at", Limit: 1, Cursor: resp.NextCursor})
+	assert.NoError(t, err)
+
+	// Going back from the second page reads the bills before its first one, in the same order.
+	secondPage := []*model.BillDetail{{BillID: "bill-2", Status: "CLOSED", ClosedAt: &closedAt}}
+	mockDB.On("GetBills", mock.Anything, model.DefaultTenant, mock.Anything, mock.MatchedBy(func(p model.BillPage) bool {
+		return p.After != nil && p.After.BillID == "bill-1" && !p.Backward
+	})).Return(secondPage, false, nil).Once()
+	resp, err = service.GetBills(context.Background(), &GetBillsParams{Sort: "closed_at", Limit: 1, Cursor: resp.NextCursor})
+	assert.NoError(t, err)
+	assert.False(t, resp.HasMore)
+	assert.Empty(t, resp.NextCursor)
+	assert.NotEmpty(t, resp.PrevCursor)
+
+	mockDB.On("GetBills", mock.Anything, model.DefaultTenant, mock.Anything, mock.MatchedBy(func(p model.BillPage) bool {
+		return p.After != nil && p.After.BillID == "bill-2" && p.Backward
+	})).Return(bills, false, nil).Once()
+	resp, err = service.GetBills(context.Background(), &GetBillsParams{Sort: "closed_at", Limit: 1, Cursor: resp.PrevCursor})
+	assert.NoError(t, err)
+	assert.Equal(t, "bill-1", resp.Bills[0].BillID)
+	assert.True(t, resp.HasMore)
+	assert.Empty(t, resp.PrevCursor)
+
+	// A cursor only continues the order it was handed out for.
+	_, err = service.GetBills(context.Background(), &GetBillsParams{Sort: "total", Limit: 1, Cursor: resp.NextCursor})
+	assert.Error(t, err)
+	mockDB.AssertExpectations(t)
+}
+
+func TestGetBills_InvalidParams(t *testing.T) {
+	tests := []struct {
+		name   string
+		params *GetBillsParams
+	}{
+		{"InvalidStatus", &GetBillsParams{Status: []string{"OPEN,PAID"}}},
+		{"InvalidPolicyType", &GetBillsParams{PolicyType: "PREPAID"}},
+		{"InvalidCurrency", &GetBillsParams{Currency: "EUR"}},
+		{"InvalidCreatedFrom", &GetBillsParams{CreatedFrom: "yesterday"}},
+		{"InvalidMinTotal", &GetBillsParams{MinTotal: "1.5"}},
+		{"MinTotalAboveMaxTotal", &GetBillsParams{MinTotal: "500", MaxTotal: "100"}},
+		{"InvalidSort", &GetBillsParams{Sort: "bill_id"}},
+		{"InvalidOrder", &GetBillsParams{Order: "up"}},
+		{"LimitTooLarge", &GetBillsParams{Limit: 1000}},
+		{"InvalidCursor", &GetBillsParams{Cursor: "not-a-cursor"}},
+		{"LegacyCursor", &GetBillsParams{Cursor: "2025-01-01T00:00:00Z"}},
+		{"TamperedCursor", &GetBillsParams{Cursor: testCursor(t, billCursorKind, billCursor{Sort: model.BillSortCreatedAt, Dir: cursorNext})[:10] + "x.AAAA"}},
+		{"LineItemCursor", &GetBillsParams{Cursor: testCursor(t, lineItemCursorKind, billCursor{Sort: model.BillSortCreatedAt, Dir: cursorNext})}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			service, mockDB, _ := setup(t)
+
+			_, err := service.GetBills(context.Background(), tc.params)
+
+			var errsErr *errs.Error
+			assert.True(t, errors.As(err, &errsErr))
+			assert.Equal(t, errs.InvalidArgument, errsErr.Code)
+			mockDB.AssertNotCalled(t, "GetBills", mock.Anything, model.DefaultTenant, mock.Anything, mock.Anything)
+		})
+	}
+}
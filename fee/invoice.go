@@ -0,0 +1,172 @@
+package fee
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"encore.app/fee/model"
+	"encore.dev"
+	"encore.dev/beta/errs"
+	"encore.dev/rlog"
+	"encore.dev/storage/objects"
+)
+
+// invoiceBucket holds the rendered invoice PDFs, keyed by tenant and invoice.
+var invoiceBucket = objects.NewBucket("invoices", objects.BucketConfig{})
+
+type ListBillInvoicesResponse struct {
+	Invoices []*model.Invoice `json:"invoices"`
+}
+
+// GetInvoice returns an issued invoice as it was when it was issued.
+//
+//encore:api auth method=GET path=/api/invoices/:invoiceID tag:scope_bills_read
+func (s *Service) GetInvoice(ctx context.Context, invoiceID string) (*model.Invoice, error) {
+	invoice, err := s.db.GetInvoice(ctx, requestTenant(), invoiceID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &errs.Error{
+				Code:    errs.NotFound,
+				Message: "invoice not found",
+			}
+		}
+		rlog.Error("failed to get invoice", "error", err)
+		return nil, err
+	}
+	return invoice, nil
+}
+
+// ListBillInvoices returns the invoices issued for a bill, oldest first. A bill is invoiced each
+// time it closes with charges.
+//
+//encore:api auth method=GET path=/api/bills/:billID/invoices tag:scope_bills_read
+func (s *Service) ListBillInvoices(ctx context.Context, billID string) (*ListBillInvoicesResponse, error) {
+	tenantID := requestTenant()
+	exists, err := s.db.IsBillExists(ctx, tenantID, billID)
+	if err != nil {
+		rlog.Error("failed in checking bill exists in db", "error", err)
+		return nil, err
+	}
+	if !exists {
+		return nil, &errs.Error{
+			Code:    errs.NotFound,
+			Message: "bill not found",
+		}
+	}
+	invoices, err := s.db.ListBillInvoices(ctx, tenantID, billID)
+	if err != nil {
+		rlog.Error("failed to list bill invoices", "error", err)
+		return nil, err
+	}
+	if invoices == nil {
+		invoices = []*model.Invoice{}
+	}
+	return &ListBillInvoicesResponse{Invoices: invoices}, nil
+}
+
+// GetBillInvoicePDF downloads the PDF of the bill's latest invoice. It is rendered after the bill
+// closes, until then and after the bill is reopened there is none.
+//
+//encore:api auth raw method=GET path=/api/bills/:billID/invoice.pdf tag:scope_bills_read
+func (s *Service) GetBillInvoicePDF(w http.ResponseWriter, req *http.Request) {
+	billID := encore.CurrentRequest().PathParams.Get("billID")
+	key, err := s.db.GetBillInvoicePDF(req.Context(), requestTenant(), billID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			errs.HTTPError(w, &errs.Error{Code: errs.NotFound, Message: "bill not found"})
+			return
+		}
+		rlog.Error("failed to get bill invoice pdf", "error", err)
+		errs.HTTPError(w, err)
+		return
+	}
+	if key == "" {
+		errs.HTTPError(w, &errs.Error{Code: errs.NotFound, Message: "invoice pdf not rendered"})
+		return
+	}
+
+	r := invoiceBucket.Download(req.Context(), key)
+	defer r.Close()
+	// Read the start before sending headers, so a missing object is still reported as an error.
+	head := make([]byte, 512)
+	n, err := io.ReadFull(r, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		if errors.Is(err, objects.ErrObjectNotFound) {
+			errs.HTTPError(w, &errs.Error{Code: errs.NotFound, Message: "invoice pdf not found"})
+			return
+		}
+		rlog.Error("failed to download invoice pdf", "error", err, "key", key)
+		errs.HTTPError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", billID+"-invoice.pdf"))
+	_, _ = w.Write(head[:n])
+	if _, err := io.Copy(w, r); err != nil {
+		// Headers are already sent, so the download is cut short and the failure only logged.
+		rlog.Error("failed to stream invoice pdf", "error", err, "key", key)
+	}
+}
+
+type SetInvoiceIssuerParams struct {
+	Name             string `json:"name"`
+	Email            string `json:"email"`
+	Address          string `json:"address"`
+	TaxID            string `json:"tax_id"`
+	NumberPrefix     string `json:"number_prefix"`      // optional, INV- when empty
+	PaymentTermsDays int    `json:"payment_terms_days"` // optional, 30 when zero
+
+	Locale              string `json:"locale"`               // optional, en (default), de or fr
+	PaymentInstructions string `json:"payment_instructions"` // optional, printed on the invoice PDF
+	Logo                []byte `json:"logo"`                 // optional, a base64 encoded JPEG of at most 256 KiB
+}
+
+// SetInvoiceIssuer sets the details the tenant issues its invoices under. They apply to invoices
+// issued from now on.
+//
+//encore:api auth method=PUT path=/api/admin/invoice-issuer tag:scope_admin
+func (s *Service) SetInvoiceIssuer(ctx context.Context, params *SetInvoiceIssuerParams) (*model.InvoiceIssuer, error) {
+	issuer := &model.InvoiceIssuer{
+		Name:                params.Name,
+		Email:               params.Email,
+		Address:             params.Address,
+		TaxID:               params.TaxID,
+		NumberPrefix:        params.NumberPrefix,
+		PaymentTermsDays:    params.PaymentTermsDays,
+		Locale:              params.Locale,
+		PaymentInstructions: params.PaymentInstructions,
+		Logo:                params.Logo,
+	}
+	if err := issuer.Validate(); err != nil {
+		return nil, &errs.Error{
+			Code:    errs.InvalidArgument,
+			Message: err.Error(),
+		}
+	}
+	if err := s.db.SetInvoiceIssuer(ctx, requestTenant(), issuer); err != nil {
+		rlog.Error("failed to set invoice issuer", "error", err)
+		return nil, err
+	}
+	return issuer, nil
+}
+
+//encore:api auth method=GET path=/api/admin/invoice-issuer tag:scope_admin
+func (s *Service) GetInvoiceIssuer(ctx context.Context) (*model.InvoiceIssuer, error) {
+	issuer, err := s.db.GetInvoiceIssuer(ctx, requestTenant())
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &errs.Error{
+				Code:    errs.NotFound,
+				Message: "invoice issuer not set",
+			}
+		}
+		rlog.Error("failed to get invoice issuer", "error", err)
+		return nil, err
+	}
+	return issuer, nil
+}
@@ -0,0 +1,88 @@
+package fee
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"encore.app/fee/model"
+	"encore.dev/beta/errs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetInvoice_NotFound(t *testing.T) {
+	service, mockDB, _ := setup(t)
+	mockDB.On("GetInvoice", mock.Anything, model.DefaultTenant, "inv-1").Return(nil, sql.ErrNoRows).Once()
+
+	resp, err := service.GetInvoice(context.Background(), "inv-1")
+
+	assert.Nil(t, resp)
+	assert.Equal(t, errs.NotFound, errs.Code(err))
+	mockDB.AssertExpectations(t)
+}
+
+func TestListBillInvoices(t *testing.T) {
+	service, mockDB, _ := setup(t)
+	invoices := []*model.Invoice{
+		{InvoiceID: "inv-1", InvoiceNumber: 1, Number: "INV-000001", BillID: "bill-1", TotalAmount: 100},
+		{InvoiceID: "inv-2", InvoiceNumber: 4, Number: "INV-000004", BillID: "bill-1", TotalAmount: 150},
+	}
+	mockDB.On("IsBillExists", mock.Anything, model.DefaultTenant, "bill-1").Return(true, nil).Once()
+	mockDB.On("ListBillInvoices", mock.Anything, model.DefaultTenant, "bill-1").Return(invoices, nil).Once()
+
+	resp, err := service.ListBillInvoices(context.Background(), "bill-1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, invoices, resp.Invoices)
+	mockDB.AssertExpectations(t)
+}
+
+func TestListBillInvoices_BillNotFound(t *testing.T) {
+	service, mockDB, _ := setup(t)
+	mockDB.On("IsBillExists", mock.Anything, model.DefaultTenant, "bill-1").Return(false, nil).Once()
+
+	_, err := service.ListBillInvoices(context.Background(), "bill-1")
+
+	assert.Equal(t, errs.NotFound, errs.Code(err))
+	mockDB.AssertNotCalled(t, "ListBillInvoices", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSetInvoiceIssuer_Defaults(t *testing.T) {
+	service, mockDB, _ := setup(t)
+	want := &model.InvoiceIssuer{Name: "Acme", NumberPrefix: model.DefaultInvoicePrefix, PaymentTermsDays: model.DefaultPaymentTermsDays, Locale: model.LocaleEN}
+	mockDB.On("SetInvoiceIssuer", mock.Anything, model.DefaultTenant, want).Return(nil).Once()
+
+	resp, err := service.SetInvoiceIssuer(context.Background(), &SetInvoiceIssuerParams{Name: "Acme"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, want, resp)
+	mockDB.AssertExpectations(t)
+}
+
+func TestSetInvoiceIssuer_Invalid(t *testing.T) {
+	service, mockDB, _ := setup(t)
+
+	_, err := service.SetInvoiceIssuer(context.Background(), &SetInvoiceIssuerParams{Name: "Acme", PaymentTermsDays: -5})
+
+	assert.Equal(t, errs.InvalidArgument, errs.Code(err))
+	mockDB.AssertNotCalled(t, "SetInvoiceIssuer", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPutCustomer_MissingName(t *testing.T) {
+	service, mockDB, _ := setup(t)
+
+	_, err := service.PutCustomer(context.Background(), "cust-1", &PutCustomerParams{Email: "a@example.com"})
+
+	assert.Equal(t, errs.InvalidArgument, errs.Code(err))
+	mockDB.AssertNotCalled(t, "UpsertCustomer", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPutCustomer_InvalidBillingEmail(t *testing.T) {
+	service, mockDB, _ := setup(t)
+
+	_, err := service.PutCustomer(context.Background(), "cust-1", &PutCustomerParams{Name: "Globex", BillingEmails: []string{"ap@globex.example", "nope"}})
+
+	assert.Equal(t, errs.InvalidArgument, errs.Code(err))
+	mockDB.AssertNotCalled(t, "UpsertCustomer", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,123 @@
+package fee
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"encore.app/fee/model"
+	temporal "encore.app/fee/workflow"
+	"encore.dev/beta/errs"
+	"encore.dev/rlog"
+)
+
+type ListLineItemsParams struct {
+	Status string `query:"status"` // ACTIVE or VOIDED, all line items when empty
+	Order  string `query:"order"`  // desc (default, newest first) or asc
+	Limit  int    `query:"limit"`
+	Cursor string `query:"cursor"`
+}
+
+type ListLineItemsResponse struct {
+	LineItems  []temporal.LineItem `json:"line_items"`
+	HasMore    bool                `json:"has_more"`
+	NextCursor string              `json:"next_cursor,omitempty"` // pass as cursor, with the same status and order, for the next page
+	PrevCursor string              `json:"prev_cursor,omitempty"` // likewise for the previous page
+}
+
+const lineItemCursorKind = "line-items"
+
+// lineItemCursor is the signed cursor of a line item list, it only continues a page of the same bill and order.
+type lineItemCursor struct {
+	BillID string `json:"bill"`
+	Status string `json:"status,omitempty"`
+	Asc    bool   `json:"asc,omitempty"`
+	Dir    string `json:"dir"` // next or prev
+	model.LineItemCursor
+}
+
+// page parses the parameters into the status filter and the page to read.
+func (p *ListLineItemsParams) page(billID string) (model.LineItemStatus, model.LineItemPage, error) {
+	var status model.LineItemStatus
+	page := model.LineItemPage{Limit: p.Limit}
+	if p.Status != "" {
+		var err error
+		status, err = model.ToLineItemStatus(strings.ToUpper(p.Status))
+		if err != nil {
+			return "", page, fmt.Errorf("invalid status")
+		}
+	}
+	switch strings.ToLower(p.Order) {
+	case "", "desc":
+	case "asc":
+		page.Asc = true
+	default:
+		return "", page, fmt.Errorf("invalid order")
+	}
+	if page.Limit == 0 {
+		page.Limit = 50
+	}
+	if page.Limit < 0 || page.Limit > 500 {
+		return "", page, fmt.Errorf("limit must be between 1 and 500")
+	}
+	if p.Cursor != "" {
+		var cursor lineItemCursor
+		if err := decodeCursor(lineItemCursorKind, p.Cursor, &cursor); err != nil {
+			return "", page, err
+		}
+		if cursor.BillID != billID || cursor.Status != string(status) || cursor.Asc != page.Asc {
+			return "", page, fmt.Errorf("cursor does not match bill, status and order")
+		}
+		page.After = &cursor.LineItemCursor
+		page.Backward = cursor.Dir == cursorPrev
+	}
+	return status, page, nil
+}
+
+// ListLineItems pages through the line items of a bill, for bills too large to read with GetBill.
+//
+//encore:api auth method=GET path=/api/bills/:billID/line-items tag:scope_bills_read
+func (s *Service) ListLineItems(ctx context.Context, billID string, params *ListLineItemsParams) (*ListLineItemsResponse, error) {
+	status, page, err := params.page(billID)
+	if err != nil {
+		return nil, &errs.Error{
+			Code:    errs.InvalidArgument,
+			Message: err.Error(),
+		}
+	}
+
+	result, err := s.activity.ListLineItems(ctx, requestTenant(), billID, status, page)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &errs.Error{
+				Code:    errs.NotFound,
+				Message: "bill not found",
+			}
+		}
+		rlog.Error("failed to list line items", "error", err, "bill_id", billID)
+		return nil, err
+	}
+
+	items := result.LineItems
+	resp := &ListLineItemsResponse{LineItems: items}
+	cursorOf := func(item temporal.LineItem, dir string) string {
+		return encodeCursor(lineItemCursorKind, lineItemCursor{
+			BillID:         billID,
+			Status:         string(status),
+			Asc:            page.Asc,
+			Dir:            dir,
+			LineItemCursor: model.LineItemCursor{CreatedAt: item.CreatedAt, LineItemID: item.LineItemID},
+		})
+	}
+	next, prev := pageLinks(len(items), page.After != nil, page.Backward, result.HasMore)
+	if next {
+		resp.NextCursor = cursorOf(items[len(items)-1], cursorNext)
+	}
+	if prev {
+		resp.PrevCursor = cursorOf(items[0], cursorPrev)
+	}
+	resp.HasMore = next
+	return resp, nil
+}
@@ -0,0 +1,106 @@
+package fee
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"encore.app/fee/model"
+	temporal "encore.app/fee/workflow"
+	"encore.dev/beta/errs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestListLineItems_Pages(t *testing.T) {
+	service, mockDB, _ := setup(t)
+	service.activity = temporal.NewActivity(mockDB, nil, nil, nil, nil, nil, nil)
+
+	createdAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	firstPage := []model.LineItem{
+		{LineItemID: "line-item-2", Amount: 200, Metadata: `{"description":"API calls"}`, CreatedAt: createdAt, Status: "ACTIVE"},
+		{LineItemID: "line-item-1", Amount: 100, Metadata: `{}`, CreatedAt: createdAt, Status: "ACTIVE"},
+	}
+	mockDB.On("GetBillCurrency", mock.Anything, model.DefaultTenant, "bill-1").Return("USD", nil)
+	mockDB.On("ListLineItems", mock.Anything, model.DefaultTenant, "bill-1", model.LineItemStatusActive, model.LineItemPage{Limit: 2}).Return(firstPage, true, nil).Once()
+
+	resp, err := service.ListLineItems(context.Background(), "bill-1", &ListLineItemsParams{Status: "active", Limit: 2})
+
+	assert.NoError(t, err)
+	assert.Len(t, resp.LineItems, 2)
+	assert.Equal(t, "USD", resp.LineItems[0].Currency)
+	assert.Equal(t, "API calls", resp.LineItems[0].Description)
+	assert.True(t, resp.HasMore)
+	assert.Empty(t, resp.PrevCursor)
+
+	// Line items sharing a created_at are told apart by their ID.
+	mockDB.On("ListLineItems", mock.Anything, model.DefaultTenant, "bill-1", model.LineItemStatusActive, mock.MatchedBy(func(p model.LineItemPage) bool {
+		return p.After != nil && p.After.LineItemID == "line-item-1" && p.After.CreatedAt.Equal(createdAt) && !p.Backward
+	})).Return([]model.LineItem{}, false, nil).Once()
+
+	resp, err = service.ListLineItems(context.Background(), "bill-1", &ListLineItemsParams{Status: "active", Limit: 2, Cursor: resp.NextCursor})
+
+	assert.NoError(t, err)
+	assert.Empty(t, resp.LineItems)
+	assert.False(t, resp.HasMore)
+	mockDB.AssertExpectations(t)
+}
+
+func TestListLineItems_BillNotFound(t *testing.T) {
+	service, mockDB, _ := setup(t)
+	service.activity = temporal.NewActivity(mockDB, nil, nil, nil, nil, nil, nil)
+
+	mockDB.On("GetBillCurrency", mock.Anything, model.DefaultTenant, "missing").Return("", sql.ErrNoRows).Once()
+
+	_, err := service.ListLineItems(context.Background(), "missing", &ListLineItemsParams{})
+
+	var errsErr *errs.Error
+	assert.True(t, errors.As(err, &errsErr))
+	assert.Equal(t, errs.NotFound, errsErr.Code)
+	mockDB.AssertNotCalled(t, "ListLineItems", mock.Anything, model.DefaultTenant, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestListLineItems_CursorOfAnotherBill(t *testing.T) {
+	service, mockDB, _ := setup(t)
+	service.activity = temporal.NewActivity(mockDB, nil, nil, nil, nil, nil, nil)
+
+	cursor := testCursor(t, lineItemCursorKind, lineItemCursor{
+		BillID:         "bill-1",
+		Dir:            cursorNext,
+		LineItemCursor: model.LineItemCursor{CreatedAt: time.Now(), LineItemID: "line-item-1"},
+	})
+
+	_, err := service.ListLineItems(context.Background(), "bill-2", &ListLineItemsParams{Cursor: cursor})
+
+	var errsErr *errs.Error
+	assert.True(t, errors.As(err, &errsErr))
+	assert.Equal(t, errs.InvalidArgument, errsErr.Code)
+	mockDB.AssertNotCalled(t, "GetBillCurrency", mock.Anything, model.DefaultTenant, mock.Anything)
+}
+
+func TestListLineItems_InvalidParams(t *testing.T) {
+	tests := []struct {
+		name   string
+		params *ListLineItemsParams
+	}{
+		{"InvalidStatus", &ListLineItemsParams{Status: "DELETED"}},
+		{"InvalidOrder", &ListLineItemsParams{Order: "newest"}},
+		{"LimitTooLarge", &ListLineItemsParams{Limit: 10000}},
+		{"InvalidCursor", &ListLineItemsParams{Cursor: "2025-01-01T00:00:00Z"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			service, mockDB, _ := setup(t)
+			service.activity = temporal.NewActivity(mockDB, nil, nil, nil, nil, nil, nil)
+
+			_, err := service.ListLineItems(context.Background(), "bill-1", tc.params)
+
+			var errsErr *errs.Error
+			assert.True(t, errors.As(err, &errsErr))
+			assert.Equal(t, errs.InvalidArgument, errsErr.Code)
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"encore.dev/rlog"
+)
+
+// SMTPMailer sends messages through an SMTP server, with STARTTLS when the server offers it.
+type SMTPMailer struct {
+	Addr     string // host:port
+	Username string // no authentication when empty
+	Password string
+	From     string // the envelope sender, and the From address when the message has none
+}
+
+func (s *SMTPMailer) Send(ctx context.Context, msg *Message) error {
+	if msg.From.Address == "" {
+		msg.From.Address = s.From
+	}
+	if msg.From.Address == "" {
+		return ErrNoFrom
+	}
+	var auth smtp.Auth
+	if s.Username != "" {
+		host, _, err := net.SplitHostPort(s.Addr)
+		if err != nil {
+			return fmt.Errorf("invalid smtp address: %w", err)
+		}
+		auth = smtp.PlainAuth("", s.Username, s.Password, host)
+	}
+	if err := smtp.SendMail(s.Addr, auth, s.From, msg.To, msg.Bytes()); err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+	return nil
+}
+
+// FileMailer writes every message into Dir as <message id>.eml, to be opened in a mail client.
+type FileMailer struct {
+	Dir  string
+	From string // the From address when the message has none
+}
+
+func (f *FileMailer) Send(ctx context.Context, msg *Message) error {
+	if msg.From.Address == "" {
+		msg.From.Address = f.From
+	}
+	if msg.From.Address == "" {
+		return ErrNoFrom
+	}
+	if err := os.MkdirAll(f.Dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create mail directory: %w", err)
+	}
+	name := strings.NewReplacer("/", "_", "@", "_").Replace(msg.ID) + ".eml"
+	if err := os.WriteFile(filepath.Join(f.Dir, name), msg.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write mail: %w", err)
+	}
+	return nil
+}
+
+// LogMailer only logs the messages it is given.
+type LogMailer struct{}
+
+func (LogMailer) Send(ctx context.Context, msg *Message) error {
+	attachments := make([]string, len(msg.Attachments))
+	for i, a := range msg.Attachments {
+		attachments[i] = a.Filename
+	}
+	rlog.Info("mail not sent, no mailer configured", "message_id", msg.ID, "to", strings.Join(msg.To, ", "),
+		"subject", msg.Subject, "attachments", strings.Join(attachments, ", "))
+	return nil
+}
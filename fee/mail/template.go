@@ -0,0 +1,87 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"strings"
+	"text/template"
+)
+
+// Templates are kept as templates/<name>.<locale>.{subject,txt,html}.tmpl, English is the
+// fallback of every other locale.
+//
+//go:embed templates/*.tmpl
+var templates embed.FS
+
+const fallbackLocale = "en"
+
+// TemplateBillClosed is sent with the invoice of a closed bill.
+const TemplateBillClosed = "bill_closed"
+
+// BillClosed is the data of TemplateBillClosed, with the amounts and dates already localized.
+type BillClosed struct {
+	IssuerName          string
+	CustomerName        string
+	InvoiceNumber       string
+	Total               string
+	DueDate             string
+	BillID              string
+	PaymentInstructions string
+	PaymentURL          string // the link to pay the invoice online, if there is one
+}
+
+// PaymentInstructionLines splits the payment instructions for the HTML body.
+func (b BillClosed) PaymentInstructionLines() []string {
+	return strings.Split(b.PaymentInstructions, "\n")
+}
+
+// Rendered is a template rendered for one message.
+type Rendered struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Render renders the subject, text and HTML of the template in locale. The HTML is escaped,
+// the subject and text are not.
+func Render(name, locale string, data any) (*Rendered, error) {
+	if _, err := fs.Stat(templates, fileName(name, locale, "subject")); err != nil {
+		locale = fallbackLocale
+	}
+	subject, err := renderText(fileName(name, locale, "subject"), data)
+	if err != nil {
+		return nil, err
+	}
+	text, err := renderText(fileName(name, locale, "txt"), data)
+	if err != nil {
+		return nil, err
+	}
+	html, err := htmltemplate.ParseFS(templates, fileName(name, locale, "html"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse mail template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := html.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to render mail template: %w", err)
+	}
+	return &Rendered{Subject: strings.TrimSpace(subject), Text: text, HTML: buf.String()}, nil
+}
+
+func renderText(file string, data any) (string, error) {
+	t, err := template.ParseFS(templates, file)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse mail template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render mail template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+func fileName(name, locale, part string) string {
+	return "templates/" + name + "." + locale + "." + part + ".tmpl"
+}
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// Scope is a permission carried by an API key.
+type Scope string
+
+const (
+	ScopeBillsRead      Scope = "bills:read"
+	ScopeBillsWrite     Scope = "bills:write"
+	ScopeLineItemsWrite Scope = "line_items:write"
+	ScopeBillsClose     Scope = "bills:close"
+	ScopeAdmin          Scope = "admin" // grants every other scope
+)
+
+func ToScope(s string) (Scope, error) {
+	switch Scope(s) {
+	case ScopeBillsRead:
+		return ScopeBillsRead, nil
+	case ScopeBillsWrite:
+		return ScopeBillsWrite, nil
+	case ScopeLineItemsWrite:
+		return ScopeLineItemsWrite, nil
+	case ScopeBillsClose:
+		return ScopeBillsClose, nil
+	case ScopeAdmin:
+		return ScopeAdmin, nil
+	default:
+		return "", fmt.Errorf("invalid scope: %s", s)
+	}
+}
+
+// APIKey is a service API key. Only a hash of the secret is stored, the key itself is shown once.
+type APIKey struct {
+	KeyID       string     `json:"key_id"`
+	TenantID    string     `json:"tenant_id"`
+	Name        string     `json:"name"`
+	KeyHash     string     `json:"-"`
+	Scopes      []Scope    `json:"scopes"`
+	CreatedBy   string     `json:"created_by"`
+	RotatedFrom string     `json:"rotated_from,omitempty"` // the key this one replaced
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"` // set on keys that were rotated, for their grace period
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Active tells whether the key can still authenticate at the time.
+func (k *APIKey) Active(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
+
+// Allows tells whether the key carries the scope, admin keys carry all of them.
+func (k *APIKey) Allows(scope Scope) bool {
+	for _, s := range k.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
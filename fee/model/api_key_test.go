@@ -0,0 +1,76 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestToScope(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    Scope
+		wantErr bool
+	}{
+		{"ValidBillsRead", "bills:read", ScopeBillsRead, false},
+		{"ValidBillsWrite", "bills:write", ScopeBillsWrite, false},
+		{"ValidLineItemsWrite", "line_items:write", ScopeLineItemsWrite, false},
+		{"ValidBillsClose", "bills:close", ScopeBillsClose, false},
+		{"ValidAdmin", "admin", ScopeAdmin, false},
+		{"InvalidScope", "bills:delete", "", true},
+		{"EmptyString", "", "", true},
+		{"Uppercase", "ADMIN", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToScope(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ToScope() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ToScope() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPIKeyAllows(t *testing.T) {
+	reader := &APIKey{Scopes: []Scope{ScopeBillsRead}}
+	if !reader.Allows(ScopeBillsRead) {
+		t.Errorf("Allows(bills:read) = false, want true")
+	}
+	if reader.Allows(ScopeBillsClose) {
+		t.Errorf("Allows(bills:close) = true, want false")
+	}
+
+	admin := &APIKey{Scopes: []Scope{ScopeAdmin}}
+	if !admin.Allows(ScopeLineItemsWrite) {
+		t.Errorf("admin Allows(line_items:write) = false, want true")
+	}
+}
+
+func TestAPIKeyActive(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Minute), now.Add(time.Minute)
+
+	tests := []struct {
+		name string
+		key  APIKey
+		want bool
+	}{
+		{"NoExpiry", APIKey{}, true},
+		{"InGracePeriod", APIKey{ExpiresAt: &future}, true},
+		{"Expired", APIKey{ExpiresAt: &past}, false},
+		{"Revoked", APIKey{RevokedAt: &past}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.key.Active(now); got != tt.want {
+				t.Errorf("Active() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
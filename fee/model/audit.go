@@ -0,0 +1,71 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"encore.app/fee/utils"
+)
+
+// AuditEvent is the kind of financial event an audit entry records.
+type AuditEvent string
+
+const (
+	AuditBillCreated     AuditEvent = "BILL_CREATED"
+	AuditLineItemAdded   AuditEvent = "LINE_ITEM_ADDED"
+	AuditLineItemVoided  AuditEvent = "LINE_ITEM_VOIDED"
+	AuditBillClosed      AuditEvent = "BILL_CLOSED"
+	AuditBillCancelled   AuditEvent = "BILL_CANCELLED"
+	AuditBillReopened    AuditEvent = "BILL_REOPENED"
+	AuditPaymentRecorded AuditEvent = "PAYMENT_RECORDED"
+	AuditCreditIssued    AuditEvent = "CREDIT_ISSUED"
+)
+
+func ToAuditEvent(s string) (AuditEvent, error) {
+	switch e := AuditEvent(s); e {
+	case AuditBillCreated, AuditLineItemAdded, AuditLineItemVoided, AuditBillClosed,
+		AuditBillCancelled, AuditBillReopened, AuditPaymentRecorded, AuditCreditIssued:
+		return e, nil
+	default:
+		return "", fmt.Errorf("invalid audit event: %s", s)
+	}
+}
+
+// AuditSource is who made a change and the API request it came from. Key identifies the
+// attempt to make the change, a retried attempt keeps its key so it is audited once.
+type AuditSource struct {
+	Actor     string
+	RequestID string
+	Key       string
+}
+
+// EntryID derives the ID of the audit entry of an event about subject, a bill or line item ID.
+func (s AuditSource) EntryID(event AuditEvent, subject string) string {
+	return utils.NameUUID(s.Key + "/" + string(event) + "/" + subject)
+}
+
+// AuditEntry is one financial event of a bill, with the values before and after the change.
+// Entries are append-only, they are never updated or deleted.
+type AuditEntry struct {
+	Seq        int64           `json:"seq"` // increases with every entry of the log
+	EntryID    string          `json:"entry_id"`
+	BillID     string          `json:"bill_id"`
+	LineItemID string          `json:"line_item_id,omitempty"`
+	Event      AuditEvent      `json:"event"`
+	Actor      string          `json:"actor"`
+	RequestID  string          `json:"request_id,omitempty"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// AuditFilter narrows an audit search, zero fields match every entry.
+type AuditFilter struct {
+	BillID    string
+	Events    []AuditEvent
+	Actor     string
+	RequestID string
+	From      *time.Time // inclusive
+	To        *time.Time // exclusive
+}
@@ -0,0 +1,65 @@
+package model
+
+import (
+	"testing"
+)
+
+func TestToAuditEvent(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    AuditEvent
+		wantErr bool
+	}{
+		{"ValidBillCreated", "BILL_CREATED", AuditBillCreated, false},
+		{"ValidLineItemAdded", "LINE_ITEM_ADDED", AuditLineItemAdded, false},
+		{"ValidLineItemVoided", "LINE_ITEM_VOIDED", AuditLineItemVoided, false},
+		{"ValidBillClosed", "BILL_CLOSED", AuditBillClosed, false},
+		{"ValidPaymentRecorded", "PAYMENT_RECORDED", AuditPaymentRecorded, false},
+		{"ValidCreditIssued", "CREDIT_ISSUED", AuditCreditIssued, false},
+		{"InvalidEvent", "BILL_DELETED", "", true},
+		{"EmptyString", "", "", true},
+		{"Lowercase", "bill_closed", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToAuditEvent(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ToAuditEvent() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ToAuditEvent() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuditSource_EntryID(t *testing.T) {
+	source := AuditSource{Actor: "key:abc", Key: "bill-1/run-1/5"}
+	retried := AuditSource{Actor: "key:abc", Key: "bill-1/run-1/5"}
+	next := AuditSource{Actor: "key:abc", Key: "bill-1/run-2/5"}
+
+	tests := []struct {
+		name string
+		a, b string
+		same bool
+	}{
+		{"RetrySameEntry", source.EntryID(AuditBillClosed, "bill-1"), retried.EntryID(AuditBillClosed, "bill-1"), true},
+		{"OtherRun", source.EntryID(AuditBillClosed, "bill-1"), next.EntryID(AuditBillClosed, "bill-1"), false},
+		{"OtherEvent", source.EntryID(AuditLineItemAdded, "li-1"), source.EntryID(AuditLineItemVoided, "li-1"), false},
+		{"OtherSubject", source.EntryID(AuditLineItemAdded, "li-1"), source.EntryID(AuditLineItemAdded, "li-2"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if (tt.a == tt.b) != tt.same {
+				t.Errorf("EntryID() %q vs %q, want same = %v", tt.a, tt.b, tt.same)
+			}
+			if len(tt.a) != 32 {
+				t.Errorf("EntryID() = %q, want 32 hex characters", tt.a)
+			}
+		})
+	}
+}
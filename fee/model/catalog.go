@@ -0,0 +1,58 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// PriceType represents how a price is charged.
+type PriceType string
+
+const (
+	PriceTypeOneTime   PriceType = "ONE_TIME"
+	PriceTypeRecurring PriceType = "RECURRING"
+)
+
+func ToPriceType(s string) (PriceType, error) {
+	switch PriceType(s) {
+	case PriceTypeOneTime:
+		return PriceTypeOneTime, nil
+	case PriceTypeRecurring:
+		return PriceTypeRecurring, nil
+	default:
+		return "", fmt.Errorf("invalid PriceType: %s", s)
+	}
+}
+
+type Product struct {
+	ProductID   string    `json:"product_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Active      bool      `json:"active"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Price is a catalog price resolved to a single plan version.
+type Price struct {
+	PriceID           string    `json:"price_id"`
+	ProductID         string    `json:"product_id"`
+	Currency          string    `json:"currency"`
+	Unit              string    `json:"unit"`
+	Type              string    `json:"type"`
+	RecurringInterval string    `json:"recurring_interval"`
+	Active            bool      `json:"active"`
+	Version           int       `json:"version"`
+	UnitAmount        int64     `json:"unit_amount"`
+	EffectiveFrom     time.Time `json:"effective_from"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+type PriceVersion struct {
+	PriceID       string    `json:"price_id"`
+	Version       int       `json:"version"`
+	UnitAmount    int64     `json:"unit_amount"`
+	EffectiveFrom time.Time `json:"effective_from"`
+	CreatedAt     time.Time `json:"created_at"`
+}
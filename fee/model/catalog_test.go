@@ -0,0 +1,33 @@
+package model
+
+import (
+	"testing"
+)
+
+func TestToPriceType(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    PriceType
+		wantErr bool
+	}{
+		{"ValidOneTime", "ONE_TIME", PriceTypeOneTime, false},
+		{"ValidRecurring", "RECURRING", PriceTypeRecurring, false},
+		{"InvalidType", "INVALID", "", true},
+		{"EmptyString", "", "", true},
+		{"Lowercase", "recurring", "", true}, // Should fail, as it expects uppercase
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToPriceType(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ToPriceType() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ToPriceType() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
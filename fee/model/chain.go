@@ -0,0 +1,60 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// ChainRecord is what the bill chain seals when a bill closes: the bill's total and every line
+// item, voided ones included, ordered by line item ID.
+type ChainRecord struct {
+	BillID      string          `json:"bill_id"`
+	Currency    string          `json:"currency"`
+	TotalAmount int64           `json:"total_amount"`
+	ClosedAt    time.Time       `json:"closed_at"`
+	LineItems   []ChainLineItem `json:"line_items"`
+}
+
+type ChainLineItem struct {
+	LineItemID string `json:"line_item_id"`
+	Amount     int64  `json:"amount"`
+	Status     string `json:"status"`
+}
+
+// Content is the canonical encoding of the record that its link hashes. It is stored as text,
+// re-encoding the same record always yields the same content.
+func (r ChainRecord) Content() string {
+	r.ClosedAt = r.ClosedAt.UTC()
+	if r.LineItems == nil {
+		r.LineItems = []ChainLineItem{}
+	}
+	b, _ := json.Marshal(r)
+	return string(b)
+}
+
+// ChainHash is the hash of a link, it covers the previous link's hash so changing or removing
+// a link breaks every link after it. The first link of a chain has an empty previous hash.
+func ChainHash(prevHash, content string) string {
+	sum := sha256.Sum256([]byte(prevHash + "\n" + content))
+	return hex.EncodeToString(sum[:])
+}
+
+// ChainLink is one closed bill in the chain of its tenant.
+type ChainLink struct {
+	Seq        int64
+	BillID     string
+	Content    string
+	PrevHash   string
+	Hash       string
+	Latest     bool       // no later link seals the same bill
+	BillStatus BillStatus // current status of the bill, empty when the bill is gone
+}
+
+// ChainBreak is the first link of a chain that doesn't verify.
+type ChainBreak struct {
+	Seq    int64  `json:"seq"`
+	BillID string `json:"bill_id,omitempty"`
+	Reason string `json:"reason"`
+}
@@ -0,0 +1,73 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestChainRecord_Content(t *testing.T) {
+	closedAt := time.Date(2025, 9, 25, 21, 25, 0, 123456000, time.UTC)
+	record := ChainRecord{
+		BillID:      "bill-1",
+		Currency:    "USD",
+		TotalAmount: 150,
+		ClosedAt:    closedAt,
+		LineItems: []ChainLineItem{
+			{LineItemID: "a", Amount: 100, Status: "ACTIVE"},
+			{LineItemID: "b", Amount: 50, Status: "ACTIVE"},
+			{LineItemID: "c", Amount: 70, Status: "VOIDED"},
+		},
+	}
+	tampered := record
+	tampered.LineItems = append([]ChainLineItem(nil), record.LineItems...)
+	tampered.LineItems[1].Amount = 5
+	otherZone := record
+	otherZone.ClosedAt = closedAt.In(time.FixedZone("UTC+8", 8*60*60))
+
+	tests := []struct {
+		name string
+		a, b ChainRecord
+		same bool
+	}{
+		{"SameRecord", record, record, true},
+		{"SameInstantOtherZone", record, otherZone, true},
+		{"ChangedAmount", record, tampered, false},
+		{"ChangedTotal", record, ChainRecord{BillID: "bill-1", Currency: "USD", TotalAmount: 151, ClosedAt: closedAt, LineItems: record.LineItems}, false},
+		{"NoLineItems", ChainRecord{BillID: "bill-1"}, ChainRecord{BillID: "bill-1", LineItems: []ChainLineItem{}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if (tt.a.Content() == tt.b.Content()) != tt.same {
+				t.Errorf("Content() %s vs %s, want same = %v", tt.a.Content(), tt.b.Content(), tt.same)
+			}
+		})
+	}
+}
+
+func TestChainHash(t *testing.T) {
+	first := ChainHash("", `{"bill_id":"bill-1"}`)
+	second := ChainHash(first, `{"bill_id":"bill-2"}`)
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+		same bool
+	}{
+		{"Deterministic", ChainHash("", `{"bill_id":"bill-1"}`), first, true},
+		{"LinksPrevious", ChainHash(ChainHash("", `{"bill_id":"bill-x"}`), `{"bill_id":"bill-2"}`), second, false},
+		{"CoversContent", ChainHash(first, `{"bill_id":"bill-3"}`), second, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if (tt.got == tt.want) != tt.same {
+				t.Errorf("ChainHash() = %s, want same as %s = %v", tt.got, tt.want, tt.same)
+			}
+			if len(tt.got) != 64 {
+				t.Errorf("ChainHash() = %s, want 64 hex characters", tt.got)
+			}
+		})
+	}
+}
@@ -20,24 +20,97 @@ const (
 type BillStatus string
 
 const (
-	BillStatusOpen    BillStatus = "OPEN"
-	BillStatusClosed  BillStatus = "CLOSED"
-	BillStatusSettled BillStatus = "SETTLED"
+	BillStatusScheduled BillStatus = "SCHEDULED"
+	BillStatusOpen      BillStatus = "OPEN"
+	BillStatusClosed    BillStatus = "CLOSED"
+	BillStatusSettled   BillStatus = "SETTLED"
+	BillStatusCancelled BillStatus = "CANCELLED"
 )
 
 func ToBillStatus(s string) (BillStatus, error) {
 	switch BillStatus(s) {
+	case BillStatusScheduled:
+		return BillStatusScheduled, nil
 	case BillStatusOpen:
 		return BillStatusOpen, nil
 	case BillStatusClosed:
 		return BillStatusClosed, nil
 	case BillStatusSettled:
 		return BillStatusSettled, nil
+	case BillStatusCancelled:
+		return BillStatusCancelled, nil
 	default:
 		return "", fmt.Errorf("invalid BillStatus: %s", s)
 	}
 }
 
+// CloseReason records why a bill was closed.
+type CloseReason string
+
+const (
+	CloseReasonPeriodEnd       CloseReason = "PERIOD_END" // closed by the billing period timer
+	CloseReasonCustomerRequest CloseReason = "CUSTOMER_REQUEST"
+	CloseReasonFraud           CloseReason = "FRAUD"
+	CloseReasonMigration       CloseReason = "MIGRATION"
+	CloseReasonErrorCorrection CloseReason = "ERROR_CORRECTION"
+	CloseReasonOther           CloseReason = "OTHER"
+)
+
+func ToCloseReason(s string) (CloseReason, error) {
+	switch CloseReason(s) {
+	case CloseReasonPeriodEnd:
+		return CloseReasonPeriodEnd, nil
+	case CloseReasonCustomerRequest:
+		return CloseReasonCustomerRequest, nil
+	case CloseReasonFraud:
+		return CloseReasonFraud, nil
+	case CloseReasonMigration:
+		return CloseReasonMigration, nil
+	case CloseReasonErrorCorrection:
+		return CloseReasonErrorCorrection, nil
+	case CloseReasonOther:
+		return CloseReasonOther, nil
+	default:
+		return "", fmt.Errorf("invalid CloseReason: %s", s)
+	}
+}
+
+// CloseTrigger tells whether a bill was closed on request or by its billing period timer.
+type CloseTrigger string
+
+const (
+	CloseTriggerManual CloseTrigger = "MANUAL"
+	CloseTriggerTimer  CloseTrigger = "TIMER"
+)
+
+// BillClosure is persisted on the bill when it is closed.
+type BillClosure struct {
+	Reason  CloseReason  `json:"reason"`
+	Note    string       `json:"note,omitempty"`
+	Actor   string       `json:"actor"`
+	Trigger CloseTrigger `json:"trigger"`
+}
+
+// ScheduledLineItemMode decides what a scheduled bill does with line items
+// received before its billing period starts.
+type ScheduledLineItemMode string
+
+const (
+	ScheduledLineItemsReject ScheduledLineItemMode = "REJECT"
+	ScheduledLineItemsQueue  ScheduledLineItemMode = "QUEUE"
+)
+
+func ToScheduledLineItemMode(s string) (ScheduledLineItemMode, error) {
+	switch ScheduledLineItemMode(s) {
+	case ScheduledLineItemsReject:
+		return ScheduledLineItemsReject, nil
+	case ScheduledLineItemsQueue:
+		return ScheduledLineItemsQueue, nil
+	default:
+		return "", fmt.Errorf("invalid ScheduledLineItemMode: %s", s)
+	}
+}
+
 // LineItemStatus represents the status of a bill.
 type LineItemStatus string
 
@@ -46,6 +119,17 @@ const (
 	LineItemStatusVoided LineItemStatus = "VOIDED"
 )
 
+func ToLineItemStatus(s string) (LineItemStatus, error) {
+	switch LineItemStatus(s) {
+	case LineItemStatusActive:
+		return LineItemStatusActive, nil
+	case LineItemStatusVoided:
+		return LineItemStatusVoided, nil
+	default:
+		return "", fmt.Errorf("invalid LineItemStatus: %s", s)
+	}
+}
+
 // ToCurrency converts a string to a Currency type, validating it against known currencies.
 // It accepts lowercase inputs and converts them to uppercase for validation.
 func ToCurrency(s string) (Currency, error) {
@@ -88,11 +172,85 @@ type Recurring struct {
 	Description string `json:"description"`
 	Amount      int64  `json:"amount"`
 	Interval    string `json:"interval"`
+	PriceID     string `json:"price_id,omitempty"`
 }
 type BillMetadata struct {
 	Recurring *Recurring `json:"recurring,omitempty"`
 }
 
+// BillFilter selects bills in GetBills. Zero fields don't filter, ranges are [from, to).
+type BillFilter struct {
+	Statuses      []BillStatus
+	PolicyType    PolicyType
+	Currency      string
+	CustomerID    string
+	CloseReason   CloseReason
+	CreatedFrom   *time.Time
+	CreatedTo     *time.Time
+	ClosedFrom    *time.Time
+	ClosedTo      *time.Time
+	PeriodEndFrom *time.Time
+	PeriodEndTo   *time.Time
+	MinTotal      *int64 // inclusive, in minor units
+	MaxTotal      *int64 // inclusive, in minor units
+}
+
+// BillSort is the order of GetBills.
+type BillSort string
+
+const (
+	BillSortCreatedAt BillSort = "CREATED_AT"
+	BillSortClosedAt  BillSort = "CLOSED_AT"
+	BillSortTotal     BillSort = "TOTAL"
+)
+
+func ToBillSort(s string) (BillSort, error) {
+	switch BillSort(s) {
+	case BillSortCreatedAt:
+		return BillSortCreatedAt, nil
+	case BillSortClosedAt:
+		return BillSortClosedAt, nil
+	case BillSortTotal:
+		return BillSortTotal, nil
+	default:
+		return "", fmt.Errorf("invalid BillSort: %s", s)
+	}
+}
+
+// BillPage is the order and keyset position of a GetBills page. Sorting by CLOSED_AT only
+// lists bills that have a closed_at.
+type BillPage struct {
+	Sort     BillSort // CREATED_AT when empty
+	Asc      bool
+	Limit    int
+	After    *BillCursor // nil for the first page
+	Backward bool        // the page before After instead of after it, still in the page order
+}
+
+// BillCursor is the sort key and ID of the last bill of the previous page, only the
+// field of the page's sort is used.
+type BillCursor struct {
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	ClosedAt  *time.Time `json:"closed_at,omitempty"`
+	Total     int64      `json:"total,omitempty"`
+	BillID    string     `json:"bill_id"`
+}
+
+// CursorOf returns the cursor that continues a page after the bill.
+func (b *BillDetail) CursorOf(sort BillSort) *BillCursor {
+	c := &BillCursor{BillID: b.BillID}
+	switch sort {
+	case BillSortClosedAt:
+		c.ClosedAt = b.ClosedAt
+	case BillSortTotal:
+		c.Total = b.TotalAmount
+	default:
+		createdAt := b.CreatedAt
+		c.CreatedAt = &createdAt
+	}
+	return c
+}
+
 type Bill struct {
 	BillID      string     `json:"bill_id"`
 	PolicyType  string     `json:"policy_type"`
@@ -105,7 +263,27 @@ type Bill struct {
 }
 
 type LineItemMetadata struct {
-	Description string `json:"description"`
+	Description string     `json:"description"`
+	PriceID     string     `json:"price_id,omitempty"`
+	Quantity    int64      `json:"quantity,omitempty"`
+	UnitAmount  int64      `json:"unit_amount,omitempty"`
+	ExternalRef string     `json:"external_ref,omitempty"`
+	OccurredAt  *time.Time `json:"occurred_at,omitempty"`
+}
+
+// LineItemInput is a line item to be inserted as part of a batch.
+type LineItemInput struct {
+	LineItemID string            `json:"line_item_id"`
+	Amount     int64             `json:"amount"`
+	Metadata   *LineItemMetadata `json:"metadata"`
+}
+
+// AddedLineItem is the outcome of adding a line item. LineItemID is the line item that represents
+// it, the original one when its external_ref was already billed. Inserted is only set when the
+// line item was added by this change, only then does its amount count towards the bill's total.
+type AddedLineItem struct {
+	LineItemID string `json:"line_item_id"`
+	Inserted   bool   `json:"inserted"`
 }
 
 type LineItem struct {
@@ -116,6 +294,20 @@ type LineItem struct {
 	Status     string    `json:"status"`
 }
 
+// LineItemPage is the order and keyset position of a ListLineItems page.
+type LineItemPage struct {
+	Asc      bool
+	Limit    int
+	After    *LineItemCursor // nil for the first page
+	Backward bool            // the page before After instead of after it, still in the page order
+}
+
+// LineItemCursor is the created_at and ID of the last line item of the previous page.
+type LineItemCursor struct {
+	CreatedAt  time.Time `json:"created_at"`
+	LineItemID string    `json:"line_item_id"`
+}
+
 type LineItemSummary struct {
 	Currency string `json:"currency"`
 	Amount   int64  `json:"amount"`
@@ -127,12 +319,17 @@ type TotalSummary struct {
 }
 
 type BillDetail struct {
-	BillID      string     `json:"bill_id"`
-	Status      string     `json:"status"`
-	PolicyType  string     `json:"policy_type"`
-	CreatedAt   time.Time  `json:"created_at"`
-	ClosedAt    *time.Time `json:"closed_at,omitempty"`
-	LineItems   []LineItem `json:"line_items"`
-	Currency    string     `json:"currency"`
-	TotalAmount int64      `json:"total_amount"`
+	BillID      string       `json:"bill_id"`
+	Status      string       `json:"status"`
+	PolicyType  string       `json:"policy_type"`
+	CreatedAt   time.Time    `json:"created_at"`
+	ClosedAt    *time.Time   `json:"closed_at,omitempty"`
+	LineItems   []LineItem   `json:"line_items"`
+	Currency    string       `json:"currency"`
+	TotalAmount int64        `json:"total_amount"`
+	Closure     *BillClosure `json:"closure,omitempty"`
+	CustomerID  string       `json:"customer_id,omitempty"`
+	PeriodStart time.Time    `json:"period_start"`
+	PeriodEnd   *time.Time   `json:"period_end,omitempty"` // unknown for open bills created before it was stored
+	Metadata    BillMetadata `json:"metadata"`
 }
@@ -11,9 +11,11 @@ func TestToBillStatus(t *testing.T) {
 		want    BillStatus
 		wantErr bool
 	}{
+		{"ValidScheduled", "SCHEDULED", BillStatusScheduled, false},
 		{"ValidOpen", "OPEN", BillStatusOpen, false},
 		{"ValidClosed", "CLOSED", BillStatusClosed, false},
 		{"ValidSettled", "SETTLED", BillStatusSettled, false},
+		{"ValidCancelled", "CANCELLED", BillStatusCancelled, false},
 		{"InvalidStatus", "INVALID", "", true},
 		{"EmptyString", "", "", true},
 		{"Lowercase", "open", "", true}, // Should fail, as it expects uppercase
@@ -33,6 +35,120 @@ func TestToBillStatus(t *testing.T) {
 	}
 }
 
+func TestToScheduledLineItemMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    ScheduledLineItemMode
+		wantErr bool
+	}{
+		{"ValidReject", "REJECT", ScheduledLineItemsReject, false},
+		{"ValidQueue", "QUEUE", ScheduledLineItemsQueue, false},
+		{"InvalidMode", "DROP", "", true},
+		{"EmptyString", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToScheduledLineItemMode(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ToScheduledLineItemMode() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ToScheduledLineItemMode() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToCloseReason(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    CloseReason
+		wantErr bool
+	}{
+		{"ValidPeriodEnd", "PERIOD_END", CloseReasonPeriodEnd, false},
+		{"ValidCustomerRequest", "CUSTOMER_REQUEST", CloseReasonCustomerRequest, false},
+		{"ValidFraud", "FRAUD", CloseReasonFraud, false},
+		{"ValidMigration", "MIGRATION", CloseReasonMigration, false},
+		{"ValidErrorCorrection", "ERROR_CORRECTION", CloseReasonErrorCorrection, false},
+		{"ValidOther", "OTHER", CloseReasonOther, false},
+		{"InvalidReason", "BORED", "", true},
+		{"EmptyString", "", "", true},
+		{"Lowercase", "fraud", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToCloseReason(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ToCloseReason() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ToCloseReason() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToLineItemStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    LineItemStatus
+		wantErr bool
+	}{
+		{"ValidActive", "ACTIVE", LineItemStatusActive, false},
+		{"ValidVoided", "VOIDED", LineItemStatusVoided, false},
+		{"InvalidStatus", "DELETED", "", true},
+		{"EmptyString", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToLineItemStatus(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ToLineItemStatus() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ToLineItemStatus() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToBillSort(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    BillSort
+		wantErr bool
+	}{
+		{"ValidCreatedAt", "CREATED_AT", BillSortCreatedAt, false},
+		{"ValidClosedAt", "CLOSED_AT", BillSortClosedAt, false},
+		{"ValidTotal", "TOTAL", BillSortTotal, false},
+		{"InvalidSort", "BILL_ID", "", true},
+		{"EmptyString", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToBillSort(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ToBillSort() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ToBillSort() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestToCurrency(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -0,0 +1,39 @@
+package model
+
+import (
+	"time"
+
+	"encore.app/fee/utils"
+)
+
+// EmailDeliveryStatus is the outcome of an email delivery.
+type EmailDeliveryStatus string
+
+const (
+	EmailDeliverySending EmailDeliveryStatus = "SENDING"
+	EmailDeliverySent    EmailDeliveryStatus = "SENT"
+	EmailDeliveryFailed  EmailDeliveryStatus = "FAILED"  // the mailer refused it, the next attempt sends it again
+	EmailDeliverySkipped EmailDeliveryStatus = "SKIPPED" // there was no one to send it to
+	EmailDeliveryUnknown EmailDeliveryStatus = "UNKNOWN" // an attempt stopped while sending, it isn't sent again
+)
+
+// EmailDeliveryID is the ID of the email sent with template for an invoice, the same for every
+// attempt to send it.
+func EmailDeliveryID(invoiceID, template string) string {
+	return utils.NameUUID(invoiceID + "/" + template)
+}
+
+type EmailDelivery struct {
+	DeliveryID string              `json:"delivery_id"`
+	BillID     string              `json:"bill_id"`
+	InvoiceID  string              `json:"invoice_id"`
+	Template   string              `json:"template"`
+	Recipients []string            `json:"recipients"`
+	Subject    string              `json:"subject"`
+	Status     EmailDeliveryStatus `json:"status"`
+	Attempts   int                 `json:"attempts"`
+	LastError  string              `json:"last_error,omitempty"`
+	CreatedAt  time.Time           `json:"created_at"`
+	UpdatedAt  time.Time           `json:"updated_at"`
+	SentAt     *time.Time          `json:"sent_at,omitempty"`
+}
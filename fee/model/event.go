@@ -0,0 +1,178 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Bill domain events are published to Encore Pub/Sub through the event outbox. Every event type
+// has a schema per major version and a topic per schema: a breaking change adds a -v2 topic that
+// is published next to -v1 until every subscriber moved over. Fields are only ever added to a
+// published schema.
+const (
+	TopicBillCreatedV1       = "bill-created-v1"
+	TopicLineItemAddedV1     = "line-item-added-v1"
+	TopicLineItemVoidedV1    = "line-item-voided-v1"
+	TopicBillClosedV1        = "bill-closed-v1"
+	TopicBillCancelledV1     = "bill-cancelled-v1"
+	TopicBillReopenedV1      = "bill-reopened-v1"
+	TopicBillSettledV1       = "bill-settled-v1"
+	TopicPostProcessFailedV1 = "post-process-failed-v1"
+)
+
+// EventMeta is what every event carries about itself.
+type EventMeta struct {
+	EventID       string    `json:"event_id"` // the ID of the audit entry of the change, if it was audited
+	SchemaVersion int       `json:"schema_version"`
+	TenantID      string    `json:"tenant_id"`
+	BillID        string    `json:"bill_id"`
+	Actor         string    `json:"actor,omitempty"`
+	RequestID     string    `json:"request_id,omitempty"`
+	OccurredAt    time.Time `json:"occurred_at"`
+}
+
+type BillCreatedV1 struct {
+	Meta        EventMeta `json:"meta"`
+	Status      string    `json:"status"`
+	PolicyType  string    `json:"policy_type"`
+	Currency    string    `json:"currency"`
+	CustomerID  string    `json:"customer_id,omitempty"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+}
+
+type LineItemAddedV1 struct {
+	Meta        EventMeta `json:"meta"`
+	LineItemID  string    `json:"line_item_id"`
+	Amount      int64     `json:"amount"`
+	Description string    `json:"description,omitempty"`
+	PriceID     string    `json:"price_id,omitempty"`
+	Quantity    int64     `json:"quantity,omitempty"`
+	ExternalRef string    `json:"external_ref,omitempty"`
+}
+
+type LineItemVoidedV1 struct {
+	Meta       EventMeta `json:"meta"`
+	LineItemID string    `json:"line_item_id"`
+	Amount     int64     `json:"amount"` // the amount taken off the bill
+}
+
+type BillClosedV1 struct {
+	Meta        EventMeta `json:"meta"`
+	TotalAmount int64     `json:"total_amount"`
+	Reason      string    `json:"reason,omitempty"`
+	Trigger     string    `json:"trigger,omitempty"`
+	Note        string    `json:"note,omitempty"`
+}
+
+type BillCancelledV1 struct {
+	Meta    EventMeta `json:"meta"`
+	Reason  string    `json:"reason,omitempty"`
+	Trigger string    `json:"trigger,omitempty"`
+	Note    string    `json:"note,omitempty"`
+}
+
+type BillReopenedV1 struct {
+	Meta          EventMeta `json:"meta"`
+	PreviousTotal int64     `json:"previous_total"` // the total the bill was closed with
+}
+
+type BillSettledV1 struct {
+	Meta      EventMeta `json:"meta"`
+	Amount    int64     `json:"amount"`
+	LinkID    string    `json:"link_id"`
+	PaymentID string    `json:"payment_id"`
+}
+
+type PostProcessFailedV1 struct {
+	Meta  EventMeta `json:"meta"`
+	Error string    `json:"error"`
+}
+
+// OutboxEvent is an event waiting in the outbox to be published to its topic.
+type OutboxEvent struct {
+	Seq     int64 // increases with every event of the outbox, events are published in its order
+	EventID string
+	Topic   string
+	Payload json.RawMessage
+}
+
+// auditValues are the values audit entries record, as far as events carry them.
+type auditValues struct {
+	Status      string            `json:"status"`
+	PolicyType  string            `json:"policy_type"`
+	Currency    string            `json:"currency"`
+	CustomerID  string            `json:"customer_id"`
+	PeriodStart time.Time         `json:"period_start"`
+	PeriodEnd   time.Time         `json:"period_end"`
+	Amount      int64             `json:"amount"`
+	TotalAmount int64             `json:"total_amount"`
+	Metadata    *LineItemMetadata `json:"metadata"`
+	Closure     *BillClosure      `json:"closure"`
+	LinkID      string            `json:"link_id"`
+	PaymentID   string            `json:"payment_id"`
+}
+
+// OutboxEventsOf returns the events of an audit entry of the tenant, made at the time at. Their
+// event ID is the entry's, so an entry that is written once is published once.
+func OutboxEventsOf(tenantID string, entry AuditEntry, at time.Time) ([]OutboxEvent, error) {
+	var before, after auditValues
+	if len(entry.Before) > 0 {
+		if err := json.Unmarshal(entry.Before, &before); err != nil {
+			return nil, err
+		}
+	}
+	if len(entry.After) > 0 {
+		if err := json.Unmarshal(entry.After, &after); err != nil {
+			return nil, err
+		}
+	}
+	meta := EventMeta{
+		EventID:       entry.EntryID,
+		SchemaVersion: 1,
+		TenantID:      tenantID,
+		BillID:        entry.BillID,
+		Actor:         entry.Actor,
+		RequestID:     entry.RequestID,
+		OccurredAt:    at,
+	}
+
+	var topic string
+	var payload any
+	switch entry.Event {
+	case AuditBillCreated:
+		topic, payload = TopicBillCreatedV1, BillCreatedV1{Meta: meta, Status: after.Status, PolicyType: after.PolicyType,
+			Currency: after.Currency, CustomerID: after.CustomerID, PeriodStart: after.PeriodStart, PeriodEnd: after.PeriodEnd}
+	case AuditLineItemAdded:
+		event := LineItemAddedV1{Meta: meta, LineItemID: entry.LineItemID, Amount: after.Amount}
+		if m := after.Metadata; m != nil {
+			event.Description, event.PriceID, event.Quantity, event.ExternalRef = m.Description, m.PriceID, m.Quantity, m.ExternalRef
+		}
+		topic, payload = TopicLineItemAddedV1, event
+	case AuditLineItemVoided:
+		topic, payload = TopicLineItemVoidedV1, LineItemVoidedV1{Meta: meta, LineItemID: entry.LineItemID, Amount: before.Amount}
+	case AuditBillClosed:
+		event := BillClosedV1{Meta: meta, TotalAmount: after.TotalAmount}
+		if c := after.Closure; c != nil {
+			event.Reason, event.Trigger, event.Note = string(c.Reason), string(c.Trigger), c.Note
+		}
+		topic, payload = TopicBillClosedV1, event
+	case AuditBillCancelled:
+		event := BillCancelledV1{Meta: meta}
+		if c := after.Closure; c != nil {
+			event.Reason, event.Trigger, event.Note = string(c.Reason), string(c.Trigger), c.Note
+		}
+		topic, payload = TopicBillCancelledV1, event
+	case AuditBillReopened:
+		topic, payload = TopicBillReopenedV1, BillReopenedV1{Meta: meta, PreviousTotal: before.TotalAmount}
+	case AuditPaymentRecorded:
+		topic, payload = TopicBillSettledV1, BillSettledV1{Meta: meta, Amount: after.Amount, LinkID: after.LinkID, PaymentID: after.PaymentID}
+	default:
+		return nil, nil
+	}
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return []OutboxEvent{{EventID: entry.EntryID, Topic: topic, Payload: b}}, nil
+}
@@ -0,0 +1,86 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestOutboxEventsOf(t *testing.T) {
+	at := time.Date(2025, 9, 30, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		event     AuditEvent
+		before    string
+		after     string
+		wantTopic string
+		want      string // fields of the payload besides meta
+	}{
+		{"BillCreated", AuditBillCreated, ``,
+			`{"status":"OPEN","policy_type":"USAGE_BASED","currency":"USD","customer_id":"cus-1","period_start":"2025-09-01T00:00:00Z","period_end":"2025-10-01T00:00:00Z","metadata":{}}`,
+			TopicBillCreatedV1, `{"status":"OPEN","policy_type":"USAGE_BASED","currency":"USD","customer_id":"cus-1","period_start":"2025-09-01T00:00:00Z","period_end":"2025-10-01T00:00:00Z"}`},
+		{"LineItemAdded", AuditLineItemAdded, ``,
+			`{"status":"ACTIVE","amount":1500,"metadata":{"description":"API calls","price_id":"price-1","quantity":3}}`,
+			TopicLineItemAddedV1, `{"line_item_id":"li-1","amount":1500,"description":"API calls","price_id":"price-1","quantity":3}`},
+		{"LineItemVoided", AuditLineItemVoided, `{"status":"ACTIVE","amount":1500}`, `{"status":"VOIDED"}`,
+			TopicLineItemVoidedV1, `{"line_item_id":"li-1","amount":1500}`},
+		{"BillClosed", AuditBillClosed, `{"status":"OPEN","total_amount":0}`,
+			`{"status":"CLOSED","total_amount":4500,"closure":{"reason":"PERIOD_END","actor":"system","trigger":"TIMER"}}`,
+			TopicBillClosedV1, `{"total_amount":4500,"reason":"PERIOD_END","trigger":"TIMER"}`},
+		{"BillCancelled", AuditBillCancelled, `{"status":"OPEN"}`,
+			`{"status":"CANCELLED","closure":{"reason":"FRAUD","note":"chargeback","actor":"key:1","trigger":"API"}}`,
+			TopicBillCancelledV1, `{"reason":"FRAUD","trigger":"API","note":"chargeback"}`},
+		{"BillReopened", AuditBillReopened, `{"status":"CLOSED","total_amount":4500,"closure":null}`, `{"status":"OPEN"}`,
+			TopicBillReopenedV1, `{"previous_total":4500}`},
+		{"PaymentRecorded", AuditPaymentRecorded, `{"status":"CLOSED"}`,
+			`{"status":"SETTLED","amount":4500,"link_id":"link-1","payment_id":"pay-1"}`,
+			TopicBillSettledV1, `{"amount":4500,"link_id":"link-1","payment_id":"pay-1"}`},
+		{"CreditIssued", AuditCreditIssued, ``, `{}`, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := AuditEntry{EntryID: "e-1", BillID: "bill-1", Event: tt.event, Actor: "key:1", RequestID: "req-1",
+				Before: json.RawMessage(tt.before), After: json.RawMessage(tt.after)}
+			if tt.event == AuditLineItemAdded || tt.event == AuditLineItemVoided {
+				entry.LineItemID = "li-1"
+			}
+			got, err := OutboxEventsOf("acme", entry, at)
+			if err != nil {
+				t.Fatalf("OutboxEventsOf() error = %v", err)
+			}
+			if tt.wantTopic == "" {
+				if len(got) != 0 {
+					t.Errorf("OutboxEventsOf() got = %+v, want no events", got)
+				}
+				return
+			}
+			if len(got) != 1 || got[0].Topic != tt.wantTopic || got[0].EventID != "e-1" {
+				t.Fatalf("OutboxEventsOf() got = %+v, want one %s event", got, tt.wantTopic)
+			}
+
+			var payload map[string]json.RawMessage
+			if err := json.Unmarshal(got[0].Payload, &payload); err != nil {
+				t.Fatal(err)
+			}
+			var meta EventMeta
+			if err := json.Unmarshal(payload["meta"], &meta); err != nil {
+				t.Fatal(err)
+			}
+			wantMeta := EventMeta{EventID: "e-1", SchemaVersion: 1, TenantID: "acme", BillID: "bill-1", Actor: "key:1", RequestID: "req-1", OccurredAt: at}
+			if meta != wantMeta {
+				t.Errorf("meta got = %+v, want %+v", meta, wantMeta)
+			}
+			delete(payload, "meta")
+			var want map[string]json.RawMessage
+			if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			gotFields, _ := json.Marshal(payload)
+			wantFields, _ := json.Marshal(want)
+			if string(gotFields) != string(wantFields) {
+				t.Errorf("payload got = %s, want %s", gotFields, wantFields)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// FailedLineItemSource tells which path produced a failed line item.
+type FailedLineItemSource string
+
+const (
+	FailedLineItemSourceSignal    FailedLineItemSource = "SIGNAL"
+	FailedLineItemSourceRecurring FailedLineItemSource = "RECURRING"
+)
+
+// FailedLineItemStatus represents the resolution of a failed line item.
+type FailedLineItemStatus string
+
+const (
+	FailedLineItemPending   FailedLineItemStatus = "PENDING"
+	FailedLineItemRetried   FailedLineItemStatus = "RETRIED"
+	FailedLineItemDiscarded FailedLineItemStatus = "DISCARDED"
+)
+
+func ToFailedLineItemStatus(s string) (FailedLineItemStatus, error) {
+	switch s {
+	case string(FailedLineItemPending):
+		return FailedLineItemPending, nil
+	case string(FailedLineItemRetried):
+		return FailedLineItemRetried, nil
+	case string(FailedLineItemDiscarded):
+		return FailedLineItemDiscarded, nil
+	default:
+		return "", fmt.Errorf("invalid failed line item status: %s", s)
+	}
+}
+
+type FailedLineItem struct {
+	LineItemID string     `json:"line_item_id"`
+	BillID     string     `json:"bill_id"`
+	Source     string     `json:"source"`
+	Amount     int64      `json:"amount"`
+	Payload    string     `json:"payload"` // the original signal, as JSON
+	Error      string     `json:"error"`
+	Status     string     `json:"status"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	ResolvedAt *time.Time `json:"resolved_at"`
+}
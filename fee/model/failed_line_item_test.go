@@ -0,0 +1,34 @@
+package model
+
+import (
+	"testing"
+)
+
+func TestToFailedLineItemStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    FailedLineItemStatus
+		wantErr bool
+	}{
+		{"ValidPending", "PENDING", FailedLineItemPending, false},
+		{"ValidRetried", "RETRIED", FailedLineItemRetried, false},
+		{"ValidDiscarded", "DISCARDED", FailedLineItemDiscarded, false},
+		{"InvalidStatus", "FAILED", "", true},
+		{"EmptyString", "", "", true},
+		{"Lowercase", "pending", "", true}, // Should fail, as it expects uppercase
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToFailedLineItemStatus(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ToFailedLineItemStatus() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ToFailedLineItemStatus() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
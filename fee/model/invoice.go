@@ -0,0 +1,227 @@
+package model
+
+import (
+	"bytes"
+	"fmt"
+	"image/jpeg"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultInvoicePrefix       = "INV-"
+	DefaultPaymentTermsDays    = 30
+	maxInvoicePrefixLength     = 16
+	maxPaymentTermsDays        = 365
+	invoiceNumberMinimumDigits = 6
+	maxLogoSize                = 256 << 10
+	maxBillingEmails           = 10
+)
+
+// Locales invoices are rendered in.
+const (
+	LocaleEN = "en"
+	LocaleDE = "de"
+	LocaleFR = "fr"
+)
+
+func ToLocale(s string) (string, error) {
+	switch s {
+	case LocaleEN, LocaleDE, LocaleFR:
+		return s, nil
+	default:
+		return "", fmt.Errorf("invalid locale: %s", s)
+	}
+}
+
+// FormatLocalizedAmount converts an amount in cents to the way the locale writes it, eg 1,234.56
+// in en, 1.234,56 in de and 1 234,56 in fr.
+func FormatLocalizedAmount(amount int64, locale string) string {
+	group, point := ",", "."
+	switch locale {
+	case LocaleDE:
+		group, point = ".", ","
+	case LocaleFR:
+		group, point = "\u00a0", ","
+	}
+	s := FormatAmount(amount)
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	whole, fraction, _ := strings.Cut(s, ".")
+	var sb strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			sb.WriteString(group)
+		}
+		sb.WriteRune(digit)
+	}
+	return sign + sb.String() + point + fraction
+}
+
+// Party is the issuer or the customer named on an invoice.
+type Party struct {
+	ID      string `json:"id,omitempty"` // the customer ID, empty for the issuer
+	Name    string `json:"name"`
+	Email   string `json:"email,omitempty"`
+	Address string `json:"address,omitempty"`
+	TaxID   string `json:"tax_id,omitempty"`
+}
+
+// Customer holds the billing details of a customer, bills refer to it by customer_id.
+type Customer struct {
+	CustomerID string `json:"customer_id"`
+	Name       string `json:"name"`
+	Email      string `json:"email,omitempty"`
+	Address    string `json:"address,omitempty"`
+	TaxID      string `json:"tax_id,omitempty"`
+	// BillingEmails receive the invoice emails, Email does when there are none.
+	BillingEmails []string  `json:"billing_emails"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+func (c *Customer) Party() Party {
+	return Party{ID: c.CustomerID, Name: c.Name, Email: c.Email, Address: c.Address, TaxID: c.TaxID}
+}
+
+// Validate checks the customer's name and email addresses.
+func (c *Customer) Validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("name is a required field")
+	}
+	if c.Email != "" {
+		if _, err := mail.ParseAddress(c.Email); err != nil {
+			return fmt.Errorf("invalid email: %s", c.Email)
+		}
+	}
+	if len(c.BillingEmails) > maxBillingEmails {
+		return fmt.Errorf("billing_emails must have at most %d addresses", maxBillingEmails)
+	}
+	for _, email := range c.BillingEmails {
+		if _, err := mail.ParseAddress(email); err != nil {
+			return fmt.Errorf("invalid billing email: %s", email)
+		}
+	}
+	return nil
+}
+
+// Recipients are the addresses the customer's invoice emails go to.
+func (c *Customer) Recipients() []string {
+	if len(c.BillingEmails) > 0 {
+		return c.BillingEmails
+	}
+	if c.Email != "" {
+		return []string{c.Email}
+	}
+	return nil
+}
+
+// InvoiceIssuer is the tenant's own details printed on its invoices, and how it numbers them.
+type InvoiceIssuer struct {
+	Name             string `json:"name"`
+	Email            string `json:"email,omitempty"`
+	Address          string `json:"address,omitempty"`
+	TaxID            string `json:"tax_id,omitempty"`
+	NumberPrefix     string `json:"number_prefix"`
+	PaymentTermsDays int    `json:"payment_terms_days"` // the due date is this many days after issue
+
+	// The template of the invoice PDF.
+	Locale              string    `json:"locale"`                         // of labels, amounts and dates
+	PaymentInstructions string    `json:"payment_instructions,omitempty"` // printed below the totals, eg bank details
+	Logo                []byte    `json:"logo,omitempty"`                 // a JPEG, base64 encoded in JSON
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+func (i *InvoiceIssuer) Party() Party {
+	return Party{Name: i.Name, Email: i.Email, Address: i.Address, TaxID: i.TaxID}
+}
+
+// Validate checks the issuer and fills in the default prefix, payment terms and locale.
+func (i *InvoiceIssuer) Validate() error {
+	if i.Name == "" {
+		return fmt.Errorf("name is a required field")
+	}
+	if i.NumberPrefix == "" {
+		i.NumberPrefix = DefaultInvoicePrefix
+	}
+	if len(i.NumberPrefix) > maxInvoicePrefixLength {
+		return fmt.Errorf("number_prefix must be at most %d characters", maxInvoicePrefixLength)
+	}
+	if i.PaymentTermsDays == 0 {
+		i.PaymentTermsDays = DefaultPaymentTermsDays
+	}
+	if i.PaymentTermsDays < 0 || i.PaymentTermsDays > maxPaymentTermsDays {
+		return fmt.Errorf("payment_terms_days must be between 1 and %d", maxPaymentTermsDays)
+	}
+	if i.Locale == "" {
+		i.Locale = LocaleEN
+	}
+	if _, err := ToLocale(i.Locale); err != nil {
+		return err
+	}
+	if len(i.Logo) > maxLogoSize {
+		return fmt.Errorf("logo must be at most %d KiB", maxLogoSize>>10)
+	}
+	if len(i.Logo) > 0 {
+		if _, err := jpeg.DecodeConfig(bytes.NewReader(i.Logo)); err != nil {
+			return fmt.Errorf("logo must be a JPEG")
+		}
+	}
+	return nil
+}
+
+// FormatInvoiceNumber renders an invoice number with the issuer's prefix, eg INV-000042.
+func FormatInvoiceNumber(prefix string, n int64) string {
+	return fmt.Sprintf("%s%0*d", prefix, invoiceNumberMinimumDigits, n)
+}
+
+// InvoiceLineItem is a charged line item as it was when the invoice was issued.
+type InvoiceLineItem struct {
+	LineItemID  string    `json:"line_item_id"`
+	Description string    `json:"description"`
+	Amount      int64     `json:"amount"`
+	PriceID     string    `json:"price_id,omitempty"`
+	Quantity    int64     `json:"quantity,omitempty"`
+	UnitAmount  int64     `json:"unit_amount,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// InvoiceSnapshot is the bill as it was when the invoice was issued, voided line items left out.
+type InvoiceSnapshot struct {
+	BillID      string            `json:"bill_id"`
+	PolicyType  string            `json:"policy_type"`
+	Currency    string            `json:"currency"`
+	PeriodStart time.Time         `json:"period_start"`
+	PeriodEnd   *time.Time        `json:"period_end,omitempty"`
+	ClosedAt    time.Time         `json:"closed_at"`
+	TotalAmount int64             `json:"total_amount"`
+	LineItems   []InvoiceLineItem `json:"line_items"`
+}
+
+// Invoice is issued when a bill with charges closes. It is never changed afterwards, a bill that
+// is reopened and closed again gets a new invoice.
+type Invoice struct {
+	InvoiceID     string          `json:"invoice_id"`
+	InvoiceNumber int64           `json:"invoice_number"` // gap-free per tenant
+	Number        string          `json:"number"`         // invoice_number with the issuer's prefix
+	BillID        string          `json:"bill_id"`
+	Currency      string          `json:"currency"`
+	TotalAmount   int64           `json:"total_amount"`
+	DisplayAmount string          `json:"display_amount"`
+	IssuedAt      time.Time       `json:"issued_at"`
+	DueAt         time.Time       `json:"due_at"`
+	Issuer        Party           `json:"issuer"`
+	Customer      *Party          `json:"customer,omitempty"`
+	Snapshot      InvoiceSnapshot `json:"snapshot"`
+	CreatedAt     time.Time       `json:"created_at"`
+	// An invoice is voided when its bill is reopened, the next invoice of the bill supersedes it.
+	VoidedAt            *time.Time `json:"voided_at,omitempty"`
+	SupersedesInvoiceID string     `json:"supersedes_invoice_id,omitempty"`
+	SupersededBy        string     `json:"superseded_by,omitempty"`
+}
+
+// InvoiceVoidBillReopened is why an invoice is voided when its bill is reopened.
+const InvoiceVoidBillReopened = "BILL_REOPENED"
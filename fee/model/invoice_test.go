@@ -0,0 +1,143 @@
+package model
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"testing"
+)
+
+func TestFormatInvoiceNumber(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix string
+		number int64
+		want   string
+	}{
+		{"Padded", "INV-", 42, "INV-000042"},
+		{"NoPrefix", "", 7, "000007"},
+		{"Wide", "2025/", 1234567, "2025/1234567"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatInvoiceNumber(tt.prefix, tt.number); got != tt.want {
+				t.Errorf("FormatInvoiceNumber() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInvoiceIssuer_Validate(t *testing.T) {
+	tests := []struct {
+		name       string
+		issuer     InvoiceIssuer
+		wantPrefix string
+		wantTerms  int
+		wantErr    bool
+	}{
+		{"Defaults", InvoiceIssuer{Name: "Acme"}, DefaultInvoicePrefix, DefaultPaymentTermsDays, false},
+		{"Custom", InvoiceIssuer{Name: "Acme", NumberPrefix: "AC-", PaymentTermsDays: 14}, "AC-", 14, false},
+		{"MissingName", InvoiceIssuer{}, "", 0, true},
+		{"LongPrefix", InvoiceIssuer{Name: "Acme", NumberPrefix: "ABCDEFGHIJKLMNOPQ"}, "", 0, true},
+		{"NegativeTerms", InvoiceIssuer{Name: "Acme", PaymentTermsDays: -1}, "", 0, true},
+		{"TermsTooLong", InvoiceIssuer{Name: "Acme", PaymentTermsDays: 366}, "", 0, true},
+		{"UnknownLocale", InvoiceIssuer{Name: "Acme", Locale: "xx"}, "", 0, true},
+		{"LogoNotJPEG", InvoiceIssuer{Name: "Acme", Logo: []byte("GIF89a")}, "", 0, true},
+		{"LogoTooLarge", InvoiceIssuer{Name: "Acme", Logo: make([]byte, maxLogoSize+1)}, "", 0, true},
+		{"Logo", InvoiceIssuer{Name: "Acme", Locale: LocaleDE, Logo: testJPEG(t)}, DefaultInvoicePrefix, DefaultPaymentTermsDays, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issuer := tt.issuer
+			err := issuer.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if issuer.NumberPrefix != tt.wantPrefix || issuer.PaymentTermsDays != tt.wantTerms {
+				t.Errorf("Validate() got = %q/%d, want %q/%d", issuer.NumberPrefix, issuer.PaymentTermsDays, tt.wantPrefix, tt.wantTerms)
+			}
+		})
+	}
+}
+
+func testJPEG(t *testing.T) []byte {
+	var b bytes.Buffer
+	if err := jpeg.Encode(&b, image.NewGray(image.Rect(0, 0, 4, 4)), nil); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestFormatLocalizedAmount(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount int64
+		locale string
+		want   string
+	}{
+		{"English", 123456789, LocaleEN, "1,234,567.89"},
+		{"German", 123456789, LocaleDE, "1.234.567,89"},
+		{"French", 123456, LocaleFR, "1\u00a0234,56"},
+		{"Small", 5, LocaleEN, "0.05"},
+		{"ThreeDigits", 99900, LocaleDE, "999,00"},
+		{"Negative", -123456, LocaleEN, "-1,234.56"},
+		{"UnknownLocale", 123456, "", "1,234.56"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatLocalizedAmount(tt.amount, tt.locale); got != tt.want {
+				t.Errorf("FormatLocalizedAmount() got = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCustomer_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		customer Customer
+		wantErr  bool
+	}{
+		{"Valid", Customer{Name: "Globex", Email: "ap@globex.example", BillingEmails: []string{"a@globex.example", "Billing <b@globex.example>"}}, false},
+		{"MissingName", Customer{Email: "ap@globex.example"}, true},
+		{"InvalidEmail", Customer{Name: "Globex", Email: "not-an-email"}, true},
+		{"InvalidBillingEmail", Customer{Name: "Globex", BillingEmails: []string{"a@globex.example", "nope"}}, true},
+		{"TooManyBillingEmails", Customer{Name: "Globex", BillingEmails: make([]string, maxBillingEmails+1)}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.customer.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCustomer_Recipients(t *testing.T) {
+	tests := []struct {
+		name     string
+		customer Customer
+		want     []string
+	}{
+		{"BillingEmails", Customer{Email: "ap@globex.example", BillingEmails: []string{"a@globex.example"}}, []string{"a@globex.example"}},
+		{"Email", Customer{Email: "ap@globex.example"}, []string{"ap@globex.example"}},
+		{"None", Customer{}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.customer.Recipients()
+			if len(got) != len(tt.want) || (len(got) > 0 && got[0] != tt.want[0]) {
+				t.Errorf("Recipients() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"encore.app/fee/utils"
+)
+
+// PaymentLinkStatus is the state of a payment link.
+type PaymentLinkStatus string
+
+const (
+	PaymentLinkPending   PaymentLinkStatus = "PENDING"
+	PaymentLinkPaid      PaymentLinkStatus = "PAID"
+	PaymentLinkExpired   PaymentLinkStatus = "EXPIRED"
+	PaymentLinkCancelled PaymentLinkStatus = "CANCELLED" // the bill was reopened before it was paid
+)
+
+func ToPaymentLinkStatus(s string) (PaymentLinkStatus, error) {
+	switch st := PaymentLinkStatus(s); st {
+	case PaymentLinkPending, PaymentLinkPaid, PaymentLinkExpired, PaymentLinkCancelled:
+		return st, nil
+	default:
+		return "", fmt.Errorf("invalid PaymentLinkStatus: %s", s)
+	}
+}
+
+// PaymentLinkID is the ID of the payment link of an invoice, the same for every attempt to create it.
+func PaymentLinkID(invoiceID string) string {
+	return utils.NameUUID(invoiceID + "/payment_link")
+}
+
+// PaymentLink is a link a payment provider created to pay an invoice of a bill.
+type PaymentLink struct {
+	TenantID       string            `json:"-"`
+	LinkID         string            `json:"link_id"`
+	BillID         string            `json:"bill_id"`
+	InvoiceID      string            `json:"invoice_id"`
+	Provider       string            `json:"provider"`
+	ProviderLinkID string            `json:"provider_link_id"`
+	URL            string            `json:"url"`
+	Amount         int64             `json:"amount"`
+	Currency       string            `json:"currency"`
+	Status         PaymentLinkStatus `json:"status"`
+	ExpiresAt      time.Time         `json:"expires_at"`
+	PaymentID      string            `json:"payment_id,omitempty"` // the provider's ID of the payment
+	PaidAt         *time.Time        `json:"paid_at,omitempty"`
+	CreatedAt      time.Time         `json:"created_at"`
+}
+
+// UnappliedPayment is a payment of a link whose bill couldn't be settled any more when it was
+// reported, e.g. because the bill was reopened. It is kept to be refunded or applied by hand.
+type UnappliedPayment struct {
+	TenantID  string `json:"-"`
+	Provider  string `json:"provider"`
+	PaymentID string `json:"payment_id"`
+	LinkID    string `json:"link_id"`
+	BillID    string `json:"bill_id"`
+	Amount    int64  `json:"amount"`
+	Currency  string `json:"currency"`
+}
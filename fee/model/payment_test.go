@@ -0,0 +1,43 @@
+package model
+
+import (
+	"testing"
+)
+
+func TestToPaymentLinkStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    PaymentLinkStatus
+		wantErr bool
+	}{
+		{"ValidPending", "PENDING", PaymentLinkPending, false},
+		{"ValidPaid", "PAID", PaymentLinkPaid, false},
+		{"ValidExpired", "EXPIRED", PaymentLinkExpired, false},
+		{"ValidCancelled", "CANCELLED", PaymentLinkCancelled, false},
+		{"InvalidStatus", "REFUNDED", "", true},
+		{"Lowercase", "paid", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToPaymentLinkStatus(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ToPaymentLinkStatus() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ToPaymentLinkStatus() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPaymentLinkID(t *testing.T) {
+	if PaymentLinkID("inv-1") != PaymentLinkID("inv-1") {
+		t.Errorf("PaymentLinkID() is not stable")
+	}
+	if PaymentLinkID("inv-1") == PaymentLinkID("inv-2") {
+		t.Errorf("PaymentLinkID() is the same for different invoices")
+	}
+}
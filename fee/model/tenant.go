@@ -0,0 +1,20 @@
+package model
+
+import "fmt"
+
+// DefaultTenant owns the rows written before tenants existed and is the tenant of the bootstrap key.
+const DefaultTenant = "default"
+
+// ValidateTenantID checks a tenant ID. Tenant IDs end up in workflow IDs, so they are kept to
+// lowercase letters, digits, '-' and '_'.
+func ValidateTenantID(tenantID string) error {
+	if tenantID == "" || len(tenantID) > 64 {
+		return fmt.Errorf("tenant_id must be between 1 and 64 characters")
+	}
+	for _, r := range tenantID {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' && r != '_' {
+			return fmt.Errorf("tenant_id may only contain lowercase letters, digits, '-' and '_'")
+		}
+	}
+	return nil
+}
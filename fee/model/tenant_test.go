@@ -0,0 +1,28 @@
+package model
+
+import "testing"
+
+func TestValidateTenantID(t *testing.T) {
+	tests := []struct {
+		name     string
+		tenantID string
+		wantErr  bool
+	}{
+		{"Default", DefaultTenant, false},
+		{"LettersDigitsAndSeparators", "payments-team_2", false},
+		{"Empty", "", true},
+		{"TooLong", "abcdefghijklmnopqrstuvwxyzabcdefghijklmnopqrstuvwxyzabcdefghijklm", true},
+		{"Uppercase", "Payments", true},
+		{"Slash", "payments/eu", true},
+		{"Space", "payments eu", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTenantID(tt.tenantID)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateTenantID(%q) error = %v, wantErr %v", tt.tenantID, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package model
+
+import "time"
+
+// UsageImportStatus represents the status of a CSV usage import.
+type UsageImportStatus string
+
+const (
+	UsageImportStatusPending   UsageImportStatus = "PENDING"
+	UsageImportStatusRunning   UsageImportStatus = "RUNNING"
+	UsageImportStatusCompleted UsageImportStatus = "COMPLETED"
+)
+
+// UsageImportRowStatus represents the status of a single row of a CSV usage import.
+type UsageImportRowStatus string
+
+const (
+	UsageImportRowPending   UsageImportRowStatus = "PENDING"
+	UsageImportRowSubmitted UsageImportRowStatus = "SUBMITTED"
+	UsageImportRowFailed    UsageImportRowStatus = "FAILED"
+)
+
+type UsageImport struct {
+	ImportID      string     `json:"import_id"`
+	FileHash      string     `json:"file_hash"`
+	Status        string     `json:"status"`
+	TotalRows     int        `json:"total_rows"`
+	ProcessedRows int        `json:"processed_rows"`
+	FailedRows    int        `json:"failed_rows"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	CompletedAt   *time.Time `json:"completed_at"`
+}
+
+type UsageImportRow struct {
+	RowNumber   int        `json:"row_number"`
+	BillID      string     `json:"bill_id"`
+	Amount      int64      `json:"amount"`
+	Description string     `json:"description"`
+	ExternalRef string     `json:"external_ref"`
+	OccurredAt  *time.Time `json:"occurred_at"`
+	LineItemID  string     `json:"line_item_id"`
+	Status      string     `json:"status"`
+	Error       string     `json:"error"`
+}
+
+// UsageImportRowResult is the outcome of handing one import row to its bill workflow.
+type UsageImportRowResult struct {
+	RowNumber int    `json:"row_number"`
+	Error     string `json:"error"` // empty when the row was submitted
+}
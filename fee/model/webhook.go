@@ -0,0 +1,192 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// WebhookEventType is a kind of bill lifecycle event sent to webhook subscriptions.
+type WebhookEventType string
+
+const (
+	WebhookBillCreated       WebhookEventType = "bill.created"
+	WebhookLineItemAdded     WebhookEventType = "line_item.added"
+	WebhookLineItemVoided    WebhookEventType = "line_item.voided"
+	WebhookBillClosed        WebhookEventType = "bill.closed"
+	WebhookBillSettled       WebhookEventType = "bill.settled"
+	WebhookPostProcessFailed WebhookEventType = "post_process.failed"
+)
+
+func ToWebhookEventType(s string) (WebhookEventType, error) {
+	switch t := WebhookEventType(s); t {
+	case WebhookBillCreated, WebhookLineItemAdded, WebhookLineItemVoided, WebhookBillClosed, WebhookBillSettled,
+		WebhookPostProcessFailed:
+		return t, nil
+	default:
+		return "", fmt.Errorf("invalid webhook event type: %s", s)
+	}
+}
+
+// auditWebhookEvents are the audited events that are also sent to webhooks.
+var auditWebhookEvents = map[AuditEvent]WebhookEventType{
+	AuditBillCreated:     WebhookBillCreated,
+	AuditLineItemAdded:   WebhookLineItemAdded,
+	AuditLineItemVoided:  WebhookLineItemVoided,
+	AuditBillClosed:      WebhookBillClosed,
+	AuditPaymentRecorded: WebhookBillSettled,
+}
+
+// WebhookEventOf returns the webhook event of an audit entry, false if the entry isn't sent to
+// webhooks. The event shares the entry's ID and carries its values after the change.
+func WebhookEventOf(entry AuditEntry) (*WebhookEvent, bool) {
+	eventType, ok := auditWebhookEvents[entry.Event]
+	if !ok {
+		return nil, false
+	}
+	return &WebhookEvent{
+		EventID:    entry.EntryID,
+		Type:       eventType,
+		BillID:     entry.BillID,
+		LineItemID: entry.LineItemID,
+		Data:       entry.After,
+	}, true
+}
+
+// WebhookEvent is the body of a webhook delivery.
+type WebhookEvent struct {
+	EventID    string           `json:"id"`
+	Type       WebhookEventType `json:"type"`
+	BillID     string           `json:"bill_id"`
+	LineItemID string           `json:"line_item_id,omitempty"`
+	Data       json.RawMessage  `json:"data,omitempty"`
+	CreatedAt  time.Time        `json:"created_at"`
+}
+
+const (
+	maxWebhookURLLength         = 2048
+	maxWebhookDescriptionLength = 256
+)
+
+// WebhookSubscription is an endpoint of a tenant that receives its bill lifecycle events. The
+// secret signs the deliveries, it is only shown when the subscription is created.
+type WebhookSubscription struct {
+	SubscriptionID string             `json:"subscription_id"`
+	URL            string             `json:"url"`
+	EventTypes     []WebhookEventType `json:"event_types"` // every event type when empty
+	Description    string             `json:"description,omitempty"`
+	Secret         string             `json:"secret,omitempty"`
+	Active         bool               `json:"active"`
+	CreatedAt      time.Time          `json:"created_at"`
+	UpdatedAt      time.Time          `json:"updated_at"`
+}
+
+// Validate checks the subscription. Deliveries are posted from inside the network, so the url
+// must not name a host there; plain http is only accepted when allowHTTP is set, for development.
+func (s *WebhookSubscription) Validate(allowHTTP bool) error {
+	if s.URL == "" {
+		return fmt.Errorf("url is a required field")
+	}
+	u, err := url.Parse(s.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Hostname() == "" || len(s.URL) > maxWebhookURLLength {
+		return fmt.Errorf("url must be an absolute http or https url")
+	}
+	if u.Scheme != "https" && !allowHTTP {
+		return fmt.Errorf("url must be an https url")
+	}
+	if !WebhookHostAllowed(u.Hostname()) {
+		return fmt.Errorf("url must not point to a loopback, private, link-local or metadata address")
+	}
+	for _, t := range s.EventTypes {
+		if _, err := ToWebhookEventType(string(t)); err != nil {
+			return err
+		}
+	}
+	if len(s.Description) > maxWebhookDescriptionLength {
+		return fmt.Errorf("description must be at most %d characters", maxWebhookDescriptionLength)
+	}
+	return nil
+}
+
+// cgnat is the shared address space of carrier-grade NAT, some clouds serve their metadata there.
+var cgnat = netip.MustParsePrefix("100.64.0.0/10")
+
+// WebhookAddrAllowed tells whether webhooks may be delivered to the address. Loopback, private,
+// link-local (cloud metadata at 169.254.169.254 included), shared, unspecified and multicast
+// addresses are refused, deliveries would reach hosts inside the network.
+func WebhookAddrAllowed(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() && addr.IsGlobalUnicast() && !addr.IsPrivate() && !cgnat.Contains(addr)
+}
+
+// WebhookHostAllowed tells whether the host of a webhook url may be delivered to. A name is only
+// refused when it is local by definition, the addresses it resolves to are checked when dialing.
+func WebhookHostAllowed(host string) bool {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return WebhookAddrAllowed(addr)
+	}
+	// .internal names the hosts of a private network, metadata.google.internal among them.
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	return host != "localhost" && !strings.HasSuffix(host, ".localhost") && !strings.HasSuffix(host, ".internal")
+}
+
+// Matches tells whether the subscription receives events of the type.
+func (s *WebhookSubscription) Matches(eventType WebhookEventType) bool {
+	if len(s.EventTypes) == 0 {
+		return true
+	}
+	for _, t := range s.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDeliveryStatus is the state of a webhook delivery.
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "PENDING" // waiting for its next attempt
+	WebhookDeliveryDelivered WebhookDeliveryStatus = "DELIVERED"
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "FAILED" // every attempt failed, it can be redelivered
+)
+
+func ToWebhookDeliveryStatus(s string) (WebhookDeliveryStatus, error) {
+	switch st := WebhookDeliveryStatus(s); st {
+	case WebhookDeliveryPending, WebhookDeliveryDelivered, WebhookDeliveryFailed:
+		return st, nil
+	default:
+		return "", fmt.Errorf("invalid webhook delivery status: %s", s)
+	}
+}
+
+// WebhookDelivery is the delivery of an event to a subscription, with its attempts.
+type WebhookDelivery struct {
+	DeliveryID     string                `json:"delivery_id"`
+	SubscriptionID string                `json:"subscription_id"`
+	EventID        string                `json:"event_id"`
+	EventType      WebhookEventType      `json:"event_type"`
+	BillID         string                `json:"bill_id"`
+	RedeliveryOf   string                `json:"redelivery_of,omitempty"` // the delivery this one repeats
+	Status         WebhookDeliveryStatus `json:"status"`
+	Attempts       int                   `json:"attempts"`
+	LastStatusCode int                   `json:"last_status_code,omitempty"`
+	LastError      string                `json:"last_error,omitempty"`
+	CreatedAt      time.Time             `json:"created_at"`
+	UpdatedAt      time.Time             `json:"updated_at"`
+	DeliveredAt    *time.Time            `json:"delivered_at,omitempty"`
+	AttemptLog     []WebhookAttempt      `json:"attempt_log,omitempty"` // only read for a single delivery
+}
+
+// WebhookAttempt is one request of a delivery to the subscription's endpoint.
+type WebhookAttempt struct {
+	Attempt    int       `json:"attempt"`
+	StatusCode int       `json:"status_code,omitempty"` // zero when no response was received
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,121 @@
+package model
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestToWebhookEventType(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    WebhookEventType
+		wantErr bool
+	}{
+		{"ValidBillCreated", "bill.created", WebhookBillCreated, false},
+		{"ValidLineItemVoided", "line_item.voided", WebhookLineItemVoided, false},
+		{"ValidBillSettled", "bill.settled", WebhookBillSettled, false},
+		{"ValidPostProcessFailed", "post_process.failed", WebhookPostProcessFailed, false},
+		{"InvalidType", "bill.deleted", "", true},
+		{"AuditEventName", "BILL_CLOSED", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToWebhookEventType(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ToWebhookEventType() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ToWebhookEventType() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebhookEventOf(t *testing.T) {
+	tests := []struct {
+		name   string
+		event  AuditEvent
+		want   WebhookEventType
+		wantOK bool
+	}{
+		{"BillCreated", AuditBillCreated, WebhookBillCreated, true},
+		{"LineItemAdded", AuditLineItemAdded, WebhookLineItemAdded, true},
+		{"LineItemVoided", AuditLineItemVoided, WebhookLineItemVoided, true},
+		{"BillClosed", AuditBillClosed, WebhookBillClosed, true},
+		{"PaymentRecorded", AuditPaymentRecorded, WebhookBillSettled, true},
+		{"BillReopened", AuditBillReopened, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := AuditEntry{EntryID: "e-1", BillID: "bill-1", LineItemID: "li-1", Event: tt.event, After: json.RawMessage(`{"amount":100}`)}
+			got, ok := WebhookEventOf(entry)
+			if ok != tt.wantOK {
+				t.Fatalf("WebhookEventOf() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && (got.Type != tt.want || got.EventID != "e-1" || got.BillID != "bill-1" || got.LineItemID != "li-1" || string(got.Data) != `{"amount":100}`) {
+				t.Errorf("WebhookEventOf() got = %+v", got)
+			}
+		})
+	}
+}
+
+func TestWebhookSubscription_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		sub     WebhookSubscription
+		wantErr bool
+	}{
+		{"Valid", WebhookSubscription{URL: "https://hooks.example.com/fee", EventTypes: []WebhookEventType{WebhookBillClosed}}, false},
+		{"AllEvents", WebhookSubscription{URL: "https://hooks.example.com/fee"}, false},
+		{"PublicIP", WebhookSubscription{URL: "https://203.0.113.10/hooks"}, false},
+		{"PlainHTTP", WebhookSubscription{URL: "http://hooks.example.com/fee"}, true},
+		{"Localhost", WebhookSubscription{URL: "https://localhost:8080/hooks"}, true},
+		{"Loopback", WebhookSubscription{URL: "https://127.0.0.1/hooks"}, true},
+		{"LoopbackIPv6", WebhookSubscription{URL: "https://[::1]/hooks"}, true},
+		{"MappedLoopback", WebhookSubscription{URL: "https://[::ffff:127.0.0.1]/hooks"}, true},
+		{"Private", WebhookSubscription{URL: "https://10.0.0.5/hooks"}, true},
+		{"Metadata", WebhookSubscription{URL: "http://169.254.169.254/latest/meta-data"}, true},
+		{"MetadataName", WebhookSubscription{URL: "https://metadata.google.internal/computeMetadata/v1"}, true},
+		{"SharedAddress", WebhookSubscription{URL: "https://100.100.100.200/hooks"}, true},
+		{"Unspecified", WebhookSubscription{URL: "https://0.0.0.0/hooks"}, true},
+		{"MissingURL", WebhookSubscription{}, true},
+		{"RelativeURL", WebhookSubscription{URL: "/hooks"}, true},
+		{"OtherScheme", WebhookSubscription{URL: "ftp://hooks.example.com"}, true},
+		{"LongDescription", WebhookSubscription{URL: "https://hooks.example.com", Description: strings.Repeat("x", 257)}, true},
+		{"UnknownEventType", WebhookSubscription{URL: "https://hooks.example.com", EventTypes: []WebhookEventType{"bill.deleted"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.sub.Validate(false); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWebhookSubscription_ValidateAllowHTTP(t *testing.T) {
+	if err := (&WebhookSubscription{URL: "http://hooks.example.com/fee"}).Validate(true); err != nil {
+		t.Errorf("Validate(true) error = %v, want plain http accepted", err)
+	}
+	if err := (&WebhookSubscription{URL: "http://localhost:8080/hooks"}).Validate(true); err == nil {
+		t.Errorf("Validate(true) accepted a loopback url")
+	}
+}
+
+func TestWebhookSubscription_Matches(t *testing.T) {
+	all := WebhookSubscription{}
+	closed := WebhookSubscription{EventTypes: []WebhookEventType{WebhookBillClosed, WebhookBillSettled}}
+
+	if !all.Matches(WebhookLineItemAdded) {
+		t.Errorf("a subscription without event types must match every event")
+	}
+	if !closed.Matches(WebhookBillSettled) || closed.Matches(WebhookLineItemAdded) {
+		t.Errorf("Matches() doesn't filter by the event types")
+	}
+}
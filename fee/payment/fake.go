@@ -0,0 +1,98 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"encore.app/fee/model"
+	"encore.app/fee/utils"
+)
+
+// SignatureHeader carries the signature of a callback, made with the provider's secret by utils.Sign.
+const SignatureHeader = utils.SignatureHeader
+
+// signatureTolerance is how old a signed callback may be, older ones could be replays.
+const signatureTolerance = 5 * time.Minute
+
+// maxCallbackBytes limits the body of a callback.
+const maxCallbackBytes = 64 << 10
+
+// FakeProvider is a local provider for development and tests. Its links are never paid by
+// anyone: posting a callback signed with Secret pays them. Links are only kept in memory.
+type FakeProvider struct {
+	BaseURL string // the links are <BaseURL>/<link id>
+	Secret  []byte // signs the callbacks, none are accepted without it
+
+	mu    sync.Mutex
+	links map[string]*fakeLink
+}
+
+type fakeLink struct {
+	request *LinkRequest
+	event   Event
+}
+
+func NewFakeProvider(baseURL string, secret []byte) *FakeProvider {
+	return &FakeProvider{BaseURL: strings.TrimSuffix(baseURL, "/"), Secret: secret, links: map[string]*fakeLink{}}
+}
+
+func (f *FakeProvider) Name() string {
+	return "fake"
+}
+
+func (f *FakeProvider) CreateLink(ctx context.Context, req *LinkRequest) (*Link, error) {
+	id := "fake_" + utils.NameUUID(req.Reference)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	link, ok := f.links[id]
+	if !ok {
+		link = &fakeLink{request: req, event: Event{LinkID: id, Status: model.PaymentLinkPending}}
+		f.links[id] = link
+	}
+	return &Link{ID: id, URL: f.BaseURL + "/" + id, ExpiresAt: link.request.ExpiresAt}, nil
+}
+
+func (f *FakeProvider) FetchStatus(ctx context.Context, linkID string) (*Event, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	link, ok := f.links[linkID]
+	if !ok {
+		return nil, ErrUnknownLink
+	}
+	event := link.event
+	if event.Status == model.PaymentLinkPending && time.Now().After(link.request.ExpiresAt) {
+		event.Status = model.PaymentLinkExpired
+	}
+	return &event, nil
+}
+
+// HandleCallback accepts a JSON Event signed with Secret in SignatureHeader, see utils.Sign.
+func (f *FakeProvider) HandleCallback(req *http.Request) (*Event, error) {
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxCallbackBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read payment callback: %w", err)
+	}
+	if !utils.VerifySignature(f.Secret, req.Header.Get(SignatureHeader), body, time.Now(), signatureTolerance) {
+		return nil, ErrInvalidSignature
+	}
+	var event Event
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, fmt.Errorf("invalid payment callback: %w", err)
+	}
+	if _, err := model.ToPaymentLinkStatus(string(event.Status)); err != nil {
+		return nil, fmt.Errorf("invalid payment callback: %w", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if link, ok := f.links[event.LinkID]; ok {
+		link.event = event
+	}
+	return &event, nil
+}
@@ -0,0 +1,124 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"encore.app/fee/model"
+	"encore.app/fee/utils"
+)
+
+func TestFakeProvider_CreateLink(t *testing.T) {
+	p := NewFakeProvider("https://pay.example.com/", []byte("secret"))
+	req := &LinkRequest{Reference: "link-1", Amount: 1500, Currency: "USD", ExpiresAt: time.Now().Add(time.Hour)}
+
+	first, err := p.CreateLink(context.Background(), req)
+	if err != nil {
+		t.Fatalf("CreateLink() error = %v", err)
+	}
+	again, _ := p.CreateLink(context.Background(), req)
+	if *again != *first {
+		t.Errorf("CreateLink() got = %+v for the same reference, want %+v", again, first)
+	}
+	if first.URL != "https://pay.example.com/"+first.ID {
+		t.Errorf("URL got = %q", first.URL)
+	}
+	event, err := p.FetchStatus(context.Background(), first.ID)
+	if err != nil || event.Status != model.PaymentLinkPending {
+		t.Errorf("FetchStatus() got = %+v, %v, want PENDING", event, err)
+	}
+}
+
+func TestFakeProvider_FetchStatusExpired(t *testing.T) {
+	p := NewFakeProvider("https://pay.example.com", []byte("secret"))
+	link, _ := p.CreateLink(context.Background(), &LinkRequest{Reference: "link-1", ExpiresAt: time.Now().Add(-time.Second)})
+
+	event, err := p.FetchStatus(context.Background(), link.ID)
+	if err != nil || event.Status != model.PaymentLinkExpired {
+		t.Errorf("FetchStatus() got = %+v, %v, want EXPIRED", event, err)
+	}
+}
+
+func TestFakeProvider_FetchStatusUnknown(t *testing.T) {
+	p := NewFakeProvider("https://pay.example.com", []byte("secret"))
+
+	if _, err := p.FetchStatus(context.Background(), "fake_1"); !errors.Is(err, ErrUnknownLink) {
+		t.Errorf("FetchStatus() error = %v, want %v", err, ErrUnknownLink)
+	}
+}
+
+func TestFakeProvider_HandleCallback(t *testing.T) {
+	secret := []byte("secret")
+	p := NewFakeProvider("https://pay.example.com", secret)
+	link, _ := p.CreateLink(context.Background(), &LinkRequest{Reference: "link-1", Amount: 1500, Currency: "USD", ExpiresAt: time.Now().Add(time.Hour)})
+	body := `{"link_id": "` + link.ID + `", "status": "PAID", "payment_id": "pay-1", "amount": 1500, "currency": "USD"}`
+
+	tests := []struct {
+		name      string
+		body      string
+		signature string
+		wantErr   error
+	}{
+		{"Valid", body, utils.Sign(secret, []byte(body), time.Now()), nil},
+		{"TamperedBody", strings.Replace(body, "1500", "1", 1), utils.Sign(secret, []byte(body), time.Now()), ErrInvalidSignature},
+		{"WrongSecret", body, utils.Sign([]byte("other"), []byte(body), time.Now()), ErrInvalidSignature},
+		{"Stale", body, utils.Sign(secret, []byte(body), time.Now().Add(-10*time.Minute)), ErrInvalidSignature},
+		{"Missing", body, "", ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/payments/callback", strings.NewReader(tt.body))
+			req.Header.Set(SignatureHeader, tt.signature)
+			event, err := p.HandleCallback(req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("HandleCallback() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && (event.Status != model.PaymentLinkPaid || event.PaymentID != "pay-1" || event.Amount != 1500) {
+				t.Errorf("HandleCallback() got = %+v", event)
+			}
+		})
+	}
+
+	event, _ := p.FetchStatus(context.Background(), link.ID)
+	if event.Status != model.PaymentLinkPaid {
+		t.Errorf("FetchStatus() after the callback got = %v, want PAID", event.Status)
+	}
+}
+
+func TestFakeProvider_HandleCallbackWithoutSecret(t *testing.T) {
+	p := NewFakeProvider("https://pay.example.com", nil)
+	body := `{"link_id": "fake_1", "status": "PAID"}`
+	req := httptest.NewRequest("POST", "/api/payments/callback", strings.NewReader(body))
+	req.Header.Set(SignatureHeader, utils.Sign(nil, []byte(body), time.Now()))
+
+	if _, err := p.HandleCallback(req); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("HandleCallback() error = %v, want %v", err, ErrInvalidSignature)
+	}
+}
+
+func TestEvent_Pays(t *testing.T) {
+	link := &model.PaymentLink{Amount: 1500, Currency: "USD"}
+	tests := []struct {
+		name  string
+		event Event
+		want  bool
+	}{
+		{"Paid", Event{Status: model.PaymentLinkPaid, Amount: 1500, Currency: "usd"}, true},
+		{"Partial", Event{Status: model.PaymentLinkPaid, Amount: 1000, Currency: "USD"}, false},
+		{"OtherCurrency", Event{Status: model.PaymentLinkPaid, Amount: 1500, Currency: "GEL"}, false},
+		{"Pending", Event{Status: model.PaymentLinkPending, Amount: 1500, Currency: "USD"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.event.Pays(link); got != tt.want {
+				t.Errorf("Pays() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
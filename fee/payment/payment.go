@@ -0,0 +1,117 @@
+// Package payment creates the links customers pay their invoices with and reads the callbacks
+// a payment provider sends when a link is paid.
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"encore.app/fee/model"
+)
+
+var (
+	// ErrInvalidSignature is returned by HandleCallback for a request the provider didn't sign.
+	ErrInvalidSignature = errors.New("invalid payment callback signature")
+	// ErrUnknownLink is returned by FetchStatus for a link the provider doesn't know, which can't
+	// have been paid.
+	ErrUnknownLink = errors.New("unknown payment link")
+	// ErrDisabled is returned by the Disabled provider, bills get no payment links.
+	ErrDisabled = errors.New("payment links are disabled")
+)
+
+// FakeBaseURL is where the links of the fake provider point, they can't be opened.
+const FakeBaseURL = "https://pay.fake.invalid"
+
+// New returns the provider configured by rawURL. The fake provider keeps its links in memory, a
+// restart loses them, so it is only available when dev is set:
+//
+//	fake://   the FakeProvider, its links point to FakeBaseURL
+//	empty     the FakeProvider when dev is set, Disabled otherwise
+//
+// callbackSecret signs the provider's callbacks.
+func New(rawURL string, callbackSecret []byte, dev bool) (Provider, error) {
+	if rawURL == "" {
+		if dev {
+			return NewFakeProvider(FakeBaseURL, callbackSecret), nil
+		}
+		return Disabled{}, nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payment provider url: %w", err)
+	}
+	switch u.Scheme {
+	case "fake":
+		if !dev {
+			return nil, fmt.Errorf("the fake payment provider is only available locally and in tests")
+		}
+		return NewFakeProvider(FakeBaseURL, callbackSecret), nil
+	default:
+		return nil, fmt.Errorf("unsupported payment provider url scheme: %s", u.Scheme)
+	}
+}
+
+// Disabled is the provider of a service without payment links. It creates none and accepts no
+// callbacks.
+type Disabled struct{}
+
+func (Disabled) Name() string {
+	return "disabled"
+}
+
+func (Disabled) CreateLink(ctx context.Context, req *LinkRequest) (*Link, error) {
+	return nil, ErrDisabled
+}
+
+func (Disabled) FetchStatus(ctx context.Context, linkID string) (*Event, error) {
+	return nil, ErrDisabled
+}
+
+func (Disabled) HandleCallback(req *http.Request) (*Event, error) {
+	return nil, ErrDisabled
+}
+
+type Provider interface {
+	// Name identifies the provider, it is stored with every link it created.
+	Name() string
+	// CreateLink creates a link to pay the request. A link already created for the request's
+	// reference is returned again, so a retried call doesn't create a second one.
+	CreateLink(ctx context.Context, req *LinkRequest) (*Link, error)
+	// FetchStatus asks the provider for the state of a link, for callbacks that never arrived.
+	FetchStatus(ctx context.Context, linkID string) (*Event, error)
+	// HandleCallback verifies a callback request of the provider and returns what it reports.
+	HandleCallback(req *http.Request) (*Event, error)
+}
+
+type LinkRequest struct {
+	Reference   string // identifies the link on our side, the payment link ID
+	Amount      int64  // in minor units
+	Currency    string
+	Description string
+	ExpiresAt   time.Time
+}
+
+type Link struct {
+	ID        string // the provider's ID of the link
+	URL       string
+	ExpiresAt time.Time
+}
+
+// Event is the state of a link reported by the provider.
+type Event struct {
+	LinkID    string                  `json:"link_id"`
+	Status    model.PaymentLinkStatus `json:"status"`
+	PaymentID string                  `json:"payment_id,omitempty"` // set once the link is paid
+	Amount    int64                   `json:"amount,omitempty"`
+	Currency  string                  `json:"currency,omitempty"`
+}
+
+// Pays tells whether the event reports the link paid in full.
+func (e *Event) Pays(link *model.PaymentLink) bool {
+	return e.Status == model.PaymentLinkPaid && e.Amount == link.Amount && strings.EqualFold(e.Currency, link.Currency)
+}
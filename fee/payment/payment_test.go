@@ -0,0 +1,33 @@
+package payment
+
+import (
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name     string
+		url      string
+		dev      bool
+		wantName string
+		wantErr  bool
+	}{
+		{"DevDefault", "", true, "fake", false},
+		{"DevFake", "fake://", true, "fake", false},
+		{"Disabled", "", false, "disabled", false},
+		{"FakeOutsideDev", "fake://", false, "", true},
+		{"UnknownScheme", "https://pay.example.com", true, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(tt.url, []byte("secret"), tt.dev)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got.Name() != tt.wantName {
+				t.Errorf("New() got = %q, want %q", got.Name(), tt.wantName)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package fee
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"encore.app/fee/model"
+	"encore.app/fee/payment"
+	temporal "encore.app/fee/workflow"
+	"encore.dev/beta/errs"
+	"encore.dev/rlog"
+	"go.temporal.io/api/serviceerror"
+)
+
+// PaymentCallback receives the callbacks of the payment provider. They carry the provider's
+// signature instead of an API key. A callback that reports a payment link paid in full signals
+// the bill's post-processing, which settles the bill, or settles it directly when the
+// post-processing stopped waiting; other callbacks are acknowledged.
+//
+//encore:api public raw method=POST path=/api/payments/callback
+func (s *Service) PaymentCallback(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	event, err := s.payments.HandleCallback(req)
+	if err != nil {
+		if errors.Is(err, payment.ErrInvalidSignature) {
+			errs.HTTPError(w, &errs.Error{Code: errs.Unauthenticated, Message: "invalid payment callback signature"})
+			return
+		}
+		if errors.Is(err, payment.ErrDisabled) {
+			errs.HTTPError(w, &errs.Error{Code: errs.Unavailable, Message: err.Error()})
+			return
+		}
+		errs.HTTPError(w, &errs.Error{Code: errs.InvalidArgument, Message: err.Error()})
+		return
+	}
+	link, err := s.db.LookupPaymentLink(ctx, s.payments.Name(), event.LinkID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			errs.HTTPError(w, &errs.Error{Code: errs.NotFound, Message: "payment link not found"})
+			return
+		}
+		rlog.Error("failed to get payment link", "error", err, "provider_link_id", event.LinkID)
+		errs.HTTPError(w, err)
+		return
+	}
+	if event.Status != model.PaymentLinkPaid {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if !event.Pays(link) {
+		errs.HTTPError(w, &errs.Error{Code: errs.InvalidArgument, Message: "payment doesn't match the payment link"})
+		return
+	}
+
+	signal := temporal.SettleBillRequest{
+		LinkID:    link.LinkID,
+		PaymentID: event.PaymentID,
+		Amount:    event.Amount,
+		Actor:     "payment:" + link.Provider,
+		RequestID: requestID(),
+	}
+	err = s.client.SignalWorkflow(ctx, temporal.BillPostprocessWorkflowID(link.TenantID, link.BillID), "", temporal.SettleBillSignal, signal)
+	if err != nil {
+		var notFound *serviceerror.NotFound
+		if !errors.As(err, &notFound) {
+			rlog.Error("failed to signal bill settlement", "error", err, "bill_id", link.BillID)
+			errs.HTTPError(w, err)
+			return
+		}
+		// A repeated callback of a settled bill finds the post-processing already completed, a late
+		// one finds it stopped waiting, e.g. because the link expired first.
+		if link.Status != model.PaymentLinkPaid {
+			if err := s.settleLatePayment(ctx, link, event, signal); err != nil {
+				errs.HTTPError(w, err)
+				return
+			}
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// settleLatePayment settles the bill of a payment no post-processing waits for anymore. A payment
+// whose bill can't be settled, e.g. because it was reopened, is kept for reconciliation: the
+// customer paid, so the callback is never rejected.
+func (s *Service) settleLatePayment(ctx context.Context, link *model.PaymentLink, event *payment.Event, signal temporal.SettleBillRequest) error {
+	source := model.AuditSource{
+		Actor:     signal.Actor,
+		RequestID: signal.RequestID,
+		// The provider repeats a callback until it is acknowledged, the payment is recorded once.
+		Key: "payment/" + link.Provider + "/" + event.PaymentID,
+	}
+	err := s.db.SettleBill(ctx, link.TenantID, link.LinkID, event.PaymentID, source)
+	if err == nil {
+		rlog.Info("settled bill from a late payment", "bill_id", link.BillID, "link_id", link.LinkID, "payment_id", event.PaymentID)
+		return nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		rlog.Error("failed to settle bill from a late payment", "error", err, "bill_id", link.BillID)
+		return err
+	}
+	err = s.db.RecordUnappliedPayment(ctx, link.TenantID, &model.UnappliedPayment{
+		Provider:  link.Provider,
+		PaymentID: event.PaymentID,
+		LinkID:    link.LinkID,
+		BillID:    link.BillID,
+		Amount:    event.Amount,
+		Currency:  link.Currency,
+	})
+	if err != nil {
+		rlog.Error("failed to record unapplied payment", "error", err, "bill_id", link.BillID, "payment_id", event.PaymentID)
+		return err
+	}
+	rlog.Warn("recorded a payment of a bill that can't be settled anymore, it needs reconciliation", "bill_id", link.BillID, "link_id", link.LinkID, "payment_id", event.PaymentID)
+	return nil
+}
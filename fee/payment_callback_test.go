@@ -0,0 +1,125 @@
+package fee
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"encore.app/fee/dao/mocks"
+	"encore.app/fee/model"
+	"encore.app/fee/payment"
+	"encore.app/fee/utils"
+	temporal "encore.app/fee/workflow"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.temporal.io/api/serviceerror"
+)
+
+var callbackSecret = []byte("callback-secret")
+
+func paymentCallbackRequest(body string, secret []byte) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/api/payments/callback", strings.NewReader(body))
+	req.Header.Set(payment.SignatureHeader, utils.Sign(secret, []byte(body), time.Now()))
+	return req
+}
+
+func setupPayments(t *testing.T) (*Service, *mocks.DB, *mockTemporalClient, *model.PaymentLink) {
+	service, mockDB, mockTemporalClient := setup(t)
+	provider := payment.NewFakeProvider("https://pay.fake.invalid", callbackSecret)
+	service.payments = provider
+	created, err := provider.CreateLink(context.Background(), &payment.LinkRequest{Reference: "link-1", Amount: 1500, Currency: "USD", ExpiresAt: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	link := &model.PaymentLink{TenantID: "acme", LinkID: "link-1", BillID: "bill-1", Provider: provider.Name(), ProviderLinkID: created.ID,
+		Amount: 1500, Currency: "USD", Status: model.PaymentLinkPending}
+	mockDB.On("LookupPaymentLink", mock.Anything, provider.Name(), created.ID).Return(link, nil)
+	return service, mockDB, mockTemporalClient, link
+}
+
+func TestPaymentCallback_SignalsSettlement(t *testing.T) {
+	service, _, mockTemporalClient, link := setupPayments(t)
+	mockTemporalClient.On("SignalWorkflow", mock.Anything, temporal.BillPostprocessWorkflowID("acme", "bill-1"), "", temporal.SettleBillSignal,
+		mock.MatchedBy(func(signal temporal.SettleBillRequest) bool {
+			return signal.LinkID == "link-1" && signal.PaymentID == "pay-1" && signal.Amount == 1500 && signal.Actor == "payment:fake"
+		})).Return(nil).Once()
+
+	w := httptest.NewRecorder()
+	body := `{"link_id": "` + link.ProviderLinkID + `", "status": "PAID", "payment_id": "pay-1", "amount": 1500, "currency": "USD"}`
+	service.PaymentCallback(w, paymentCallbackRequest(body, callbackSecret))
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	mockTemporalClient.AssertExpectations(t)
+}
+
+func TestPaymentCallback_Rejected(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     func(link *model.PaymentLink) string
+		secret   []byte
+		wantCode int
+	}{
+		{"InvalidSignature", func(link *model.PaymentLink) string {
+			return `{"link_id": "` + link.ProviderLinkID + `", "status": "PAID", "payment_id": "pay-1", "amount": 1500, "currency": "USD"}`
+		}, []byte("forged"), http.StatusUnauthorized},
+		{"PartialPayment", func(link *model.PaymentLink) string {
+			return `{"link_id": "` + link.ProviderLinkID + `", "status": "PAID", "payment_id": "pay-1", "amount": 100, "currency": "USD"}`
+		}, callbackSecret, http.StatusBadRequest},
+		{"UnknownLink", func(link *model.PaymentLink) string {
+			return `{"link_id": "fake_unknown", "status": "PAID", "payment_id": "pay-1", "amount": 1500, "currency": "USD"}`
+		}, callbackSecret, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, mockDB, mockTemporalClient, link := setupPayments(t)
+			mockDB.On("LookupPaymentLink", mock.Anything, "fake", "fake_unknown").Return(nil, sql.ErrNoRows)
+
+			w := httptest.NewRecorder()
+			service.PaymentCallback(w, paymentCallbackRequest(tt.body(link), tt.secret))
+
+			assert.Equal(t, tt.wantCode, w.Code)
+			mockTemporalClient.AssertNotCalled(t, "SignalWorkflow", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestPaymentCallback_LatePaymentSettlesBill(t *testing.T) {
+	service, mockDB, mockTemporalClient, link := setupPayments(t)
+	link.Status = model.PaymentLinkExpired
+	mockTemporalClient.On("SignalWorkflow", mock.Anything, mock.Anything, mock.Anything, temporal.SettleBillSignal, mock.Anything).
+		Return(serviceerror.NewNotFound("workflow not found")).Once()
+	mockDB.On("SettleBill", mock.Anything, "acme", "link-1", "pay-1", mock.MatchedBy(func(source model.AuditSource) bool {
+		return source.Actor == "payment:fake" && source.Key == "payment/fake/pay-1"
+	})).Return(nil).Once()
+
+	w := httptest.NewRecorder()
+	body := `{"link_id": "` + link.ProviderLinkID + `", "status": "PAID", "payment_id": "pay-1", "amount": 1500, "currency": "USD"}`
+	service.PaymentCallback(w, paymentCallbackRequest(body, callbackSecret))
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	mockDB.AssertExpectations(t)
+	mockDB.AssertNotCalled(t, "RecordUnappliedPayment", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPaymentCallback_LatePaymentOfReopenedBillIsKept(t *testing.T) {
+	service, mockDB, mockTemporalClient, link := setupPayments(t)
+	link.Status = model.PaymentLinkCancelled
+	mockTemporalClient.On("SignalWorkflow", mock.Anything, mock.Anything, mock.Anything, temporal.SettleBillSignal, mock.Anything).
+		Return(serviceerror.NewNotFound("workflow not found")).Once()
+	mockDB.On("SettleBill", mock.Anything, "acme", "link-1", "pay-1", mock.Anything).Return(sql.ErrNoRows).Once()
+	mockDB.On("RecordUnappliedPayment", mock.Anything, "acme", &model.UnappliedPayment{
+		Provider: "fake", PaymentID: "pay-1", LinkID: "link-1", BillID: "bill-1", Amount: 1500, Currency: "USD",
+	}).Return(nil).Once()
+
+	w := httptest.NewRecorder()
+	body := `{"link_id": "` + link.ProviderLinkID + `", "status": "PAID", "payment_id": "pay-1", "amount": 1500, "currency": "USD"}`
+	service.PaymentCallback(w, paymentCallbackRequest(body, callbackSecret))
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	mockDB.AssertExpectations(t)
+}
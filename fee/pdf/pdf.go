@@ -0,0 +1,260 @@
+// Package pdf writes simple PDF documents: A4 pages with text in the standard Helvetica fonts,
+// lines and JPEG images. It needs no font files, so text is limited to the characters of
+// Windows-1252, others are written as "?".
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image/color"
+	"image/jpeg"
+	"strings"
+)
+
+// A4 in points. Positions on a page are given from its top-left corner.
+const (
+	PageWidth  = 595.28
+	PageHeight = 841.89
+)
+
+type Font int
+
+const (
+	Regular Font = iota
+	Bold
+)
+
+var fontNames = [...]string{Regular: "Helvetica", Bold: "Helvetica-Bold"}
+
+type Document struct {
+	pages  []*Page
+	images []jpegImage
+}
+
+type Page struct {
+	doc     *Document
+	content bytes.Buffer
+	images  []int
+}
+
+type jpegImage struct {
+	data          []byte
+	width, height int
+	colorSpace    string
+}
+
+func New() *Document {
+	return &Document{}
+}
+
+// AddPage appends an empty page and returns it.
+func (d *Document) AddPage() *Page {
+	p := &Page{doc: d}
+	d.pages = append(d.pages, p)
+	return p
+}
+
+// Text writes s with its baseline at y, starting at x.
+func (p *Page) Text(x, y float64, font Font, size float64, s string) {
+	fmt.Fprintf(&p.content, "BT /F%d %s Tf %s %s Td (%s) Tj ET\n", font, num(size), num(x), num(PageHeight-y), escape(encode(s)))
+}
+
+// TextRight writes s with its baseline at y, ending at x.
+func (p *Page) TextRight(x, y float64, font Font, size float64, s string) {
+	p.Text(x-TextWidth(font, size, s), y, font, size, s)
+}
+
+// Line draws a black line from (x1, y1) to (x2, y2).
+func (p *Page) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(&p.content, "%s w %s %s m %s %s l S\n", num(width), num(x1), num(PageHeight-y1), num(x2), num(PageHeight-y2))
+}
+
+// JPEG draws a baseline JPEG with its top-left corner at (x, y), scaled to fit w by h while
+// keeping its aspect ratio.
+func (p *Page) JPEG(data []byte, x, y, w, h float64) error {
+	config, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("invalid jpeg: %w", err)
+	}
+	colorSpace := "DeviceRGB"
+	switch config.ColorModel {
+	case color.GrayModel:
+		colorSpace = "DeviceGray"
+	case color.CMYKModel:
+		return fmt.Errorf("cmyk jpegs are not supported")
+	}
+	if config.Width == 0 || config.Height == 0 {
+		return fmt.Errorf("invalid jpeg: empty image")
+	}
+	scale := min(w/float64(config.Width), h/float64(config.Height))
+	dw, dh := float64(config.Width)*scale, float64(config.Height)*scale
+
+	index := len(p.doc.images)
+	p.doc.images = append(p.doc.images, jpegImage{data: data, width: config.Width, height: config.Height, colorSpace: colorSpace})
+	p.images = append(p.images, index)
+	fmt.Fprintf(&p.content, "q %s 0 0 %s %s %s cm /Im%d Do Q\n", num(dw), num(dh), num(x), num(PageHeight-y-dh), index)
+	return nil
+}
+
+// TextWidth is the width of s written in font at size.
+func TextWidth(font Font, size float64, s string) float64 {
+	widths := &helveticaWidths
+	if font == Bold {
+		widths = &helveticaBoldWidths
+	}
+	var units int
+	for _, b := range encode(s) {
+		if b >= 32 && b < 127 {
+			units += widths[b-32]
+		} else {
+			units += 556
+		}
+	}
+	return float64(units) * size / 1000
+}
+
+// Bytes renders the document.
+func (d *Document) Bytes() []byte {
+	w := &writer{}
+	w.buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// Objects 1 and 2 are the catalog and the page tree, fonts follow, then the images, then a
+	// page and its content stream for every page.
+	const catalog, pages, fonts = 1, 2, 3
+	images := fonts + len(fontNames)
+	firstPage := images + len(d.images)
+
+	w.object(catalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pages))
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+	w.object(pages, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	for i, name := range fontNames {
+		w.object(fonts+i, fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", name))
+	}
+	for i, img := range d.images {
+		w.stream(images+i, fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /%s /BitsPerComponent 8 /Filter /DCTDecode",
+			img.width, img.height, img.colorSpace), img.data)
+	}
+
+	fontRefs := make([]string, len(fontNames))
+	for i := range fontNames {
+		fontRefs[i] = fmt.Sprintf("/F%d %d 0 R", i, fonts+i)
+	}
+	for i, p := range d.pages {
+		resources := "/Font << " + strings.Join(fontRefs, " ") + " >>"
+		if len(p.images) > 0 {
+			refs := make([]string, len(p.images))
+			for j, index := range p.images {
+				refs[j] = fmt.Sprintf("/Im%d %d 0 R", index, images+index)
+			}
+			resources += " /XObject << " + strings.Join(refs, " ") + " >>"
+		}
+		page, content := firstPage+2*i, firstPage+2*i+1
+		w.object(page, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources << %s >> /Contents %d 0 R >>",
+			pages, num(PageWidth), num(PageHeight), resources, content))
+		var compressed bytes.Buffer
+		zw := zlib.NewWriter(&compressed)
+		_, _ = zw.Write(p.content.Bytes())
+		_ = zw.Close()
+		w.stream(content, "/Filter /FlateDecode", compressed.Bytes())
+	}
+
+	xref := w.buf.Len()
+	fmt.Fprintf(&w.buf, "xref\n0 %d\n0000000000 65535 f \n", len(w.offsets)+1)
+	for _, offset := range w.offsets {
+		fmt.Fprintf(&w.buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&w.buf, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(w.offsets)+1, catalog, xref)
+	return w.buf.Bytes()
+}
+
+// writer keeps the offset of every object for the cross-reference table, objects must be
+// written in order of their number.
+type writer struct {
+	buf     bytes.Buffer
+	offsets []int
+}
+
+func (w *writer) object(n int, body string) {
+	w.offsets = append(w.offsets, w.buf.Len())
+	fmt.Fprintf(&w.buf, "%d 0 obj\n%s\nendobj\n", n, body)
+}
+
+func (w *writer) stream(n int, dict string, data []byte) {
+	w.offsets = append(w.offsets, w.buf.Len())
+	fmt.Fprintf(&w.buf, "%d 0 obj\n<< %s /Length %d >>\nstream\n", n, dict, len(data))
+	w.buf.Write(data)
+	w.buf.WriteString("\nendstream\nendobj\n")
+}
+
+func num(f float64) string {
+	s := strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.2f", f), "0"), ".")
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+func escape(b []byte) string {
+	var sb strings.Builder
+	for _, c := range b {
+		switch c {
+		case '(', ')', '\\':
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		case '\n', '\r', '\t':
+			sb.WriteByte(' ')
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+// encode converts s to Windows-1252, the encoding of the standard fonts.
+func encode(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r < 0x80 || (r >= 0xa0 && r <= 0xff):
+			out = append(out, byte(r))
+		default:
+			if b, ok := cp1252[r]; ok {
+				out = append(out, b)
+			} else {
+				out = append(out, '?')
+			}
+		}
+	}
+	return out
+}
+
+// cp1252 maps the characters Windows-1252 puts in 0x80-0x9f.
+var cp1252 = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87, 'ˆ': 0x88, '‰': 0x89,
+	'Š': 0x8a, '‹': 0x8b, 'Œ': 0x8c, 'Ž': 0x8e, '‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95,
+	'–': 0x96, '—': 0x97, '˜': 0x98, '™': 0x99, 'š': 0x9a, '›': 0x9b, 'œ': 0x9c, 'ž': 0x9e, 'Ÿ': 0x9f,
+}
+
+// Glyph widths of the printable ASCII characters, from the fonts' Adobe metrics. Other characters
+// are counted as wide as a digit.
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278, // space to /
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556, // 0 to ?
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778, // @ to O
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556, // P to _
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556, // ` to o
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584, // p to ~
+}
+
+var helveticaBoldWidths = [95]int{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}
@@ -0,0 +1,85 @@
+package pdf
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestDocument_Bytes(t *testing.T) {
+	doc := New()
+	page := doc.AddPage()
+	page.Text(50, 50, Bold, 18, "Invoice (draft)")
+	page.Line(50, 60, 545, 60, 0.5)
+	var logo bytes.Buffer
+	if err := jpeg.Encode(&logo, image.NewRGBA(image.Rect(0, 0, 40, 20)), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := page.JPEG(logo.Bytes(), 400, 40, 100, 100); err != nil {
+		t.Fatalf("JPEG() error = %v", err)
+	}
+	doc.AddPage().Text(50, 50, Regular, 10, "page 2")
+
+	out := doc.Bytes()
+	if !bytes.HasPrefix(out, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(out, []byte("%%EOF\n")) {
+		t.Fatalf("Bytes() is not framed as a PDF")
+	}
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(out)
+	if m == nil {
+		t.Fatalf("Bytes() has no startxref")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	if !bytes.HasPrefix(out[xref:], []byte("xref\n0 10\n")) {
+		t.Errorf("startxref doesn't point at the xref table of 9 objects")
+	}
+	// Every offset in the table points at its object.
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(out[xref:], -1)
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(string(entry[1]))
+		want := strconv.Itoa(i+1) + " 0 obj\n"
+		if !bytes.HasPrefix(out[offset:], []byte(want)) {
+			t.Errorf("xref entry %d points at %q", i+1, out[offset:offset+len(want)])
+		}
+	}
+}
+
+func TestPage_JPEG_Invalid(t *testing.T) {
+	if err := New().AddPage().JPEG([]byte("not a jpeg"), 0, 0, 10, 10); err == nil {
+		t.Errorf("JPEG() error = nil, want an error")
+	}
+}
+
+func TestEscapeEncode(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"Parentheses", `a(b)\c`, `a\(b\)\\c`},
+		{"Latin1", "Müller", "M\xfcller"},
+		{"Euro", "€5", "\x805"},
+		{"Unsupported", "ქ", "?"},
+		{"Newline", "a\nb", "a b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := escape(encode(tt.input)); got != tt.want {
+				t.Errorf("escape(encode()) got = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTextWidth(t *testing.T) {
+	// "Hi" is H (722) and i (222) in Helvetica, H (722) and i (278) in Helvetica-Bold.
+	if got := TextWidth(Regular, 10, "Hi"); got != 9.44 {
+		t.Errorf("TextWidth(Regular) got = %v, want 9.44", got)
+	}
+	if got := TextWidth(Bold, 10, "Hi"); got != 10 {
+		t.Errorf("TextWidth(Bold) got = %v, want 10", got)
+	}
+}
@@ -0,0 +1,299 @@
+package fee
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"encore.app/fee/dao"
+	"encore.app/fee/model"
+	"encore.app/fee/utils"
+	"encore.dev/beta/errs"
+	"encore.dev/rlog"
+)
+
+type PriceVersion struct {
+	Version       int       `json:"version"`
+	UnitAmount    int64     `json:"unit_amount"`
+	DisplayAmount string    `json:"display_amount"`
+	EffectiveFrom time.Time `json:"effective_from"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type Price struct {
+	PriceID           string         `json:"price_id"`
+	ProductID         string         `json:"product_id"`
+	Currency          string         `json:"currency"`
+	Unit              string         `json:"unit"`
+	Type              string         `json:"type"`
+	RecurringInterval string         `json:"recurring_interval,omitempty"`
+	Active            bool           `json:"active"`
+	Current           PriceVersion   `json:"current"`
+	Versions          []PriceVersion `json:"versions,omitempty"`
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+}
+
+func toPrice(p *model.Price) *Price {
+	return &Price{
+		PriceID:           p.PriceID,
+		ProductID:         p.ProductID,
+		Currency:          p.Currency,
+		Unit:              p.Unit,
+		Type:              p.Type,
+		RecurringInterval: p.RecurringInterval,
+		Active:            p.Active,
+		Current:           toPriceVersion(model.PriceVersion{PriceID: p.PriceID, Version: p.Version, UnitAmount: p.UnitAmount, EffectiveFrom: p.EffectiveFrom}),
+		CreatedAt:         p.CreatedAt,
+		UpdatedAt:         p.UpdatedAt,
+	}
+}
+
+func toPriceVersion(v model.PriceVersion) PriceVersion {
+	return PriceVersion{
+		Version:       v.Version,
+		UnitAmount:    v.UnitAmount,
+		DisplayAmount: model.FormatAmount(v.UnitAmount),
+		EffectiveFrom: v.EffectiveFrom,
+		CreatedAt:     v.CreatedAt,
+	}
+}
+
+type CreatePriceParams struct {
+	PriceID           string         `json:"price_id"` // optional, generated when empty
+	ProductID         string         `json:"product_id"`
+	Currency          string         `json:"currency"`
+	Unit              string         `json:"unit"` // eg: "api_call", "seat"
+	Type              string         `json:"type"`
+	UnitAmount        int64          `json:"unit_amount"`
+	RecurringInterval utils.Duration `json:"recurring_interval"`
+	EffectiveFrom     time.Time      `json:"effective_from"`
+	IdempotencyKey    string         `header:"X-Idempotency-Key"`
+}
+
+func (p *CreatePriceParams) Validate() error {
+	if p.ProductID == "" {
+		return fmt.Errorf("product_id is a required field")
+	}
+	currency, err := model.ToCurrency(p.Currency)
+	if err != nil {
+		return err
+	}
+	p.Currency = string(currency)
+	priceType, err := model.ToPriceType(strings.ToUpper(p.Type))
+	if err != nil {
+		return fmt.Errorf("invalid type")
+	}
+	p.Type = string(priceType)
+	if p.UnitAmount <= 0 {
+		return fmt.Errorf("unit_amount must be more than zero")
+	}
+	if priceType == model.PriceTypeRecurring && p.RecurringInterval.Duration <= 0 {
+		return fmt.Errorf("recurring_interval is mandatory for type=RECURRING")
+	}
+	if priceType == model.PriceTypeOneTime && p.RecurringInterval.Duration != 0 {
+		return fmt.Errorf("recurring_interval is only allowed for type=RECURRING")
+	}
+	if p.Unit == "" {
+		p.Unit = "unit"
+	}
+	if p.EffectiveFrom.IsZero() {
+		p.EffectiveFrom = time.Now()
+	}
+	return nil
+}
+
+//encore:api auth method=POST path=/api/prices tag:idempotency tag:scope_admin
+func (s *Service) CreatePrice(ctx context.Context, params *CreatePriceParams) (*Price, error) {
+	if err := params.Validate(); err != nil {
+		return nil, &errs.Error{
+			Code:    errs.InvalidArgument,
+			Message: err.Error(),
+		}
+	}
+
+	product, err := s.db.GetProduct(ctx, requestTenant(), params.ProductID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &errs.Error{
+				Code:    errs.NotFound,
+				Message: "product not found",
+			}
+		}
+		rlog.Error("failed to get product", "error", err)
+		return nil, err
+	}
+	if !product.Active {
+		return nil, &errs.Error{
+			Code:    errs.FailedPrecondition,
+			Message: "product is archived",
+		}
+	}
+
+	price := &model.Price{
+		PriceID:       params.PriceID,
+		ProductID:     params.ProductID,
+		Currency:      params.Currency,
+		Unit:          params.Unit,
+		Type:          params.Type,
+		UnitAmount:    params.UnitAmount,
+		EffectiveFrom: params.EffectiveFrom,
+	}
+	if params.RecurringInterval.Duration > 0 {
+		price.RecurringInterval = params.RecurringInterval.String()
+	}
+	if price.PriceID == "" {
+		price.PriceID = utils.UUID()
+	}
+	if err := s.db.CreatePrice(ctx, requestTenant(), price); err != nil {
+		if errors.Is(err, dao.ErrPriceExists) {
+			return nil, &errs.Error{
+				Code:    errs.AlreadyExists,
+				Message: "duplicate price id",
+			}
+		}
+		rlog.Error("failed to create price", "error", err)
+		return nil, err
+	}
+	return toPrice(price), nil
+}
+
+// GetPrice returns a price with the plan version effective now, and its full version history.
+//
+//encore:api auth method=GET path=/api/prices/:priceID tag:scope_bills_read
+func (s *Service) GetPrice(ctx context.Context, priceID string) (*Price, error) {
+	price, err := s.db.GetPrice(ctx, requestTenant(), priceID, time.Now())
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &errs.Error{
+				Code:    errs.NotFound,
+				Message: "price not found or not yet effective",
+			}
+		}
+		rlog.Error("failed to get price", "error", err)
+		return nil, err
+	}
+	versions, err := s.db.ListPriceVersions(ctx, requestTenant(), priceID)
+	if err != nil {
+		rlog.Error("failed to list price versions", "error", err)
+		return nil, err
+	}
+
+	resp := toPrice(price)
+	resp.Versions = make([]PriceVersion, len(versions))
+	for i, version := range versions {
+		resp.Versions[i] = toPriceVersion(version)
+	}
+	return resp, nil
+}
+
+type ListPricesResponse struct {
+	Prices []*Price `json:"prices"`
+}
+
+//encore:api auth method=GET path=/api/products/:productID/prices tag:scope_bills_read
+func (s *Service) ListPrices(ctx context.Context, productID string) (*ListPricesResponse, error) {
+	prices, err := s.db.ListPrices(ctx, requestTenant(), productID, time.Now())
+	if err != nil {
+		rlog.Error("failed to list prices", "error", err)
+		return nil, err
+	}
+	resp := &ListPricesResponse{Prices: make([]*Price, len(prices))}
+	for i, price := range prices {
+		resp.Prices[i] = toPrice(price)
+	}
+	return resp, nil
+}
+
+type UpdatePriceParams struct {
+	Active         bool   `json:"active"`
+	IdempotencyKey string `header:"X-Idempotency-Key"`
+}
+
+//encore:api auth method=PATCH path=/api/prices/:priceID tag:idempotency tag:scope_admin
+func (s *Service) UpdatePrice(ctx context.Context, priceID string, params *UpdatePriceParams) (*Price, error) {
+	if err := s.db.SetPriceActive(ctx, requestTenant(), priceID, params.Active); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &errs.Error{
+				Code:    errs.NotFound,
+				Message: "price not found",
+			}
+		}
+		rlog.Error("failed to update price", "error", err)
+		return nil, err
+	}
+	return s.GetPrice(ctx, priceID)
+}
+
+// ArchivePrice deactivates a price so it can no longer be referenced by new line items or bills.
+//
+//encore:api auth method=DELETE path=/api/prices/:priceID tag:scope_admin
+func (s *Service) ArchivePrice(ctx context.Context, priceID string) (*Price, error) {
+	return s.UpdatePrice(ctx, priceID, &UpdatePriceParams{Active: false})
+}
+
+type AddPriceVersionParams struct {
+	UnitAmount     int64     `json:"unit_amount"`
+	EffectiveFrom  time.Time `json:"effective_from"` // defaults to now
+	IdempotencyKey string    `header:"X-Idempotency-Key"`
+}
+
+// AddPriceVersion schedules a new amount for a price. Callers referencing the price_id
+// pick up the new amount from effective_from onwards without any change on their side.
+//
+//encore:api auth method=POST path=/api/prices/:priceID/versions tag:idempotency tag:scope_admin
+func (s *Service) AddPriceVersion(ctx context.Context, priceID string, params *AddPriceVersionParams) (*PriceVersion, error) {
+	if params.UnitAmount <= 0 {
+		return nil, &errs.Error{
+			Code:    errs.InvalidArgument,
+			Message: "unit_amount must be more than zero",
+		}
+	}
+	if params.EffectiveFrom.IsZero() {
+		params.EffectiveFrom = time.Now()
+	}
+	version, err := s.db.AddPriceVersion(ctx, requestTenant(), priceID, params.UnitAmount, params.EffectiveFrom)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &errs.Error{
+				Code:    errs.NotFound,
+				Message: "price not found",
+			}
+		}
+		rlog.Error("failed to add price version", "error", err)
+		return nil, err
+	}
+	resp := toPriceVersion(*version)
+	return &resp, nil
+}
+
+// resolvePrice looks up the catalog price effective at the given time and checks it can be charged.
+func (s *Service) resolvePrice(ctx context.Context, priceID string, priceType model.PriceType, at time.Time) (*model.Price, error) {
+	price, err := s.db.GetPrice(ctx, requestTenant(), priceID, at)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &errs.Error{
+				Code:    errs.NotFound,
+				Message: "price not found or not yet effective",
+			}
+		}
+		rlog.Error("failed to get price", "error", err, "price_id", priceID)
+		return nil, err
+	}
+	if !price.Active {
+		return nil, &errs.Error{
+			Code:    errs.FailedPrecondition,
+			Message: "price is archived",
+		}
+	}
+	if price.Type != string(priceType) {
+		return nil, &errs.Error{
+			Code:    errs.InvalidArgument,
+			Message: fmt.Sprintf("price must be of type %s", priceType),
+		}
+	}
+	return price, nil
+}
@@ -0,0 +1,168 @@
+package fee
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"encore.app/fee/dao"
+	"encore.app/fee/model"
+	"encore.app/fee/utils"
+	"encore.dev/beta/errs"
+	"encore.dev/rlog"
+)
+
+type Product struct {
+	ProductID   string    `json:"product_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Active      bool      `json:"active"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func toProduct(p *model.Product) *Product {
+	return &Product{
+		ProductID:   p.ProductID,
+		Name:        p.Name,
+		Description: p.Description,
+		Active:      p.Active,
+		CreatedAt:   p.CreatedAt,
+		UpdatedAt:   p.UpdatedAt,
+	}
+}
+
+type CreateProductParams struct {
+	ProductID      string `json:"product_id"` // optional, generated when empty
+	Name           string `json:"name"`
+	Description    string `json:"description"`
+	IdempotencyKey string `header:"X-Idempotency-Key"`
+}
+
+//encore:api auth method=POST path=/api/products tag:idempotency tag:scope_admin
+func (s *Service) CreateProduct(ctx context.Context, params *CreateProductParams) (*Product, error) {
+	if params.Name == "" {
+		return nil, &errs.Error{
+			Code:    errs.InvalidArgument,
+			Message: "name is a required field",
+		}
+	}
+	product := &model.Product{
+		ProductID:   params.ProductID,
+		Name:        params.Name,
+		Description: params.Description,
+	}
+	if product.ProductID == "" {
+		product.ProductID = utils.UUID()
+	}
+	if err := s.db.CreateProduct(ctx, requestTenant(), product); err != nil {
+		if errors.Is(err, dao.ErrProductExists) {
+			return nil, &errs.Error{
+				Code:    errs.AlreadyExists,
+				Message: "duplicate product id",
+			}
+		}
+		rlog.Error("failed to create product", "error", err)
+		return nil, err
+	}
+	return toProduct(product), nil
+}
+
+//encore:api auth method=GET path=/api/products/:productID tag:scope_bills_read
+func (s *Service) GetProduct(ctx context.Context, productID string) (*Product, error) {
+	product, err := s.db.GetProduct(ctx, requestTenant(), productID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &errs.Error{
+				Code:    errs.NotFound,
+				Message: "product not found",
+			}
+		}
+		rlog.Error("failed to get product", "error", err)
+		return nil, err
+	}
+	return toProduct(product), nil
+}
+
+type ListProductsParams struct {
+	IncludeArchived bool   `query:"include_archived"`
+	Limit           int    `query:"limit"`
+	Cursor          string `query:"cursor"`
+}
+
+type ListProductsResponse struct {
+	Products []*Product `json:"products"`
+	HasMore  bool       `json:"has_more"`
+}
+
+//encore:api auth method=GET path=/api/products tag:scope_bills_read
+func (s *Service) ListProducts(ctx context.Context, params *ListProductsParams) (*ListProductsResponse, error) {
+	if params.Limit == 0 {
+		params.Limit = 10
+	}
+
+	cursor := time.Now()
+	if params.Cursor != "" {
+		var err error
+		cursor, err = time.Parse(time.RFC3339, params.Cursor)
+		if err != nil {
+			return nil, &errs.Error{
+				Code:    errs.InvalidArgument,
+				Message: "invalid cursor",
+			}
+		}
+	}
+
+	products, hasMore, err := s.db.ListProducts(ctx, requestTenant(), !params.IncludeArchived, params.Limit, cursor)
+	if err != nil {
+		rlog.Error("failed to list products", "error", err)
+		return nil, err
+	}
+	resp := &ListProductsResponse{
+		HasMore:  hasMore,
+		Products: make([]*Product, len(products)),
+	}
+	for i, product := range products {
+		resp.Products[i] = toProduct(product)
+	}
+	return resp, nil
+}
+
+type UpdateProductParams struct {
+	Name           *string `json:"name"`
+	Description    *string `json:"description"`
+	Active         *bool   `json:"active"`
+	IdempotencyKey string  `header:"X-Idempotency-Key"`
+}
+
+//encore:api auth method=PATCH path=/api/products/:productID tag:idempotency tag:scope_admin
+func (s *Service) UpdateProduct(ctx context.Context, productID string, params *UpdateProductParams) (*Product, error) {
+	if params.Name != nil && *params.Name == "" {
+		return nil, &errs.Error{
+			Code:    errs.InvalidArgument,
+			Message: "name must not be empty",
+		}
+	}
+	product, err := s.db.UpdateProduct(ctx, requestTenant(), productID, params.Name, params.Description, params.Active)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &errs.Error{
+				Code:    errs.NotFound,
+				Message: "product not found",
+			}
+		}
+		rlog.Error("failed to update product", "error", err)
+		return nil, err
+	}
+	return toProduct(product), nil
+}
+
+// ArchiveProduct deactivates a product. Products are never hard deleted because
+// existing prices and line items keep referencing them.
+//
+//encore:api auth method=DELETE path=/api/products/:productID tag:scope_admin
+func (s *Service) ArchiveProduct(ctx context.Context, productID string) (*Product, error) {
+	active := false
+	return s.UpdateProduct(ctx, productID, &UpdateProductParams{Active: &active})
+}
@@ -0,0 +1,26 @@
+package fee
+
+import (
+	"context"
+	"time"
+)
+
+// relayInterval is how often the relay sweeps the tenants for events no change handed over.
+const relayInterval = 30 * time.Second
+
+// relay publishes the outbox and starts the webhook deliveries of every tenant until ctx is done.
+// The activity that commits a change already hands over what it wrote, the relay picks up what it
+// left behind.
+func (s *Service) relay(ctx context.Context) {
+	ticker := time.NewTicker(relayInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.activity.RelayOutbox(ctx)
+			s.activity.DispatchWebhooks(ctx)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package fee
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"encore.app/fee/model"
+	"encore.app/fee/utils"
+	temporal "encore.app/fee/workflow"
+	"encore.dev/beta/errs"
+	"encore.dev/rlog"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
+)
+
+// reopenWindow is how long after closing a bill it can still be reopened.
+const reopenWindow = 7 * 24 * time.Hour
+
+type ReopenBillParams struct {
+	IdempotencyKey   string    `header:"X-Idempotency-Key"`
+	BillingPeriodEnd time.Time `json:"billing_period_end"` // the reopened bill closes again at this time
+}
+
+func (p *ReopenBillParams) Validate() error {
+	if p.BillingPeriodEnd.IsZero() {
+		return fmt.Errorf("billing_period_end is a required field")
+	}
+	if p.BillingPeriodEnd.Before(time.Now().Add(temporal.MinPeriodLead)) {
+		return fmt.Errorf("billing_period_end is too short, must be at least 1 minutes ahead")
+	}
+	return nil
+}
+
+type ReopenBillResponse struct {
+	BillID          string `json:"bill_id"`
+	Status          string `json:"status"`
+	WorkflowID      string `json:"workflow_id"`
+	TotalAmount     int64  `json:"total_amount"`      // rebuilt from the active line items
+	FailedLineItems int    `json:"failed_line_items"` // unresolved, they block the next close again
+}
+
+// ReopenBill restores a recently closed, unpaid bill into a new workflow so line items
+// can be corrected before invoicing. Its post-processing is cancelled.
+//
+//encore:api auth method=POST path=/api/admin/bills/:billID/reopen tag:idempotency tag:scope_admin
+func (s *Service) ReopenBill(ctx context.Context, billID string, params *ReopenBillParams) (*ReopenBillResponse, error) {
+	if err := params.Validate(); err != nil {
+		return nil, &errs.Error{
+			Code:    errs.InvalidArgument,
+			Message: err.Error(),
+		}
+	}
+
+	tenantID := requestTenant()
+	bill, err := s.db.GetBill(ctx, tenantID, billID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &errs.Error{
+				Code:    errs.NotFound,
+				Message: "bill not found",
+			}
+		}
+		rlog.Error("failed to get bill", "error", err, "bill_id", billID)
+		return nil, err
+	}
+	if bill.Status != string(model.BillStatusClosed) {
+		return nil, &errs.Error{
+			Code:    errs.FailedPrecondition,
+			Message: "only closed, unpaid bills can be reopened",
+		}
+	}
+	if bill.ClosedAt == nil || time.Since(*bill.ClosedAt) > reopenWindow {
+		return nil, &errs.Error{
+			Code:    errs.FailedPrecondition,
+			Message: "bill was closed too long ago to be reopened",
+		}
+	}
+
+	failed, err := s.db.CountFailedLineItems(ctx, tenantID, billID, model.FailedLineItemPending)
+	if err != nil {
+		rlog.Error("failed to count failed line items", "error", err, "bill_id", billID)
+		return nil, err
+	}
+	state := &temporal.BillState{
+		BillID:          billID,
+		Total:           activeTotal(bill.LineItems),
+		FailedLineItems: failed,
+	}
+
+	req := &temporal.BillLifecycleWorkflowRequest{
+		TenantID:          tenantID,
+		BillID:            billID,
+		PolicyType:        model.PolicyType(bill.PolicyType),
+		BilingPeriodStart: bill.PeriodStart,
+		BillingPeriodEnd:  params.BillingPeriodEnd,
+		Currency:          bill.Currency,
+		CustomerID:        bill.CustomerID,
+		PreviousState:     state,
+		Reopen:            true,
+		Actor:             requestActor(""),
+		RequestID:         requestID(),
+	}
+	if recurring := bill.Metadata.Recurring; recurring != nil {
+		interval, err := time.ParseDuration(recurring.Interval)
+		if err != nil {
+			rlog.Error("invalid recurring interval on bill", "error", err, "bill_id", billID)
+			return nil, err
+		}
+		req.Recurring = temporal.RecurringPolicy{
+			Amount:      recurring.Amount,
+			Interval:    utils.Duration{Duration: interval},
+			Description: recurring.Description,
+			PriceID:     recurring.PriceID,
+		}
+	}
+
+	// Stop the invoice, payment link and email of the closed bill, the next close starts them again.
+	err = s.client.CancelWorkflow(ctx, temporal.BillPostprocessWorkflowID(tenantID, billID), "")
+	var notFound *serviceerror.NotFound
+	if err != nil && !errors.As(err, &notFound) {
+		rlog.Error("failed to cancel bill post-processing", "error", err, "bill_id", billID)
+		return nil, err
+	}
+
+	w, err := s.client.ExecuteWorkflow(ctx, client.StartWorkflowOptions{
+		ID:        temporal.BillCycleWorkflowID(tenantID, billID),
+		TaskQueue: temporal.BillCycleTaskQueue,
+	}, temporal.BillLifecycleWorkflow, req)
+	if err != nil {
+		rlog.Error("failed to start bill lifecycle workflow to reopen bill", "error", err, "bill_id", billID)
+		return nil, errors.New("failed to start workflow: " + billID)
+	}
+
+	return &ReopenBillResponse{
+		BillID:          billID,
+		Status:          string(model.BillStatusOpen),
+		WorkflowID:      w.GetID(),
+		TotalAmount:     state.Total,
+		FailedLineItems: failed,
+	}, nil
+}
+
+// activeTotal sums the line items that were not voided.
+func activeTotal(lineItems []model.LineItem) int64 {
+	var total int64
+	for _, item := range lineItems {
+		if item.Status == string(model.LineItemStatusActive) {
+			total += item.Amount
+		}
+	}
+	return total
+}
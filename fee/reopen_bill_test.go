@@ -0,0 +1,77 @@
+package fee
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"encore.app/fee/model"
+	temporal "encore.app/fee/workflow"
+	"encore.dev/beta/errs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
+)
+
+func TestReopenBill(t *testing.T) {
+	service, mockDB, mockTemporalClient := setup(t)
+
+	closedAt := time.Now().Add(-time.Hour)
+	mockDB.On("GetBill", mock.Anything, model.DefaultTenant, "bill-1").Return(&model.BillDetail{
+		BillID:     "bill-1",
+		Status:     string(model.BillStatusClosed),
+		PolicyType: string(model.UsageBased),
+		Currency:   "USD",
+		ClosedAt:   &closedAt,
+		LineItems: []model.LineItem{
+			{LineItemID: "a", Amount: 100, Status: string(model.LineItemStatusActive)},
+			{LineItemID: "b", Amount: 40, Status: string(model.LineItemStatusVoided)},
+			{LineItemID: "c", Amount: 25, Status: string(model.LineItemStatusActive)},
+		},
+	}, nil).Once()
+	mockDB.On("CountFailedLineItems", mock.Anything, model.DefaultTenant, "bill-1", model.FailedLineItemPending).Return(1, nil).Once()
+	mockTemporalClient.On("CancelWorkflow", mock.Anything, temporal.BillPostprocessWorkflowID(model.DefaultTenant, "bill-1"), "").
+		Return(serviceerror.NewNotFound("workflow not found")).Once()
+	mockTemporalClient.On("ExecuteWorkflow", mock.Anything, mock.MatchedBy(func(options client.StartWorkflowOptions) bool {
+		return options.ID == temporal.BillCycleWorkflowID(model.DefaultTenant, "bill-1")
+	}), mock.Anything, mock.MatchedBy(func(req *temporal.BillLifecycleWorkflowRequest) bool {
+		return req.Reopen && req.PreviousState.Total == 125 && req.PreviousState.FailedLineItems == 1
+	})).Return(&mockWorkflowRun{}, nil).Once()
+
+	resp, err := service.ReopenBill(context.Background(), "bill-1", &ReopenBillParams{BillingPeriodEnd: time.Now().Add(time.Hour)})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "OPEN", resp.Status)
+	assert.Equal(t, int64(125), resp.TotalAmount)
+	mockDB.AssertExpectations(t)
+	mockTemporalClient.AssertExpectations(t)
+}
+
+func TestReopenBill_NotReopenable(t *testing.T) {
+	longAgo := time.Now().Add(-reopenWindow - time.Hour)
+	recently := time.Now().Add(-time.Hour)
+	tests := []struct {
+		name string
+		bill *model.BillDetail
+	}{
+		{"settled", &model.BillDetail{BillID: "bill-1", Status: string(model.BillStatusSettled), ClosedAt: &recently}},
+		{"open", &model.BillDetail{BillID: "bill-1", Status: string(model.BillStatusOpen)}},
+		{"closed too long ago", &model.BillDetail{BillID: "bill-1", Status: string(model.BillStatusClosed), ClosedAt: &longAgo}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, mockDB, mockTemporalClient := setup(t)
+			mockDB.On("GetBill", mock.Anything, model.DefaultTenant, "bill-1").Return(tt.bill, nil).Once()
+
+			_, err := service.ReopenBill(context.Background(), "bill-1", &ReopenBillParams{BillingPeriodEnd: time.Now().Add(time.Hour)})
+
+			var errsErr *errs.Error
+			assert.True(t, errors.As(err, &errsErr))
+			assert.Equal(t, errs.FailedPrecondition, errsErr.Code)
+			mockTemporalClient.AssertNotCalled(t, "ExecuteWorkflow", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
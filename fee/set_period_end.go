@@ -0,0 +1,54 @@
+package fee
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	temporal "encore.app/fee/workflow"
+	"encore.dev/beta/errs"
+)
+
+type SetPeriodEndParams struct {
+	IdempotencyKey   string    `header:"X-Idempotency-Key"`
+	BillingPeriodEnd time.Time `json:"billing_period_end"`
+}
+
+func (p *SetPeriodEndParams) Validate() error {
+	if p.BillingPeriodEnd.IsZero() {
+		return fmt.Errorf("billing_period_end is a required field")
+	}
+	if p.BillingPeriodEnd.Before(time.Now().Add(temporal.MinPeriodLead)) {
+		return fmt.Errorf("billing_period_end is too short, must be at least 1 minutes ahead")
+	}
+	return nil
+}
+
+type SetPeriodEndResponse struct {
+	BillID           string    `json:"bill_id"`
+	BillingPeriodEnd time.Time `json:"billing_period_end"`
+}
+
+// SetPeriodEnd extends or shortens the billing period of an open bill. The workflow
+// replaces its close timer, so the bill closes at the new period end.
+//
+//encore:api auth method=PUT path=/api/bills/:billID/period-end tag:idempotency tag:scope_bills_write
+func (s *Service) SetPeriodEnd(ctx context.Context, billID string, params *SetPeriodEndParams) (*SetPeriodEndResponse, error) {
+	if err := params.Validate(); err != nil {
+		return nil, &errs.Error{
+			Code:    errs.InvalidArgument,
+			Message: err.Error(),
+		}
+	}
+
+	req := temporal.SetPeriodEndRequest{
+		BillID:           billID,
+		BillingPeriodEnd: params.BillingPeriodEnd,
+		Actor:            requestActor(""),
+	}
+	var periodEnd time.Time
+	if err := s.updateBill(ctx, billID, temporal.SetPeriodEndUpdate, req, &periodEnd); err != nil {
+		return nil, err
+	}
+	return &SetPeriodEndResponse{BillID: billID, BillingPeriodEnd: periodEnd}, nil
+}
@@ -0,0 +1,56 @@
+package fee
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	temporal "encore.app/fee/workflow"
+	"encore.dev/beta/errs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.temporal.io/sdk/client"
+	temporalsdk "go.temporal.io/sdk/temporal"
+)
+
+func TestSetPeriodEnd(t *testing.T) {
+	service, _, mockTemporalClient := setup(t)
+
+	periodEnd := time.Now().Add(48 * time.Hour).UTC().Truncate(time.Second)
+	mockTemporalClient.On("UpdateWorkflow", mock.Anything, mock.MatchedBy(func(options client.UpdateWorkflowOptions) bool {
+		req, ok := options.Args[0].(temporal.SetPeriodEndRequest)
+		return ok && options.UpdateName == temporal.SetPeriodEndUpdate && req.BillingPeriodEnd.Equal(periodEnd)
+	})).Return(&mockUpdateHandle{result: periodEnd}, nil).Once()
+
+	resp, err := service.SetPeriodEnd(context.Background(), "bill-1", &SetPeriodEndParams{BillingPeriodEnd: periodEnd})
+
+	assert.NoError(t, err)
+	assert.True(t, periodEnd.Equal(resp.BillingPeriodEnd))
+	mockTemporalClient.AssertExpectations(t)
+}
+
+func TestSetPeriodEnd_TooSoon(t *testing.T) {
+	service, _, mockTemporalClient := setup(t)
+
+	_, err := service.SetPeriodEnd(context.Background(), "bill-1", &SetPeriodEndParams{BillingPeriodEnd: time.Now().Add(10 * time.Second)})
+
+	var errsErr *errs.Error
+	assert.True(t, errors.As(err, &errsErr))
+	assert.Equal(t, errs.InvalidArgument, errsErr.Code)
+	mockTemporalClient.AssertNotCalled(t, "UpdateWorkflow", mock.Anything, mock.Anything)
+}
+
+func TestSetPeriodEnd_RejectedByWorkflow(t *testing.T) {
+	service, _, mockTemporalClient := setup(t)
+
+	mockTemporalClient.On("UpdateWorkflow", mock.Anything, mock.Anything).Return(&mockUpdateHandle{
+		err: temporalsdk.NewApplicationError("billing_period_end must be at least 1m0s after billing_period_start", temporal.ErrTypeInvalidPeriod),
+	}, nil).Once()
+
+	_, err := service.SetPeriodEnd(context.Background(), "bill-1", &SetPeriodEndParams{BillingPeriodEnd: time.Now().Add(time.Hour)})
+
+	var errsErr *errs.Error
+	assert.True(t, errors.As(err, &errsErr))
+	assert.Equal(t, errs.InvalidArgument, errsErr.Code)
+}
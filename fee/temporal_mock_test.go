@@ -2,6 +2,7 @@ package fee
 
 import (
 	"context"
+	"reflect"
 
 	"github.com/stretchr/testify/mock"
 	"go.temporal.io/sdk/client"
@@ -35,6 +36,36 @@ func (m *mockTemporalClient) SignalWorkflow(ctx context.Context, workflowID stri
 	return a.Error(0)
 }
 
+func (m *mockTemporalClient) CancelWorkflow(ctx context.Context, workflowID string, runID string) error {
+	a := m.Called(ctx, workflowID, runID)
+	return a.Error(0)
+}
+
+func (m *mockTemporalClient) UpdateWorkflow(ctx context.Context, options client.UpdateWorkflowOptions) (client.WorkflowUpdateHandle, error) {
+	a := m.Called(ctx, options)
+	if a.Get(0) == nil {
+		return nil, a.Error(1)
+	}
+	return a.Get(0).(client.WorkflowUpdateHandle), a.Error(1)
+}
+
+// mockUpdateHandle completes an update with a fixed result or error.
+type mockUpdateHandle struct {
+	client.WorkflowUpdateHandle
+	result interface{}
+	err    error
+}
+
+func (h *mockUpdateHandle) Get(ctx context.Context, valuePtr interface{}) error {
+	if h.err != nil {
+		return h.err
+	}
+	if h.result != nil {
+		reflect.ValueOf(valuePtr).Elem().Set(reflect.ValueOf(h.result))
+	}
+	return nil
+}
+
 type mockWorkflowRun struct {
 	client.WorkflowRun
 }
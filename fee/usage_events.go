@@ -0,0 +1,135 @@
+package fee
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"encore.app/fee/model"
+	"encore.app/fee/utils"
+	temporal "encore.app/fee/workflow"
+	"encore.dev/pubsub"
+	"encore.dev/rlog"
+	"go.temporal.io/api/serviceerror"
+)
+
+// UsageEvent is a single billable usage event published by an upstream service.
+type UsageEvent struct {
+	EventID     string    `json:"event_id"` // unique per event, used for de-duplication
+	BillID      string    `json:"bill_id"`
+	Amount      int64     `json:"amount"`
+	Description string    `json:"description"`
+	OccurredAt  time.Time `json:"occurred_at"`
+}
+
+func (e *UsageEvent) Validate() error {
+	if e.EventID == "" {
+		return fmt.Errorf("event_id is a required field")
+	}
+	if e.BillID == "" {
+		return fmt.Errorf("bill_id is a required field")
+	}
+	if e.Amount <= 0 {
+		return fmt.Errorf("amount must be positive")
+	}
+	return nil
+}
+
+// UsageEventBatch is the message published to the usage-events topic. Publishers should
+// group the events they already have in hand; each bill in a batch receives one signal.
+type UsageEventBatch struct {
+	TenantID string       `json:"tenant_id"` // tenant of every bill in the batch, the default tenant when empty
+	Events   []UsageEvent `json:"events"`
+}
+
+// UsageEvents lets upstream services report usage asynchronously instead of calling
+// the add line item API once per event.
+var UsageEvents = pubsub.NewTopic[*UsageEventBatch]("usage-events", pubsub.TopicConfig{
+	DeliveryGuarantee: pubsub.AtLeastOnce,
+})
+
+// Messages that keep failing are retried with backoff and then dead-lettered by the Pub/Sub provider.
+var _ = pubsub.NewSubscription(UsageEvents, "ingest-usage-events", pubsub.SubscriptionConfig[*UsageEventBatch]{
+	Handler: pubsub.MethodHandler((*Service).IngestUsageEvents),
+	RetryPolicy: &pubsub.RetryPolicy{
+		MinBackoff: 1 * time.Second,
+		MaxBackoff: 5 * time.Minute,
+		MaxRetries: 10,
+	},
+})
+
+// IngestUsageEvents validates a batch of usage events and hands them to each bill workflow as a
+// single AddLineItems signal per bill. The line item ID of an event is derived from its event_id,
+// so a redelivered event is a no-op insert in the workflow and is not billed twice.
+func (s *Service) IngestUsageEvents(ctx context.Context, batch *UsageEventBatch) error {
+	tenantID := batch.TenantID
+	if tenantID == "" {
+		tenantID = model.DefaultTenant
+	}
+	if err := model.ValidateTenantID(tenantID); err != nil {
+		// Redelivering the batch can't fix its tenant either.
+		rlog.Warn("dropping usage events with invalid tenant", "error", err, "count", len(batch.Events))
+		return nil
+	}
+
+	// Group per bill, keeping the order events were published in.
+	var billIDs []string
+	signals := make(map[string]*temporal.AddLineItemsSignalRequest)
+	seen := make(map[string]bool, len(batch.Events))
+	for _, event := range batch.Events {
+		// Invalid events can't be fixed by a redelivery, so they are dropped instead of failing the batch.
+		if err := event.Validate(); err != nil {
+			rlog.Warn("dropping invalid usage event", "error", err, "event_id", event.EventID, "bill_id", event.BillID)
+			continue
+		}
+		if seen[event.EventID] {
+			continue
+		}
+		seen[event.EventID] = true
+		if event.OccurredAt.IsZero() {
+			event.OccurredAt = time.Now()
+		}
+		signal, ok := signals[event.BillID]
+		if !ok {
+			signal = &temporal.AddLineItemsSignalRequest{BillID: event.BillID, Actor: "usage-events", RequestID: requestID()}
+			signals[event.BillID] = signal
+			billIDs = append(billIDs, event.BillID)
+		}
+		item := temporal.AddLineItemSignalRequest{
+			LineItemID: utils.NameUUID(tenantID + "/" + event.BillID + "/" + event.EventID),
+			Amount:     event.Amount,
+			BillID:     event.BillID,
+			Actor:      "usage-events",
+		}
+		if event.Description != "" {
+			item.Metadata = &model.LineItemMetadata{Description: event.Description}
+		}
+		signal.Items = append(signal.Items, item)
+	}
+
+	var failed int
+	for _, billID := range billIDs {
+		signal := signals[billID]
+		err := s.client.SignalWorkflow(ctx, temporal.BillCycleWorkflowID(tenantID, billID), "", temporal.AddLineItemsSignal, *signal)
+		if err == nil {
+			continue
+		}
+		var notFound *serviceerror.NotFound
+		if errors.As(err, &notFound) || errors.Is(err, sql.ErrNoRows) {
+			// Usage for a closed or unknown bill can't be billed by retrying either.
+			rlog.Warn("dropping usage events for bill not found or already closed", "bill_id", billID, "count", len(signal.Items))
+			continue
+		}
+		rlog.Error("failed to signal usage events to workflow", "error", err, "bill_id", billID)
+		failed += len(signal.Items)
+	}
+
+	if failed > 0 {
+		// The redelivered message signals every bill again, the bills that already got their
+		// events skip them because their line items exist.
+		return fmt.Errorf("failed to deliver %d usage events", failed)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package fee
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"encore.app/fee/model"
+	temporal "encore.app/fee/workflow"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestIngestUsageEvents(t *testing.T) {
+	service, _, mockTemporalClient := setup(t)
+
+	batch := &UsageEventBatch{
+		Events: []UsageEvent{
+			{EventID: "evt-1", BillID: "bill-a", Amount: 100},
+			{EventID: "evt-2", BillID: "bill-b", Amount: 200},
+			{EventID: "evt-3", BillID: "bill-a", Amount: 300},
+			{EventID: "evt-1", BillID: "bill-a", Amount: 100}, // duplicate within the batch
+			{EventID: "evt-4", BillID: "bill-a", Amount: 0},   // invalid
+		},
+	}
+
+	mockTemporalClient.On(
+		"SignalWorkflow",
+		mock.Anything,
+		temporal.BillCycleWorkflowID(model.DefaultTenant, "bill-a"),
+		"",
+		temporal.AddLineItemsSignal,
+		mock.MatchedBy(func(signal temporal.AddLineItemsSignalRequest) bool {
+			return len(signal.Items) == 2 && signal.Items[0].Amount == 100 && signal.Items[1].Amount == 300
+		}),
+	).Return(nil).Once()
+	mockTemporalClient.On(
+		"SignalWorkflow",
+		mock.Anything,
+		temporal.BillCycleWorkflowID(model.DefaultTenant, "bill-b"),
+		"",
+		temporal.AddLineItemsSignal,
+		mock.MatchedBy(func(signal temporal.AddLineItemsSignalRequest) bool {
+			return len(signal.Items) == 1 && signal.Items[0].Amount == 200
+		}),
+	).Return(nil).Once()
+
+	err := service.IngestUsageEvents(context.Background(), batch)
+
+	assert.NoError(t, err)
+	mockTemporalClient.AssertExpectations(t)
+}
+
+func TestIngestUsageEvents_RedeliveryKeepsLineItemIDs(t *testing.T) {
+	service, _, mockTemporalClient := setup(t)
+
+	batch := &UsageEventBatch{
+		Events: []UsageEvent{
+			{EventID: "evt-1", BillID: "bill-a", Amount: 100},
+		},
+	}
+
+	var lineItemIDs []string
+	mockTemporalClient.On("SignalWorkflow", mock.Anything, mock.Anything, mock.Anything, temporal.AddLineItemsSignal, mock.Anything).
+		Run(func(args mock.Arguments) {
+			signal := args.Get(4).(temporal.AddLineItemsSignalRequest)
+			lineItemIDs = append(lineItemIDs, signal.Items[0].LineItemID)
+		}).Return(nil).Twice()
+
+	// The bill workflow skips the line item of a redelivered event, it already exists.
+	assert.NoError(t, service.IngestUsageEvents(context.Background(), batch))
+	assert.NoError(t, service.IngestUsageEvents(context.Background(), batch))
+	if assert.Len(t, lineItemIDs, 2) {
+		assert.Equal(t, lineItemIDs[0], lineItemIDs[1])
+	}
+	mockTemporalClient.AssertExpectations(t)
+}
+
+func TestIngestUsageEvents_SignalError(t *testing.T) {
+	service, _, mockTemporalClient := setup(t)
+
+	batch := &UsageEventBatch{
+		Events: []UsageEvent{
+			{EventID: "evt-1", BillID: "bill-a", Amount: 100},
+		},
+	}
+
+	mockTemporalClient.On("SignalWorkflow", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(errors.New("temporal unavailable")).Once()
+
+	err := service.IngestUsageEvents(context.Background(), batch)
+
+	assert.Error(t, err)
+	mockTemporalClient.AssertExpectations(t)
+}
@@ -0,0 +1,348 @@
+package fee
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"encore.app/fee/dao"
+	"encore.app/fee/model"
+	"encore.app/fee/utils"
+	temporal "encore.app/fee/workflow"
+	"encore.dev"
+	"encore.dev/beta/errs"
+	"encore.dev/rlog"
+	"go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
+)
+
+const (
+	maxUsageImportBytes = 10 << 20
+	maxUsageImportRows  = 50000
+	// maxUsageImportErrors caps the validation errors returned for a rejected file.
+	maxUsageImportErrors = 100
+	usageImportErrorPage = 1000
+)
+
+var usageImportColumns = []string{"bill_id", "amount", "description", "external_ref", "occurred_at"}
+
+type UsageImportRowError struct {
+	Row   int    `json:"row"` // 1-based data row, the header is not counted
+	Error string `json:"error"`
+}
+
+// UsageImportValidationErrors is returned as the error details when a file is rejected.
+type UsageImportValidationErrors struct {
+	Errors []UsageImportRowError `json:"errors"`
+}
+
+func (UsageImportValidationErrors) ErrDetails() {}
+
+type UsageImport struct {
+	ImportID      string     `json:"import_id"`
+	Status        string     `json:"status"`
+	TotalRows     int        `json:"total_rows"`
+	ProcessedRows int        `json:"processed_rows"`
+	FailedRows    int        `json:"failed_rows"`
+	WorkflowID    string     `json:"workflow_id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	CompletedAt   *time.Time `json:"completed_at"`
+}
+
+func toUsageImport(tenantID string, i *model.UsageImport) *UsageImport {
+	return &UsageImport{
+		ImportID:      i.ImportID,
+		Status:        i.Status,
+		TotalRows:     i.TotalRows,
+		ProcessedRows: i.ProcessedRows,
+		FailedRows:    i.FailedRows,
+		WorkflowID:    temporal.UsageImportWorkflowID(tenantID, i.ImportID),
+		CreatedAt:     i.CreatedAt,
+		CompletedAt:   i.CompletedAt,
+	}
+}
+
+// ImportUsage accepts a CSV file with the columns bill_id, amount, description, external_ref
+// and occurred_at. Every row is validated before anything is stored; a file with any invalid
+// row is rejected as a whole. The import is keyed by the file's SHA-256, so uploading the same
+// file again returns the original import instead of billing the rows twice.
+//
+//encore:api auth raw method=POST path=/api/usage-imports tag:scope_line_items_write
+func (s *Service) ImportUsage(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxUsageImportBytes+1))
+	if err != nil {
+		errs.HTTPError(w, err)
+		return
+	}
+	if len(body) > maxUsageImportBytes {
+		errs.HTTPError(w, &errs.Error{
+			Code:    errs.InvalidArgument,
+			Message: fmt.Sprintf("file must not be larger than %d bytes", maxUsageImportBytes),
+		})
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	fileHash := hex.EncodeToString(sum[:])
+	importID := fileHash[:32]
+
+	tenantID := requestTenant()
+	rows, rowErrors := parseUsageImport(bytes.NewReader(body), importID)
+	if len(rowErrors) == 0 {
+		rowErrors, err = s.checkUsageImportBills(req.Context(), tenantID, rows)
+		if err != nil {
+			errs.HTTPError(w, err)
+			return
+		}
+	}
+	if len(rowErrors) > 0 {
+		errs.HTTPError(w, &errs.Error{
+			Code:    errs.InvalidArgument,
+			Message: fmt.Sprintf("file contains %d invalid rows", len(rowErrors)),
+			Details: UsageImportValidationErrors{Errors: rowErrors[:min(len(rowErrors), maxUsageImportErrors)]},
+		})
+		return
+	}
+
+	usageImport := &model.UsageImport{ImportID: importID, FileHash: fileHash}
+	created, err := s.db.CreateUsageImport(req.Context(), tenantID, usageImport, rows)
+	if err != nil {
+		rlog.Error("failed to create usage import", "error", err)
+		errs.HTTPError(w, err)
+		return
+	}
+	status := http.StatusAccepted
+	if !created {
+		rlog.Info("usage import already exists, returning the original import", "import_id", importID)
+		status = http.StatusOK
+		usageImport, err = s.db.GetUsageImport(req.Context(), tenantID, importID)
+		if err != nil {
+			rlog.Error("failed to get usage import", "error", err)
+			errs.HTTPError(w, err)
+			return
+		}
+	}
+
+	// A pending import may be a re-upload after the workflow failed to start, so start it again.
+	// The workflow ID is derived from the import, so an already running import is left alone.
+	if usageImport.Status == string(model.UsageImportStatusPending) {
+		if err := s.startUsageImport(req.Context(), tenantID, importID); err != nil {
+			rlog.Error("failed to start usage import workflow", "error", err, "import_id", importID)
+			errs.HTTPError(w, errors.New("failed to start usage import: "+importID))
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(toUsageImport(tenantID, usageImport)); err != nil {
+		rlog.Error("failed to write usage import response", "error", err)
+	}
+}
+
+func (s *Service) startUsageImport(ctx context.Context, tenantID, importID string) error {
+	_, err := s.client.ExecuteWorkflow(ctx, client.StartWorkflowOptions{
+		ID:                    temporal.UsageImportWorkflowID(tenantID, importID),
+		TaskQueue:             temporal.BillCycleTaskQueue,
+		WorkflowIDReusePolicy: enums.WORKFLOW_ID_REUSE_POLICY_REJECT_DUPLICATE,
+	}, temporal.UsageImportWorkflow, &temporal.UsageImportWorkflowRequest{TenantID: tenantID, ImportID: importID})
+	var alreadyStarted *serviceerror.WorkflowExecutionAlreadyStarted
+	if err != nil && !errors.As(err, &alreadyStarted) {
+		return err
+	}
+	return nil
+}
+
+// checkUsageImportBills rejects rows for bills that don't exist or no longer accept line items.
+func (s *Service) checkUsageImportBills(ctx context.Context, tenantID string, rows []model.UsageImportRow) ([]UsageImportRowError, error) {
+	problems := make(map[string]string)
+	var rowErrors []UsageImportRowError
+	for _, row := range rows {
+		problem, checked := problems[row.BillID]
+		if !checked {
+			status, err := s.db.GetBillStatus(ctx, tenantID, row.BillID)
+			switch {
+			case errors.Is(err, dao.ErrBillNotFound):
+				problem = "bill not found"
+			case err != nil:
+				rlog.Error("failed to get bill status", "error", err, "bill_id", row.BillID)
+				return nil, err
+			case status != model.BillStatusOpen && status != model.BillStatusScheduled:
+				problem = fmt.Sprintf("bill is %s", strings.ToLower(string(status)))
+			}
+			problems[row.BillID] = problem
+		}
+		if problem != "" {
+			rowErrors = append(rowErrors, UsageImportRowError{Row: row.RowNumber, Error: problem})
+		}
+	}
+	return rowErrors, nil
+}
+
+// GetUsageImport reports the progress of a usage import.
+//
+//encore:api auth method=GET path=/api/usage-imports/:importID tag:scope_bills_read
+func (s *Service) GetUsageImport(ctx context.Context, importID string) (*UsageImport, error) {
+	tenantID := requestTenant()
+	usageImport, err := s.db.GetUsageImport(ctx, tenantID, importID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &errs.Error{
+				Code:    errs.NotFound,
+				Message: "usage import not found",
+			}
+		}
+		rlog.Error("failed to get usage import", "error", err)
+		return nil, err
+	}
+	return toUsageImport(tenantID, usageImport), nil
+}
+
+// GetUsageImportErrors downloads the rows of an import that could not be billed as a CSV report.
+//
+//encore:api auth raw method=GET path=/api/usage-imports/:importID/errors tag:scope_bills_read
+func (s *Service) GetUsageImportErrors(w http.ResponseWriter, req *http.Request) {
+	importID := encore.CurrentRequest().PathParams.Get("importID")
+	if _, err := s.GetUsageImport(req.Context(), importID); err != nil {
+		errs.HTTPError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "usage-import-"+importID+"-errors.csv"))
+	out := csv.NewWriter(w)
+	_ = out.Write([]string{"row", "bill_id", "amount", "external_ref", "error"})
+
+	afterRow := 0
+	for {
+		rows, err := s.db.ListUsageImportRows(req.Context(), requestTenant(), importID, afterRow, usageImportErrorPage, true)
+		if err != nil {
+			// Headers are already sent, so the report is cut short and the failure only logged.
+			rlog.Error("failed to list failed usage import rows", "error", err, "import_id", importID)
+			break
+		}
+		for _, row := range rows {
+			_ = out.Write([]string{strconv.Itoa(row.RowNumber), row.BillID, strconv.FormatInt(row.Amount, 10), row.ExternalRef, row.Error})
+		}
+		if len(rows) < usageImportErrorPage {
+			break
+		}
+		afterRow = rows[len(rows)-1].RowNumber
+	}
+	out.Flush()
+}
+
+// parseUsageImport reads and validates every row of a usage CSV. The header row names the
+// columns, so they may come in any order; bill_id and amount are required. Line item IDs are
+// derived from the import and row number, which makes redelivering a row harmless.
+func parseUsageImport(r io.Reader, importID string) ([]model.UsageImportRow, []UsageImportRowError) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, []UsageImportRowError{{Row: 0, Error: "file is empty"}}
+		}
+		return nil, []UsageImportRowError{{Row: 0, Error: err.Error()}}
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, required := range usageImportColumns[:2] {
+		if _, ok := columns[required]; !ok {
+			return nil, []UsageImportRowError{{Row: 0, Error: fmt.Sprintf("missing required column %s", required)}}
+		}
+	}
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var rows []model.UsageImportRow
+	var rowErrors []UsageImportRowError
+	refs := make(map[string]int)
+	for rowNumber := 1; ; rowNumber++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if rowNumber > maxUsageImportRows {
+			return nil, []UsageImportRowError{{Row: rowNumber, Error: fmt.Sprintf("file must not contain more than %d rows", maxUsageImportRows)}}
+		}
+		if err != nil {
+			rowErrors = append(rowErrors, UsageImportRowError{Row: rowNumber, Error: err.Error()})
+			continue
+		}
+
+		row := model.UsageImportRow{
+			RowNumber:   rowNumber,
+			BillID:      field(record, "bill_id"),
+			Description: field(record, "description"),
+			ExternalRef: field(record, "external_ref"),
+			LineItemID:  utils.NameUUID(importID + "/" + strconv.Itoa(rowNumber)),
+		}
+		if err := validateUsageImportRow(&row, field(record, "amount"), field(record, "occurred_at")); err != nil {
+			rowErrors = append(rowErrors, UsageImportRowError{Row: rowNumber, Error: err.Error()})
+			continue
+		}
+		if row.ExternalRef != "" {
+			key := row.BillID + "/" + row.ExternalRef
+			if first, ok := refs[key]; ok {
+				rowErrors = append(rowErrors, UsageImportRowError{Row: rowNumber, Error: fmt.Sprintf("external_ref duplicates row %d", first)})
+				continue
+			}
+			refs[key] = rowNumber
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 && len(rowErrors) == 0 {
+		return nil, []UsageImportRowError{{Row: 0, Error: "file contains no rows"}}
+	}
+	return rows, rowErrors
+}
+
+func validateUsageImportRow(row *model.UsageImportRow, amount, occurredAt string) error {
+	if row.BillID == "" {
+		return fmt.Errorf("bill_id is a required field")
+	}
+	if len(row.BillID) > 64 {
+		return fmt.Errorf("bill_id must not be longer than 64 characters")
+	}
+	value, err := strconv.ParseInt(amount, 10, 64)
+	if err != nil {
+		return fmt.Errorf("amount must be an integer in minor units")
+	}
+	if value <= 0 {
+		return fmt.Errorf("amount must be positive")
+	}
+	row.Amount = value
+	if len(row.ExternalRef) > 128 {
+		return fmt.Errorf("external_ref must not be longer than 128 characters")
+	}
+	if occurredAt != "" {
+		at, err := time.Parse(time.RFC3339, occurredAt)
+		if err != nil {
+			return fmt.Errorf("occurred_at must be an RFC 3339 timestamp")
+		}
+		row.OccurredAt = &at
+	}
+	return nil
+}
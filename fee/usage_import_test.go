@@ -0,0 +1,102 @@
+package fee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"encore.app/fee/dao"
+	"encore.app/fee/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestParseUsageImport(t *testing.T) {
+	tests := []struct {
+		name       string
+		file       string
+		wantRows   int
+		wantErrors []UsageImportRowError
+	}{
+		{
+			name:     "valid file with columns in any order",
+			file:     "amount,bill_id,external_ref,description,occurred_at\n100,bill-a,ref-1,API calls,2025-01-01T00:00:00Z\n200,bill-b,,,\n",
+			wantRows: 2,
+		},
+		{
+			name:       "missing required column",
+			file:       "bill_id,description\nbill-a,API calls\n",
+			wantErrors: []UsageImportRowError{{Row: 0, Error: "missing required column amount"}},
+		},
+		{
+			name: "invalid rows are all reported",
+			file: "bill_id,amount,external_ref,occurred_at\n,100,,\nbill-a,-5,,\nbill-a,abc,,\nbill-a,100,,yesterday\nbill-a,100,ref-1,\nbill-a,100,ref-1,\n",
+			wantErrors: []UsageImportRowError{
+				{Row: 1, Error: "bill_id is a required field"},
+				{Row: 2, Error: "amount must be positive"},
+				{Row: 3, Error: "amount must be an integer in minor units"},
+				{Row: 4, Error: "occurred_at must be an RFC 3339 timestamp"},
+				{Row: 6, Error: "external_ref duplicates row 5"},
+			},
+		},
+		{
+			name:       "header only",
+			file:       "bill_id,amount\n",
+			wantErrors: []UsageImportRowError{{Row: 0, Error: "file contains no rows"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, rowErrors := parseUsageImport(strings.NewReader(tt.file), "import-1")
+			assert.Equal(t, tt.wantErrors, rowErrors)
+			if tt.wantErrors == nil {
+				assert.Len(t, rows, tt.wantRows)
+			}
+		})
+	}
+}
+
+func TestParseUsageImport_StableLineItemIDs(t *testing.T) {
+	file := "bill_id,amount\nbill-a,100\nbill-a,100\n"
+
+	first, _ := parseUsageImport(strings.NewReader(file), "import-1")
+	second, _ := parseUsageImport(strings.NewReader(file), "import-1")
+
+	assert.Equal(t, first[0].LineItemID, second[0].LineItemID)
+	assert.NotEqual(t, first[0].LineItemID, first[1].LineItemID)
+}
+
+func TestImportUsage(t *testing.T) {
+	service, mockDB, mockTemporalClient := setup(t)
+
+	mockDB.On("GetBillStatus", mock.Anything, model.DefaultTenant, "bill-a").Return(model.BillStatusOpen, nil).Once()
+	mockDB.On("CreateUsageImport", mock.Anything, model.DefaultTenant, mock.Anything, mock.MatchedBy(func(rows []model.UsageImportRow) bool {
+		return len(rows) == 2
+	})).Return(true, nil).Once()
+	mockTemporalClient.On("ExecuteWorkflow", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(&mockWorkflowRun{}, nil).Once()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/usage-imports", strings.NewReader("bill_id,amount\nbill-a,100\nbill-a,200\n"))
+	w := httptest.NewRecorder()
+	service.ImportUsage(w, req)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	mockDB.AssertExpectations(t)
+	mockTemporalClient.AssertExpectations(t)
+}
+
+func TestImportUsage_UnknownBill(t *testing.T) {
+	service, mockDB, mockTemporalClient := setup(t)
+
+	mockDB.On("GetBillStatus", mock.Anything, model.DefaultTenant, "bill-x").Return(model.BillStatus(""), dao.ErrBillNotFound).Once()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/usage-imports", strings.NewReader("bill_id,amount\nbill-x,100\n"))
+	w := httptest.NewRecorder()
+	service.ImportUsage(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "bill not found")
+	mockDB.AssertNotCalled(t, "CreateUsageImport", mock.Anything, model.DefaultTenant, mock.Anything, mock.Anything)
+	mockTemporalClient.AssertNotCalled(t, "ExecuteWorkflow", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
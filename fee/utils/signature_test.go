@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVerifySignature(t *testing.T) {
+	secret := []byte("whsec_test")
+	body := []byte(`{"id":"e-1"}`)
+	now := time.Now()
+
+	tests := []struct {
+		name   string
+		secret []byte
+		header string
+		want   bool
+	}{
+		{"Valid", secret, Sign(secret, body, now), true},
+		{"OtherSecret", secret, Sign([]byte("other"), body, now), false},
+		{"Expired", secret, Sign(secret, body, now.Add(-10*time.Minute)), false},
+		{"Malformed", secret, "v1=abc", false},
+		{"EmptySecret", nil, Sign(nil, body, now), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifySignature(tt.secret, tt.header, body, now, 5*time.Minute); got != tt.want {
+				t.Errorf("VerifySignature() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
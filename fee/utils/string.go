@@ -9,3 +9,8 @@ import (
 func UUID() string {
 	return strings.ReplaceAll(uuid.NewString(), "-", "")
 }
+
+// NameUUID derives a stable ID from name, so the same name always yields the same ID.
+func NameUUID(name string) string {
+	return strings.ReplaceAll(uuid.NewSHA1(uuid.NameSpaceOID, []byte(name)).String(), "-", "")
+}
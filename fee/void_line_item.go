@@ -2,25 +2,26 @@ package fee
 
 import (
 	"context"
-	"database/sql"
-	"errors"
 
 	"encore.app/fee/model"
 	temporal "encore.app/fee/workflow"
 	"encore.dev/beta/errs"
 	"encore.dev/rlog"
-	"go.temporal.io/api/serviceerror"
 )
 
 type RemoveLineItemResponse struct {
 	LineItemID string `json:"line_item_id"`
 	BillID     string `json:"bill_id"`
+	Amount     int64  `json:"amount"`
+	Total      int64  `json:"total"` // running total of the bill after the void
 	WorkflowID string `json:"workflow_id"`
 }
 
-//encore:api public method=PUT path=/api/bills/:billID/line-items/:lineItemID/void tag:idempotency
+// VoidLineItem voids a line item through a workflow update and waits for the bill's new running total.
+//
+//encore:api auth method=PUT path=/api/bills/:billID/line-items/:lineItemID/void tag:idempotency tag:scope_line_items_write
 func (s *Service) VoidLineItem(ctx context.Context, billID, lineItemID string) (*RemoveLineItemResponse, error) {
-	exits, err := s.db.IsLineItemExists(ctx, billID, lineItemID, string(model.LineItemStatusActive))
+	exits, err := s.db.IsLineItemExists(ctx, requestTenant(), billID, lineItemID, string(model.LineItemStatusActive))
 	if err != nil {
 		rlog.Error("failed to check is line item exists", "error", err)
 		return nil, err
@@ -36,25 +37,21 @@ func (s *Service) VoidLineItem(ctx context.Context, billID, lineItemID string) (
 		LineItemID: lineItemID,
 		BillID:     billID,
 		Status:     model.LineItemStatusVoided,
+		Actor:      requestActor(""),
+		RequestID:  requestID(),
 	}
-	workflowID := temporal.BillCycleWorkflowID(billID)
+	workflowID := temporal.BillCycleWorkflowID(requestTenant(), billID)
 
-	err = s.client.SignalWorkflow(ctx, workflowID, "", temporal.UpdateLineItemSignal, signal)
-	if err != nil {
-		var notFound *serviceerror.NotFound
-		if errors.As(err, &notFound) || errors.Is(err, sql.ErrNoRows) {
-			return nil, &errs.Error{
-				Code:    errs.NotFound,
-				Message: "bill not found or already closed",
-			}
-		}
-		rlog.Error("failed to signal update line item workflow", "error", err)
+	var result temporal.VoidLineItemUpdateResult
+	if err := s.updateBill(ctx, billID, temporal.VoidLineItemUpdate, signal, &result); err != nil {
 		return nil, err
 	}
 
 	return &RemoveLineItemResponse{
 		LineItemID: lineItemID,
 		BillID:     billID,
+		Amount:     result.Amount,
+		Total:      result.Total,
 		WorkflowID: workflowID,
 	}, nil
 }
@@ -0,0 +1,293 @@
+package fee
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+
+	"encore.app/fee/model"
+	"encore.app/fee/utils"
+	temporal "encore.app/fee/workflow"
+	"encore.dev/beta/errs"
+	"encore.dev/rlog"
+)
+
+const webhookSecretPrefix = "whsec_"
+
+type CreateWebhookParams struct {
+	IdempotencyKey string                   `header:"X-Idempotency-Key"`
+	URL            string                   `json:"url"`
+	EventTypes     []model.WebhookEventType `json:"event_types"` // every event type when empty
+	Description    string                   `json:"description"`
+}
+
+func (p *CreateWebhookParams) Validate() error {
+	return (&model.WebhookSubscription{URL: p.URL, EventTypes: p.EventTypes, Description: p.Description}).Validate(devEnv())
+}
+
+type UpdateWebhookParams struct {
+	IdempotencyKey string                    `header:"X-Idempotency-Key"`
+	URL            *string                   `json:"url"`
+	EventTypes     *[]model.WebhookEventType `json:"event_types"`
+	Description    *string                   `json:"description"`
+	Active         *bool                     `json:"active"`
+}
+
+type ListWebhooksResponse struct {
+	Webhooks []*model.WebhookSubscription `json:"webhooks"`
+}
+
+type ListWebhookDeliveriesParams struct {
+	Status string `query:"status"` // PENDING, DELIVERED or FAILED, every status when empty
+	Limit  int    `query:"limit"`
+}
+
+type ListWebhookDeliveriesResponse struct {
+	Deliveries []*model.WebhookDelivery `json:"deliveries"`
+}
+
+type RedeliverWebhookParams struct {
+	IdempotencyKey string `header:"X-Idempotency-Key"`
+}
+
+// CreateWebhook subscribes an endpoint to the bill lifecycle events of the caller's tenant. The
+// secret the deliveries are signed with is only ever returned here.
+//
+//encore:api auth method=POST path=/api/admin/webhooks tag:idempotency tag:scope_admin
+func (s *Service) CreateWebhook(ctx context.Context, params *CreateWebhookParams) (*model.WebhookSubscription, error) {
+	if err := params.Validate(); err != nil {
+		return nil, &errs.Error{
+			Code:    errs.InvalidArgument,
+			Message: err.Error(),
+		}
+	}
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+	sub := &model.WebhookSubscription{
+		SubscriptionID: utils.UUID(),
+		URL:            params.URL,
+		EventTypes:     params.EventTypes,
+		Description:    params.Description,
+		Secret:         secret,
+		Active:         true,
+	}
+	if sub.EventTypes == nil {
+		sub.EventTypes = []model.WebhookEventType{}
+	}
+	if err := s.db.CreateWebhookSubscription(ctx, requestTenant(), sub); err != nil {
+		rlog.Error("failed to create webhook subscription", "error", err)
+		return nil, err
+	}
+	return sub, nil
+}
+
+// ListWebhooks lists the webhook subscriptions of the caller's tenant, without their secrets.
+//
+//encore:api auth method=GET path=/api/admin/webhooks tag:scope_admin
+func (s *Service) ListWebhooks(ctx context.Context) (*ListWebhooksResponse, error) {
+	subs, err := s.db.ListWebhookSubscriptions(ctx, requestTenant())
+	if err != nil {
+		rlog.Error("failed to list webhook subscriptions", "error", err)
+		return nil, err
+	}
+	if subs == nil {
+		subs = []*model.WebhookSubscription{}
+	}
+	for _, sub := range subs {
+		sub.Secret = ""
+	}
+	return &ListWebhooksResponse{Webhooks: subs}, nil
+}
+
+//encore:api auth method=GET path=/api/admin/webhooks/:subscriptionID tag:scope_admin
+func (s *Service) GetWebhook(ctx context.Context, subscriptionID string) (*model.WebhookSubscription, error) {
+	sub, err := s.getWebhookSubscription(ctx, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	sub.Secret = ""
+	return sub, nil
+}
+
+// UpdateWebhook changes the endpoint, event types or description of a subscription, or pauses
+// it. A paused subscription gets no new deliveries and its pending ones fail.
+//
+//encore:api auth method=PATCH path=/api/admin/webhooks/:subscriptionID tag:idempotency tag:scope_admin
+func (s *Service) UpdateWebhook(ctx context.Context, subscriptionID string, params *UpdateWebhookParams) (*model.WebhookSubscription, error) {
+	sub, err := s.getWebhookSubscription(ctx, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	if params.URL != nil {
+		sub.URL = *params.URL
+	}
+	if params.EventTypes != nil {
+		sub.EventTypes = *params.EventTypes
+		if sub.EventTypes == nil {
+			sub.EventTypes = []model.WebhookEventType{}
+		}
+	}
+	if params.Description != nil {
+		sub.Description = *params.Description
+	}
+	if params.Active != nil {
+		sub.Active = *params.Active
+	}
+	if err := sub.Validate(devEnv()); err != nil {
+		return nil, &errs.Error{
+			Code:    errs.InvalidArgument,
+			Message: err.Error(),
+		}
+	}
+
+	if err := s.db.UpdateWebhookSubscription(ctx, requestTenant(), sub); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, webhookNotFound()
+		}
+		rlog.Error("failed to update webhook subscription", "error", err, "subscription_id", subscriptionID)
+		return nil, err
+	}
+	sub.Secret = ""
+	return sub, nil
+}
+
+// ListWebhookDeliveries lists the latest deliveries of a subscription, newest first.
+//
+//encore:api auth method=GET path=/api/admin/webhooks/:subscriptionID/deliveries tag:scope_admin
+func (s *Service) ListWebhookDeliveries(ctx context.Context, subscriptionID string, params *ListWebhookDeliveriesParams) (*ListWebhookDeliveriesResponse, error) {
+	var status model.WebhookDeliveryStatus
+	if params.Status != "" {
+		var err error
+		if status, err = model.ToWebhookDeliveryStatus(params.Status); err != nil {
+			return nil, &errs.Error{
+				Code:    errs.InvalidArgument,
+				Message: err.Error(),
+			}
+		}
+	}
+	limit := params.Limit
+	if limit == 0 {
+		limit = 50
+	}
+	if limit < 0 || limit > 500 {
+		return nil, &errs.Error{
+			Code:    errs.InvalidArgument,
+			Message: "limit must be between 1 and 500",
+		}
+	}
+	if _, err := s.getWebhookSubscription(ctx, subscriptionID); err != nil {
+		return nil, err
+	}
+
+	deliveries, err := s.db.ListWebhookDeliveries(ctx, requestTenant(), subscriptionID, status, limit)
+	if err != nil {
+		rlog.Error("failed to list webhook deliveries", "error", err, "subscription_id", subscriptionID)
+		return nil, err
+	}
+	if deliveries == nil {
+		deliveries = []*model.WebhookDelivery{}
+	}
+	return &ListWebhookDeliveriesResponse{Deliveries: deliveries}, nil
+}
+
+// GetWebhookDelivery returns a delivery with the log of its attempts.
+//
+//encore:api auth method=GET path=/api/admin/webhooks/:subscriptionID/deliveries/:deliveryID tag:scope_admin
+func (s *Service) GetWebhookDelivery(ctx context.Context, subscriptionID, deliveryID string) (*model.WebhookDelivery, error) {
+	return s.getWebhookDelivery(ctx, subscriptionID, deliveryID)
+}
+
+// RedeliverWebhook sends the event of a delivery to its subscription again, as a new delivery
+// that refers to the old one. The receiver sees the same event ID.
+//
+//encore:api auth method=POST path=/api/admin/webhooks/:subscriptionID/deliveries/:deliveryID/redeliver tag:idempotency tag:scope_admin
+func (s *Service) RedeliverWebhook(ctx context.Context, subscriptionID, deliveryID string, params *RedeliverWebhookParams) (*model.WebhookDelivery, error) {
+	sub, err := s.getWebhookSubscription(ctx, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	if !sub.Active {
+		return nil, &errs.Error{
+			Code:    errs.FailedPrecondition,
+			Message: "webhook subscription is inactive",
+		}
+	}
+	if _, err := s.getWebhookDelivery(ctx, subscriptionID, deliveryID); err != nil {
+		return nil, err
+	}
+
+	tenantID := requestTenant()
+	delivery, err := s.db.RedeliverWebhook(ctx, tenantID, deliveryID, utils.UUID())
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, webhookDeliveryNotFound()
+		}
+		rlog.Error("failed to redeliver webhook", "error", err, "delivery_id", deliveryID)
+		return nil, err
+	}
+	if err := temporal.StartWebhookDelivery(ctx, s.client, tenantID, delivery.DeliveryID); err != nil {
+		rlog.Error("failed to start webhook delivery workflow", "error", err, "delivery_id", delivery.DeliveryID)
+		return nil, err
+	}
+	if err := s.db.MarkWebhookDeliveriesDispatched(ctx, tenantID, []string{delivery.DeliveryID}); err != nil {
+		// The workflow is running, a later dispatch only finds it already started.
+		rlog.Error("failed to mark webhook delivery dispatched", "error", err, "delivery_id", delivery.DeliveryID)
+	}
+	return delivery, nil
+}
+
+func (s *Service) getWebhookSubscription(ctx context.Context, subscriptionID string) (*model.WebhookSubscription, error) {
+	sub, err := s.db.GetWebhookSubscription(ctx, requestTenant(), subscriptionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, webhookNotFound()
+		}
+		rlog.Error("failed to get webhook subscription", "error", err, "subscription_id", subscriptionID)
+		return nil, err
+	}
+	return sub, nil
+}
+
+// getWebhookDelivery returns a delivery of the subscription, deliveries of other subscriptions
+// aren't found.
+func (s *Service) getWebhookDelivery(ctx context.Context, subscriptionID, deliveryID string) (*model.WebhookDelivery, error) {
+	delivery, err := s.db.GetWebhookDelivery(ctx, requestTenant(), deliveryID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, webhookDeliveryNotFound()
+		}
+		rlog.Error("failed to get webhook delivery", "error", err, "delivery_id", deliveryID)
+		return nil, err
+	}
+	if delivery.SubscriptionID != subscriptionID {
+		return nil, webhookDeliveryNotFound()
+	}
+	return delivery, nil
+}
+
+// newWebhookSecret returns a random secret to sign the deliveries of a subscription.
+func newWebhookSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return webhookSecretPrefix + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+func webhookNotFound() error {
+	return &errs.Error{
+		Code:    errs.NotFound,
+		Message: "webhook subscription not found",
+	}
+}
+
+func webhookDeliveryNotFound() error {
+	return &errs.Error{
+		Code:    errs.NotFound,
+		Message: "webhook delivery not found",
+	}
+}
@@ -0,0 +1,112 @@
+// Package webhook posts bill lifecycle events to the endpoints tenants subscribed, signed with
+// the secret of their subscription.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+
+	"encore.app/fee/model"
+	"encore.app/fee/utils"
+)
+
+const (
+	// EventHeader carries the event type of a delivery.
+	EventHeader = "X-Fee-Event"
+	// DeliveryHeader carries the delivery ID, a redelivery has a new one but the same event ID.
+	DeliveryHeader = "X-Fee-Delivery"
+	// SignatureHeader carries the signature of a delivery, made with the subscription's secret by
+	// utils.Sign. Receivers check it like utils.VerifySignature does.
+	SignatureHeader = utils.SignatureHeader
+)
+
+// timeout bounds one request to an endpoint, a slow endpoint fails the attempt.
+const timeout = 10 * time.Second
+
+// Sender posts the events, one request per attempt of a delivery.
+type Sender struct {
+	Client *http.Client
+}
+
+// NewSender returns a sender that doesn't follow redirects, an endpoint that moved fails. It only
+// connects to addresses model.WebhookAddrAllowed accepts, checked on the address actually dialed
+// so a name that resolves to an internal host once it was validated is refused too.
+func NewSender() *Sender {
+	return newSender(dialControl)
+}
+
+func newSender(control func(network, address string, c syscall.RawConn) error) *Sender {
+	dialer := &net.Dialer{Timeout: timeout, Control: control}
+	return &Sender{Client: &http.Client{
+		Timeout: timeout,
+		// No proxy, the dialed address must be the endpoint's.
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			ForceAttemptHTTP2:   true,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConnsPerHost: 2,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}}
+}
+
+// dialControl refuses connections to addresses webhooks must not be delivered to.
+func dialControl(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("invalid webhook address %q: %w", address, err)
+	}
+	if !model.WebhookAddrAllowed(addrPort.Addr()) {
+		return fmt.Errorf("webhook address %s is not allowed", addrPort.Addr())
+	}
+	return nil
+}
+
+// Delivery is one attempt to post an event to a subscription.
+type Delivery struct {
+	DeliveryID string
+	URL        string
+	Secret     string
+	Event      *model.WebhookEvent
+}
+
+// Send posts the event as JSON and returns the status code of the response, zero if there was
+// none. Any status other than 2xx is an error.
+func (s *Sender) Send(ctx context.Context, delivery *Delivery) (int, error) {
+	body, err := json.Marshal(delivery.Event)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode webhook event: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("invalid webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "fee-webhooks/1")
+	req.Header.Set(EventHeader, string(delivery.Event.Type))
+	req.Header.Set(DeliveryHeader, delivery.DeliveryID)
+	req.Header.Set(SignatureHeader, utils.Sign([]byte(delivery.Secret), body, time.Now()))
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to post webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	// Drained so the connection can be reused.
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook endpoint responded %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
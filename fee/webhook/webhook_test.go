@@ -0,0 +1,99 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"encore.app/fee/model"
+	"encore.app/fee/utils"
+)
+
+// testSender delivers to the test servers, which listen on loopback.
+func testSender() *Sender {
+	return newSender(nil)
+}
+
+func testDelivery(url string) *Delivery {
+	return &Delivery{
+		DeliveryID: "d-1",
+		URL:        url,
+		Secret:     "whsec_test",
+		Event:      &model.WebhookEvent{EventID: "e-1", Type: model.WebhookBillClosed, BillID: "bill-1", Data: json.RawMessage(`{"status":"CLOSED"}`)},
+	}
+}
+
+func TestSender_Send(t *testing.T) {
+	var got *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	status, err := testSender().Send(context.Background(), testDelivery(server.URL))
+	if err != nil || status != http.StatusNoContent {
+		t.Fatalf("Send() got = %d, %v, want 204", status, err)
+	}
+	if got.Header.Get(EventHeader) != "bill.closed" || got.Header.Get(DeliveryHeader) != "d-1" {
+		t.Errorf("headers got = %v", got.Header)
+	}
+	if !utils.VerifySignature([]byte("whsec_test"), got.Header.Get(SignatureHeader), body, time.Now(), time.Minute) {
+		t.Errorf("signature %q doesn't verify", got.Header.Get(SignatureHeader))
+	}
+	var event model.WebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil || event.EventID != "e-1" || string(event.Data) != `{"status":"CLOSED"}` {
+		t.Errorf("body got = %s, %v", body, err)
+	}
+}
+
+func TestSender_SendFailure(t *testing.T) {
+	tests := []struct {
+		name       string
+		handler    http.HandlerFunc
+		wantStatus int
+	}{
+		{"ServerError", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusInternalServerError) }, http.StatusInternalServerError},
+		{"Redirect", func(w http.ResponseWriter, r *http.Request) { http.Redirect(w, r, "/moved", http.StatusFound) }, http.StatusFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			status, err := testSender().Send(context.Background(), testDelivery(server.URL))
+			if err == nil || status != tt.wantStatus {
+				t.Errorf("Send() got = %d, %v, want %d and an error", status, err, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestSender_SendUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	if status, err := testSender().Send(context.Background(), testDelivery(server.URL)); err == nil || status != 0 {
+		t.Errorf("Send() got = %d, %v, want 0 and an error", status, err)
+	}
+}
+
+func TestSender_SendInternalAddress(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	status, err := NewSender().Send(context.Background(), testDelivery(server.URL))
+	if err == nil || status != 0 || called {
+		t.Errorf("Send() got = %d, %v, want 0 and an error without reaching the loopback server", status, err)
+	}
+}
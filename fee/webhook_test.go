@@ -0,0 +1,172 @@
+package fee
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"encore.app/fee/model"
+	temporal "encore.app/fee/workflow"
+	"encore.dev/beta/errs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.temporal.io/sdk/client"
+)
+
+func TestCreateWebhook(t *testing.T) {
+	service, mockDB, _ := setup(t)
+
+	mockDB.On("CreateWebhookSubscription", mock.Anything, model.DefaultTenant, mock.MatchedBy(func(sub *model.WebhookSubscription) bool {
+		return sub.URL == "https://hooks.example.com/fee" && sub.Active && strings.HasPrefix(sub.Secret, webhookSecretPrefix) &&
+			len(sub.EventTypes) == 1 && sub.EventTypes[0] == model.WebhookBillClosed
+	})).Return(nil).Once()
+
+	sub, err := service.CreateWebhook(context.Background(), &CreateWebhookParams{
+		URL:        "https://hooks.example.com/fee",
+		EventTypes: []model.WebhookEventType{model.WebhookBillClosed},
+	})
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, sub.SubscriptionID)
+	assert.NotEmpty(t, sub.Secret)
+	mockDB.AssertExpectations(t)
+}
+
+func TestCreateWebhook_Invalid(t *testing.T) {
+	testCases := []struct {
+		name   string
+		params *CreateWebhookParams
+	}{
+		{"MissingURL", &CreateWebhookParams{}},
+		{"NotHTTP", &CreateWebhookParams{URL: "ftp://hooks.example.com"}},
+		{"InternalAddress", &CreateWebhookParams{URL: "https://169.254.169.254/latest/meta-data"}},
+		{"UnknownEventType", &CreateWebhookParams{URL: "https://hooks.example.com", EventTypes: []model.WebhookEventType{"bill.deleted"}}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service, mockDB, _ := setup(t)
+
+			_, err := service.CreateWebhook(context.Background(), tc.params)
+
+			var errsErr *errs.Error
+			assert.True(t, errors.As(err, &errsErr))
+			assert.Equal(t, errs.InvalidArgument, errsErr.Code)
+			mockDB.AssertNotCalled(t, "CreateWebhookSubscription", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestListWebhooks_HidesSecrets(t *testing.T) {
+	service, mockDB, _ := setup(t)
+
+	mockDB.On("ListWebhookSubscriptions", mock.Anything, model.DefaultTenant).Return([]*model.WebhookSubscription{
+		{SubscriptionID: "sub-1", URL: "https://hooks.example.com", Secret: "whsec_1", Active: true},
+	}, nil).Once()
+
+	resp, err := service.ListWebhooks(context.Background())
+
+	assert.NoError(t, err)
+	assert.Len(t, resp.Webhooks, 1)
+	assert.Empty(t, resp.Webhooks[0].Secret)
+}
+
+func TestUpdateWebhook(t *testing.T) {
+	service, mockDB, _ := setup(t)
+
+	mockDB.On("GetWebhookSubscription", mock.Anything, model.DefaultTenant, "sub-1").Return(&model.WebhookSubscription{
+		SubscriptionID: "sub-1", URL: "https://hooks.example.com", Secret: "whsec_1", Active: true,
+	}, nil).Once()
+	mockDB.On("UpdateWebhookSubscription", mock.Anything, model.DefaultTenant, mock.MatchedBy(func(sub *model.WebhookSubscription) bool {
+		return !sub.Active && sub.URL == "https://hooks.example.com"
+	})).Return(nil).Once()
+
+	active := false
+	sub, err := service.UpdateWebhook(context.Background(), "sub-1", &UpdateWebhookParams{Active: &active})
+
+	assert.NoError(t, err)
+	assert.False(t, sub.Active)
+	assert.Empty(t, sub.Secret)
+	mockDB.AssertExpectations(t)
+}
+
+func TestUpdateWebhook_NotFound(t *testing.T) {
+	service, mockDB, _ := setup(t)
+
+	mockDB.On("GetWebhookSubscription", mock.Anything, model.DefaultTenant, "sub-1").Return(nil, sql.ErrNoRows).Once()
+
+	_, err := service.UpdateWebhook(context.Background(), "sub-1", &UpdateWebhookParams{})
+
+	var errsErr *errs.Error
+	assert.True(t, errors.As(err, &errsErr))
+	assert.Equal(t, errs.NotFound, errsErr.Code)
+}
+
+func TestListWebhookDeliveries_InvalidStatus(t *testing.T) {
+	service, mockDB, _ := setup(t)
+
+	_, err := service.ListWebhookDeliveries(context.Background(), "sub-1", &ListWebhookDeliveriesParams{Status: "LOST"})
+
+	var errsErr *errs.Error
+	assert.True(t, errors.As(err, &errsErr))
+	assert.Equal(t, errs.InvalidArgument, errsErr.Code)
+	mockDB.AssertNotCalled(t, "ListWebhookDeliveries", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGetWebhookDelivery_OtherSubscription(t *testing.T) {
+	service, mockDB, _ := setup(t)
+
+	mockDB.On("GetWebhookDelivery", mock.Anything, model.DefaultTenant, "d-1").Return(&model.WebhookDelivery{
+		DeliveryID: "d-1", SubscriptionID: "sub-2",
+	}, nil).Once()
+
+	_, err := service.GetWebhookDelivery(context.Background(), "sub-1", "d-1")
+
+	var errsErr *errs.Error
+	assert.True(t, errors.As(err, &errsErr))
+	assert.Equal(t, errs.NotFound, errsErr.Code)
+}
+
+func TestRedeliverWebhook(t *testing.T) {
+	service, mockDB, mockTemporalClient := setup(t)
+
+	mockDB.On("GetWebhookSubscription", mock.Anything, model.DefaultTenant, "sub-1").Return(&model.WebhookSubscription{
+		SubscriptionID: "sub-1", Active: true,
+	}, nil).Once()
+	mockDB.On("GetWebhookDelivery", mock.Anything, model.DefaultTenant, "d-1").Return(&model.WebhookDelivery{
+		DeliveryID: "d-1", SubscriptionID: "sub-1", Status: model.WebhookDeliveryFailed,
+	}, nil).Once()
+	mockDB.On("RedeliverWebhook", mock.Anything, model.DefaultTenant, "d-1", mock.Anything).Return(&model.WebhookDelivery{
+		DeliveryID: "d-2", SubscriptionID: "sub-1", RedeliveryOf: "d-1", Status: model.WebhookDeliveryPending,
+	}, nil).Once()
+	mockTemporalClient.On("ExecuteWorkflow", mock.Anything, mock.MatchedBy(func(options client.StartWorkflowOptions) bool {
+		return options.ID == temporal.WebhookDeliveryWorkflowID(model.DefaultTenant, "d-2") && options.TaskQueue == temporal.WebhookTaskQueue
+	}), mock.Anything, &temporal.WebhookDeliveryWorkflowRequest{TenantID: model.DefaultTenant, DeliveryID: "d-2"}).
+		Return(&mockWorkflowRun{}, nil).Once()
+	mockDB.On("MarkWebhookDeliveriesDispatched", mock.Anything, model.DefaultTenant, []string{"d-2"}).Return(nil).Once()
+
+	delivery, err := service.RedeliverWebhook(context.Background(), "sub-1", "d-1", &RedeliverWebhookParams{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "d-1", delivery.RedeliveryOf)
+	mockDB.AssertExpectations(t)
+	mockTemporalClient.AssertExpectations(t)
+}
+
+func TestRedeliverWebhook_Inactive(t *testing.T) {
+	service, mockDB, mockTemporalClient := setup(t)
+
+	mockDB.On("GetWebhookSubscription", mock.Anything, model.DefaultTenant, "sub-1").Return(&model.WebhookSubscription{
+		SubscriptionID: "sub-1", Active: false,
+	}, nil).Once()
+
+	_, err := service.RedeliverWebhook(context.Background(), "sub-1", "d-1", &RedeliverWebhookParams{})
+
+	var errsErr *errs.Error
+	assert.True(t, errors.As(err, &errsErr))
+	assert.Equal(t, errs.FailedPrecondition, errsErr.Code)
+	mockDB.AssertNotCalled(t, "RedeliverWebhook", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockTemporalClient.AssertNotCalled(t, "ExecuteWorkflow", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -2,63 +2,223 @@ package temporal
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	netmail "net/mail"
 	"time"
 
 	"encore.app/fee/dao"
+	"encore.app/fee/mail"
 	"encore.app/fee/model"
+	"encore.app/fee/payment"
+	"encore.app/fee/webhook"
+	"encore.dev/storage/objects"
+	"go.temporal.io/sdk/activity"
 	"go.temporal.io/sdk/temporal"
 )
 
 const (
 	errNotFound = "NotFound"
+	errTemplate = "TemplateError"
+	errInvoice  = "InvoiceError"
+	errMailer   = "MailerError"
 )
 
 type Activities struct {
-	db dao.DB
+	db       dao.DB
+	invoices InvoiceStore
+	mailer   mail.Mailer
+	payments payment.Provider
+	webhooks *webhook.Sender
+	// workflows starts the webhook deliveries that changes queue.
+	workflows WorkflowStarter
+	events    EventPublisher
 }
 
-func NewActivity(db dao.DB) *Activities {
-	return &Activities{db: db}
+// EventPublisher publishes the events of the outbox to their Pub/Sub topics.
+type EventPublisher interface {
+	Publish(ctx context.Context, event *model.OutboxEvent) error
 }
 
-func (a *Activities) AddLineItem(ctx context.Context, billID string, amount int64, metadata *model.LineItemMetadata, lineItemID string) error {
-	err := a.db.AddLineItem(ctx, billID, amount, metadata, lineItemID)
+// InvoiceStore is the bucket invoice PDFs are kept in.
+type InvoiceStore interface {
+	objects.Uploader
+	objects.Downloader
+}
+
+// NewActivity creates the activities, invoices is the bucket invoice PDFs are stored in, mailer
+// sends the bill emails, payments creates the payment links, webhooks posts the webhook
+// deliveries that workflows starts and events publishes the domain events.
+func NewActivity(db dao.DB, invoices InvoiceStore, mailer mail.Mailer, payments payment.Provider, webhooks *webhook.Sender, workflows WorkflowStarter, events EventPublisher) *Activities {
+	return &Activities{db: db, invoices: invoices, mailer: mailer, payments: payments, webhooks: webhooks, workflows: workflows, events: events}
+}
+
+// audited keys the audit entries of a change by the activity that makes it. The workflow run and
+// activity ID stay the same when the activity is retried, so a retry doesn't audit the change twice.
+func audited(ctx context.Context, source model.AuditSource) model.AuditSource {
+	info := activity.GetInfo(ctx)
+	source.Key = info.WorkflowExecution.ID + "/" + info.WorkflowExecution.RunID + "/" + info.ActivityID
+	return source
+}
+
+// AddLineItem returns the line item that represents the request, which is the original line item
+// when the bill already has one with the same external_ref. It is only Inserted when it was added now.
+func (a *Activities) AddLineItem(ctx context.Context, tenantID, billID string, amount int64, metadata *model.LineItemMetadata, lineItemID string, source model.AuditSource) (model.AddedLineItem, error) {
+	added, err := a.db.AddLineItem(ctx, tenantID, billID, amount, metadata, lineItemID, audited(ctx, source))
 	if err != nil {
-		return fmt.Errorf("failed to add line item: %s", err)
+		return added, fmt.Errorf("failed to add line item: %s", err)
 	}
-	return nil
+	a.relayChanges(ctx, tenantID)
+	return added, nil
 }
 
-func (a *Activities) UpdateLineItem(ctx context.Context, billID, lineItemID, status string) (*model.LineItem, error) {
-	lineItem, err := a.db.UpdateLineItem(ctx, billID, lineItemID, status)
+// AddLineItems persists a batch of line items with one database round trip and returns
+// the outcome of every item, in order.
+func (a *Activities) AddLineItems(ctx context.Context, tenantID, billID string, items []model.LineItemInput, source model.AuditSource) ([]model.AddedLineItem, error) {
+	added, err := a.db.AddLineItems(ctx, tenantID, billID, items, audited(ctx, source))
+	if err != nil {
+		return nil, fmt.Errorf("failed to add line items: %s", err)
+	}
+	a.relayChanges(ctx, tenantID)
+	return added, nil
+}
+
+func (a *Activities) UpdateLineItem(ctx context.Context, tenantID, billID, lineItemID, status string, source model.AuditSource) (*model.LineItem, error) {
+	lineItem, err := a.db.UpdateLineItem(ctx, tenantID, billID, lineItemID, status, audited(ctx, source))
 	if err != nil {
 		return nil, temporal.NewNonRetryableApplicationError("failed in update line item", errNotFound, err)
 	}
+	a.relayChanges(ctx, tenantID)
 	return lineItem, nil
 }
 
-func (a *Activities) CreateBill(ctx context.Context, billID, policyType, currency string, startAt time.Time, recurring RecurringPolicy) error {
+func (a *Activities) CreateBill(ctx context.Context, tenantID, billID, policyType, currency, customerID string, status model.BillStatus, startAt, periodEnd time.Time, recurring RecurringPolicy, source model.AuditSource) error {
 	metadata := model.BillMetadata{}
 	if recurring.Amount > 0 && recurring.Interval.Duration > 0 {
 		metadata.Recurring = &model.Recurring{
 			Description: recurring.Description,
 			Amount:      recurring.Amount,
 			Interval:    recurring.Interval.String(),
+			PriceID:     recurring.PriceID,
+		}
+	}
+
+	err := a.db.CreateBill(ctx, tenantID, billID, string(policyType), currency, customerID, status, startAt, periodEnd, metadata, audited(ctx, source))
+	if err != nil {
+		return err
+	}
+	a.relayChanges(ctx, tenantID)
+	return nil
+}
+
+// SetBillPeriodEnd records a changed billing period end of an open bill.
+func (a *Activities) SetBillPeriodEnd(ctx context.Context, tenantID, billID string, periodEnd time.Time) error {
+	err := a.db.SetBillPeriodEnd(ctx, tenantID, billID, periodEnd)
+	if errors.Is(err, sql.ErrNoRows) {
+		return temporal.NewNonRetryableApplicationError("bill is not open", errNotFound, err)
+	}
+	return err
+}
+
+func (a *Activities) ActivateBill(ctx context.Context, tenantID, billID string) error {
+	return a.db.ActivateBill(ctx, tenantID, billID)
+}
+
+func (a *Activities) CancelBill(ctx context.Context, tenantID, billID string, closure model.BillClosure, source model.AuditSource) error {
+	err := a.db.CancelBill(ctx, tenantID, billID, closure, audited(ctx, source))
+	if err != nil {
+		return err
+	}
+	a.relayChanges(ctx, tenantID)
+	return nil
+}
+
+func (a *Activities) ReopenBill(ctx context.Context, tenantID, billID string, source model.AuditSource) error {
+	err := a.db.ReopenBill(ctx, tenantID, billID, audited(ctx, source))
+	if errors.Is(err, sql.ErrNoRows) {
+		return temporal.NewNonRetryableApplicationError("bill is no longer closed", errNotFound, err)
+	}
+	if err != nil {
+		return err
+	}
+	a.relayChanges(ctx, tenantID)
+	return nil
+}
+
+// ResolvePrice returns the unit amount of a catalog price effective at the given time.
+func (a *Activities) ResolvePrice(ctx context.Context, tenantID, priceID string, at time.Time) (int64, error) {
+	price, err := a.db.GetPrice(ctx, tenantID, priceID, at)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, temporal.NewNonRetryableApplicationError("price not found or not yet effective", errNotFound, err)
 		}
+		return 0, fmt.Errorf("failed to get price: %w", err)
+	}
+	return price.UnitAmount, nil
+}
+
+func (a *Activities) LoadUsageImportRows(ctx context.Context, tenantID, importID string, afterRow, limit int) ([]model.UsageImportRow, error) {
+	return a.db.ListUsageImportRows(ctx, tenantID, importID, afterRow, limit, false)
+}
+
+func (a *Activities) RecordUsageImportResults(ctx context.Context, tenantID, importID string, results []model.UsageImportRowResult) error {
+	return a.db.RecordUsageImportResults(ctx, tenantID, importID, results)
+}
+
+func (a *Activities) SetUsageImportStatus(ctx context.Context, tenantID, importID string, status model.UsageImportStatus) error {
+	return a.db.SetUsageImportStatus(ctx, tenantID, importID, status)
+}
+
+// RecordFailedLineItem dead-letters a line item whose activity failed after all retries,
+// keeping the original signal so it can be retried later.
+func (a *Activities) RecordFailedLineItem(ctx context.Context, tenantID string, source model.FailedLineItemSource, item AddLineItemSignalRequest, cause string) error {
+	payload, err := json.Marshal(item)
+	if err != nil {
+		return temporal.NewNonRetryableApplicationError("failed to encode failed line item", "EncodeError", err)
 	}
+	return a.db.RecordFailedLineItem(ctx, tenantID, &model.FailedLineItem{
+		LineItemID: item.LineItemID,
+		BillID:     item.BillID,
+		Source:     string(source),
+		Amount:     item.Amount,
+		Payload:    string(payload),
+		Error:      cause,
+	})
+}
 
-	return a.db.CreateBill(ctx, billID, string(policyType), currency, startAt, metadata)
+func (a *Activities) GetFailedLineItem(ctx context.Context, tenantID, lineItemID string) (*model.FailedLineItem, error) {
+	item, err := a.db.GetFailedLineItem(ctx, tenantID, lineItemID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, temporal.NewNonRetryableApplicationError("failed line item not found", errNotFound, err)
+		}
+		return nil, err
+	}
+	return item, nil
 }
 
-func (a *Activities) CloseBillFromState(ctx context.Context, state BillState) error {
-	err := a.db.CloseBill(ctx, state.BillID, state.Total)
+func (a *Activities) ResolveFailedLineItem(ctx context.Context, tenantID, lineItemID string, status model.FailedLineItemStatus) error {
+	err := a.db.ResolveFailedLineItem(ctx, tenantID, lineItemID, status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return temporal.NewNonRetryableApplicationError("failed line item is already resolved", ErrTypeAlreadyResolved, err)
+	}
 	return err
 }
 
-func (a *Activities) GetBillDetail(ctx context.Context, billID string) (*BillResponse, error) {
-	bill, err := a.db.GetBill(ctx, billID)
+func (a *Activities) CloseBillFromState(ctx context.Context, tenantID string, state BillState, closure model.BillClosure, source model.AuditSource) error {
+	err := a.db.CloseBill(ctx, tenantID, state.BillID, state.Total, closure, audited(ctx, source))
+	if err != nil {
+		return err
+	}
+	a.relayChanges(ctx, tenantID)
+	return nil
+}
+
+func (a *Activities) GetBillDetail(ctx context.Context, tenantID, billID string) (*BillResponse, error) {
+	bill, err := a.db.GetBill(ctx, tenantID, billID)
 	if err != nil {
 		return nil, err
 	}
@@ -67,40 +227,236 @@ func (a *Activities) GetBillDetail(ctx context.Context, billID string) (*BillRes
 		Currency:      bill.Currency,
 		Status:        bill.Status,
 		PolicyType:    bill.PolicyType,
+		CustomerID:    bill.CustomerID,
 		CreatedAt:     bill.CreatedAt,
 		ClosedAt:      bill.ClosedAt,
+		PeriodStart:   bill.PeriodStart,
+		PeriodEnd:     bill.PeriodEnd,
 		TotalAmount:   bill.TotalAmount,
 		DisplayAmount: model.FormatAmount(bill.TotalAmount),
+		Closure:       bill.Closure,
+	}
+	resp.PaymentLinks, err = a.db.ListBillPaymentLinks(ctx, tenantID, billID)
+	if err != nil {
+		return nil, err
 	}
 
 	resp.LineItems = make([]LineItem, 0, len(bill.LineItems))
 	for _, item := range bill.LineItems {
-		var metadata model.LineItemMetadata
-		err := json.Unmarshal([]byte(item.Metadata), &metadata)
+		lineItem, err := newLineItem(bill.Currency, item)
 		if err != nil {
 			return nil, err
 		}
-		resp.LineItems = append(resp.LineItems, LineItem{
-			LineItemID:    item.LineItemID,
-			Currency:      bill.Currency,
-			Amount:        item.Amount,
-			Description:   metadata.Description,
-			CreatedAt:     item.CreatedAt,
-			DisplayAmount: model.FormatAmount(item.Amount),
-			Status:        item.Status,
-		})
+		resp.LineItems = append(resp.LineItems, lineItem)
 	}
 
 	return resp, nil
 }
 
-func (a *Activities) GeneratePDFInvoive(ctx context.Context, billID string) error {
-	// TODO: Implement generate PDF invoice
-	return nil
+// ListLineItems reads a page of the line items of a bill, it returns sql.ErrNoRows when the bill doesn't exist.
+func (a *Activities) ListLineItems(ctx context.Context, tenantID, billID string, status model.LineItemStatus, page model.LineItemPage) (*LineItemsPage, error) {
+	currency, err := a.db.GetBillCurrency(ctx, tenantID, billID)
+	if err != nil {
+		return nil, err
+	}
+	items, hasMore, err := a.db.ListLineItems(ctx, tenantID, billID, status, page)
+	if err != nil {
+		return nil, err
+	}
+	resp := &LineItemsPage{
+		LineItems: make([]LineItem, 0, len(items)),
+		HasMore:   hasMore,
+	}
+	for _, item := range items {
+		lineItem, err := newLineItem(currency, item)
+		if err != nil {
+			return nil, err
+		}
+		resp.LineItems = append(resp.LineItems, lineItem)
+	}
+	return resp, nil
 }
 
-func (a *Activities) SendBillEmail(ctx context.Context, billID string) error {
-	// TODO: Implement email sending logic
+func newLineItem(currency string, item model.LineItem) (LineItem, error) {
+	var metadata model.LineItemMetadata
+	if err := json.Unmarshal([]byte(item.Metadata), &metadata); err != nil {
+		return LineItem{}, err
+	}
+	return LineItem{
+		LineItemID:    item.LineItemID,
+		Currency:      currency,
+		Amount:        item.Amount,
+		Description:   metadata.Description,
+		PriceID:       metadata.PriceID,
+		Quantity:      metadata.Quantity,
+		ExternalRef:   metadata.ExternalRef,
+		CreatedAt:     item.CreatedAt,
+		DisplayAmount: model.FormatAmount(item.Amount),
+		Status:        item.Status,
+	}, nil
+}
+
+// GeneratePDFInvoive renders the bill's latest invoice into the invoices bucket and records the
+// object key on the bill. Bills closed before invoices were issued have none and are skipped.
+func (a *Activities) GeneratePDFInvoive(ctx context.Context, tenantID, billID string) error {
+	invoices, err := a.db.ListBillInvoices(ctx, tenantID, billID)
+	if err != nil {
+		return fmt.Errorf("failed to list bill invoices: %w", err)
+	}
+	if len(invoices) == 0 {
+		return nil
+	}
+	invoice := invoices[len(invoices)-1]
+	template, err := a.invoiceTemplate(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+
+	// The invoice and its snapshot are never changed, a retry can't render it either.
+	content, err := renderInvoicePDF(invoice, template)
+	if err != nil {
+		return temporal.NewNonRetryableApplicationError("failed to render invoice pdf", errInvoice, err)
+	}
+
+	// A retry renders the same invoice to the same key again.
+	key := InvoicePDFKey(tenantID, invoice.InvoiceID)
+	w := a.invoices.Upload(ctx, key, objects.WithUploadAttrs(objects.UploadAttrs{ContentType: "application/pdf"}))
+	if _, err := w.Write(content); err != nil {
+		w.Abort(err)
+		return fmt.Errorf("failed to upload invoice pdf: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to upload invoice pdf: %w", err)
+	}
+	return a.db.SetBillInvoicePDF(ctx, tenantID, billID, key)
+}
+
+// invoiceTemplate returns the tenant's issuer, whose template renders its invoices, or the
+// default template if it has none.
+func (a *Activities) invoiceTemplate(ctx context.Context, tenantID string) (*model.InvoiceIssuer, error) {
+	template, err := a.db.GetInvoiceIssuer(ctx, tenantID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &model.InvoiceIssuer{Locale: model.LocaleEN}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invoice template: %w", err)
+	}
+	return template, nil
+}
+
+// SendBillEmail emails the bill's latest invoice with its PDF and payment link to the customer's
+// billing contacts, in the locale of the invoice template. The delivery is recorded before the
+// email is sent, so a retried activity never sends it twice: after a failed send it is sent
+// again, after an attempt that stopped while sending it is marked UNKNOWN instead.
+func (a *Activities) SendBillEmail(ctx context.Context, tenantID, billID string) error {
+	invoices, err := a.db.ListBillInvoices(ctx, tenantID, billID)
+	if err != nil {
+		return fmt.Errorf("failed to list bill invoices: %w", err)
+	}
+	if len(invoices) == 0 {
+		return nil
+	}
+	invoice := invoices[len(invoices)-1]
+	template, err := a.invoiceTemplate(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+
+	var customerName string
+	var recipients []string
+	if invoice.Customer != nil {
+		customerName = invoice.Customer.Name
+		customer, err := a.db.GetCustomer(ctx, tenantID, invoice.Customer.ID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("failed to get customer: %w", err)
+		}
+		if customer != nil {
+			recipients = customer.Recipients()
+		}
+	}
+
+	var paymentURL string
+	link, err := a.db.GetPaymentLink(ctx, tenantID, model.PaymentLinkID(invoice.InvoiceID))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to get payment link: %w", err)
+	}
+	if link != nil && link.Status == model.PaymentLinkPending {
+		paymentURL = link.URL
+	}
+
+	labels, ok := invoiceLocales[template.Locale]
+	if !ok {
+		labels = invoiceLocales[model.LocaleEN]
+	}
+	rendered, err := mail.Render(mail.TemplateBillClosed, template.Locale, mail.BillClosed{
+		IssuerName:          invoice.Issuer.Name,
+		CustomerName:        customerName,
+		InvoiceNumber:       invoice.Number,
+		Total:               model.FormatLocalizedAmount(invoice.TotalAmount, template.Locale) + " " + invoice.Currency,
+		DueDate:             invoice.DueAt.UTC().Format(labels.dateFormat),
+		BillID:              billID,
+		PaymentInstructions: template.PaymentInstructions,
+		PaymentURL:          paymentURL,
+	})
+	if err != nil {
+		return temporal.NewNonRetryableApplicationError("failed to render bill email", errTemplate, err)
+	}
+
+	delivery := &model.EmailDelivery{
+		DeliveryID: model.EmailDeliveryID(invoice.InvoiceID, mail.TemplateBillClosed),
+		BillID:     billID,
+		InvoiceID:  invoice.InvoiceID,
+		Template:   mail.TemplateBillClosed,
+		Recipients: recipients,
+		Subject:    rendered.Subject,
+	}
+	send, err := a.db.BeginEmailDelivery(ctx, tenantID, delivery)
+	if err != nil {
+		return fmt.Errorf("failed to begin email delivery: %w", err)
+	}
+	if !send {
+		return nil
+	}
+	if len(recipients) == 0 {
+		return a.db.FinishEmailDelivery(ctx, tenantID, delivery.DeliveryID, model.EmailDeliverySkipped, "customer has no billing contacts")
+	}
+
+	msg := &mail.Message{
+		ID:      delivery.DeliveryID + "@fee",
+		From:    netmail.Address{Name: invoice.Issuer.Name}, // the mailer sets its from address
+		ReplyTo: invoice.Issuer.Email,
+		To:      recipients,
+		Subject: rendered.Subject,
+		Text:    rendered.Text,
+		HTML:    rendered.HTML,
+		Date:    time.Now(),
+	}
+	err = a.attachInvoicePDF(ctx, tenantID, invoice, msg)
+	if err == nil {
+		err = a.mailer.Send(ctx, msg)
+	}
+	if err != nil {
+		if finishErr := a.db.FinishEmailDelivery(ctx, tenantID, delivery.DeliveryID, model.EmailDeliveryFailed, err.Error()); finishErr != nil {
+			return finishErr
+		}
+		// The mailer's configuration has to change first, a retry would fail the same way.
+		if errors.Is(err, mail.ErrNoFrom) {
+			return temporal.NewNonRetryableApplicationError("failed to send bill email", errMailer, err)
+		}
+		return fmt.Errorf("failed to send bill email: %w", err)
+	}
+	return a.db.FinishEmailDelivery(ctx, tenantID, delivery.DeliveryID, model.EmailDeliverySent, "")
+}
+
+// attachInvoicePDF attaches the invoice's PDF, GeneratePDFInvoive renders it before the email is sent.
+func (a *Activities) attachInvoicePDF(ctx context.Context, tenantID string, invoice *model.Invoice, msg *mail.Message) error {
+	r := a.invoices.Download(ctx, InvoicePDFKey(tenantID, invoice.InvoiceID))
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read invoice pdf: %w", err)
+	}
+	msg.Attachments = append(msg.Attachments, mail.Attachment{Filename: invoice.Number + ".pdf", ContentType: "application/pdf", Data: data})
 	return nil
 }
 
@@ -110,8 +466,81 @@ func (a *Activities) CurrencyConversion(ctx context.Context, billID string, targ
 	return 0, nil
 }
 
-func (a *Activities) CreatePaymentLink(ctx context.Context, billID string) error {
-	// TODO: Implement payment link creation logic
+// CreatePaymentLink creates the link to pay the bill's latest invoice, valid until the invoice is
+// due. A retried activity returns the link it created before. Bills without an invoice get none.
+func (a *Activities) CreatePaymentLink(ctx context.Context, tenantID, billID string) (*model.PaymentLink, error) {
+	invoices, err := a.db.ListBillInvoices(ctx, tenantID, billID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list bill invoices: %w", err)
+	}
+	if len(invoices) == 0 {
+		return nil, nil
+	}
+	invoice := invoices[len(invoices)-1]
+	linkID := model.PaymentLinkID(invoice.InvoiceID)
+	link, err := a.db.GetPaymentLink(ctx, tenantID, linkID)
+	if err == nil {
+		return link, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	created, err := a.payments.CreateLink(ctx, &payment.LinkRequest{
+		Reference:   linkID,
+		Amount:      invoice.TotalAmount,
+		Currency:    invoice.Currency,
+		Description: invoice.Issuer.Name + " " + invoice.Number,
+		ExpiresAt:   invoice.DueAt,
+	})
+	if errors.Is(err, payment.ErrDisabled) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create payment link: %w", err)
+	}
+	err = a.db.CreatePaymentLink(ctx, tenantID, &model.PaymentLink{
+		LinkID:         linkID,
+		BillID:         billID,
+		InvoiceID:      invoice.InvoiceID,
+		Provider:       a.payments.Name(),
+		ProviderLinkID: created.ID,
+		URL:            created.URL,
+		Amount:         invoice.TotalAmount,
+		Currency:       invoice.Currency,
+		Status:         model.PaymentLinkPending,
+		ExpiresAt:      created.ExpiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a.db.GetPaymentLink(ctx, tenantID, linkID)
+}
+
+// ExpirePaymentLink asks the provider about a link that expired without a callback. A link that
+// was paid after all is returned as its settlement, any other is marked EXPIRED, also when payment
+// links have been disabled since it was created.
+func (a *Activities) ExpirePaymentLink(ctx context.Context, tenantID string, link *model.PaymentLink) (*SettleBillRequest, error) {
+	event, err := a.payments.FetchStatus(ctx, link.ProviderLinkID)
+	if err != nil && !errors.Is(err, payment.ErrUnknownLink) && !errors.Is(err, payment.ErrDisabled) {
+		return nil, fmt.Errorf("failed to fetch payment link status: %w", err)
+	}
+	if err == nil && event.Pays(link) {
+		return &SettleBillRequest{LinkID: link.LinkID, PaymentID: event.PaymentID, Amount: event.Amount, Actor: "payment:" + link.Provider}, nil
+	}
+	return nil, a.db.ExpirePaymentLink(ctx, tenantID, link.LinkID)
+}
+
+// SettleBill records the payment of the bill's payment link and settles the bill.
+func (a *Activities) SettleBill(ctx context.Context, tenantID string, settlement SettleBillRequest) error {
+	err := a.db.SettleBill(ctx, tenantID, settlement.LinkID, settlement.PaymentID, audited(ctx, settlement.source()))
+	if errors.Is(err, sql.ErrNoRows) {
+		return temporal.NewNonRetryableApplicationError("bill is no longer closed", errNotFound, err)
+	}
+	if err != nil {
+		return err
+	}
+	a.relayChanges(ctx, tenantID)
 	return nil
 }
 
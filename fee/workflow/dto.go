@@ -1,6 +1,7 @@
 package temporal
 
 import (
+	"encoding/json"
 	"time"
 
 	"encore.app/fee/model"
@@ -11,36 +12,202 @@ type RecurringPolicy struct {
 	Amount      int64
 	Interval    utils.Duration
 	Description string
+	PriceID     string // when set, each charge is re-priced from the catalog
 }
 
 type BillLifecycleWorkflowRequest struct {
-	BillID            string
-	PolicyType        model.PolicyType
-	BilingPeriodStart time.Time
-	BillingPeriodEnd  time.Time
-	Currency          string
-	Recurring         RecurringPolicy
-	PreviousState     *BillState
+	TenantID           string // the default tenant when empty, for workflows started before tenants existed
+	BillID             string
+	PolicyType         model.PolicyType
+	BilingPeriodStart  time.Time
+	BillingPeriodEnd   time.Time
+	Currency           string
+	CustomerID         string
+	Recurring          RecurringPolicy
+	ScheduledLineItems model.ScheduledLineItemMode // applies while the bill waits for BilingPeriodStart
+	PreviousState      *BillState
+	Reopen             bool   // the bill was closed and is reopened with PreviousState rebuilt from its line items
+	Actor              string // who created or reopened the bill
+	RequestID          string // the API request that created or reopened the bill
+}
+
+func (r *BillLifecycleWorkflowRequest) source() model.AuditSource {
+	return model.AuditSource{Actor: r.Actor, RequestID: r.RequestID}
 }
 
 type BillClosedPostProcessWorkflowRequest struct {
-	BillID string
+	TenantID string
+	BillID   string
 }
 type AddLineItemSignalRequest struct {
 	LineItemID string
 	Amount     int64
 	BillID     string
+	Currency   string // currency of the catalog price, empty for raw amounts
 	Metadata   *model.LineItemMetadata
+	Actor      string // who added the line item, the API key or the system path
+	RequestID  string // the API request that added the line item, empty for system paths
+}
+
+func (r AddLineItemSignalRequest) source() model.AuditSource {
+	return model.AuditSource{Actor: r.Actor, RequestID: r.RequestID}
+}
+
+// AddLineItemsSignalRequest delivers several line items for the same bill in one signal.
+type AddLineItemsSignalRequest struct {
+	BillID    string
+	Items     []AddLineItemSignalRequest
+	Actor     string
+	RequestID string
+}
+
+func (r AddLineItemsSignalRequest) source() model.AuditSource {
+	return model.AuditSource{Actor: r.Actor, RequestID: r.RequestID}
+}
+
+// AddLineItemUpdateResult is the outcome of an add line item update.
+type AddLineItemUpdateResult struct {
+	LineItemID string
+	Amount     int64
+	Duplicate  bool  // the external_ref was already billed, LineItemID is the original line item
+	Queued     bool  // the bill is scheduled, the item is added when the billing period starts
+	Total      int64 // running total of the bill after the update
+}
+
+// addedLineItem decodes the result of the AddLineItem and AddLineItems activities. Activities that
+// completed before the result carried the inserted flag returned the bare line item ID, they are
+// still found in the history of running workflows.
+type addedLineItem struct {
+	model.AddedLineItem
+	legacy bool
+}
+
+func (r *addedLineItem) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		r.legacy = true
+		return json.Unmarshal(b, &r.LineItemID)
+	}
+	return json.Unmarshal(b, &r.AddedLineItem)
+}
+
+// inserted reports whether the line item requested as lineItemID was added by the activity.
+func (r addedLineItem) inserted(lineItemID string) bool {
+	if r.legacy {
+		return r.LineItemID == lineItemID
+	}
+	return r.Inserted
+}
+
+// VoidLineItemUpdateResult is the outcome of a void line item update.
+type VoidLineItemUpdateResult struct {
+	LineItemID string
+	Amount     int64
+	Total      int64 // running total of the bill after the update
 }
 
 type UpdateLineItemSignalRequest struct {
 	LineItemID string
 	BillID     string
 	Status     model.LineItemStatus
+	Actor      string
+	RequestID  string
+}
+
+func (r UpdateLineItemSignalRequest) source() model.AuditSource {
+	return model.AuditSource{Actor: r.Actor, RequestID: r.RequestID}
 }
 
 type ClosedBillRequest struct {
-	BillID string
+	BillID    string
+	Force     bool              // close even though the bill has unresolved failed line items
+	Reason    model.CloseReason // OTHER when empty
+	Note      string
+	Actor     string // who asked for the close
+	RequestID string
+}
+
+// closure is what a manual close records on the bill.
+func (r ClosedBillRequest) closure() model.BillClosure {
+	return manualClosure(r.Reason, r.Note, r.Actor)
+}
+
+func (r ClosedBillRequest) source() model.AuditSource {
+	return model.AuditSource{Actor: r.Actor, RequestID: r.RequestID}
+}
+
+// ResolveFailedLineItemRequest retries or discards a dead-lettered line item of the bill.
+type ResolveFailedLineItemRequest struct {
+	LineItemID string
+	Status     model.FailedLineItemStatus // RETRIED or DISCARDED
+	Actor      string
+	RequestID  string
+}
+
+func (r ResolveFailedLineItemRequest) source() model.AuditSource {
+	return model.AuditSource{Actor: r.Actor, RequestID: r.RequestID}
+}
+
+// ResolveFailedLineItemResult is the outcome of a resolve failed line item update.
+type ResolveFailedLineItemResult struct {
+	LineItemID      string
+	Status          model.FailedLineItemStatus
+	Total           int64 // running total of the bill after the update
+	FailedLineItems int   // unresolved failed line items left on the bill
+}
+
+// SetPeriodEndRequest moves the billing period end of an open bill.
+type SetPeriodEndRequest struct {
+	BillID           string
+	BillingPeriodEnd time.Time
+	Actor            string
+}
+
+// CancelBillRequest abandons a bill without charging it, it is recorded like a close.
+type CancelBillRequest struct {
+	BillID    string
+	Reason    model.CloseReason // OTHER when empty
+	Note      string
+	Actor     string
+	RequestID string
+}
+
+func (r CancelBillRequest) closure() model.BillClosure {
+	return manualClosure(r.Reason, r.Note, r.Actor)
+}
+
+func (r CancelBillRequest) source() model.AuditSource {
+	return model.AuditSource{Actor: r.Actor, RequestID: r.RequestID}
+}
+
+// SettleBillRequest reports the payment of a bill's payment link.
+type SettleBillRequest struct {
+	LinkID    string
+	PaymentID string // the provider's ID of the payment
+	Amount    int64
+	Actor     string
+	RequestID string
+}
+
+func (r SettleBillRequest) source() model.AuditSource {
+	return model.AuditSource{Actor: r.Actor, RequestID: r.RequestID}
+}
+
+func manualClosure(reason model.CloseReason, note, actor string) model.BillClosure {
+	if reason == "" {
+		reason = model.CloseReasonOther
+	}
+	return model.BillClosure{Reason: reason, Note: note, Actor: actor, Trigger: model.CloseTriggerManual}
+}
+
+type WebhookDeliveryWorkflowRequest struct {
+	TenantID   string
+	DeliveryID string
+}
+
+type UsageImportWorkflowRequest struct {
+	TenantID string
+	ImportID string
+	AfterRow int // rows up to this row number were already handed over by a previous run
 }
 
 type TotalSummary struct {
@@ -54,19 +221,52 @@ type LineItem struct {
 	Currency      string    `json:"currency"`
 	Amount        int64     `json:"amount"`
 	Description   string    `json:"description"`
+	PriceID       string    `json:"price_id,omitempty"`
+	Quantity      int64     `json:"quantity,omitempty"`
+	ExternalRef   string    `json:"external_ref,omitempty"`
 	CreatedAt     time.Time `json:"created_at"`
 	DisplayAmount string    `json:"display_amount"`
 	Status        string    `json:"status"`
 }
 
+// LineItemsPage is a page of the line items of a bill.
+type LineItemsPage struct {
+	LineItems []LineItem
+	HasMore   bool
+}
+
 type BillResponse struct {
-	BillID        string     `json:"bill_id"`
-	Status        string     `json:"status"`
-	PolicyType    string     `json:"policy_type"`
-	CreatedAt     time.Time  `json:"created_at"`
-	ClosedAt      *time.Time `json:"closed_at,omitempty"`
-	Currency      string     `json:"currency"`
-	TotalAmount   int64      `json:"total_amount"`
-	DisplayAmount string     `json:"display_amount"`
-	LineItems     []LineItem `json:"line_items"`
+	BillID        string               `json:"bill_id"`
+	Status        string               `json:"status"`
+	PolicyType    string               `json:"policy_type"`
+	CustomerID    string               `json:"customer_id,omitempty"`
+	CreatedAt     time.Time            `json:"created_at"`
+	ClosedAt      *time.Time           `json:"closed_at,omitempty"`
+	PeriodStart   time.Time            `json:"period_start"`
+	PeriodEnd     *time.Time           `json:"period_end,omitempty"` // live value from the workflow for open bills
+	Currency      string               `json:"currency"`
+	TotalAmount   int64                `json:"total_amount"`
+	DisplayAmount string               `json:"display_amount"`
+	LineItems     []LineItem           `json:"line_items"`
+	Closure       *model.BillClosure   `json:"closure,omitempty"`       // why, how and by whom the bill was closed
+	PaymentLinks  []*model.PaymentLink `json:"payment_links,omitempty"` // one for each invoice of the bill
+	Live          *LiveBillState       `json:"live,omitempty"`          // set for open bills, read from the running workflow
+}
+
+// PolicyState is the live view of a billing policy, see BillingPolicy.State.
+type PolicyState struct {
+	Type                  model.PolicyType `json:"type"`
+	RecurringAmount       int64            `json:"recurring_amount,omitempty"`
+	NextRecurringChargeAt *time.Time       `json:"next_recurring_charge_at,omitempty"`
+	RemainingBalance      int64            `json:"remaining_balance"` // recurring charges still due before the period ends
+}
+
+// LiveBillState is the full state of a running bill workflow, returned by QueryBillState.
+type LiveBillState struct {
+	Total            int64       `json:"total"`
+	EventCount       int         `json:"event_count"`
+	PendingPeriodEnd time.Time   `json:"pending_period_end"`
+	Closing          bool        `json:"closing"`
+	FailedLineItems  int         `json:"failed_line_items"` // unresolved, the bill can't close until they are retried or discarded
+	Policy           PolicyState `json:"policy"`
 }
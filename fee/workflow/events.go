@@ -0,0 +1,44 @@
+package temporal
+
+import (
+	"context"
+
+	"encore.dev/rlog"
+)
+
+// outboxPublishBatch is how many outbox events one relay publishes.
+const outboxPublishBatch = 100
+
+// relayChanges hands the events a committed change queued to their consumers: it publishes the
+// outbox to Pub/Sub and starts the webhook deliveries. Both were written with the change, so
+// anything a relay leaves behind is handed over by the next change of the tenant, or the periodic
+// relay, instead of failing this one.
+func (a *Activities) relayChanges(ctx context.Context, tenantID string) {
+	a.publishEvents(ctx, tenantID)
+	a.dispatchWebhooks(ctx, tenantID)
+}
+
+// publishEvents publishes a batch of the tenant's outbox in the order it was written, stopping at
+// the first event that fails so none overtakes an earlier one. It returns how many it published.
+func (a *Activities) publishEvents(ctx context.Context, tenantID string) int {
+	n, err := a.db.PublishOutboxEvents(ctx, tenantID, outboxPublishBatch, a.events.Publish)
+	if err != nil {
+		rlog.Error("failed to publish outbox events", "error", err, "tenant_id", tenantID)
+	}
+	return n
+}
+
+// RelayOutbox publishes the outbox of every tenant. It hands over the events whose change didn't
+// publish them, because the publish failed or the process stopped first, when the tenant makes
+// no further change.
+func (a *Activities) RelayOutbox(ctx context.Context) {
+	tenantIDs, err := a.db.ListOutboxTenants(ctx)
+	if err != nil {
+		rlog.Error("failed to list outbox tenants", "error", err)
+		return
+	}
+	for _, tenantID := range tenantIDs {
+		for ctx.Err() == nil && a.publishEvents(ctx, tenantID) == outboxPublishBatch {
+		}
+	}
+}
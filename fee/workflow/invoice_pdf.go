@@ -0,0 +1,218 @@
+package temporal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"encore.app/fee/model"
+	"encore.app/fee/pdf"
+	"encore.dev/rlog"
+)
+
+// InvoicePDFKey is the object key of an invoice's PDF in the invoices bucket.
+func InvoicePDFKey(tenantID, invoiceID string) string {
+	return tenantID + "/" + invoiceID + ".pdf"
+}
+
+// invoiceLabels are the texts of the invoice PDF in each locale.
+type invoiceLabels struct {
+	title, number, issued, due, billTo, taxID, description, quantity, unitPrice, amount,
+	subtotal, total, payment, bill, period, dateFormat string
+}
+
+var invoiceLocales = map[string]invoiceLabels{
+	model.LocaleEN: {
+		title: "INVOICE", number: "Invoice no.", issued: "Issue date", due: "Due date", billTo: "Bill to", taxID: "Tax ID",
+		description: "Description", quantity: "Qty", unitPrice: "Unit price", amount: "Amount",
+		subtotal: "Subtotal", total: "Total", payment: "Payment instructions", bill: "Bill", period: "Period",
+		dateFormat: "02 Jan 2006",
+	},
+	model.LocaleDE: {
+		title: "RECHNUNG", number: "Rechnungsnr.", issued: "Rechnungsdatum", due: "Fällig am", billTo: "Rechnung an", taxID: "USt-IdNr.",
+		description: "Beschreibung", quantity: "Menge", unitPrice: "Einzelpreis", amount: "Betrag",
+		subtotal: "Zwischensumme", total: "Gesamt", payment: "Zahlungshinweise", bill: "Abrechnung", period: "Zeitraum",
+		dateFormat: "02.01.2006",
+	},
+	model.LocaleFR: {
+		title: "FACTURE", number: "Facture n°", issued: "Date d'émission", due: "Échéance", billTo: "Facturé à", taxID: "N° TVA",
+		description: "Désignation", quantity: "Qté", unitPrice: "Prix unitaire", amount: "Montant",
+		subtotal: "Sous-total", total: "Total", payment: "Modalités de paiement", bill: "Facturation", period: "Période",
+		dateFormat: "02/01/2006",
+	},
+}
+
+// Layout of the invoice PDF, in points from the top-left corner of the page.
+const (
+	marginLeft   = 50.0
+	marginRight  = pdf.PageWidth - 50
+	marginBottom = pdf.PageHeight - 60
+	rightColumn  = 320.0
+	quantityX    = 360.0 // right edges of the table columns
+	unitPriceX   = 450.0
+	rowHeight    = 16.0
+	bodySize     = 10.0
+)
+
+// renderInvoicePDF lays the invoice out on A4 pages with the issuer's template: logo, locale and
+// payment instructions. Line items that don't fit continue on further pages.
+// Bills carry no tax, an invoice whose line items don't add up to its total is not rendered.
+func renderInvoicePDF(invoice *model.Invoice, template *model.InvoiceIssuer) ([]byte, error) {
+	var subtotal int64
+	for _, item := range invoice.Snapshot.LineItems {
+		subtotal += item.Amount
+	}
+	if subtotal != invoice.TotalAmount {
+		return nil, fmt.Errorf("invoice %s line items add up to %d, its total is %d", invoice.Number, subtotal, invoice.TotalAmount)
+	}
+
+	labels, ok := invoiceLocales[template.Locale]
+	if !ok {
+		labels = invoiceLocales[model.LocaleEN]
+	}
+	amount := func(v int64) string { return model.FormatLocalizedAmount(v, template.Locale) }
+	date := func(t time.Time) string { return t.UTC().Format(labels.dateFormat) }
+
+	doc := pdf.New()
+	page := doc.AddPage()
+	if len(template.Logo) > 0 {
+		// The logo was checked when it was set, a logo that still doesn't draw is left out.
+		if err := page.JPEG(template.Logo, marginLeft, 40, 160, 60); err != nil {
+			rlog.Warn("failed to draw invoice logo", "error", err, "invoice_id", invoice.InvoiceID)
+		}
+	}
+	page.TextRight(marginRight, 60, pdf.Bold, 20, labels.title)
+	y := 80.0
+	for _, field := range [][2]string{
+		{labels.number, invoice.Number},
+		{labels.issued, date(invoice.IssuedAt)},
+		{labels.due, date(invoice.DueAt)},
+	} {
+		page.TextRight(marginRight-110, y, pdf.Regular, bodySize, field[0])
+		page.TextRight(marginRight, y, pdf.Bold, bodySize, field[1])
+		y += 14
+	}
+
+	// Issuer on the left, customer on the right.
+	partyY := 140.0
+	issuerEnd := writeParty(page, marginLeft, partyY, "", invoice.Issuer, labels)
+	customerEnd := partyY
+	if invoice.Customer != nil {
+		customerEnd = writeParty(page, rightColumn, partyY, labels.billTo, *invoice.Customer, labels)
+	}
+	y = max(issuerEnd, customerEnd) + 30
+
+	tableHeader := func(page *pdf.Page, y float64) float64 {
+		page.Text(marginLeft, y, pdf.Bold, bodySize, labels.description)
+		page.TextRight(quantityX, y, pdf.Bold, bodySize, labels.quantity)
+		page.TextRight(unitPriceX, y, pdf.Bold, bodySize, labels.unitPrice)
+		page.TextRight(marginRight, y, pdf.Bold, bodySize, labels.amount+" ("+invoice.Currency+")")
+		page.Line(marginLeft, y+5, marginRight, y+5, 0.75)
+		return y + rowHeight + 4
+	}
+	y = tableHeader(page, y)
+
+	for _, item := range invoice.Snapshot.LineItems {
+		if y > marginBottom {
+			page = doc.AddPage()
+			y = tableHeader(page, 60)
+		}
+		description := item.Description
+		if description == "" {
+			description = item.LineItemID
+		}
+		page.Text(marginLeft, y, pdf.Regular, bodySize, fit(description, pdf.Regular, bodySize, quantityX-marginLeft-40))
+		if item.Quantity != 0 {
+			page.TextRight(quantityX, y, pdf.Regular, bodySize, strconv.FormatInt(item.Quantity, 10))
+		}
+		if item.UnitAmount != 0 {
+			page.TextRight(unitPriceX, y, pdf.Regular, bodySize, amount(item.UnitAmount))
+		}
+		page.TextRight(marginRight, y, pdf.Regular, bodySize, amount(item.Amount))
+		y += rowHeight
+	}
+
+	// Totals, payment instructions and the bill reference are kept together.
+	footerLines := 5 + len(lines(template.PaymentInstructions))
+	if y+float64(footerLines)*rowHeight > marginBottom {
+		page = doc.AddPage()
+		y = 60
+	}
+	page.Line(rightColumn, y-6, marginRight, y-6, 0.5)
+	y += 6
+	for _, total := range []struct {
+		label string
+		value int64
+		font  pdf.Font
+	}{
+		{labels.subtotal, subtotal, pdf.Regular},
+		{labels.total, invoice.TotalAmount, pdf.Bold},
+	} {
+		page.Text(rightColumn, y, total.font, bodySize, total.label)
+		page.TextRight(marginRight, y, total.font, bodySize, amount(total.value)+" "+invoice.Currency)
+		y += rowHeight
+	}
+
+	if instructions := lines(template.PaymentInstructions); len(instructions) > 0 {
+		y += 14
+		page.Text(marginLeft, y, pdf.Bold, bodySize, labels.payment)
+		y += 14
+		for _, line := range instructions {
+			page.Text(marginLeft, y, pdf.Regular, bodySize, line)
+			y += 13
+		}
+	}
+
+	period := date(invoice.Snapshot.PeriodStart)
+	if invoice.Snapshot.PeriodEnd != nil {
+		period += " - " + date(*invoice.Snapshot.PeriodEnd)
+	}
+	page.Text(marginLeft, y+20, pdf.Regular, 8, labels.bill+": "+invoice.BillID+"   "+labels.period+": "+period)
+	return doc.Bytes(), nil
+}
+
+// writeParty writes a party's name, address and tax ID from y and returns where it ends.
+func writeParty(page *pdf.Page, x, y float64, heading string, party model.Party, labels invoiceLabels) float64 {
+	if heading != "" {
+		page.Text(x, y, pdf.Regular, 8, strings.ToUpper(heading))
+		y += 14
+	}
+	page.Text(x, y, pdf.Bold, 11, party.Name)
+	y += 14
+	details := lines(party.Address)
+	if party.TaxID != "" {
+		details = append(details, labels.taxID+": "+party.TaxID)
+	}
+	if party.Email != "" {
+		details = append(details, party.Email)
+	}
+	for _, line := range details {
+		page.Text(x, y, pdf.Regular, bodySize, line)
+		y += 13
+	}
+	return y
+}
+
+// lines splits text into its non-empty lines.
+func lines(text string) []string {
+	var out []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			out = append(out, line)
+		}
+	}
+	return out
+}
+
+// fit shortens s with an ellipsis until it fits width.
+func fit(s string, font pdf.Font, size, width float64) string {
+	if pdf.TextWidth(font, size, s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && pdf.TextWidth(font, size, string(runes)+"...") > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
@@ -0,0 +1,41 @@
+package temporal
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"encore.app/fee/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func testInvoice(total int64, amounts ...int64) *model.Invoice {
+	invoice := &model.Invoice{
+		InvoiceID:   "invoice-1",
+		Number:      "INV-1",
+		BillID:      "bill-1",
+		Currency:    "USD",
+		TotalAmount: total,
+		IssuedAt:    time.Now(),
+		DueAt:       time.Now().Add(14 * 24 * time.Hour),
+		Issuer:      model.Party{Name: "Fee Inc."},
+		Snapshot:    model.InvoiceSnapshot{BillID: "bill-1", Currency: "USD", PeriodStart: time.Now(), TotalAmount: total},
+	}
+	for _, amount := range amounts {
+		invoice.Snapshot.LineItems = append(invoice.Snapshot.LineItems, model.InvoiceLineItem{LineItemID: "line-item", Amount: amount})
+	}
+	return invoice
+}
+
+func TestRenderInvoicePDF(t *testing.T) {
+	content, err := renderInvoicePDF(testInvoice(1500, 1000, 500), &model.InvoiceIssuer{Locale: model.LocaleEN})
+
+	assert.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(content, []byte("%PDF-")))
+}
+
+func TestRenderInvoicePDF_TotalMismatch(t *testing.T) {
+	_, err := renderInvoicePDF(testInvoice(1800, 1000, 500), &model.InvoiceIssuer{Locale: model.LocaleEN})
+
+	assert.ErrorContains(t, err, "add up to 1500, its total is 1800")
+}
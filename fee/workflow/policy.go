@@ -2,6 +2,7 @@ package temporal
 
 import (
 	"fmt"
+	"time"
 
 	"encore.app/fee/model"
 	"go.temporal.io/sdk/workflow"
@@ -11,16 +12,28 @@ import (
 // Each policy encapsulates the specific logic for handling signals and events
 // for a particular type of bill (e.g., usage-based, subscription).
 type BillingPolicy interface {
+	// ValidateLineItemChange reports whether callers may add or void line items on this bill.
+	// It is used by the update validators, so it must not change any state.
+	ValidateLineItemChange() error
+
 	// HandleAddLineItem processes the logic for adding a line item.
-	// It returns true if the workflow's total should be updated.
-	HandleAddLineItem(ctx workflow.Context, activities *Activities, signal AddLineItemSignalRequest) (updateTotals bool)
+	// It returns the line item that represents the request, which is only Inserted, and only
+	// counts towards the totals, when the request added it. A repeated external_ref resolves to
+	// the original line item.
+	HandleAddLineItem(ctx workflow.Context, activities *Activities, signal AddLineItemSignalRequest) (added model.AddedLineItem, err error)
+
+	// HandleAddLineItems processes a batch of line items for the same bill.
+	// It returns the items that were persisted and should be added to the totals, or the
+	// activity error if the batch could not be persisted.
+	HandleAddLineItems(ctx workflow.Context, activities *Activities, signal AddLineItemsSignalRequest) (added []AddLineItemSignalRequest, err error)
 
 	// HandleUpdateLineItem processes the logic for updating a line item (e.g., voiding).
 	// It returns the line item that was updated.
-	HandleUpdateLineItem(ctx workflow.Context, activities *Activities, signal UpdateLineItemSignalRequest) *model.LineItem
+	HandleUpdateLineItem(ctx workflow.Context, activities *Activities, signal UpdateLineItemSignalRequest) (*model.LineItem, error)
 
-	// TODO: add descirption
-	HandleRecurringItem(ctx workflow.Context, activities *Activities, onSuccess func(newAmount int64)) error
+	// HandleRecurringItem charges the recurring fee when RecurringFuture fires and schedules the next charge.
+	// onFailure receives the charge if it could not be persisted, so it is not lost.
+	HandleRecurringItem(ctx workflow.Context, activities *Activities, onSuccess func(newAmount int64), onFailure func(item AddLineItemSignalRequest, err error)) error
 
 	// OnBillClose is called just before the bill is finalized.
 	// It allows the policy to perform any final calculations or state changes.
@@ -32,18 +45,21 @@ type BillingPolicy interface {
 
 	// TODO: add descirption
 	RecurringFuture() workflow.Future
+
+	// State reports the policy's live state for the bill state query. It must not change any state.
+	State(now, periodEnd time.Time) PolicyState
 }
 
 // NewBillingPolicy is a factory that returns the appropriate policy implementation.
 func NewBillingPolicy(ctx workflow.Context, req *BillLifecycleWorkflowRequest) (BillingPolicy, error) {
 	switch req.PolicyType {
 	case model.UsageBased:
-		return NewUsagePolicy(), nil
+		return NewUsagePolicy(req.TenantID), nil
 	case model.Subscription:
 		if req.Recurring.Interval.Duration <= 0 {
 			return nil, fmt.Errorf("recurring is mandatory for subscription policy")
 		}
-		return NewSubscriptionPolicy(ctx, req.BillID, req.Currency, req.Recurring)
+		return NewSubscriptionPolicy(ctx, req.TenantID, req.BillID, req.Currency, req.Recurring)
 	default:
 		return nil, fmt.Errorf("unsupported policy type: %s", req.PolicyType)
 	}
@@ -6,6 +6,7 @@ import (
 
 	"encore.app/fee/model"
 	"encore.app/fee/utils"
+	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/workflow"
 )
 
@@ -14,13 +15,16 @@ type SubscriptionPolicy struct {
 	Currency                string
 	Amount                  int64
 	Description             string
+	PriceID                 string
+	TenantID                string
 	BillID                  string
 	RecurringInterval       time.Duration
 	RecurringFeeTimerFuture workflow.Future
+	NextChargeAt            time.Time
 	CancelFutureFn          workflow.CancelFunc
 }
 
-func NewSubscriptionPolicy(ctx workflow.Context, billID, currency string, recurring RecurringPolicy) (*SubscriptionPolicy, error) {
+func NewSubscriptionPolicy(ctx workflow.Context, tenantID, billID, currency string, recurring RecurringPolicy) (*SubscriptionPolicy, error) {
 	recurringTimerCtx, cancelRecurringTimer := workflow.WithCancel(ctx)
 	recurringFeeInterval, err := time.ParseDuration(recurring.Interval.String())
 	if err != nil {
@@ -28,41 +32,86 @@ func NewSubscriptionPolicy(ctx workflow.Context, billID, currency string, recurr
 	}
 	recurringFeeTimerFuture := workflow.NewTimer(recurringTimerCtx, recurringFeeInterval)
 	return &SubscriptionPolicy{
+		TenantID:                tenantID,
 		BillID:                  billID,
 		Amount:                  recurring.Amount,
 		Description:             recurring.Description,
+		PriceID:                 recurring.PriceID,
 		Currency:                currency,
 		RecurringInterval:       recurring.Interval.Duration,
 		RecurringFeeTimerFuture: recurringFeeTimerFuture,
+		NextChargeAt:            workflow.Now(ctx).Add(recurringFeeInterval),
 		CancelFutureFn:          cancelRecurringTimer,
 	}, nil
 }
 
-func (p *SubscriptionPolicy) HandleRecurringItem(ctx workflow.Context, activities *Activities, onSuccess func(newAmount int64)) error {
-	lineItemID := utils.UUID()
+func (p *SubscriptionPolicy) HandleRecurringItem(ctx workflow.Context, activities *Activities, onSuccess func(newAmount int64), onFailure func(item AddLineItemSignalRequest, err error)) error {
+	// The next charge is scheduled whatever happens to this one, otherwise the fired timer
+	// would keep the selector busy.
+	defer func() {
+		p.RecurringFeeTimerFuture = workflow.NewTimer(ctx, p.RecurringInterval)
+		p.NextChargeAt = workflow.Now(ctx).Add(p.RecurringInterval)
+	}()
+
+	if p.PriceID != "" {
+		// Re-price every charge so catalog changes take effect from their effective date.
+		var amount int64
+		err := workflow.ExecuteActivity(ctx, activities.ResolvePrice, p.TenantID, p.PriceID, workflow.Now(ctx)).Get(ctx, &amount)
+		if err != nil {
+			workflow.GetLogger(ctx).Error("Failed to resolve catalog price, charging the last known amount.", "Error", err, "PriceID", p.PriceID)
+		} else {
+			p.Amount = amount
+		}
+	}
+
+	// Derived from the charge time so a retried charge from the dead letter keeps its ID.
+	lineItemID := utils.NameUUID(fmt.Sprintf("%s/recurring/%d", p.BillID, workflow.Now(ctx).UnixNano()))
 	metadata := &model.LineItemMetadata{Description: p.Description}
-	err := workflow.ExecuteActivity(ctx, activities.AddLineItem, p.BillID, p.Amount, metadata, lineItemID).Get(ctx, nil)
+	if p.PriceID != "" {
+		metadata.PriceID = p.PriceID
+		metadata.Quantity = 1
+		metadata.UnitAmount = p.Amount
+	}
+	err := workflow.ExecuteActivity(ctx, activities.AddLineItem, p.TenantID, p.BillID, p.Amount, metadata, lineItemID, model.AuditSource{Actor: "system"}).Get(ctx, nil)
 	if err != nil {
 		workflow.GetLogger(ctx).Error("Failed to recurring add line item after all retries.", "Error", err)
+		onFailure(AddLineItemSignalRequest{
+			LineItemID: lineItemID,
+			Amount:     p.Amount,
+			BillID:     p.BillID,
+			Metadata:   metadata,
+			Actor:      "system",
+		}, err)
 		return err
 	}
 	workflow.GetLogger(ctx).Debug("Recurring add line item activity completed.", "BillID", p.BillID)
 	onSuccess(p.Amount)
-	p.RecurringFeeTimerFuture = workflow.NewTimer(ctx, p.RecurringInterval)
 	return nil
 }
 
+// ValidateLineItemChange for SubscriptionPolicy
+// Subscriptions only carry their recurring charges, callers can't add or void line items.
+func (p *SubscriptionPolicy) ValidateLineItemChange() error {
+	return temporal.NewApplicationError("line items are not allowed on subscription bills", ErrTypePolicyForbidden)
+}
+
 // HandleAddLineItem for SubscriptionPolicy
 // Subscriptions might not allow adding ad-hoc line items.
-func (p *SubscriptionPolicy) HandleAddLineItem(ctx workflow.Context, activities *Activities, signal AddLineItemSignalRequest) bool {
+func (p *SubscriptionPolicy) HandleAddLineItem(ctx workflow.Context, activities *Activities, signal AddLineItemSignalRequest) (model.AddedLineItem, error) {
 	workflow.GetLogger(ctx).Warn("Attempted to add a line item to a subscription-based bill. This is not allowed.")
-	return false
+	return model.AddedLineItem{}, p.ValidateLineItemChange()
+}
+
+// HandleAddLineItems for SubscriptionPolicy
+func (p *SubscriptionPolicy) HandleAddLineItems(ctx workflow.Context, activities *Activities, signal AddLineItemsSignalRequest) ([]AddLineItemSignalRequest, error) {
+	workflow.GetLogger(ctx).Warn("Attempted to add line items to a subscription-based bill. This is not allowed.", "Count", len(signal.Items))
+	return nil, p.ValidateLineItemChange()
 }
 
 // HandleUpdateLineItem for SubscriptionPolicy
-func (p *SubscriptionPolicy) HandleUpdateLineItem(ctx workflow.Context, activities *Activities, signal UpdateLineItemSignalRequest) *model.LineItem {
+func (p *SubscriptionPolicy) HandleUpdateLineItem(ctx workflow.Context, activities *Activities, signal UpdateLineItemSignalRequest) (*model.LineItem, error) {
 	workflow.GetLogger(ctx).Warn("Attempted to update a line item to a subscription-based bill. This is not allowed.")
-	return nil
+	return nil, p.ValidateLineItemChange()
 }
 
 // OnBillClose for SubscriptionPolicy
@@ -84,3 +133,22 @@ func (p *SubscriptionPolicy) OnTimerFired(ctx workflow.Context, state *BillState
 func (p *SubscriptionPolicy) RecurringFuture() workflow.Future {
 	return p.RecurringFeeTimerFuture
 }
+
+// State for SubscriptionPolicy
+// The remaining balance is what the recurring charges still due before the period ends add up to.
+func (p *SubscriptionPolicy) State(now, periodEnd time.Time) PolicyState {
+	state := PolicyState{
+		Type:            model.Subscription,
+		RecurringAmount: p.Amount,
+	}
+	next := p.NextChargeAt
+	if next.Before(now) {
+		next = now
+	}
+	state.NextRecurringChargeAt = &next
+	if next.Before(periodEnd) && p.RecurringInterval > 0 {
+		remaining := int64(periodEnd.Sub(next)-1)/int64(p.RecurringInterval) + 1
+		state.RemainingBalance = remaining * p.Amount
+	}
+	return state
+}
@@ -0,0 +1,402 @@
+package temporal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"encore.app/fee/model"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// Application error types returned by the update validators, so the API can tell the reasons apart.
+const (
+	ErrTypeBillClosing     = "BillClosing"
+	ErrTypeBillNotStarted  = "BillNotStarted"
+	ErrTypePolicyForbidden = "PolicyForbidden"
+	ErrTypeInvalidLineItem = "InvalidLineItem"
+	ErrTypeNotFound        = errNotFound
+	ErrTypeInvalidPeriod   = "InvalidPeriod"
+
+	ErrTypeUnresolvedFailures = "UnresolvedFailures"
+	ErrTypeAlreadyResolved    = "AlreadyResolved"
+)
+
+// billLifecycle is the state BillLifecycleWorkflow shares with its update handlers.
+// Handlers run in their own coroutines, so everything the main loop needs to know about
+// is recorded here and the loop is woken up through wake.
+type billLifecycle struct {
+	req             *BillLifecycleWorkflowRequest
+	state           *BillState
+	policy          BillingPolicy // nil while a scheduled bill waits for its billing period
+	closing         bool
+	closeRequested  bool
+	cancelRequested bool
+	periodEnded     bool                       // the billing period is over, the bill waits for its failures to be resolved
+	periodChanged   bool                       // req.BillingPeriodEnd changed, the main loop restarts its timer
+	changingPeriod  bool                       // a period end change is being persisted
+	inFlight        int                        // updates that are still persisting
+	queued          []AddLineItemSignalRequest // accepted while scheduled in QUEUE mode
+	resolving       map[string]bool            // failed line items with a resolve update in progress
+	closure         *model.BillClosure         // set by a manual close or cancel, the timer closes without one
+	closeSource     model.AuditSource          // who asked for the manual close or cancel
+	closed          *BillResponse              // set once the bill is closed
+	wake            workflow.Channel
+}
+
+func newBillLifecycle(ctx workflow.Context, req *BillLifecycleWorkflowRequest, state *BillState) *billLifecycle {
+	return &billLifecycle{
+		req:       req,
+		state:     state,
+		resolving: make(map[string]bool),
+		wake:      workflow.NewBufferedChannel(ctx, 1),
+	}
+}
+
+func (b *billLifecycle) setUpdateHandlers(ctx workflow.Context) error {
+	err := workflow.SetUpdateHandlerWithOptions(ctx, AddLineItemUpdate, b.addLineItem, workflow.UpdateHandlerOptions{
+		Validator: b.validateAddLineItem,
+	})
+	if err != nil {
+		return err
+	}
+	err = workflow.SetUpdateHandlerWithOptions(ctx, VoidLineItemUpdate, b.voidLineItem, workflow.UpdateHandlerOptions{
+		Validator: b.validateVoidLineItem,
+	})
+	if err != nil {
+		return err
+	}
+	err = workflow.SetUpdateHandlerWithOptions(ctx, ResolveFailedLineItemUpdate, b.resolveFailedLineItem, workflow.UpdateHandlerOptions{
+		Validator: b.validateResolveFailedLineItem,
+	})
+	if err != nil {
+		return err
+	}
+	err = workflow.SetUpdateHandlerWithOptions(ctx, SetPeriodEndUpdate, b.setPeriodEnd, workflow.UpdateHandlerOptions{
+		Validator: b.validateSetPeriodEnd,
+	})
+	if err != nil {
+		return err
+	}
+	err = workflow.SetUpdateHandlerWithOptions(ctx, CancelBillUpdate, b.cancelBill, workflow.UpdateHandlerOptions{
+		Validator: b.validateCancelBill,
+	})
+	if err != nil {
+		return err
+	}
+	return workflow.SetUpdateHandlerWithOptions(ctx, CloseBillUpdate, b.closeBill, workflow.UpdateHandlerOptions{
+		Validator: b.validateCloseBill,
+	})
+}
+
+func (b *billLifecycle) validateAddLineItem(ctx workflow.Context, req AddLineItemSignalRequest) error {
+	if b.closing {
+		return temporal.NewApplicationError("bill is closing", ErrTypeBillClosing)
+	}
+	if b.periodEnded {
+		return temporal.NewApplicationError("billing period has ended", ErrTypeBillClosing)
+	}
+	if req.Amount <= 0 {
+		return temporal.NewApplicationError("amount must be positive", ErrTypeInvalidLineItem)
+	}
+	if req.Currency != "" && !strings.EqualFold(req.Currency, b.req.Currency) {
+		return temporal.NewApplicationError("line item currency does not match bill currency", ErrTypeInvalidLineItem)
+	}
+	if b.policy == nil {
+		if b.req.ScheduledLineItems != model.ScheduledLineItemsQueue {
+			return temporal.NewApplicationError("bill has not started yet", ErrTypeBillNotStarted)
+		}
+		return nil
+	}
+	return b.policy.ValidateLineItemChange()
+}
+
+func (b *billLifecycle) addLineItem(ctx workflow.Context, req AddLineItemSignalRequest) (*AddLineItemUpdateResult, error) {
+	if b.policy == nil {
+		// The bill is scheduled in QUEUE mode, the item is added once the billing period starts.
+		b.queued = append(b.queued, req)
+		return &AddLineItemUpdateResult{LineItemID: req.LineItemID, Amount: req.Amount, Queued: true}, nil
+	}
+
+	var activities *Activities
+	b.inFlight++
+	defer b.handled()
+	added, err := b.policy.HandleAddLineItem(ctx, activities, req)
+	if err != nil {
+		return nil, err
+	}
+	result := &AddLineItemUpdateResult{LineItemID: added.LineItemID, Amount: req.Amount}
+	if added.Inserted {
+		b.state.Total += req.Amount
+	} else {
+		result.Duplicate = true
+	}
+	result.Total = b.state.Total
+	return result, nil
+}
+
+func (b *billLifecycle) validateVoidLineItem(ctx workflow.Context, req UpdateLineItemSignalRequest) error {
+	if b.closing {
+		return temporal.NewApplicationError("bill is closing", ErrTypeBillClosing)
+	}
+	if b.policy == nil {
+		return temporal.NewApplicationError("bill has not started yet", ErrTypeBillNotStarted)
+	}
+	return b.policy.ValidateLineItemChange()
+}
+
+func (b *billLifecycle) voidLineItem(ctx workflow.Context, req UpdateLineItemSignalRequest) (*VoidLineItemUpdateResult, error) {
+	var activities *Activities
+	workflow.GetLogger(ctx).Info("Received void line item update.", "BillID", b.req.BillID, "LineItemID", req.LineItemID, "Actor", req.Actor)
+	b.inFlight++
+	defer b.handled()
+	req.Status = model.LineItemStatusVoided
+	lineItem, err := b.policy.HandleUpdateLineItem(ctx, activities, req)
+	if err != nil {
+		return nil, err
+	}
+	b.state.Total -= lineItem.Amount
+	return &VoidLineItemUpdateResult{LineItemID: req.LineItemID, Amount: lineItem.Amount, Total: b.state.Total}, nil
+}
+
+func (b *billLifecycle) validateCloseBill(ctx workflow.Context, req ClosedBillRequest) error {
+	if b.closing {
+		return temporal.NewApplicationError("bill is already closing", ErrTypeBillClosing)
+	}
+	if b.policy == nil {
+		return temporal.NewApplicationError("bill has not started yet, cancel it instead", ErrTypeBillNotStarted)
+	}
+	if b.state.FailedLineItems > 0 && !req.Force {
+		return temporal.NewApplicationError(fmt.Sprintf("bill has %d unresolved failed line items, retry or discard them or force the close", b.state.FailedLineItems), ErrTypeUnresolvedFailures)
+	}
+	return nil
+}
+
+// closeBill asks the main loop to close the bill and waits for the final bill.
+func (b *billLifecycle) closeBill(ctx workflow.Context, req ClosedBillRequest) (*BillResponse, error) {
+	workflow.GetLogger(ctx).Info("Received close bill update.", "BillID", b.req.BillID, "Actor", req.Actor)
+	b.closing = true
+	b.closeRequested = true
+	closure := req.closure()
+	b.closure = &closure
+	b.closeSource = req.source()
+	b.wake.SendAsync(true)
+	if err := workflow.Await(ctx, func() bool { return b.closed != nil }); err != nil {
+		return nil, err
+	}
+	return b.closed, nil
+}
+
+func (b *billLifecycle) validateSetPeriodEnd(ctx workflow.Context, req SetPeriodEndRequest) error {
+	if b.closing {
+		return temporal.NewApplicationError("bill is closing", ErrTypeBillClosing)
+	}
+	if b.policy == nil {
+		return temporal.NewApplicationError("bill has not started yet", ErrTypeBillNotStarted)
+	}
+	if req.BillingPeriodEnd.Before(workflow.Now(ctx).Add(MinPeriodLead)) {
+		return temporal.NewApplicationError(fmt.Sprintf("billing_period_end must be at least %s ahead", MinPeriodLead), ErrTypeInvalidPeriod)
+	}
+	if req.BillingPeriodEnd.Before(b.req.BilingPeriodStart.Add(MinPeriodLead)) {
+		return temporal.NewApplicationError(fmt.Sprintf("billing_period_end must be at least %s after billing_period_start", MinPeriodLead), ErrTypeInvalidPeriod)
+	}
+	return nil
+}
+
+func (b *billLifecycle) setPeriodEnd(ctx workflow.Context, req SetPeriodEndRequest) (time.Time, error) {
+	workflow.GetLogger(ctx).Info("Received set period end update.", "BillID", b.req.BillID, "BillingPeriodEnd", req.BillingPeriodEnd, "Actor", req.Actor)
+	b.inFlight++
+	defer b.handled()
+	if err := b.applyPeriodEnd(ctx, req.BillingPeriodEnd); err != nil {
+		return time.Time{}, err
+	}
+	return b.req.BillingPeriodEnd, nil
+}
+
+// applyPeriodEnd persists a new billing period end and asks the main loop to restart its
+// timer. Changes are applied one at a time so the database and the workflow agree on the last one.
+func (b *billLifecycle) applyPeriodEnd(ctx workflow.Context, periodEnd time.Time) error {
+	if err := workflow.Await(ctx, func() bool { return !b.changingPeriod }); err != nil {
+		return err
+	}
+	b.changingPeriod = true
+	defer func() { b.changingPeriod = false }()
+	// Runs recorded before the period end could change kept their first timer.
+	if workflow.GetVersion(ctx, versionReplacePeriodTimer, workflow.DefaultVersion, 1) == workflow.DefaultVersion {
+		return temporal.NewApplicationError("bill can't change its billing period end", ErrTypeInvalidPeriod)
+	}
+
+	var activities *Activities
+	if err := workflow.ExecuteActivity(ctx, activities.SetBillPeriodEnd, b.req.TenantID, b.req.BillID, periodEnd).Get(ctx, nil); err != nil {
+		return err
+	}
+	b.req.BillingPeriodEnd = periodEnd
+	b.periodChanged = true
+	b.periodEnded = false
+	return nil
+}
+
+// validateCancelBill accepts a cancel whether the bill is scheduled or open, the workflow tells
+// which one it is when the cancel is applied.
+func (b *billLifecycle) validateCancelBill(ctx workflow.Context, req CancelBillRequest) error {
+	if b.closing {
+		return temporal.NewApplicationError("bill is already closing", ErrTypeBillClosing)
+	}
+	return nil
+}
+
+// cancelBill asks the workflow to abandon the bill and waits for the cancelled bill. A scheduled
+// bill is cancelled while it waits for its billing period, an open one by the main loop.
+func (b *billLifecycle) cancelBill(ctx workflow.Context, req CancelBillRequest) (*BillResponse, error) {
+	workflow.GetLogger(ctx).Info("Received cancel bill update.", "BillID", b.req.BillID, "Actor", req.Actor)
+	b.requestCancel(req)
+	b.wake.SendAsync(true)
+	if err := workflow.Await(ctx, func() bool { return b.closed != nil }); err != nil {
+		return nil, err
+	}
+	return b.closed, nil
+}
+
+// requestCancel records a cancel, by update or signal, and stops accepting changes.
+func (b *billLifecycle) requestCancel(req CancelBillRequest) {
+	b.closing = true
+	b.cancelRequested = true
+	closure := req.closure()
+	b.closure = &closure
+	b.closeSource = req.source()
+}
+
+func (b *billLifecycle) validateResolveFailedLineItem(ctx workflow.Context, req ResolveFailedLineItemRequest) error {
+	if b.closing {
+		return temporal.NewApplicationError("bill is closing", ErrTypeBillClosing)
+	}
+	if req.Status != model.FailedLineItemRetried && req.Status != model.FailedLineItemDiscarded {
+		return temporal.NewApplicationError(fmt.Sprintf("invalid resolution: %s", req.Status), ErrTypeInvalidLineItem)
+	}
+	if b.resolving[req.LineItemID] {
+		return temporal.NewApplicationError("failed line item is already being resolved", ErrTypeAlreadyResolved)
+	}
+	if b.policy == nil && req.Status == model.FailedLineItemRetried {
+		return temporal.NewApplicationError("bill has not started yet", ErrTypeBillNotStarted)
+	}
+	return nil
+}
+
+// resolveFailedLineItem retries or discards a dead-lettered line item. A retry adds the
+// original signal again, the item stays pending if that fails once more.
+func (b *billLifecycle) resolveFailedLineItem(ctx workflow.Context, req ResolveFailedLineItemRequest) (*ResolveFailedLineItemResult, error) {
+	workflow.GetLogger(ctx).Info("Received resolve failed line item update.", "BillID", b.req.BillID, "LineItemID", req.LineItemID, "Status", req.Status, "Actor", req.Actor)
+	var activities *Activities
+	b.resolving[req.LineItemID] = true
+	defer delete(b.resolving, req.LineItemID)
+	b.inFlight++
+	defer b.handled()
+
+	var failed *model.FailedLineItem
+	if err := workflow.ExecuteActivity(ctx, activities.GetFailedLineItem, b.req.TenantID, req.LineItemID).Get(ctx, &failed); err != nil {
+		return nil, err
+	}
+	if failed.BillID != b.req.BillID {
+		return nil, temporal.NewApplicationError("failed line item not found", ErrTypeNotFound)
+	}
+	if failed.Status != string(model.FailedLineItemPending) {
+		return nil, temporal.NewApplicationError("failed line item is already resolved", ErrTypeAlreadyResolved)
+	}
+
+	if req.Status == model.FailedLineItemRetried {
+		var item AddLineItemSignalRequest
+		if err := json.Unmarshal([]byte(failed.Payload), &item); err != nil {
+			return nil, temporal.NewApplicationError("failed line item payload is invalid", ErrTypeInvalidLineItem, err)
+		}
+		var added addedLineItem
+		err := workflow.ExecuteActivity(ctx, activities.AddLineItem, b.req.TenantID, item.BillID, item.Amount, item.Metadata, item.LineItemID, req.source()).Get(ctx, &added)
+		if err != nil {
+			workflow.GetLogger(ctx).Error("Failed to retry failed line item.", "Error", err, "BillID", b.req.BillID, "LineItemID", req.LineItemID)
+			return nil, err
+		}
+		if added.inserted(item.LineItemID) {
+			b.state.Total += item.Amount
+		}
+	}
+
+	if err := workflow.ExecuteActivity(ctx, activities.ResolveFailedLineItem, b.req.TenantID, req.LineItemID, req.Status).Get(ctx, nil); err != nil {
+		return nil, err
+	}
+	if b.state.FailedLineItems > 0 {
+		b.state.FailedLineItems--
+	}
+	return &ResolveFailedLineItemResult{
+		LineItemID:      req.LineItemID,
+		Status:          req.Status,
+		Total:           b.state.Total,
+		FailedLineItems: b.state.FailedLineItems,
+	}, nil
+}
+
+// deadLetter records line items whose activity failed after all retries, so they can be
+// retried or discarded by an admin. Rejections by the policy are not failures and are skipped.
+func (b *billLifecycle) deadLetter(ctx workflow.Context, source model.FailedLineItemSource, cause error, items ...AddLineItemSignalRequest) {
+	var activityErr *temporal.ActivityError
+	if !errors.As(cause, &activityErr) {
+		return
+	}
+	// Runs recorded before dead-lettering dropped the line items.
+	if workflow.GetVersion(ctx, versionDeadLetterFailedLineItems, workflow.DefaultVersion, 1) == workflow.DefaultVersion {
+		return
+	}
+	b.recordFailed(ctx, source, cause.Error(), items...)
+}
+
+// rejectEarly dead-letters line items signalled before a scheduled bill that doesn't queue them
+// started. Signals aren't answered, so dropping them would lose the usage without anyone knowing.
+func (b *billLifecycle) rejectEarly(ctx workflow.Context, items ...AddLineItemSignalRequest) {
+	if workflow.GetVersion(ctx, versionDeadLetterEarlyLineItems, workflow.DefaultVersion, 1) == workflow.DefaultVersion {
+		return
+	}
+	b.recordFailed(ctx, model.FailedLineItemSourceSignal, "bill has not started yet", items...)
+}
+
+// recordFailed dead-letters line items with the reason they could not be added.
+func (b *billLifecycle) recordFailed(ctx workflow.Context, source model.FailedLineItemSource, cause string, items ...AddLineItemSignalRequest) {
+	var activities *Activities
+	for _, item := range items {
+		err := workflow.ExecuteActivity(ctx, activities.RecordFailedLineItem, b.req.TenantID, source, item, cause).Get(ctx, nil)
+		if err != nil {
+			workflow.GetLogger(ctx).Error("CRITICAL: Failed to record failed line item. Manual review required.", "Error", err, "BillID", b.req.BillID, "LineItemID", item.LineItemID, "Amount", item.Amount)
+			continue
+		}
+		b.state.FailedLineItems++
+	}
+}
+
+// awaitHandlers waits for the update handlers before the run moves on. Runs recorded before
+// bills were changed through updates didn't wait, so they replay without it.
+func (b *billLifecycle) awaitHandlers(ctx workflow.Context, cond func() bool) error {
+	if workflow.GetVersion(ctx, versionAwaitUpdateHandlers, workflow.DefaultVersion, 1) == workflow.DefaultVersion {
+		return nil
+	}
+	return workflow.Await(ctx, cond)
+}
+
+// handled records a finished add or void update and wakes the main loop, so the event
+// counts towards ContinueAsNew like a signal does.
+func (b *billLifecycle) handled() {
+	b.inFlight--
+	b.state.EventCount++
+	b.wake.SendAsync(true)
+}
+
+// addQueued adds the line items accepted while the bill was scheduled.
+func (b *billLifecycle) addQueued(ctx workflow.Context) {
+	for _, item := range b.queued {
+		if _, err := b.addLineItem(ctx, item); err != nil {
+			workflow.GetLogger(ctx).Error("Failed to add queued line item.", "Error", err, "BillID", b.req.BillID, "LineItemID", item.LineItemID)
+			// The caller was told the item is queued, so it must not be lost.
+			b.deadLetter(ctx, model.FailedLineItemSourceSignal, err, item)
+		}
+	}
+	b.queued = nil
+}
@@ -1,39 +1,80 @@
 package temporal
 
 import (
+	"time"
+
 	"encore.app/fee/model"
 	"go.temporal.io/sdk/workflow"
 )
 
 // UsageBasedPolicy implements the logic for a standard usage-based bill.
-type UsageBasedPolicy struct{}
+type UsageBasedPolicy struct {
+	TenantID string
+}
+
+func NewUsagePolicy(tenantID string) *UsageBasedPolicy {
+	return &UsageBasedPolicy{TenantID: tenantID}
+}
 
-func NewUsagePolicy() *UsageBasedPolicy {
-	return &UsageBasedPolicy{}
+func (p *UsageBasedPolicy) HandleRecurringItem(ctx workflow.Context, activities *Activities, onSuccess func(_ int64), onFailure func(AddLineItemSignalRequest, error)) error {
+	return nil
 }
 
-func (p *UsageBasedPolicy) HandleRecurringItem(ctx workflow.Context, activities *Activities, onSuccess func(_ int64)) error {
+func (p *UsageBasedPolicy) ValidateLineItemChange() error {
 	return nil
 }
 
-func (p *UsageBasedPolicy) HandleAddLineItem(ctx workflow.Context, activities *Activities, signal AddLineItemSignalRequest) bool {
-	err := workflow.ExecuteActivity(ctx, activities.AddLineItem, signal.BillID, signal.Amount, signal.Metadata, signal.LineItemID).Get(ctx, nil)
+func (p *UsageBasedPolicy) HandleAddLineItem(ctx workflow.Context, activities *Activities, signal AddLineItemSignalRequest) (model.AddedLineItem, error) {
+	var result addedLineItem
+	err := workflow.ExecuteActivity(ctx, activities.AddLineItem, p.TenantID, signal.BillID, signal.Amount, signal.Metadata, signal.LineItemID, signal.source()).Get(ctx, &result)
 	if err != nil {
 		workflow.GetLogger(ctx).Error("Failed to add line item after all retries.", "Error", err)
-		return false // Do not update totals if the activity failed
+		return model.AddedLineItem{}, err // Do not update totals if the activity failed
+	}
+	added := model.AddedLineItem{LineItemID: result.LineItemID, Inserted: result.inserted(signal.LineItemID)}
+	if !added.Inserted {
+		// A repeated external_ref is a no-op, the original line item is already in the totals.
+		workflow.GetLogger(ctx).Info("Skipped line item with duplicate external ref.", "BillID", signal.BillID, "LineItemID", added.LineItemID)
+		return added, nil
 	}
 	workflow.GetLogger(ctx).Debug("Add line item activity completed.", "BillID", signal.BillID)
-	return true // Signal to the workflow to update the totals
+	return added, nil
 }
 
-func (p *UsageBasedPolicy) HandleUpdateLineItem(ctx workflow.Context, activities *Activities, signal UpdateLineItemSignalRequest) *model.LineItem {
+func (p *UsageBasedPolicy) HandleAddLineItems(ctx workflow.Context, activities *Activities, signal AddLineItemsSignalRequest) ([]AddLineItemSignalRequest, error) {
+	items := make([]model.LineItemInput, len(signal.Items))
+	for i, item := range signal.Items {
+		items[i] = model.LineItemInput{LineItemID: item.LineItemID, Amount: item.Amount, Metadata: item.Metadata}
+	}
+	var results []addedLineItem
+	err := workflow.ExecuteActivity(ctx, activities.AddLineItems, p.TenantID, signal.BillID, items, signal.source()).Get(ctx, &results)
+	if err != nil {
+		workflow.GetLogger(ctx).Error("Failed to add line items after all retries.", "Error", err, "BillID", signal.BillID, "Count", len(items))
+		return nil, err
+	}
+	added := make([]AddLineItemSignalRequest, 0, len(signal.Items))
+	for i, item := range signal.Items {
+		// Items whose external_ref already exists on the bill resolve to the original line item.
+		if i < len(results) && !results[i].inserted(item.LineItemID) {
+			continue
+		}
+		added = append(added, item)
+	}
+	if skipped := len(signal.Items) - len(added); skipped > 0 {
+		workflow.GetLogger(ctx).Info("Skipped line items with duplicate external refs.", "BillID", signal.BillID, "Count", skipped)
+	}
+	workflow.GetLogger(ctx).Debug("Add line items activity completed.", "BillID", signal.BillID, "Count", len(added))
+	return added, nil
+}
+
+func (p *UsageBasedPolicy) HandleUpdateLineItem(ctx workflow.Context, activities *Activities, signal UpdateLineItemSignalRequest) (*model.LineItem, error) {
 	var lineItem *model.LineItem
-	err := workflow.ExecuteActivity(ctx, activities.UpdateLineItem, signal.BillID, signal.LineItemID, signal.Status).Get(ctx, &lineItem)
+	err := workflow.ExecuteActivity(ctx, activities.UpdateLineItem, p.TenantID, signal.BillID, signal.LineItemID, signal.Status, signal.source()).Get(ctx, &lineItem)
 	if err != nil {
 		workflow.GetLogger(ctx).Error("Failed to update line item.", "Error", err, "BillID", signal.BillID, "LineItemID", signal.LineItemID)
-		return nil
+		return nil, err
 	}
-	return lineItem
+	return lineItem, nil
 }
 
 func (p *UsageBasedPolicy) OnBillClose(ctx workflow.Context, state *BillState) error {
@@ -51,3 +92,7 @@ func (p *UsageBasedPolicy) OnTimerFired(ctx workflow.Context, state *BillState)
 func (p *UsageBasedPolicy) RecurringFuture() workflow.Future {
 	return nil
 }
+
+func (p *UsageBasedPolicy) State(now, periodEnd time.Time) PolicyState {
+	return PolicyState{Type: model.UsageBased}
+}
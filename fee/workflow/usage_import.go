@@ -0,0 +1,118 @@
+package temporal
+
+import (
+	"errors"
+
+	"encore.app/fee/model"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+const (
+	usageImportPageSize        = 500
+	usageImportPagesPerRun     = 20 // keeps the history bounded, the workflow continues as new afterwards
+	usageImportSignalBatchSize = 100
+)
+
+// UsageImportWorkflow fans the rows of a CSV usage import out to their bill workflows, one
+// AddLineItems signal per bill and chunk, and records the outcome of every row as it goes.
+// Line item IDs are fixed when the import is created, so a replayed row is never billed twice.
+func UsageImportWorkflow(ctx workflow.Context, req *UsageImportWorkflowRequest) error {
+	ao := workflow.ActivityOptions{
+		StartToCloseTimeout: startToCloseTimeout,
+		RetryPolicy: &temporal.RetryPolicy{
+			MaximumAttempts: maxRetryAttempt,
+		},
+	}
+	ctx = workflow.WithActivityOptions(ctx, ao)
+
+	var activities *Activities
+	if req.TenantID == "" {
+		req.TenantID = model.DefaultTenant
+	}
+	if req.AfterRow == 0 {
+		if err := workflow.ExecuteActivity(ctx, activities.SetUsageImportStatus, req.TenantID, req.ImportID, model.UsageImportStatusRunning).Get(ctx, nil); err != nil {
+			return err
+		}
+	}
+
+	for page := 0; page < usageImportPagesPerRun; page++ {
+		var rows []model.UsageImportRow
+		if err := workflow.ExecuteActivity(ctx, activities.LoadUsageImportRows, req.TenantID, req.ImportID, req.AfterRow, usageImportPageSize).Get(ctx, &rows); err != nil {
+			workflow.GetLogger(ctx).Error("Failed to load usage import rows.", "Error", err, "ImportID", req.ImportID)
+			return err
+		}
+		if len(rows) > 0 {
+			results := signalUsageImportRows(ctx, req.TenantID, req.ImportID, rows)
+			if err := workflow.ExecuteActivity(ctx, activities.RecordUsageImportResults, req.TenantID, req.ImportID, results).Get(ctx, nil); err != nil {
+				workflow.GetLogger(ctx).Error("Failed to record usage import results.", "Error", err, "ImportID", req.ImportID)
+				return err
+			}
+			req.AfterRow = rows[len(rows)-1].RowNumber
+		}
+		if len(rows) < usageImportPageSize {
+			if err := workflow.ExecuteActivity(ctx, activities.SetUsageImportStatus, req.TenantID, req.ImportID, model.UsageImportStatusCompleted).Get(ctx, nil); err != nil {
+				return err
+			}
+			workflow.GetLogger(ctx).Info("Usage import completed.", "ImportID", req.ImportID)
+			return nil
+		}
+	}
+
+	workflow.GetLogger(ctx).Info("Continuing usage import as new.", "ImportID", req.ImportID, "AfterRow", req.AfterRow)
+	return workflow.NewContinueAsNewError(ctx, UsageImportWorkflow, req)
+}
+
+// signalUsageImportRows groups rows per bill, keeping file order, and signals every bill in parallel.
+func signalUsageImportRows(ctx workflow.Context, tenantID, importID string, rows []model.UsageImportRow) []model.UsageImportRowResult {
+	type chunk struct {
+		signal     AddLineItemsSignalRequest
+		rowNumbers []int
+		future     workflow.Future
+	}
+
+	var chunks []*chunk
+	open := make(map[string]*chunk)
+	for _, row := range rows {
+		c, ok := open[row.BillID]
+		if !ok || len(c.signal.Items) == usageImportSignalBatchSize {
+			c = &chunk{signal: AddLineItemsSignalRequest{BillID: row.BillID, Actor: "usage-import:" + importID}}
+			open[row.BillID] = c
+			chunks = append(chunks, c)
+		}
+		c.rowNumbers = append(c.rowNumbers, row.RowNumber)
+		c.signal.Items = append(c.signal.Items, AddLineItemSignalRequest{
+			LineItemID: row.LineItemID,
+			Amount:     row.Amount,
+			BillID:     row.BillID,
+			Actor:      "usage-import:" + importID,
+			Metadata: &model.LineItemMetadata{
+				Description: row.Description,
+				ExternalRef: row.ExternalRef,
+				OccurredAt:  row.OccurredAt,
+			},
+		})
+	}
+
+	for _, c := range chunks {
+		c.future = workflow.SignalExternalWorkflow(ctx, BillCycleWorkflowID(tenantID, c.signal.BillID), "", AddLineItemsSignal, c.signal)
+	}
+
+	results := make([]model.UsageImportRowResult, 0, len(rows))
+	for _, c := range chunks {
+		var message string
+		if err := c.future.Get(ctx, nil); err != nil {
+			var notFound *temporal.UnknownExternalWorkflowExecutionError
+			if errors.As(err, &notFound) {
+				message = "bill not found or already closed"
+			} else {
+				message = err.Error()
+			}
+			workflow.GetLogger(ctx).Warn("Failed to signal usage import rows to bill.", "Error", err, "BillID", c.signal.BillID, "Count", len(c.rowNumbers))
+		}
+		for _, rowNumber := range c.rowNumbers {
+			results = append(results, model.UsageImportRowResult{RowNumber: rowNumber, Error: message})
+		}
+	}
+	return results
+}
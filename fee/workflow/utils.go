@@ -1,9 +1,29 @@
 package temporal
 
-func BillCycleWorkflowID(billID string) string {
-	return "bill-" + billID
+import "encore.app/fee/model"
+
+// BillCycleWorkflowID is the ID of the workflow of a bill. Bill IDs are only unique within a
+// tenant, so the tenant is part of the ID. Bills of the default tenant keep the IDs they had
+// before tenants existed, so their running workflows are still found.
+func BillCycleWorkflowID(tenantID, billID string) string {
+	return "bill-" + tenantPrefix(tenantID) + billID
+}
+
+func BillPostprocessWorkflowID(tenantID, billID string) string {
+	return BillCycleWorkflowID(tenantID, billID) + "-postprocess"
+}
+
+func UsageImportWorkflowID(tenantID, importID string) string {
+	return "usage-import-" + tenantPrefix(tenantID) + importID
+}
+
+func WebhookDeliveryWorkflowID(tenantID, deliveryID string) string {
+	return "webhook-delivery-" + tenantPrefix(tenantID) + deliveryID
 }
 
-func BillPostprocessWorkflowID(billID string) string {
-	return "bill-" + billID + "-postprocess"
+func tenantPrefix(tenantID string) string {
+	if tenantID == "" || tenantID == model.DefaultTenant {
+		return ""
+	}
+	return tenantID + "/"
 }
@@ -0,0 +1,21 @@
+package temporal
+
+// Change IDs for workflow.GetVersion. A change to the commands a workflow issues is gated by
+// one, so runs that started before the change keep replaying the commands they recorded.
+// Remove a gate, and the old branch, once no run that started before it is left.
+const (
+	// The billing policy is created after the bill, and after a scheduled bill started.
+	versionPolicyAfterStart = "policy-after-start"
+	// The workflow waits for running update handlers before it continues as new or completes.
+	versionAwaitUpdateHandlers = "await-update-handlers"
+	// A billing period end change is persisted and replaces the period end timer.
+	versionReplacePeriodTimer = "replace-period-timer"
+	// A period end timer that fires while the period end changes waits for the change.
+	versionHoldTimerForPeriodChange = "hold-timer-for-period-change"
+	// Line items whose activity failed are dead-lettered instead of dropped.
+	versionDeadLetterFailedLineItems = "dead-letter-failed-line-items"
+	// A failed post-process records the post_process.failed event.
+	versionPostProcessFailedEvent = "post-process-failed-event"
+	// Line items signalled to a scheduled bill that rejects them are dead-lettered instead of dropped.
+	versionDeadLetterEarlyLineItems = "dead-letter-early-line-items"
+)
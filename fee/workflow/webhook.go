@@ -0,0 +1,203 @@
+package temporal
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"encore.app/fee/model"
+	"encore.app/fee/utils"
+	"encore.app/fee/webhook"
+	"encore.dev/rlog"
+	"go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+const (
+	errInactive = "Inactive"
+
+	// webhookMaxAttempts with the backoff of WebhookDeliveryWorkflow keeps retrying a delivery
+	// for about half a day: 10s, 20s, 40s, ... up to an hour between attempts.
+	webhookMaxAttempts int32 = 20
+	// webhookDispatchBatch is how many undispatched deliveries one dispatch starts.
+	webhookDispatchBatch = 100
+)
+
+// WorkflowStarter starts workflows, it is the part of the Temporal client the activities use.
+type WorkflowStarter interface {
+	ExecuteWorkflow(ctx context.Context, options client.StartWorkflowOptions, workflow interface{}, args ...interface{}) (client.WorkflowRun, error)
+}
+
+// WebhookDeliveryWorkflow posts the event of a delivery to its subscription's endpoint, retrying
+// with exponential backoff. A delivery whose every attempt failed is marked FAILED, it can be
+// redelivered by hand.
+func WebhookDeliveryWorkflow(ctx workflow.Context, req *WebhookDeliveryWorkflowRequest) error {
+	var activities *Activities
+	if req.TenantID == "" {
+		req.TenantID = model.DefaultTenant
+	}
+
+	deliverCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: startToCloseTimeout,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    10 * time.Second,
+			BackoffCoefficient: 2,
+			MaximumInterval:    time.Hour,
+			MaximumAttempts:    webhookMaxAttempts,
+		},
+	})
+	err := workflow.ExecuteActivity(deliverCtx, activities.DeliverWebhook, req.TenantID, req.DeliveryID).Get(ctx, nil)
+	if err == nil || temporal.IsCanceledError(err) {
+		return err
+	}
+	workflow.GetLogger(ctx).Warn("Webhook delivery failed.", "Error", err, "DeliveryID", req.DeliveryID)
+
+	failCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: startToCloseTimeout,
+		RetryPolicy: &temporal.RetryPolicy{
+			MaximumAttempts: maxRetryAttempt,
+		},
+	})
+	if failErr := workflow.ExecuteActivity(failCtx, activities.FailWebhookDelivery, req.TenantID, req.DeliveryID).Get(ctx, nil); failErr != nil {
+		return failErr
+	}
+	return err
+}
+
+// StartWebhookDelivery starts the workflow of a delivery, a delivery whose workflow was already
+// started isn't started again.
+func StartWebhookDelivery(ctx context.Context, workflows WorkflowStarter, tenantID, deliveryID string) error {
+	_, err := workflows.ExecuteWorkflow(ctx, client.StartWorkflowOptions{
+		ID:                    WebhookDeliveryWorkflowID(tenantID, deliveryID),
+		TaskQueue:             WebhookTaskQueue,
+		WorkflowIDReusePolicy: enums.WORKFLOW_ID_REUSE_POLICY_REJECT_DUPLICATE,
+	}, WebhookDeliveryWorkflow, &WebhookDeliveryWorkflowRequest{TenantID: tenantID, DeliveryID: deliveryID})
+	var alreadyStarted *serviceerror.WorkflowExecutionAlreadyStarted
+	if err != nil && !errors.As(err, &alreadyStarted) {
+		return err
+	}
+	return nil
+}
+
+// dispatchWebhooks starts the workflows of a batch of the tenant's deliveries that weren't started
+// yet. It returns how many it started.
+func (a *Activities) dispatchWebhooks(ctx context.Context, tenantID string) int {
+	deliveryIDs, err := a.db.ListUndispatchedWebhookDeliveries(ctx, tenantID, webhookDispatchBatch)
+	if err != nil {
+		rlog.Error("failed to list undispatched webhook deliveries", "error", err, "tenant_id", tenantID)
+		return 0
+	}
+	dispatched := make([]string, 0, len(deliveryIDs))
+	for _, deliveryID := range deliveryIDs {
+		if err := StartWebhookDelivery(ctx, a.workflows, tenantID, deliveryID); err != nil {
+			rlog.Error("failed to start webhook delivery workflow", "error", err, "delivery_id", deliveryID)
+			continue
+		}
+		dispatched = append(dispatched, deliveryID)
+	}
+	if len(dispatched) == 0 {
+		return 0
+	}
+	if err := a.db.MarkWebhookDeliveriesDispatched(ctx, tenantID, dispatched); err != nil {
+		rlog.Error("failed to mark webhook deliveries dispatched", "error", err, "tenant_id", tenantID)
+		return 0
+	}
+	return len(dispatched)
+}
+
+// DispatchWebhooks starts the deliveries of every tenant whose change didn't start them, because
+// starting the workflow failed or the process stopped first. Starting a delivery twice is a no-op.
+func (a *Activities) DispatchWebhooks(ctx context.Context) {
+	tenantIDs, err := a.db.ListWebhookDispatchTenants(ctx)
+	if err != nil {
+		rlog.Error("failed to list webhook dispatch tenants", "error", err)
+		return
+	}
+	for _, tenantID := range tenantIDs {
+		for ctx.Err() == nil && a.dispatchWebhooks(ctx, tenantID) == webhookDispatchBatch {
+		}
+	}
+}
+
+// DeliverWebhook makes one attempt to post the event of a delivery and logs it. An attempt that
+// was sent but couldn't be logged is sent again, receivers tell repeated events by their ID.
+func (a *Activities) DeliverWebhook(ctx context.Context, tenantID, deliveryID string) error {
+	delivery, err := a.db.GetWebhookDelivery(ctx, tenantID, deliveryID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return temporal.NewNonRetryableApplicationError("webhook delivery not found", errNotFound, err)
+	}
+	if err != nil {
+		return err
+	}
+	if delivery.Status == model.WebhookDeliveryDelivered {
+		return nil
+	}
+	sub, err := a.db.GetWebhookSubscription(ctx, tenantID, delivery.SubscriptionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return temporal.NewNonRetryableApplicationError("webhook subscription not found", errNotFound, err)
+	}
+	if err != nil {
+		return err
+	}
+	if !sub.Active {
+		return temporal.NewNonRetryableApplicationError("webhook subscription is inactive", errInactive, nil)
+	}
+	event, err := a.db.GetWebhookEvent(ctx, tenantID, delivery.EventID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return temporal.NewNonRetryableApplicationError("webhook event not found", errNotFound, err)
+	}
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	statusCode, sendErr := a.webhooks.Send(ctx, &webhook.Delivery{DeliveryID: deliveryID, URL: sub.URL, Secret: sub.Secret, Event: event})
+	attempt := model.WebhookAttempt{
+		Attempt:    int(activity.GetInfo(ctx).Attempt),
+		StatusCode: statusCode,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if sendErr != nil {
+		attempt.Error = sendErr.Error()
+	}
+	if err := a.db.RecordWebhookAttempt(ctx, tenantID, deliveryID, attempt); err != nil {
+		return err
+	}
+	return sendErr
+}
+
+func (a *Activities) FailWebhookDelivery(ctx context.Context, tenantID, deliveryID string) error {
+	return a.db.FailWebhookDelivery(ctx, tenantID, deliveryID)
+}
+
+// PostProcessFailed sends the post_process.failed event of a bill whose post-processing run
+// failed, as a webhook and to Pub/Sub.
+func (a *Activities) PostProcessFailed(ctx context.Context, tenantID, billID, cause string) error {
+	info := activity.GetInfo(ctx)
+	eventID := utils.NameUUID(info.WorkflowExecution.ID + "/" + info.WorkflowExecution.RunID + "/" + string(model.WebhookPostProcessFailed))
+	data, err := json.Marshal(map[string]string{"error": cause})
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(model.PostProcessFailedV1{
+		Meta:  model.EventMeta{EventID: eventID, SchemaVersion: 1, TenantID: tenantID, BillID: billID, OccurredAt: time.Now()},
+		Error: cause,
+	})
+	if err != nil {
+		return err
+	}
+	err = a.db.AddEvent(ctx, tenantID,
+		&model.WebhookEvent{EventID: eventID, Type: model.WebhookPostProcessFailed, BillID: billID, Data: data},
+		[]model.OutboxEvent{{EventID: eventID, Topic: model.TopicPostProcessFailedV1, Payload: payload}})
+	if err != nil {
+		return err
+	}
+	a.relayChanges(ctx, tenantID)
+	return nil
+}
@@ -1,8 +1,10 @@
 package temporal
 
 import (
+	"strings"
 	"time"
 
+	"encore.app/fee/model"
 	"encore.dev"
 	"encore.dev/rlog"
 	"go.temporal.io/api/enums/v1"
@@ -14,22 +16,42 @@ var (
 	envName                   = encore.Meta().Environment.Name
 	BillCycleTaskQueue        = envName + "bill3-lifecycle"
 	ClosedBillTaskQueue       = envName + "closed-bill-lifecycle"
+	WebhookTaskQueue          = envName + "webhook-delivery"
 	startToCloseTimeout       = 1 * time.Minute
 	QueryBillTotal            = "GET_BILL_TOTAL"
+	QueryBillState            = "GET_BILL_STATE"
 	maxRetryAttempt     int32 = 10
+	// MinPeriodLead is how far in the future a billing period end must be when it is set.
+	MinPeriodLead = 1 * time.Minute
 )
 
 const (
 	AddLineItemSignal           = "add-line-item"
+	AddLineItemsSignal          = "add-line-items"
 	UpdateLineItemSignal        = "update-line-item"
 	CloseBillSignal             = "close-bill"
+	CancelBillSignal            = "cancel-bill"
+	SetPeriodEndSignal          = "set-period-end"
+	SettleBillSignal            = "settle-bill" // sent to the post-process workflow when its payment link is paid
 	ContinueAsNewEventThreshold = 500
 )
 
+// Updates let the API wait for the outcome of a change instead of firing a signal.
+const (
+	AddLineItemUpdate  = "add-line-item"
+	VoidLineItemUpdate = "void-line-item"
+	CloseBillUpdate    = "close-bill"
+	CancelBillUpdate   = "cancel-bill"
+	SetPeriodEndUpdate = "set-period-end"
+
+	ResolveFailedLineItemUpdate = "resolve-failed-line-item"
+)
+
 type BillState struct {
-	Total      int64
-	BillID     string
-	EventCount int
+	Total           int64
+	BillID          string
+	EventCount      int
+	FailedLineItems int // dead-lettered line items that are neither retried nor discarded
 }
 
 // BillLifecycleWorkflow
@@ -43,43 +65,112 @@ func BillLifecycleWorkflow(ctx workflow.Context, req *BillLifecycleWorkflowReque
 	}
 	ctx = workflow.WithActivityOptions(ctx, ao)
 	var activities *Activities
+	if req.TenantID == "" {
+		req.TenantID = model.DefaultTenant
+	}
 
-	policy, err := NewBillingPolicy(ctx, req)
-	if err != nil {
-		return nil, err
+	// Runs from before scheduled bills created the policy, and a subscription's recurring timer, first.
+	var policy BillingPolicy
+	if workflow.GetVersion(ctx, versionPolicyAfterStart, workflow.DefaultVersion, 1) == workflow.DefaultVersion {
+		var err error
+		if policy, err = NewBillingPolicy(ctx, req); err != nil {
+			return nil, err
+		}
 	}
 
 	var state BillState
+	bill := newBillLifecycle(ctx, req, &state)
+	if err := bill.setUpdateHandlers(ctx); err != nil {
+		workflow.GetLogger(ctx).Error("Failed to register update handlers", "error", err)
+		return nil, err
+	}
+
 	if req.PreviousState != nil {
 		state = *req.PreviousState
-	} else {
-		// Create bill in DB
-		// Set req.Recuring to nil will get weird error in temporal
-		if err := workflow.ExecuteActivity(ctx, activities.CreateBill, req.BillID, req.PolicyType, req.Currency, req.BilingPeriodStart, req.Recurring).Get(ctx, nil); err != nil {
-			workflow.GetLogger(ctx).Error("Failed to execute create bill acitivities", "error", err)
-			return nil, err
+		if req.Reopen {
+			if err := workflow.ExecuteActivity(ctx, activities.ReopenBill, req.TenantID, req.BillID, req.source()).Get(ctx, nil); err != nil {
+				workflow.GetLogger(ctx).Error("Failed to reopen bill.", "Error", err, "BillID", req.BillID)
+				return nil, err
+			}
+			req.Reopen = false
 		}
+	} else {
 		// Get initial state from the policy
 		state = BillState{
 			Total:      0,
 			EventCount: 0,
 			BillID:     req.BillID,
 		}
+		status := model.BillStatusOpen
+		scheduled := req.BilingPeriodStart.After(workflow.Now(ctx))
+		if scheduled {
+			status = model.BillStatusScheduled
+		}
+		// Create bill in DB
+		// Set req.Recuring to nil will get weird error in temporal
+		if err := workflow.ExecuteActivity(ctx, activities.CreateBill, req.TenantID, req.BillID, req.PolicyType, req.Currency, req.CustomerID, status, req.BilingPeriodStart, req.BillingPeriodEnd, req.Recurring, req.source()).Get(ctx, nil); err != nil {
+			workflow.GetLogger(ctx).Error("Failed to execute create bill acitivities", "error", err)
+			return nil, err
+		}
+		if scheduled {
+			cancelled, err := bill.awaitBillingPeriodStart(ctx)
+			if err != nil {
+				return nil, err
+			}
+			if cancelled {
+				billDetail, err := getBillDetail(ctx, req.TenantID, req.BillID)
+				if err != nil {
+					return nil, err
+				}
+				// Let a pending cancel update return the cancelled bill.
+				bill.closed = billDetail
+				if err := bill.awaitHandlers(ctx, func() bool { return workflow.AllHandlersFinished(ctx) }); err != nil {
+					return nil, err
+				}
+				return billDetail, nil
+			}
+		}
 	}
 
+	// The policy is created once the bill is active so recurring charges start with the billing period.
+	if policy == nil {
+		var err error
+		if policy, err = NewBillingPolicy(ctx, req); err != nil {
+			return nil, err
+		}
+	}
+	bill.policy = policy
+	bill.addQueued(ctx)
+
 	// Create a query handler for API to query the current total bills before bills is closed
-	err = workflow.SetQueryHandler(ctx, QueryBillTotal, func() (int64, error) {
+	err := workflow.SetQueryHandler(ctx, QueryBillTotal, func() (int64, error) {
 		return state.Total, nil
 	})
 	if err != nil {
 		workflow.GetLogger(ctx).Error("Failed to register query bill total handler", "error", err)
 		return nil, err
 	}
+	err = workflow.SetQueryHandler(ctx, QueryBillState, func() (*LiveBillState, error) {
+		return &LiveBillState{
+			Total:            state.Total,
+			EventCount:       state.EventCount,
+			PendingPeriodEnd: req.BillingPeriodEnd,
+			Closing:          bill.closing,
+			FailedLineItems:  state.FailedLineItems,
+			Policy:           policy.State(workflow.Now(ctx), req.BillingPeriodEnd),
+		}, nil
+	})
+	if err != nil {
+		workflow.GetLogger(ctx).Error("Failed to register query bill state handler", "error", err)
+		return nil, err
+	}
 
 	// Setup channels for signals and timer
 	addItemSignalChan := workflow.GetSignalChannel(ctx, AddLineItemSignal)
+	addItemsSignalChan := workflow.GetSignalChannel(ctx, AddLineItemsSignal)
 	updateItemSignalChan := workflow.GetSignalChannel(ctx, UpdateLineItemSignal)
 	closeChan := workflow.GetSignalChannel(ctx, CloseBillSignal)
+	setPeriodEndChan := workflow.GetSignalChannel(ctx, SetPeriodEndSignal)
 
 	// Timer for automatic bill closure
 	var timerFired bool
@@ -87,6 +178,14 @@ func BillLifecycleWorkflow(ctx workflow.Context, req *BillLifecycleWorkflowReque
 	durationUntilEnd := req.BillingPeriodEnd.Sub(workflow.Now(ctx))
 	timerFuture := workflow.NewTimer(timerCtx, durationUntilEnd)
 
+	isBillCurrency := func(signal AddLineItemSignalRequest) bool {
+		if signal.Currency != "" && !strings.EqualFold(signal.Currency, req.Currency) {
+			workflow.GetLogger(ctx).Warn("Rejected line item priced in a different currency than the bill.", "BillID", req.BillID, "LineItemID", signal.LineItemID, "Currency", signal.Currency)
+			return false
+		}
+		return true
+	}
+
 	workflowCompleted := false
 	for {
 		selector := workflow.NewSelector(ctx)
@@ -96,18 +195,60 @@ func BillLifecycleWorkflow(ctx workflow.Context, req *BillLifecycleWorkflowReque
 			var signal AddLineItemSignalRequest
 			c.Receive(ctx, &signal)
 			state.EventCount++
+			if !isBillCurrency(signal) {
+				return
+			}
 
 			// DELEGATE to the policy
-			if policy.HandleAddLineItem(ctx, activities, signal) {
+			added, err := policy.HandleAddLineItem(ctx, activities, signal)
+			if err != nil {
+				bill.deadLetter(ctx, model.FailedLineItemSourceSignal, err, signal)
+				return
+			}
+			if added.Inserted {
 				state.Total += signal.Amount
 			}
 		})
 
+		// Listen for batched AddLineItems signals. A batch is persisted by a single
+		// activity, so it only counts as one event towards ContinueAsNew.
+		selector.AddReceive(addItemsSignalChan, func(c workflow.ReceiveChannel, more bool) {
+			var signal AddLineItemsSignalRequest
+			c.Receive(ctx, &signal)
+			state.EventCount++
+
+			items := signal.Items[:0]
+			for _, item := range signal.Items {
+				if item.Actor == "" {
+					item.Actor, item.RequestID = signal.Actor, signal.RequestID
+				}
+				if isBillCurrency(item) {
+					items = append(items, item)
+				}
+			}
+			if len(items) == 0 {
+				return
+			}
+			signal.Items = items
+
+			// DELEGATE to the policy
+			added, err := policy.HandleAddLineItems(ctx, activities, signal)
+			if err != nil {
+				bill.deadLetter(ctx, model.FailedLineItemSourceSignal, err, signal.Items...)
+				return
+			}
+			for _, item := range added {
+				state.Total += item.Amount
+			}
+		})
+
 		if recurringFeeTimer := policy.RecurringFuture(); recurringFeeTimer != nil {
 			selector.AddFuture(recurringFeeTimer, func(f workflow.Future) {
 				state.EventCount++
 				policy.HandleRecurringItem(ctx, activities, func(newAmount int64) {
 					state.Total += newAmount
+				}, func(item AddLineItemSignalRequest, err error) {
+					bill.deadLetter(ctx, model.FailedLineItemSourceRecurring, err, item)
 				})
 			})
 		}
@@ -119,7 +260,7 @@ func BillLifecycleWorkflow(ctx workflow.Context, req *BillLifecycleWorkflowReque
 			state.EventCount++
 
 			// DELEGATE to the policy
-			if lineItem := policy.HandleUpdateLineItem(ctx, activities, signal); lineItem != nil {
+			if lineItem, err := policy.HandleUpdateLineItem(ctx, activities, signal); err == nil {
 				state.Total -= lineItem.Amount
 			}
 		})
@@ -128,27 +269,80 @@ func BillLifecycleWorkflow(ctx workflow.Context, req *BillLifecycleWorkflowReque
 		selector.AddReceive(closeChan, func(c workflow.ReceiveChannel, more bool) {
 			var signal ClosedBillRequest
 			c.Receive(ctx, &signal)
-			workflow.GetLogger(ctx).Info("Received explicit CloseBillSignal.")
+			workflow.GetLogger(ctx).Info("Received explicit CloseBillSignal.", "Reason", signal.Reason)
+			if state.FailedLineItems > 0 && !signal.Force {
+				workflow.GetLogger(ctx).Warn("Ignored CloseBillSignal, the bill has unresolved failed line items.", "BillID", req.BillID, "FailedLineItems", state.FailedLineItems)
+				return
+			}
 
-			// TODO: [BIZ METRICS] capture reason of manual cancel
-			// Capture the *reason*.
-			// This requires enhancing the `ClosedBillRequest` signal to include an optional `reason` enum/string.
-			// The metric would then be `bills_closed_manually_total{policy="usage_based", reason="customer_request"}`.
+			// TODO: [BIZ METRICS] count manual closes by reason
+			// The metric would be `bills_closed_manually_total{policy="usage_based", reason="customer_request"}`.
 			// This provides invaluable business insight into why bills are deviating from the automated path.
 
+			closure := signal.closure()
+			bill.closure = &closure
+			bill.closeSource = signal.source()
 			workflowCompleted = true
 		})
 
-		// Listen for the billing period end timer
-		selector.AddFuture(timerFuture, func(f workflow.Future) {
-			// DELEGATE to the policy
-			if policy.OnTimerFired(ctx, &state) {
-				timerFired = true
-				workflowCompleted = true
+		// Listen for SetPeriodEnd signals, the timer is restarted below
+		selector.AddReceive(setPeriodEndChan, func(c workflow.ReceiveChannel, more bool) {
+			var signal SetPeriodEndRequest
+			c.Receive(ctx, &signal)
+			state.EventCount++
+			if err := bill.validateSetPeriodEnd(ctx, signal); err != nil {
+				workflow.GetLogger(ctx).Warn("Rejected billing period end change.", "Error", err, "BillID", req.BillID, "BillingPeriodEnd", signal.BillingPeriodEnd)
+				return
 			}
+			if err := bill.applyPeriodEnd(ctx, signal.BillingPeriodEnd); err != nil {
+				workflow.GetLogger(ctx).Error("Failed to change billing period end.", "Error", err, "BillID", req.BillID)
+			}
+		})
+
+		// Update handlers wake the loop after they changed the state or asked to close the bill.
+		selector.AddReceive(bill.wake, func(c workflow.ReceiveChannel, more bool) {
+			c.Receive(ctx, nil)
 		})
 
+		// Listen for the billing period end timer, it stays ready once fired.
+		if !timerFired {
+			selector.AddFuture(timerFuture, func(f workflow.Future) {
+				// DELEGATE to the policy
+				if policy.OnTimerFired(ctx, &state) {
+					timerFired = true
+				}
+			})
+		}
+
 		selector.Select(ctx)
+		// The timer fired while a period end change is being persisted: the change replaces it, unless it fails.
+		if timerFired && bill.changingPeriod &&
+			workflow.GetVersion(ctx, versionHoldTimerForPeriodChange, workflow.DefaultVersion, 1) != workflow.DefaultVersion {
+			if err := workflow.Await(ctx, func() bool { return !bill.changingPeriod }); err != nil {
+				return nil, err
+			}
+		}
+		if bill.closeRequested || bill.cancelRequested {
+			workflowCompleted = true
+		}
+		// The billing period end changed: replace the timer, even one that already fired.
+		if bill.periodChanged {
+			bill.periodChanged = false
+			cancelTimer()
+			timerCtx, cancelTimer = workflow.WithCancel(ctx)
+			timerFuture = workflow.NewTimer(timerCtx, req.BillingPeriodEnd.Sub(workflow.Now(ctx)))
+			timerFired = false
+			workflow.GetLogger(ctx).Info("Billing period end changed.", "BillID", req.BillID, "BillingPeriodEnd", req.BillingPeriodEnd)
+		}
+		// A bill whose period ended only closes once its failed line items are retried or discarded.
+		if timerFired && !workflowCompleted {
+			if state.FailedLineItems == 0 {
+				workflowCompleted = true
+			} else if !bill.periodEnded {
+				bill.periodEnded = true
+				workflow.GetLogger(ctx).Warn("Billing period ended with unresolved failed line items, waiting for them to be resolved.", "BillID", req.BillID, "FailedLineItems", state.FailedLineItems)
+			}
+		}
 
 		// If the workflow is marked for completion (by signal or timer), break the loop.
 		// This must be checked *before* the ContinueAsNew condition.
@@ -166,25 +360,65 @@ func BillLifecycleWorkflow(ctx workflow.Context, req *BillLifecycleWorkflowReque
 			// is set appropriately. If this duration is consistently high, it might indicate a performance bottleneck
 			// that needs to be addressed, something a simple event count would never reveal.
 
+			// Running updates must finish first, their callers would not get a result from the new run.
+			// A close accepted meanwhile waits for this run to close the bill instead.
+			if err := bill.awaitHandlers(ctx, func() bool {
+				return workflow.AllHandlersFinished(ctx) || bill.closeRequested || bill.cancelRequested
+			}); err != nil {
+				return nil, err
+			}
+			if bill.closeRequested || bill.cancelRequested {
+				break
+			}
 			workflow.GetLogger(ctx).Info("Event threshold reached, continuing as new.", "EventCount", state.EventCount)
 			req.PreviousState = &state
 			return nil, workflow.NewContinueAsNewError(ctx, BillLifecycleWorkflow, req)
 		}
 	}
 
+	// Stop accepting updates and let the ones already accepted reach the totals before closing.
+	bill.closing = true
+	if err := bill.awaitHandlers(ctx, func() bool { return bill.inFlight == 0 }); err != nil {
+		return nil, err
+	}
+
 	// If the workflow is completing before the timer fired (e.g. via manual close from API), cancel the timer.
 	if !timerFired {
 		cancelTimer()
 	}
 
+	// A cancelled bill is abandoned: it is not charged and not post-processed.
+	if bill.cancelRequested {
+		if err := workflow.ExecuteActivity(ctx, activities.CancelBill, req.TenantID, req.BillID, *bill.closure, bill.closeSource).Get(ctx, nil); err != nil {
+			workflow.GetLogger(ctx).Error("Failed to cancel bill, failing workflow.", "Error", err, "BillID", req.BillID)
+			return nil, err
+		}
+		workflow.GetLogger(ctx).Info("Bill cancelled.", "BillID", req.BillID)
+		billDetail, err := getBillDetail(ctx, req.TenantID, req.BillID)
+		if err != nil {
+			return nil, err
+		}
+		bill.closed = billDetail
+		if err := bill.awaitHandlers(ctx, func() bool { return workflow.AllHandlersFinished(ctx) }); err != nil {
+			return nil, err
+		}
+		return billDetail, nil
+	}
+
 	// --- Close the Bill ---
 	// DELEGATE final policy logic before closing
 	if err := policy.OnBillClose(ctx, &state); err != nil {
 		return nil, err
 	}
 
+	closure := model.BillClosure{Reason: model.CloseReasonPeriodEnd, Actor: "system", Trigger: model.CloseTriggerTimer}
+	source := model.AuditSource{Actor: closure.Actor}
+	if bill.closure != nil {
+		closure, source = *bill.closure, bill.closeSource
+	}
+
 	// This logic is now outside the loop and runs if the loop was exited by either the timer or an explicit signal.
-	if err := workflow.ExecuteActivity(ctx, activities.CloseBillFromState, state).Get(ctx, nil); err != nil {
+	if err := workflow.ExecuteActivity(ctx, activities.CloseBillFromState, req.TenantID, state, closure, source).Get(ctx, nil); err != nil {
 		// If closing the bill fails, the workflow must fail to prevent incorrect financial state.
 		workflow.GetLogger(ctx).Error("Failed to close bill, failing workflow.", "Error", err, "BillID", req.BillID)
 		return nil, err
@@ -192,15 +426,14 @@ func BillLifecycleWorkflow(ctx workflow.Context, req *BillLifecycleWorkflowReque
 	workflow.GetLogger(ctx).Info("Bill closed successfully.", "BillID", req.BillID)
 
 	// After the billDetail is closed, get the final billDetail.
-	var billDetail BillResponse
-	err = workflow.ExecuteActivity(ctx, activities.GetBillDetail, req.BillID).Get(ctx, &billDetail)
+	billDetail, err := getBillDetail(ctx, req.TenantID, req.BillID)
 	if err != nil {
-		workflow.GetLogger(ctx).Error("Failed to get final bill summary, failing workflow.", "Error", err, "BillID", req.BillID)
 		return nil, err
 	}
+	bill.closed = billDetail
 
 	ctx = workflow.WithChildOptions(ctx, workflow.ChildWorkflowOptions{
-		WorkflowID:        BillPostprocessWorkflowID(req.BillID),
+		WorkflowID:        BillPostprocessWorkflowID(req.TenantID, req.BillID),
 		TaskQueue:         ClosedBillTaskQueue,
 		ParentClosePolicy: enums.PARENT_CLOSE_POLICY_ABANDON,
 	})
@@ -208,7 +441,8 @@ func BillLifecycleWorkflow(ctx workflow.Context, req *BillLifecycleWorkflowReque
 	// Only trigger post process if there is a charges required
 	if billDetail.TotalAmount > 0 {
 		childWorkflow := workflow.ExecuteChildWorkflow(ctx, ClosedBillPostProcessWorkflow, BillClosedPostProcessWorkflowRequest{
-			BillID: req.BillID,
+			TenantID: req.TenantID,
+			BillID:   req.BillID,
 		})
 		if err := childWorkflow.GetChildWorkflowExecution().Get(ctx, nil); err != nil {
 			// This is a serious problem, it means we couldn't even START the child workflow.
@@ -223,6 +457,94 @@ func BillLifecycleWorkflow(ctx workflow.Context, req *BillLifecycleWorkflowReque
 		}
 	}
 
+	// Let a pending close update return the final bill before the workflow completes.
+	if err := bill.awaitHandlers(ctx, func() bool { return workflow.AllHandlersFinished(ctx) }); err != nil {
+		return nil, err
+	}
+	return billDetail, nil
+}
+
+// awaitBillingPeriodStart holds a scheduled bill until its billing period starts.
+// Line items received meanwhile are either dead-lettered or left buffered on the signal
+// channel, depending on req.ScheduledLineItems. It returns true if the bill was
+// cancelled before it became active, by the cancel update or the cancel signal. A cancel
+// update accepted after the period started is left to the main loop.
+func (b *billLifecycle) awaitBillingPeriodStart(ctx workflow.Context) (cancelled bool, err error) {
+	var activities *Activities
+	req := b.req
+	workflow.GetLogger(ctx).Info("Bill is scheduled, waiting for billing period start.", "BillID", req.BillID, "BillingPeriodStart", req.BilingPeriodStart)
+
+	timerCtx, cancelTimer := workflow.WithCancel(ctx)
+	startTimer := workflow.NewTimer(timerCtx, req.BilingPeriodStart.Sub(workflow.Now(ctx)))
+	cancelChan := workflow.GetSignalChannel(ctx, CancelBillSignal)
+	addItemSignalChan := workflow.GetSignalChannel(ctx, AddLineItemSignal)
+	addItemsSignalChan := workflow.GetSignalChannel(ctx, AddLineItemsSignal)
+
+	started := false
+	for !started && !b.cancelRequested {
+		selector := workflow.NewSelector(ctx)
+		selector.AddFuture(startTimer, func(f workflow.Future) {
+			started = true
+		})
+		selector.AddReceive(cancelChan, func(c workflow.ReceiveChannel, more bool) {
+			var signal CancelBillRequest
+			c.Receive(ctx, &signal)
+			b.requestCancel(signal)
+		})
+		// The cancel update wakes the loop once it asked for the cancel.
+		selector.AddReceive(b.wake, func(c workflow.ReceiveChannel, more bool) {
+			c.Receive(ctx, nil)
+		})
+		// In QUEUE mode the add line item channel is left alone, so early signals are
+		// delivered to the main loop once the bill is active.
+		if req.ScheduledLineItems != model.ScheduledLineItemsQueue {
+			selector.AddReceive(addItemSignalChan, func(c workflow.ReceiveChannel, more bool) {
+				var signal AddLineItemSignalRequest
+				c.Receive(ctx, &signal)
+				workflow.GetLogger(ctx).Warn("Rejected line item for a bill that has not started yet.", "BillID", req.BillID, "LineItemID", signal.LineItemID)
+				b.rejectEarly(ctx, signal)
+			})
+			selector.AddReceive(addItemsSignalChan, func(c workflow.ReceiveChannel, more bool) {
+				var signal AddLineItemsSignalRequest
+				c.Receive(ctx, &signal)
+				workflow.GetLogger(ctx).Warn("Rejected line items for a bill that has not started yet.", "BillID", req.BillID, "Count", len(signal.Items))
+				for i, item := range signal.Items {
+					if item.Actor == "" {
+						signal.Items[i].Actor, signal.Items[i].RequestID = signal.Actor, signal.RequestID
+					}
+				}
+				b.rejectEarly(ctx, signal.Items...)
+			})
+		}
+		selector.Select(ctx)
+	}
+
+	if b.cancelRequested {
+		cancelTimer()
+		if err := workflow.ExecuteActivity(ctx, activities.CancelBill, req.TenantID, req.BillID, *b.closure, b.closeSource).Get(ctx, nil); err != nil {
+			workflow.GetLogger(ctx).Error("Failed to cancel scheduled bill.", "Error", err, "BillID", req.BillID)
+			return false, err
+		}
+		workflow.GetLogger(ctx).Info("Scheduled bill cancelled.", "BillID", req.BillID)
+		return true, nil
+	}
+
+	if err := workflow.ExecuteActivity(ctx, activities.ActivateBill, req.TenantID, req.BillID).Get(ctx, nil); err != nil {
+		workflow.GetLogger(ctx).Error("Failed to activate scheduled bill.", "Error", err, "BillID", req.BillID)
+		return false, err
+	}
+	workflow.GetLogger(ctx).Info("Scheduled bill activated.", "BillID", req.BillID)
+	return false, nil
+}
+
+func getBillDetail(ctx workflow.Context, tenantID, billID string) (*BillResponse, error) {
+	var activities *Activities
+	var billDetail BillResponse
+	err := workflow.ExecuteActivity(ctx, activities.GetBillDetail, tenantID, billID).Get(ctx, &billDetail)
+	if err != nil {
+		workflow.GetLogger(ctx).Error("Failed to get final bill summary, failing workflow.", "Error", err, "BillID", billID)
+		return nil, err
+	}
 	return &billDetail, nil
 }
 
@@ -237,34 +559,110 @@ func ClosedBillPostProcessWorkflow(ctx workflow.Context, req *BillClosedPostProc
 	ctx = workflow.WithActivityOptions(ctx, ao)
 
 	var activities *Activities
+	if req.TenantID == "" {
+		req.TenantID = model.DefaultTenant
+	}
 
+	err := postProcessBill(ctx, req)
+	// Runs recorded before the post_process.failed event failed without it.
+	if err != nil && !temporal.IsCanceledError(err) &&
+		workflow.GetVersion(ctx, versionPostProcessFailedEvent, workflow.DefaultVersion, 1) != workflow.DefaultVersion {
+		if failErr := workflow.ExecuteActivity(ctx, activities.PostProcessFailed, req.TenantID, req.BillID, err.Error()).Get(ctx, nil); failErr != nil {
+			workflow.GetLogger(ctx).Error("Failed to send post_process.failed event.", "Error", failErr, "BillID", req.BillID)
+		}
+	}
+	return err
+}
+
+// postProcessBill renders and emails the invoice of a closed bill and waits for its payment.
+func postProcessBill(ctx workflow.Context, req *BillClosedPostProcessWorkflowRequest) error {
+	var activities *Activities
 	var billDetail BillResponse
-	err := workflow.ExecuteActivity(ctx, activities.GetBillDetail, req.BillID).Get(ctx, &billDetail)
+	err := workflow.ExecuteActivity(ctx, activities.GetBillDetail, req.TenantID, req.BillID).Get(ctx, &billDetail)
 	if err != nil {
 		workflow.GetLogger(ctx).Error("Failed to get final bill summary, failing workflow.", "Error", err, "BillID", req.BillID)
 		return err
 	}
 
-	err = workflow.ExecuteActivity(ctx, activities.GeneratePDFInvoive, req.BillID).Get(ctx, &billDetail)
+	err = workflow.ExecuteActivity(ctx, activities.GeneratePDFInvoive, req.TenantID, req.BillID).Get(ctx, &billDetail)
 	if err != nil {
 		workflow.GetLogger(ctx).Error("Failed to generate pdf invoice", "Error", err, "BillID", req.BillID)
 		return err
 	}
 
-	err = workflow.ExecuteActivity(ctx, activities.CreatePaymentLink, req.BillID).Get(ctx, &billDetail)
+	var link *model.PaymentLink
+	err = workflow.ExecuteActivity(ctx, activities.CreatePaymentLink, req.TenantID, req.BillID).Get(ctx, &link)
 	if err != nil {
 		workflow.GetLogger(ctx).Error("Failed to create payment link.", "Error", err, "BillID", req.BillID)
 		return err
 	}
 
-	err = workflow.ExecuteActivity(ctx, activities.SendBillEmail, req.BillID).Get(ctx, &billDetail)
+	err = workflow.ExecuteActivity(ctx, activities.SendBillEmail, req.TenantID, req.BillID).Get(ctx, &billDetail)
 	if err != nil {
 		workflow.GetLogger(ctx).Error("Failed to send email.", "Error", err, "BillID", req.BillID)
 		return err
 	}
 
+	if link != nil && link.Status == model.PaymentLinkPending {
+		if err := awaitPayment(ctx, req, link); err != nil {
+			return err
+		}
+	}
+
 	workflow.GetLogger(ctx).Info("Bill Post-process child workflow completed.", "BillID", req.BillID)
 	rlog.Info("Post process completed.")
 
 	return nil
 }
+
+// awaitPayment waits until the payment link is paid and settles the bill. A link that expires
+// unpaid is checked with the provider once more, in case its callback was lost, before it is
+// marked EXPIRED. Reopening the bill cancels the workflow while it waits.
+func awaitPayment(ctx workflow.Context, req *BillClosedPostProcessWorkflowRequest, link *model.PaymentLink) error {
+	var activities *Activities
+	settleChan := workflow.GetSignalChannel(ctx, SettleBillSignal)
+	timerCtx, cancelTimer := workflow.WithCancel(ctx)
+	defer cancelTimer()
+	untilExpiry := link.ExpiresAt.Sub(workflow.Now(ctx))
+	if untilExpiry < 0 {
+		untilExpiry = 0
+	}
+	expiryFuture := workflow.NewTimer(timerCtx, untilExpiry)
+
+	var settlement *SettleBillRequest
+	for settlement == nil {
+		expired := false
+		selector := workflow.NewSelector(ctx)
+		selector.AddReceive(settleChan, func(c workflow.ReceiveChannel, more bool) {
+			var signal SettleBillRequest
+			c.Receive(ctx, &signal)
+			if signal.LinkID != link.LinkID {
+				workflow.GetLogger(ctx).Warn("Ignored settlement of another payment link.", "BillID", req.BillID, "LinkID", signal.LinkID)
+				return
+			}
+			settlement = &signal
+		})
+		selector.AddFuture(expiryFuture, func(f workflow.Future) {
+			expired = true
+		})
+		selector.Select(ctx)
+
+		if expired && settlement == nil {
+			if err := workflow.ExecuteActivity(ctx, activities.ExpirePaymentLink, req.TenantID, link).Get(ctx, &settlement); err != nil {
+				workflow.GetLogger(ctx).Error("Failed to expire payment link.", "Error", err, "BillID", req.BillID)
+				return err
+			}
+			if settlement == nil {
+				workflow.GetLogger(ctx).Info("Payment link expired unpaid.", "BillID", req.BillID, "LinkID", link.LinkID)
+				return nil
+			}
+		}
+	}
+
+	if err := workflow.ExecuteActivity(ctx, activities.SettleBill, req.TenantID, *settlement).Get(ctx, nil); err != nil {
+		workflow.GetLogger(ctx).Error("Failed to settle bill.", "Error", err, "BillID", req.BillID)
+		return err
+	}
+	workflow.GetLogger(ctx).Info("Bill settled.", "BillID", req.BillID, "PaymentID", settlement.PaymentID)
+	return nil
+}
@@ -0,0 +1,183 @@
+package temporal
+
+import (
+	"testing"
+	"time"
+
+	"encore.app/fee/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.temporal.io/sdk/testsuite"
+)
+
+// newBillEnv returns a test environment for BillLifecycleWorkflow with the activities every run
+// uses mocked. The state the bill is closed with is recorded in closed.
+func newBillEnv(closed *BillState) *testsuite.TestWorkflowEnvironment {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	var a *Activities
+	env.RegisterActivity(a)
+	env.OnActivity(a.CreateBill, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity(a.ActivateBill, mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	env.OnActivity(a.CloseBillFromState, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { *closed = args.Get(2).(BillState) }).Return(nil).Maybe()
+	env.OnActivity(a.GetBillDetail, mock.Anything, mock.Anything, mock.Anything).
+		Return(&BillResponse{BillID: "bill-1", Status: string(model.BillStatusClosed)}, nil)
+	return env
+}
+
+func usageBillRequest(env *testsuite.TestWorkflowEnvironment) *BillLifecycleWorkflowRequest {
+	now := env.Now()
+	return &BillLifecycleWorkflowRequest{
+		TenantID:          model.DefaultTenant,
+		BillID:            "bill-1",
+		PolicyType:        model.UsageBased,
+		BilingPeriodStart: now,
+		BillingPeriodEnd:  now.Add(24 * time.Hour),
+		Currency:          "USD",
+	}
+}
+
+func TestBillLifecycle_RetriedExternalRefKeepsTotal(t *testing.T) {
+	var closed BillState
+	env := newBillEnv(&closed)
+	var a *Activities
+
+	// The first request adds the line item, the retry with the same external_ref resolves to it.
+	env.OnActivity(a.AddLineItem, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, "line-item-1", mock.Anything).
+		Return(model.AddedLineItem{LineItemID: "line-item-1", Inserted: true}, nil).Once()
+	env.OnActivity(a.AddLineItem, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, "line-item-1", mock.Anything).
+		Return(model.AddedLineItem{LineItemID: "line-item-1"}, nil).Once()
+
+	item := AddLineItemSignalRequest{
+		LineItemID: "line-item-1",
+		Amount:     100,
+		BillID:     "bill-1",
+		Metadata:   &model.LineItemMetadata{ExternalRef: "txn-123"},
+	}
+	var results []*AddLineItemUpdateResult
+	for i, id := range []string{"request-1", "request-2"} {
+		env.RegisterDelayedCallback(func() {
+			env.UpdateWorkflow(AddLineItemUpdate, id, &testsuite.TestUpdateCallback{
+				OnReject: func(err error) { assert.Fail(t, "update rejected", err.Error()) },
+				OnAccept: func() {},
+				OnComplete: func(result interface{}, err error) {
+					assert.NoError(t, err)
+					results = append(results, result.(*AddLineItemUpdateResult))
+				},
+			}, item)
+		}, time.Duration(i+1)*time.Minute)
+	}
+
+	env.ExecuteWorkflow(BillLifecycleWorkflow, usageBillRequest(env))
+
+	assert.True(t, env.IsWorkflowCompleted())
+	assert.NoError(t, env.GetWorkflowError())
+	if assert.Len(t, results, 2) {
+		assert.False(t, results[0].Duplicate)
+		assert.Equal(t, int64(100), results[0].Total)
+		assert.True(t, results[1].Duplicate)
+		assert.Equal(t, int64(100), results[1].Total)
+	}
+	assert.Equal(t, int64(100), closed.Total)
+	env.AssertExpectations(t)
+}
+
+func TestBillLifecycle_ScheduledBillDeadLettersEarlyLineItems(t *testing.T) {
+	var closed BillState
+	env := newBillEnv(&closed)
+	var a *Activities
+
+	var failed []AddLineItemSignalRequest
+	env.OnActivity(a.RecordFailedLineItem, mock.Anything, mock.Anything, model.FailedLineItemSourceSignal, mock.Anything, "bill has not started yet").
+		Run(func(args mock.Arguments) { failed = append(failed, args.Get(3).(AddLineItemSignalRequest)) }).Return(nil).Once()
+	env.OnActivity(a.CancelBill, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+
+	req := usageBillRequest(env)
+	req.BilingPeriodStart = env.Now().Add(time.Hour)
+	req.ScheduledLineItems = model.ScheduledLineItemsReject
+
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(AddLineItemsSignal, AddLineItemsSignalRequest{
+			BillID: "bill-1",
+			Actor:  "usage-events",
+			Items:  []AddLineItemSignalRequest{{LineItemID: "line-item-1", Amount: 100, BillID: "bill-1"}},
+		})
+	}, time.Minute)
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(CancelBillSignal, CancelBillRequest{BillID: "bill-1"})
+	}, 2*time.Minute)
+
+	env.ExecuteWorkflow(BillLifecycleWorkflow, req)
+
+	assert.True(t, env.IsWorkflowCompleted())
+	assert.NoError(t, env.GetWorkflowError())
+	if assert.Len(t, failed, 1) {
+		assert.Equal(t, "line-item-1", failed[0].LineItemID)
+		assert.Equal(t, "usage-events", failed[0].Actor)
+	}
+	env.AssertExpectations(t)
+}
+
+func TestBillLifecycle_CancelScheduledBillByUpdate(t *testing.T) {
+	var closed BillState
+	env := newBillEnv(&closed)
+	var a *Activities
+	env.OnActivity(a.CancelBill, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+
+	req := usageBillRequest(env)
+	req.BilingPeriodStart = env.Now().Add(time.Hour)
+
+	var cancelled *BillResponse
+	env.RegisterDelayedCallback(func() {
+		env.UpdateWorkflow(CancelBillUpdate, "cancel-1", &testsuite.TestUpdateCallback{
+			OnReject: func(err error) { assert.Fail(t, "update rejected", err.Error()) },
+			OnAccept: func() {},
+			OnComplete: func(result interface{}, err error) {
+				assert.NoError(t, err)
+				cancelled, _ = result.(*BillResponse)
+			},
+		}, CancelBillRequest{BillID: "bill-1"})
+	}, time.Minute)
+
+	env.ExecuteWorkflow(BillLifecycleWorkflow, req)
+
+	assert.True(t, env.IsWorkflowCompleted())
+	assert.NoError(t, env.GetWorkflowError())
+	assert.NotNil(t, cancelled)
+	env.AssertExpectations(t)
+	env.AssertActivityNotCalled(t, "ActivateBill", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestBillLifecycle_TimerFiringDuringPeriodChangeWaitsForIt(t *testing.T) {
+	var closed BillState
+	env := newBillEnv(&closed)
+	var a *Activities
+
+	req := usageBillRequest(env)
+	periodEnd := req.BillingPeriodEnd.Add(24 * time.Hour)
+	// The old period ends while the new one is being persisted.
+	env.OnActivity(a.SetBillPeriodEnd, mock.Anything, mock.Anything, mock.Anything, mock.MatchedBy(periodEnd.Equal)).
+		After(10 * time.Minute).Return(nil).Once()
+
+	var changed time.Time
+	env.RegisterDelayedCallback(func() {
+		env.UpdateWorkflow(SetPeriodEndUpdate, "period-1", &testsuite.TestUpdateCallback{
+			OnReject: func(err error) { assert.Fail(t, "update rejected", err.Error()) },
+			OnAccept: func() {},
+			OnComplete: func(result interface{}, err error) {
+				assert.NoError(t, err)
+				changed, _ = result.(time.Time)
+			},
+		}, SetPeriodEndRequest{BillID: "bill-1", BillingPeriodEnd: periodEnd})
+	}, 24*time.Hour-5*time.Minute)
+
+	env.ExecuteWorkflow(BillLifecycleWorkflow, req)
+
+	assert.True(t, env.IsWorkflowCompleted())
+	assert.NoError(t, env.GetWorkflowError())
+	assert.True(t, changed.Equal(periodEnd))
+	assert.False(t, env.Now().Before(periodEnd), "bill closed before its new period end")
+	env.AssertExpectations(t)
+}
@@ -0,0 +1,53 @@
+package fee
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	temporal "encore.app/fee/workflow"
+	"encore.dev/beta/errs"
+	"encore.dev/rlog"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
+	temporalsdk "go.temporal.io/sdk/temporal"
+)
+
+// updateBill sends an update to the bill workflow and waits for its outcome. Rejections
+// by the workflow's validators are returned as errs.Error with a matching code.
+func (s *Service) updateBill(ctx context.Context, billID, updateName string, arg, result interface{}) error {
+	handle, err := s.client.UpdateWorkflow(ctx, client.UpdateWorkflowOptions{
+		WorkflowID:   temporal.BillCycleWorkflowID(requestTenant(), billID),
+		UpdateName:   updateName,
+		Args:         []interface{}{arg},
+		WaitForStage: client.WorkflowUpdateStageCompleted,
+	})
+	if err == nil {
+		err = handle.Get(ctx, result)
+	}
+	if err == nil {
+		return nil
+	}
+
+	var notFound *serviceerror.NotFound
+	if errors.As(err, &notFound) || errors.Is(err, sql.ErrNoRows) {
+		return &errs.Error{
+			Code:    errs.NotFound,
+			Message: "bill not found or already closed",
+		}
+	}
+	var appErr *temporalsdk.ApplicationError
+	if errors.As(err, &appErr) {
+		switch appErr.Type() {
+		case temporal.ErrTypeNotFound:
+			return &errs.Error{Code: errs.NotFound, Message: "line item not found"}
+		case temporal.ErrTypeInvalidLineItem, temporal.ErrTypeInvalidPeriod:
+			return &errs.Error{Code: errs.InvalidArgument, Message: appErr.Message()}
+		case temporal.ErrTypeBillClosing, temporal.ErrTypeBillNotStarted, temporal.ErrTypePolicyForbidden,
+			temporal.ErrTypeUnresolvedFailures, temporal.ErrTypeAlreadyResolved:
+			return &errs.Error{Code: errs.FailedPrecondition, Message: appErr.Message()}
+		}
+	}
+	rlog.Error("failed to update bill workflow", "error", err, "bill_id", billID, "update", updateName)
+	return err
+}